@@ -0,0 +1,191 @@
+package uaa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"golang.org/x/oauth2"
+)
+
+// LoginMethod is the way a user logs in with a LoginProvider.
+type LoginMethod string
+
+// Valid LoginMethod values.
+const (
+	// LoginMethodPassword logs in with a username and password, using the
+	// password grant.
+	LoginMethodPassword = LoginMethod("password")
+	// LoginMethodPasscode logs in with a one-time passcode that the user
+	// obtains from the UAA's passcode page.
+	LoginMethodPasscode = LoginMethod("passcode")
+	// LoginMethodBrowser logs in through the provider's pages in a browser,
+	// using the authorization code grant.
+	LoginMethodBrowser = LoginMethod("browser")
+)
+
+// LoginProvider is a way to log in to a zone, offered to users so that they
+// can choose between their accounts.
+type LoginProvider struct {
+	// Origin is the origin key of the identity provider, or "" for the
+	// passcode, which can be obtained through any provider.
+	Origin string
+	Name   string
+	Method LoginMethod
+	// URL is the page on which the user logs in with the provider, or
+	// obtains a passcode.
+	URL string
+}
+
+// LoginProvidersFromInfo returns the ways to log in that the UAA's /info
+// advertises: a username and password, if the zone prompts for them (users
+// of the uaa and LDAP providers log in this way), each SAML and OAuth
+// provider shown on the login page, and a passcode, if the zone offers one.
+func LoginProvidersFromInfo(info *Info) []LoginProvider {
+	var providers []LoginProvider
+	_, username := info.Prompts["username"]
+	_, password := info.Prompts["password"]
+	if username && password {
+		providers = append(providers, LoginProvider{Origin: string(UAAOrigin), Name: "Username and password", Method: LoginMethodPassword, URL: info.Links.Login})
+	}
+	var browser []LoginProvider
+	for origin, link := range info.IdpDefinitions {
+		browser = append(browser, LoginProvider{Origin: origin, Name: origin, Method: LoginMethodBrowser, URL: link})
+	}
+	for origin, link := range info.OAuthLinks {
+		browser = append(browser, LoginProvider{Origin: origin, Name: origin, Method: LoginMethodBrowser, URL: link})
+	}
+	sort.Slice(browser, func(i, j int) bool { return browser[i].Origin < browser[j].Origin })
+	providers = append(providers, browser...)
+	if prompt, ok := info.Prompts["passcode"]; ok {
+		name := "One-time passcode"
+		if len(prompt) > 1 {
+			name = prompt[1]
+		}
+		provider := LoginProvider{Name: name, Method: LoginMethodPasscode}
+		if info.Links.Login != "" {
+			provider.URL = info.Links.Login + "/passcode"
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// LoginProviders returns the ways to log in to the zone; see
+// LoginProvidersFromInfo.
+func (a *API) LoginProviders() ([]LoginProvider, error) {
+	info, err := a.GetInfo()
+	if err != nil {
+		return nil, err
+	}
+	providers := LoginProvidersFromInfo(info)
+	for i := range providers {
+		if providers[i].Method == LoginMethodPasscode && providers[i].URL == "" {
+			providers[i].URL = a.PasscodeURL()
+		}
+	}
+	return providers, nil
+}
+
+// AccountChooser logs a user in from a command line program after letting
+// them choose how: it lists the zone's LoginProviders, asks Choose to pick
+// one, and then logs in with the provider's method, asking Credentials for a
+// username and password, asking Passcode for a passcode, or opening a
+// browser as BrowserLogin does.
+type AccountChooser struct {
+	// BrowserLogin holds the target, the client, and the settings for
+	// logging in with a browser.
+	BrowserLogin
+
+	// Choose picks the provider to log in with, typically by showing the
+	// user a menu. If only one provider is offered it is used without
+	// calling Choose.
+	Choose func(providers []LoginProvider) (LoginProvider, error)
+	// Credentials returns the username and password for the provider.
+	Credentials func(provider LoginProvider) (username string, password string, err error)
+	// Passcode returns the passcode that the user obtained from the
+	// provider's URL.
+	Passcode func(provider LoginProvider) (string, error)
+}
+
+// Login lets the user choose a provider and logs in with it. Password logins
+// request a token straight away, so that wrong credentials are reported by
+// Login rather than by the first request.
+func (c *AccountChooser) Login(ctx context.Context) (*API, error) {
+	target, err := BuildTargetURL(c.Target)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: http.DefaultTransport}
+	lister := &API{
+		UnauthenticatedClient: client,
+		AuthenticatedClient:   client,
+		TargetURL:             target,
+		ZoneID:                c.ZoneID,
+	}
+	WithSkipSSLValidation(c.SkipSSLValidation)(lister)
+	providers, err := lister.LoginProviders()
+	if err != nil {
+		return nil, err
+	}
+	if len(providers) == 0 {
+		return nil, errors.New("the zone does not offer any way to log in")
+	}
+	provider := providers[0]
+	if len(providers) > 1 {
+		if c.Choose == nil {
+			return nil, errors.New("Choose must be set to choose between login providers")
+		}
+		if provider, err = c.Choose(providers); err != nil {
+			return nil, err
+		}
+	}
+	return c.LoginWith(ctx, provider)
+}
+
+// LoginWith logs in with the given provider.
+func (c *AccountChooser) LoginWith(ctx context.Context, provider LoginProvider) (*API, error) {
+	switch provider.Method {
+	case LoginMethodPassword:
+		if c.Credentials == nil {
+			return nil, errors.New("Credentials must be set to log in with a password")
+		}
+		username, password, err := c.Credentials(provider)
+		if err != nil {
+			return nil, err
+		}
+		opts := append([]Option{WithSkipSSLValidation(c.SkipSSLValidation)}, c.Options...)
+		if provider.Origin != "" && provider.Origin != string(UAAOrigin) {
+			opts = append([]Option{WithLoginHint(Origin(provider.Origin))}, opts...)
+		}
+		a, err := NewWithPasswordCredentials(c.Target, c.ZoneID, c.ClientID, c.ClientSecret, username, password, c.TokenFormat, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := a.Token(); err != nil {
+			if credentialsErr := badCredentials(err); credentialsErr != nil {
+				return nil, credentialsErr
+			}
+			return nil, err
+		}
+		return a, nil
+	case LoginMethodPasscode:
+		if c.Passcode == nil {
+			return nil, errors.New("Passcode must be set to log in with a passcode")
+		}
+		passcode, err := c.Passcode(provider)
+		if err != nil {
+			return nil, err
+		}
+		return NewWithPasscode(c.Target, c.ZoneID, c.ClientID, c.ClientSecret, passcode, c.TokenFormat, c.Options...)
+	case LoginMethodBrowser:
+		login := c.BrowserLogin
+		if provider.Origin != "" {
+			login.AuthCodeOptions = append([]oauth2.AuthCodeOption{LoginHintParam(Origin(provider.Origin))}, login.AuthCodeOptions...)
+		}
+		return login.Login(ctx)
+	}
+	return nil, fmt.Errorf("unknown login method %q", provider.Method)
+}
@@ -0,0 +1,147 @@
+package uaa_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestAccountChooser(t *testing.T) {
+	spec.Run(t, "AccountChooser", testAccountChooser, spec.Report(report.Terminal{}))
+}
+
+func testAccountChooser(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s         *httptest.Server
+		info      map[string]interface{}
+		tokenForm url.Values
+		authorize url.Values
+		chooser   *uaa.AccountChooser
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		tokenForm = nil
+		authorize = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch req.URL.Path {
+			case "/info":
+				json.NewEncoder(w).Encode(info)
+			case "/oauth/authorize":
+				authorize = req.URL.Query()
+				redirect, _ := url.Parse(authorize.Get("redirect_uri"))
+				redirect.RawQuery = url.Values{"code": {"code"}, "state": {authorize.Get("state")}}.Encode()
+				http.Redirect(w, req, redirect.String(), http.StatusFound)
+			case "/oauth/token":
+				req.ParseForm()
+				tokenForm = req.PostForm
+				if req.PostForm.Get("password") == "wrong" {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error":"unauthorized","error_description":"Bad credentials"}`))
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "refresh_token": "refresh", "token_type": "bearer", "expires_in": 3600})
+			}
+		}))
+		info = map[string]interface{}{
+			"links": map[string]string{"login": s.URL},
+			"prompts": map[string][]string{
+				"username": {"text", "Email"},
+				"password": {"password", "Password"},
+				"passcode": {"password", "Temporary Authentication Code"},
+			},
+			"idpDefinitions": map[string]string{"okta": s.URL + "/saml/discovery?idp=okta"},
+			"oauthLinks":     map[string]string{"github": s.URL + "/oauth/authorize?origin=github"},
+		}
+		chooser = &uaa.AccountChooser{
+			BrowserLogin: uaa.BrowserLogin{
+				Target:   s.URL,
+				ClientID: "cf",
+				Browser: uaa.BrowserFunc(func(u string) error {
+					go func() {
+						if resp, err := http.Get(u); err == nil {
+							resp.Body.Close()
+						}
+					}()
+					return nil
+				}),
+			},
+			Credentials: func(uaa.LoginProvider) (string, string, error) { return "marcus", "meditations", nil },
+			Passcode:    func(uaa.LoginProvider) (string, error) { return "abc123", nil },
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("lists the ways to log in", func() {
+		c, _ := url.Parse(s.URL)
+		providers, err := (&uaa.API{TargetURL: c, UnauthenticatedClient: http.DefaultClient, AuthenticatedClient: http.DefaultClient}).LoginProviders()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(providers).To(Equal([]uaa.LoginProvider{
+			{Origin: "uaa", Name: "Username and password", Method: uaa.LoginMethodPassword, URL: s.URL},
+			{Origin: "github", Name: "github", Method: uaa.LoginMethodBrowser, URL: s.URL + "/oauth/authorize?origin=github"},
+			{Origin: "okta", Name: "okta", Method: uaa.LoginMethodBrowser, URL: s.URL + "/saml/discovery?idp=okta"},
+			{Name: "Temporary Authentication Code", Method: uaa.LoginMethodPasscode, URL: s.URL + "/passcode"},
+		}))
+	})
+
+	it("logs in with the password provider", func() {
+		var offered []uaa.LoginProvider
+		chooser.Choose = func(providers []uaa.LoginProvider) (uaa.LoginProvider, error) {
+			offered = providers
+			return providers[0], nil
+		}
+		api, err := chooser.Login(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(api).NotTo(BeNil())
+		Expect(offered).To(HaveLen(4))
+		Expect(tokenForm.Get("username")).To(Equal("marcus"))
+		Expect(tokenForm).NotTo(HaveKey("login_hint"))
+	})
+
+	it("reports wrong passwords", func() {
+		chooser.Credentials = func(uaa.LoginProvider) (string, string, error) { return "marcus", "wrong", nil }
+		_, err := chooser.LoginWith(context.Background(), uaa.LoginProvider{Origin: "ldap", Method: uaa.LoginMethodPassword})
+		Expect(err).To(Equal(uaa.ErrBadCredentials{Code: "unauthorized", Description: "Bad credentials"}))
+		Expect(tokenForm.Get("login_hint")).To(Equal(`{"origin":"ldap"}`))
+	})
+
+	it("logs in with a passcode", func() {
+		_, err := chooser.LoginWith(context.Background(), uaa.LoginProvider{Method: uaa.LoginMethodPasscode})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tokenForm.Get("passcode")).To(Equal("abc123"))
+	})
+
+	it("logs in with the chosen provider in a browser", func() {
+		chooser.Choose = func(providers []uaa.LoginProvider) (uaa.LoginProvider, error) {
+			return providers[2], nil
+		}
+		_, err := chooser.Login(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authorize.Get("login_hint")).To(Equal(`{"origin":"okta"}`))
+		Expect(tokenForm.Get("code")).To(Equal("code"))
+	})
+
+	it("uses the only provider without asking", func() {
+		info["prompts"] = map[string][]string{}
+		delete(info, "oauthLinks")
+		chooser.Choose = func([]uaa.LoginProvider) (uaa.LoginProvider, error) {
+			return uaa.LoginProvider{}, errors.New("should not be called")
+		}
+		_, err := chooser.Login(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authorize.Get("login_hint")).To(Equal(`{"origin":"okta"}`))
+	})
+}
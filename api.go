@@ -2,11 +2,14 @@ package uaa
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudfoundry-community/go-uaa/passwordcredentials"
@@ -17,6 +20,12 @@ import (
 //go:generate go run ./generator/generator.go
 
 // API is a client to the UAA API.
+//
+// An API is safe for concurrent use by multiple goroutines once it has been
+// built. Its exported fields and its options are configuration: set them
+// before sharing the API, except Verbose, which can be changed at any time
+// with SetVerbose. The state an API keeps while in use, such as its tokens,
+// dry run log and circuit breaker, is synchronized internally.
 type API struct {
 	AuthenticatedClient   *http.Client
 	UnauthenticatedClient *http.Client
@@ -24,6 +33,53 @@ type API struct {
 	SkipSSLValidation     bool
 	Verbose               bool
 	ZoneID                string
+
+	dryRun          *dryRunLog
+	readOnly        bool
+	requestHooks    []func(*http.Request)
+	responseHooks   []func(*http.Response, error, time.Duration)
+	circuitBreaker  *CircuitBreaker
+	pageConcurrency int
+	tokenParams     url.Values
+
+	lazyAuthentication bool
+	requestToken       func(params url.Values) (*oauth2.Token, error)
+	impersonation      bool
+	scopePreflight     bool
+	pacer              *Pacer
+	progress           ProgressFunc
+
+	readAfterWriteAttempts int
+	readAfterWriteDelay    time.Duration
+
+	expiryLeeway time.Duration
+	maxClockSkew time.Duration
+	clock        Clock
+	keepAlive    *keepAlive
+	tokenEvents  func(TokenEvent)
+
+	narrowedScopes func(ScopeGrant)
+
+	secretRotation *secretRotation
+	mfaCode        MFACodeFunc
+	tokenStore     TokenStore
+
+	maxResponseSize int64
+	connectionStats bool
+	paths           *pathRewriter
+	listTimeout     time.Duration
+
+	noContextPathDetection bool
+	restrictedScopeCheck   bool
+
+	proxyURL        *url.URL
+	fallbackTargets []*url.URL
+	pinnedAddresses []string
+
+	auth *AuthConfig
+
+	mu                sync.RWMutex
+	configuredClients map[*http.Client]bool
 }
 
 // TokenFormat is the format of a token.
@@ -45,6 +101,49 @@ func (t TokenFormat) String() string {
 	return ""
 }
 
+// ParseTokenFormat returns the TokenFormat with the given name, "opaque" or
+// "jwt".
+func ParseTokenFormat(s string) (TokenFormat, error) {
+	switch s {
+	case "opaque":
+		return OpaqueToken, nil
+	case "jwt":
+		return JSONWebToken, nil
+	}
+	return OpaqueToken, fmt.Errorf("unknown token format %q, must be opaque or jwt", s)
+}
+
+// Validate returns an error if the token format is not OpaqueToken or
+// JSONWebToken.
+func (t TokenFormat) Validate() error {
+	if t.String() == "" {
+		return fmt.Errorf("unknown token format %d", int(t))
+	}
+	return nil
+}
+
+// MarshalJSON encodes the token format by name.
+func (t TokenFormat) MarshalJSON() ([]byte, error) {
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON decodes the token format from its name.
+func (t *TokenFormat) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	format, err := ParseTokenFormat(s)
+	if err != nil {
+		return err
+	}
+	*t = format
+	return nil
+}
+
 type tokenTransport struct {
 	underlyingTransport *http.Transport
 	token               oauth2.Token
@@ -55,12 +154,55 @@ func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	return t.underlyingTransport.RoundTrip(req)
 }
 
+// Token returns the token used to authenticate requests to the UAA API. If
+// the API obtains its own tokens, a token is requested or refreshed as needed.
+func (a *API) Token() (*oauth2.Token, error) {
+	if a.AuthenticatedClient == nil {
+		return nil, errors.New("the API does not have an authenticated client")
+	}
+	// The token may be fetched with the unauthenticated client.
+	a.ensureTransport(a.UnauthenticatedClient)
+	switch t := unwrapTransport(a.AuthenticatedClient.Transport).(type) {
+	case *oauth2.Transport:
+		return t.Source.Token()
+	case *tokenTransport:
+		token := t.token
+		return &token, nil
+	}
+	return nil, errors.New("the API's authenticated client does not use a token")
+}
+
+// IDToken returns the OpenID Connect ID token that was issued along with the
+// token, or "" if there is none. An ID token is issued when the openid scope
+// is requested with the authorization code or password grants.
+func IDToken(token *oauth2.Token) string {
+	idToken, _ := token.Extra("id_token").(string)
+	return idToken
+}
+
+// GrantedScopes returns the scopes that were granted with the token, which
+// may be fewer than were requested.
+func GrantedScopes(token *oauth2.Token) []string {
+	scope, _ := token.Extra("scope").(string)
+	return strings.Fields(scope)
+}
+
+// TokenSource returns an oauth2.TokenSource that supplies the API's token.
+func (a *API) TokenSource() oauth2.TokenSource {
+	return apiTokenSource{api: a}
+}
+
+type apiTokenSource struct {
+	api *API
+}
+
+func (s apiTokenSource) Token() (*oauth2.Token, error) {
+	return s.api.Token()
+}
+
 // NewWithToken builds an API that uses the given token to make authenticated
 // requests to the UAA API.
-func NewWithToken(target string, zoneID string, token oauth2.Token) (*API, error) {
-	if token.AccessToken == "" || token.Expiry.Before(time.Now()) {
-		return nil, errors.New("must supply a valid token")
-	}
+func NewWithToken(target string, zoneID string, token oauth2.Token, opts ...Option) (*API, error) {
 	u, err := BuildTargetURL(target)
 	if err != nil {
 		return nil, err
@@ -85,17 +227,26 @@ func NewWithToken(target string, zoneID string, token oauth2.Token) (*API, error
 	}
 
 	client := &http.Client{Transport: http.DefaultTransport}
-	return &API{
+	a := &API{
 		UnauthenticatedClient: client,
 		AuthenticatedClient:   tokenClient,
 		TargetURL:             u,
 		ZoneID:                zoneID,
-	}, nil
+		auth:                  &AuthConfig{Method: AuthToken},
+	}
+	a.applyOptions(opts)
+	if token.AccessToken == "" || token.Expiry.Before(a.getClock().Now()) {
+		return nil, errors.New("must supply a valid token")
+	}
+	return a, nil
 }
 
 // NewWithClientCredentials builds an API that uses the client credentials grant
 // to get a token for use with the UAA API.
-func NewWithClientCredentials(target string, zoneID string, clientID string, clientSecret string, tokenFormat TokenFormat) (*API, error) {
+func NewWithClientCredentials(target string, zoneID string, clientID string, clientSecret string, tokenFormat TokenFormat, opts ...Option) (*API, error) {
+	if err := tokenFormat.Validate(); err != nil {
+		return nil, err
+	}
 	u, err := BuildTargetURL(target)
 	if err != nil {
 		return nil, err
@@ -111,17 +262,51 @@ func NewWithClientCredentials(target string, zoneID string, clientID string, cli
 		EndpointParams: v,
 	}
 	client := &http.Client{Transport: http.DefaultTransport}
-	return &API{
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	a := &API{
 		UnauthenticatedClient: client,
-		AuthenticatedClient:   c.Client(context.WithValue(context.Background(), oauth2.HTTPClient, client)),
+		AuthenticatedClient:   c.Client(ctx),
 		TargetURL:             u,
 		ZoneID:                zoneID,
-	}, nil
+		tokenParams:           v,
+		auth:                  &AuthConfig{Method: AuthClientCredentials, ClientID: clientID, TokenFormat: tokenFormat},
+	}
+	a.requestToken = func(params url.Values) (*oauth2.Token, error) {
+		config := *c
+		config.ClientSecret = a.clientSecret(clientSecret)
+		config.EndpointParams = params
+		return config.TokenSource(ctx).Token()
+	}
+	a.applyOptions(opts)
+	a.installExpiryTokenSource(a.withRotatedSecret(clientSecret, func(secret string) (*oauth2.Token, error) {
+		config := *c
+		config.ClientSecret = secret
+		return config.TokenSource(ctx).Token()
+	}))
+	return a, nil
+}
+
+// NewForZoneSubdomain builds an API for the identity zone with the given
+// subdomain, using the client credentials grant to get a token from the zone.
+// The zone is addressed by its subdomain URL (see BuildZoneSubdomainURL)
+// rather than by the X-Identity-Zone-Id header, so the client must be
+// registered in the zone.
+func NewForZoneSubdomain(target string, subdomain string, clientID string, clientSecret string, tokenFormat TokenFormat, opts ...Option) (*API, error) {
+	u, err := BuildZoneSubdomainURL(target, subdomain)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithClientCredentials(u.String(), "", clientID, clientSecret, tokenFormat, opts...)
 }
 
 // NewWithPasswordCredentials builds an API that uses the password credentials
-// grant to get a token for use with the UAA API.
-func NewWithPasswordCredentials(target string, zoneID string, clientID string, clientSecret string, username string, password string, tokenFormat TokenFormat) (*API, error) {
+// grant to get a token for use with the UAA API. If the UAA enforces
+// multi-factor authentication for the user, supply the code with WithMFACode
+// or WithMFACodePrompt.
+func NewWithPasswordCredentials(target string, zoneID string, clientID string, clientSecret string, username string, password string, tokenFormat TokenFormat, opts ...Option) (*API, error) {
+	if err := tokenFormat.Validate(); err != nil {
+		return nil, err
+	}
 	u, err := BuildTargetURL(target)
 	if err != nil {
 		return nil, err
@@ -141,12 +326,28 @@ func NewWithPasswordCredentials(target string, zoneID string, clientID string, c
 		EndpointParams: v,
 	}
 	client := &http.Client{Transport: http.DefaultTransport}
-	return &API{
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	a := &API{
 		UnauthenticatedClient: client,
-		AuthenticatedClient:   c.Client(context.WithValue(context.Background(), oauth2.HTTPClient, client)),
 		TargetURL:             u,
 		ZoneID:                zoneID,
-	}, nil
+		tokenParams:           v,
+		auth:                  &AuthConfig{Method: AuthPassword, ClientID: clientID, Username: username, TokenFormat: tokenFormat},
+	}
+	a.AuthenticatedClient = oauth2.NewClient(ctx, oauth2.ReuseTokenSource(nil, passwordTokenSource{api: a, ctx: ctx, config: c}))
+	a.requestToken = func(params url.Values) (*oauth2.Token, error) {
+		config := *c
+		config.ClientSecret = a.clientSecret(clientSecret)
+		config.EndpointParams = params
+		return a.passwordToken(ctx, config)
+	}
+	a.applyOptions(opts)
+	a.installExpiryTokenSource(a.withRotatedSecret(clientSecret, func(secret string) (*oauth2.Token, error) {
+		config := *c
+		config.ClientSecret = secret
+		return a.passwordToken(ctx, config)
+	}))
+	return a, nil
 }
 
 // NewWithAuthorizationCode builds an API that uses the authorization code
@@ -158,15 +359,33 @@ func NewWithPasswordCredentials(target string, zoneID string, clientID string, c
 // If you do not supply an http.Client,
 //  http.Client{Transport: http.DefaultTransport}
 // will be used.
-func NewWithAuthorizationCode(target string, zoneID string, clientID string, clientSecret string, code string, skipSSLValidation bool, tokenFormat TokenFormat) (*API, error) {
+//
+// The token obtained by exchanging the code, including the ID token and the
+// granted scopes, is returned by the API's Token method; see IDToken and
+// GrantedScopes.
+func NewWithAuthorizationCode(target string, zoneID string, clientID string, clientSecret string, code string, skipSSLValidation bool, tokenFormat TokenFormat, opts ...Option) (*API, error) {
+	if err := tokenFormat.Validate(); err != nil {
+		return nil, err
+	}
 	url, err := BuildTargetURL(target)
 	if err != nil {
 		return nil, err
 	}
 
+	client := &http.Client{Transport: http.DefaultTransport}
+	a := &API{
+		UnauthenticatedClient: client,
+		TargetURL:             url,
+		ZoneID:                zoneID,
+	}
+	a.applyOptions(append([]Option{WithSkipSSLValidation(skipSSLValidation)}, opts...))
+
 	tokenURL := urlWithPath(*url, "/oauth/token")
 
 	query := tokenURL.Query()
+	for key, values := range a.tokenParams {
+		query[key] = values
+	}
 	query.Set("token_format", tokenFormat.String())
 	tokenURL.RawQuery = query.Encode()
 
@@ -178,21 +397,22 @@ func NewWithAuthorizationCode(target string, zoneID string, clientID string, cli
 		},
 	}
 
-	client := &http.Client{Transport: http.DefaultTransport}
-	a := &API{
-		UnauthenticatedClient: client,
-		TargetURL:             url,
-		SkipSSLValidation:     skipSSLValidation,
-		ZoneID:                zoneID,
-	}
-	a.ensureTransport(a.UnauthenticatedClient)
 	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, a.UnauthenticatedClient)
+	if a.lazyAuthentication {
+		source := &exchangeTokenSource{config: c, ctx: ctx, code: code}
+		a.AuthenticatedClient = oauth2.NewClient(ctx, oauth2.ReuseTokenSource(nil, source))
+		a.requestToken = refreshTokenRequester(ctx, a, c)
+		a.installExpiryTokenSource(refreshWithToken(ctx, c))
+		return a, nil
+	}
 	t, err := c.Exchange(ctx, code)
 	if err != nil {
 		return nil, err
 	}
 
 	a.AuthenticatedClient = c.Client(ctx, t)
+	a.requestToken = refreshTokenRequester(ctx, a, c)
+	a.installExpiryTokenSource(refreshWithToken(ctx, c))
 
 	return a, nil
 }
@@ -34,6 +34,51 @@ func testUAA(t *testing.T, when spec.G, it spec.S) {
 			Expect(uaa.OpaqueToken.String()).To(Equal("opaque"))
 		})
 	})
+
+	when("ParseTokenFormat()", func() {
+		it("parses the known formats", func() {
+			Expect(uaa.ParseTokenFormat("jwt")).To(Equal(uaa.JSONWebToken))
+			Expect(uaa.ParseTokenFormat("opaque")).To(Equal(uaa.OpaqueToken))
+		})
+
+		it("rejects unknown formats", func() {
+			_, err := uaa.ParseTokenFormat("JWT ")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	when("TokenFormat JSON", func() {
+		it("round-trips by name", func() {
+			j, err := json.Marshal(struct {
+				Format uaa.TokenFormat `json:"format"`
+			}{uaa.JSONWebToken})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j).To(MatchJSON(`{"format":"jwt"}`))
+
+			var decoded struct {
+				Format uaa.TokenFormat `json:"format"`
+			}
+			Expect(json.Unmarshal([]byte(`{"format":"opaque"}`), &decoded)).To(Succeed())
+			Expect(decoded.Format).To(Equal(uaa.OpaqueToken))
+			Expect(json.Unmarshal([]byte(`{"format":"saml"}`), &decoded)).NotTo(Succeed())
+		})
+
+		it("refuses to encode an unknown format", func() {
+			_, err := json.Marshal(uaa.TokenFormat(3))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	when("building an API with an unknown token format", func() {
+		it("fails", func() {
+			_, err := uaa.NewWithClientCredentials("https://uaa.example.com", "", "client", "secret", uaa.TokenFormat(3))
+			Expect(err).To(HaveOccurred())
+			_, err = uaa.NewWithPasswordCredentials("https://uaa.example.com", "", "client", "secret", "user", "pass", uaa.TokenFormat(3))
+			Expect(err).To(HaveOccurred())
+			_, err = uaa.NewForZoneSubdomain("https://uaa.example.com", "zone", "client", "secret", uaa.TokenFormat(3))
+			Expect(err).To(HaveOccurred())
+		})
+	})
 }
 
 func TestNew(t *testing.T) {
@@ -93,6 +138,37 @@ func testNew(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("Token()", func() {
+		it("returns the token of an API built with a token", func() {
+			api, err := uaa.NewWithToken("https://example.net", "", oauth2.Token{Expiry: time.Now().Add(10 * time.Second), AccessToken: "test-token"})
+			Expect(err).NotTo(HaveOccurred())
+			token, err := api.TokenSource().Token()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token.AccessToken).To(Equal("test-token"))
+		})
+
+		it("requests a token for an API built with client credentials", func() {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.URL.Path).To(Equal("/oauth/token"))
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"client-token","token_type":"bearer","expires_in":3600}`))
+			}))
+			defer s.Close()
+			api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.OpaqueToken)
+			Expect(err).NotTo(HaveOccurred())
+			token, err := api.Token()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token.AccessToken).To(Equal("client-token"))
+		})
+
+		it("returns an error when the API has no token", func() {
+			api := &uaa.API{AuthenticatedClient: &http.Client{Transport: http.DefaultTransport}}
+			token, err := api.Token()
+			Expect(err).To(HaveOccurred())
+			Expect(token).To(BeNil())
+		})
+	})
+
 	when("NewWithClientCredentials()", func() {
 		it("fails if the target url is invalid", func() {
 			api, err := uaa.NewWithClientCredentials("(*#&^@%$&%)", "", "", "", uaa.OpaqueToken)
@@ -115,6 +191,21 @@ func testNew(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("NewForZoneSubdomain()", func() {
+		it("fails if the subdomain is invalid", func() {
+			api, err := uaa.NewForZoneSubdomain("https://example.net", "zone_1", "", "", uaa.OpaqueToken)
+			Expect(err).To(HaveOccurred())
+			Expect(api).To(BeNil())
+		})
+
+		it("returns an API targeting the zone's subdomain", func() {
+			api, err := uaa.NewForZoneSubdomain("https://example.net:8443", "zone1", "", "", uaa.OpaqueToken)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(api.TargetURL.String()).To(Equal("https://zone1.example.net:8443"))
+			Expect(api.ZoneID).To(BeEmpty())
+		})
+	})
+
 	when("NewWithPasswordCredentials()", func() {
 		it("fails if the target url is invalid", func() {
 			api, err := uaa.NewWithPasswordCredentials("(*#&^@%$&%)", "", "", "", "", "", uaa.OpaqueToken)
@@ -190,6 +281,21 @@ func testNew(t *testing.T, when spec.G, it spec.S) {
 			Expect(api.AuthenticatedClient).NotTo(BeNil())
 		})
 
+		it("exposes the exchanged token with its ID token and scopes", func() {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"test-access-token","id_token":"test-id-token","scope":"openid profile","token_type":"bearer","expires_in":3600}`))
+			}))
+			defer s.Close()
+			api, err := uaa.NewWithAuthorizationCode(s.URL, "", "", "", "", false, uaa.OpaqueToken)
+			Expect(err).NotTo(HaveOccurred())
+			token, err := api.Token()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token.AccessToken).To(Equal("test-access-token"))
+			Expect(uaa.IDToken(token)).To(Equal("test-id-token"))
+			Expect(uaa.GrantedScopes(token)).To(Equal([]string{"openid", "profile"}))
+		})
+
 		it("returns an error if the token cannot be retrieved", func() {
 			returnToken = false
 			api, err := uaa.NewWithAuthorizationCode(s.URL, "", "", "", "", false, uaa.OpaqueToken)
@@ -0,0 +1,109 @@
+package uaa
+
+import (
+	"errors"
+	"time"
+)
+
+// ScopesRequiringApproval returns the scopes that the UAA asks users to
+// approve when they authorize the client, because the client does not
+// auto-approve them: those of the requested scopes that the client is
+// allowed, or all of its scopes if none are requested. Users who have
+// already approved a scope are not asked again; see API.ApprovalPrompt.
+func (c Client) ScopesRequiringApproval(requested ...string) []string {
+	prompted := []string{}
+	for _, scope := range c.allowedScopes(requested) {
+		if !c.AutoApprove.Approves(scope) {
+			prompted = append(prompted, scope)
+		}
+	}
+	return prompted
+}
+
+// allowedScopes returns the requested scopes that the client is allowed, or
+// all of its scopes if none are requested.
+func (c Client) allowedScopes(requested []string) []string {
+	if len(requested) == 0 {
+		return c.Scope
+	}
+	var allowed []string
+	for _, scope := range requested {
+		for _, pattern := range c.Scope {
+			if ScopeMatches(pattern, scope) {
+				allowed = append(allowed, scope)
+				break
+			}
+		}
+	}
+	return allowed
+}
+
+// ApprovalPrompt explains which scopes a user is asked to approve when they
+// authorize a client, for debugging unexpected approval pages.
+type ApprovalPrompt struct {
+	// Prompted are the scopes the user is asked to approve. If there are
+	// none, the approval page is not shown.
+	Prompted []string
+	// AutoApproved are the scopes the client's autoapprove setting
+	// approves.
+	AutoApproved []string
+	// PreviouslyApproved are the scopes the user has approved before, and
+	// whose approval has not expired.
+	PreviouslyApproved []string
+	// NotAllowed are the requested scopes that the client is not allowed,
+	// which the UAA drops from the request.
+	NotAllowed []string
+}
+
+// ApprovalPrompt returns the scopes that the user with the given ID is asked
+// to approve when authorizing the client with the given ID, along with the
+// reason each of the other scopes is not prompted for. If no scopes are
+// given, the client's scopes are considered. Approvals that the user denied
+// are asked for again.
+func (a *API) ApprovalPrompt(clientID string, userID string, scopes []string) (*ApprovalPrompt, error) {
+	if clientID == "" || userID == "" {
+		return nil, errors.New("clientID and userID cannot be blank")
+	}
+	client, err := a.GetClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := a.getClock().Now()
+	approved := map[string]bool{}
+	for _, approval := range user.Approvals {
+		if approval.ClientID == clientID && approval.Status == "APPROVED" && !approvalExpired(approval, now) {
+			approved[approval.Scope] = true
+		}
+	}
+
+	prompt := &ApprovalPrompt{}
+	allowed := client.allowedScopes(scopes)
+	for _, scope := range scopes {
+		if !contains(allowed, scope) {
+			prompt.NotAllowed = append(prompt.NotAllowed, scope)
+		}
+	}
+	for _, scope := range allowed {
+		switch {
+		case client.AutoApprove.Approves(scope):
+			prompt.AutoApproved = append(prompt.AutoApproved, scope)
+		case approved[scope]:
+			prompt.PreviouslyApproved = append(prompt.PreviouslyApproved, scope)
+		default:
+			prompt.Prompted = append(prompt.Prompted, scope)
+		}
+	}
+	return prompt, nil
+}
+
+// approvalExpired returns true if the approval's expiry has passed. An
+// expiry that cannot be parsed is treated as not having passed.
+func approvalExpired(approval Approval, now time.Time) bool {
+	expiresAt, err := time.Parse(time.RFC3339, approval.ExpiresAt)
+	return err == nil && !expiresAt.After(now)
+}
@@ -0,0 +1,97 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestApprovalPrompt(t *testing.T) {
+	spec.Run(t, "ApprovalPrompt", testApprovalPrompt, spec.Report(report.Terminal{}))
+}
+
+func testApprovalPrompt(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s *httptest.Server
+		a *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch req.URL.Path {
+			case "/oauth/clients/app":
+				w.Write([]byte(`{"client_id":"app","scope":["openid","profile","email","cloud_controller.*"],"autoapprove":["openid"]}`))
+			case "/Users/user-id":
+				w.Write([]byte(`{"id":"user-id","approvals":[
+					{"clientId":"app","scope":"profile","status":"APPROVED","expiresAt":"` + future + `"},
+					{"clientId":"app","scope":"email","status":"APPROVED","expiresAt":"` + past + `"},
+					{"clientId":"app","scope":"cloud_controller.read","status":"DENIED","expiresAt":"` + future + `"},
+					{"clientId":"other","scope":"cloud_controller.write","status":"APPROVED","expiresAt":"` + future + `"}
+				]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	when("AutoApprove", func() {
+		it("approves every scope", func() {
+			Expect(uaa.AutoApproveAll().All()).To(BeTrue())
+			Expect(uaa.AutoApproveAll().Approves("anything")).To(BeTrue())
+		})
+
+		it("approves the listed scopes and wildcards", func() {
+			approve := uaa.AutoApprove{"openid", "cloud_controller.*"}
+			Expect(approve.All()).To(BeFalse())
+			Expect(approve.Approves("openid")).To(BeTrue())
+			Expect(approve.Approves("cloud_controller.read")).To(BeTrue())
+			Expect(approve.Approves("profile")).To(BeFalse())
+		})
+	})
+
+	when("Client.ScopesRequiringApproval()", func() {
+		it("returns the allowed scopes that are not auto-approved", func() {
+			client := uaa.Client{Scope: []string{"openid", "profile", "cloud_controller.*"}, AutoApprove: uaa.AutoApprove{"openid"}}
+			Expect(client.ScopesRequiringApproval()).To(Equal([]string{"profile", "cloud_controller.*"}))
+			Expect(client.ScopesRequiringApproval("openid", "cloud_controller.read", "scim.write")).To(Equal([]string{"cloud_controller.read"}))
+			client.AutoApprove = uaa.AutoApproveAll()
+			Expect(client.ScopesRequiringApproval()).To(BeEmpty())
+		})
+	})
+
+	when("ApprovalPrompt()", func() {
+		it("explains which scopes the user is asked to approve", func() {
+			prompt, err := a.ApprovalPrompt("app", "user-id", []string{"openid", "profile", "email", "cloud_controller.read", "scim.write"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prompt).To(Equal(&uaa.ApprovalPrompt{
+				Prompted:           []string{"email", "cloud_controller.read"},
+				AutoApproved:       []string{"openid"},
+				PreviouslyApproved: []string{"profile"},
+				NotAllowed:         []string{"scim.write"},
+			}))
+		})
+
+		it("requires a client and a user", func() {
+			_, err := a.ApprovalPrompt("", "user-id", nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+}
@@ -0,0 +1,41 @@
+package uaa
+
+import (
+	"encoding/json"
+
+	"golang.org/x/oauth2"
+)
+
+// AuthorizeURL returns the URL of the UAA's authorization endpoint to which
+// users are sent to log in and approve the client, for the authorization code
+// grant. Options such as LoginHintParam or oauth2.AccessTypeOffline add
+// further parameters to the URL.
+func (a *API) AuthorizeURL(clientID string, redirectURI string, state string, scopes []string, opts ...oauth2.AuthCodeOption) string {
+	authURL := urlWithPath(*a.TargetURL, a.endpointPath("/oauth/authorize"))
+	c := &oauth2.Config{
+		ClientID:    clientID,
+		RedirectURL: redirectURI,
+		Scopes:      scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL: authURL.String(),
+		},
+	}
+	return c.AuthCodeURL(state, opts...)
+}
+
+// LoginHint returns the value of UAA's login_hint parameter that directs
+// authentication to the identity provider with the given origin, e.g.
+// {"origin":"ldap"}.
+func LoginHint(origin Origin) string {
+	hint, _ := json.Marshal(struct {
+		Origin Origin `json:"origin"`
+	}{origin})
+	return string(hint)
+}
+
+// LoginHintParam adds a login_hint parameter to an authorize URL, so that
+// users log in with the identity provider with the given origin rather than
+// choosing one.
+func LoginHintParam(origin Origin) oauth2.AuthCodeOption {
+	return oauth2.SetAuthURLParam("login_hint", LoginHint(origin))
+}
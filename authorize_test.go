@@ -0,0 +1,53 @@
+package uaa_test
+
+import (
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestAuthorize(t *testing.T) {
+	spec.Run(t, "Authorize", testAuthorize, spec.Report(report.Terminal{}))
+}
+
+func testAuthorize(t *testing.T, when spec.G, it spec.S) {
+	var a *uaa.API
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		u, _ := url.Parse("https://uaa.example.com")
+		a = &uaa.API{TargetURL: u}
+	})
+
+	when("LoginHint()", func() {
+		it("encodes the origin as JSON", func() {
+			Expect(uaa.LoginHint(uaa.LDAPOrigin)).To(Equal(`{"origin":"ldap"}`))
+		})
+	})
+
+	when("AuthorizeURL()", func() {
+		it("builds the authorization code URL", func() {
+			u, err := url.Parse(a.AuthorizeURL("app", "https://app.example.com/callback", "xyz", []string{"openid", "profile"}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u.Host).To(Equal("uaa.example.com"))
+			Expect(u.Path).To(Equal("/oauth/authorize"))
+			query := u.Query()
+			Expect(query.Get("response_type")).To(Equal("code"))
+			Expect(query.Get("client_id")).To(Equal("app"))
+			Expect(query.Get("redirect_uri")).To(Equal("https://app.example.com/callback"))
+			Expect(query.Get("state")).To(Equal("xyz"))
+			Expect(query.Get("scope")).To(Equal("openid profile"))
+			Expect(query).NotTo(HaveKey("login_hint"))
+		})
+
+		it("adds the login hint", func() {
+			u, err := url.Parse(a.AuthorizeURL("app", "", "xyz", nil, uaa.LoginHintParam(uaa.SAMLOrigin)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u.Query().Get("login_hint")).To(Equal(`{"origin":"saml"}`))
+		})
+	})
+}
@@ -0,0 +1,159 @@
+package uaa_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	"golang.org/x/oauth2"
+)
+
+// benchmarkServer serves token keys, a page of users and the current user, as
+// the UAA would.
+func benchmarkServer(b *testing.B, key *rsa.PrivateKey, users int) *httptest.Server {
+	resources := make([]uaa.User, users)
+	for i := range resources {
+		resources[i] = uaa.User{
+			ID:       fmt.Sprintf("00000000-0000-0000-0000-%012d", i),
+			Username: fmt.Sprintf("user-%d@example.com", i),
+			Emails:   []uaa.Email{{Value: fmt.Sprintf("user-%d@example.com", i), Primary: newTrueP()}},
+			Groups:   []uaa.UserGroup{{Display: "openid", Value: "openid-id", Type: "DIRECT"}},
+			Origin:   "uaa",
+			Meta:     &uaa.Meta{Version: 1, Created: "2019-01-01T00:00:00.000Z", LastModified: "2019-01-01T00:00:00.000Z"},
+		}
+	}
+	page, err := json.Marshal(map[string]interface{}{"resources": resources, "startIndex": 1, "itemsPerPage": users, "totalResults": users})
+	if err != nil {
+		b.Fatal(err)
+	}
+	keys, err := json.Marshal(uaa.Keys{Keys: []uaa.JWK{publicJWK(key, "key-1")}})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/token_keys":
+			w.Write(keys)
+		case "/Users":
+			w.Header().Set("Content-Length", strconv.Itoa(len(page)))
+			w.Write(page)
+		default:
+			w.Write([]byte(`{"user_id":"00000000-0000-0000-0000-000000000000","user_name":"marcus"}`))
+		}
+	}))
+}
+
+func benchmarkToken(key *rsa.PrivateKey) string {
+	return signedJWT(key, "key-1", map[string]interface{}{
+		"jti":       "2ac9b6bd9d9b4a6c8c3c0e2d6d0f5e61",
+		"sub":       "00000000-0000-0000-0000-000000000000",
+		"scope":     []string{"openid", "scim.read", "scim.write", "uaa.user"},
+		"client_id": "cf",
+		"cid":       "cf",
+		"user_name": "marcus",
+		"origin":    "uaa",
+		"iat":       time.Now().Unix(),
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"iss":       "http://localhost/oauth/token",
+		"zid":       "uaa",
+		"aud":       []string{"cf", "scim", "openid"},
+	})
+}
+
+func BenchmarkTokenValidator(b *testing.B) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := benchmarkServer(b, key, 0)
+	defer s.Close()
+	u, _ := url.Parse(s.URL)
+	a := &uaa.API{TargetURL: u, AuthenticatedClient: http.DefaultClient, UnauthenticatedClient: http.DefaultClient}
+	validator := &uaa.TokenValidator{API: a, Keys: uaa.NewKeyCache(a), Audience: "cf"}
+	token := benchmarkToken(key)
+	if _, err := validator.Validate(token); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := validator.Validate(token); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeClaims(b *testing.B) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		b.Fatal(err)
+	}
+	token := benchmarkToken(key)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := uaa.DecodeClaims(token); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListUsers(b *testing.B) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := benchmarkServer(b, key, 500)
+	defer s.Close()
+	a, err := uaa.NewWithToken(s.URL, "", oauth2.Token{AccessToken: "token", TokenType: "bearer", Expiry: time.Now().Add(time.Hour)})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		filter := fmt.Sprintf(`userName eq "user-%d@example.com" or origin eq "uaa"`, i)
+		users, _, err := a.ListUsers(filter, "userName", "", uaa.SortAscending, 1, 500)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(users) != 500 {
+			b.Fatalf("got %d users", len(users))
+		}
+	}
+}
+
+func BenchmarkAuthenticatedRequest(b *testing.B) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := benchmarkServer(b, key, 0)
+	defer s.Close()
+	a, err := uaa.NewWithToken(s.URL, "", oauth2.Token{AccessToken: "token", TokenType: "bearer", Expiry: time.Now().Add(time.Hour)})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := a.GetMe(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
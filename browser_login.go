@@ -0,0 +1,266 @@
+package uaa
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// Browser opens URLs for the user, so that they can log in to the UAA.
+type Browser interface {
+	Open(url string) error
+}
+
+// BrowserFunc adapts a function to the Browser interface.
+type BrowserFunc func(url string) error
+
+// Open calls f(url).
+func (f BrowserFunc) Open(url string) error {
+	return f(url)
+}
+
+// SystemBrowser opens URLs in the user's default browser, using open on
+// macOS, rundll32 on Windows and xdg-open elsewhere.
+var SystemBrowser Browser = systemBrowser{}
+
+type systemBrowser struct{}
+
+func (systemBrowser) Open(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+// PrintURL returns a Browser that writes the URL to w for the user to open
+// themselves, for when there is no browser that can be opened, such as in an
+// SSH session.
+func PrintURL(w io.Writer) Browser {
+	return BrowserFunc(func(url string) error {
+		_, err := fmt.Fprintf(w, "Open the following URL in a browser to log in:\n\n  %s\n\n", url)
+		return err
+	})
+}
+
+// DefaultLoginCallbackPath is the path of the redirect URI to which the UAA
+// sends the browser after a BrowserLogin.
+const DefaultLoginCallbackPath = "/callback"
+
+// BrowserLogin logs a user in with the authorization code grant from a
+// command line program: the authorize URL is opened in a browser, and the
+// code is received by a server listening on the loopback interface. The
+// client must allow a redirect URI such as http://127.0.0.1:*/callback.
+type BrowserLogin struct {
+	Target            string
+	ZoneID            string
+	ClientID          string
+	ClientSecret      string
+	Scopes            []string
+	TokenFormat       TokenFormat
+	SkipSSLValidation bool
+
+	// Browser opens the authorize URL. Defaults to SystemBrowser.
+	Browser Browser
+	// ListenAddr is the address on which to receive the code. Defaults to
+	// 127.0.0.1 on a port chosen by the system.
+	ListenAddr string
+	// CallbackPath is the path of the redirect URI. Defaults to
+	// DefaultLoginCallbackPath.
+	CallbackPath string
+	// RedirectURI is the redirect URI used by LoginHeadless. Defaults to
+	// http://127.0.0.1 followed by the CallbackPath.
+	RedirectURI string
+	// AuthCodeOptions add parameters to the authorize URL, such as
+	// LoginHintParam.
+	AuthCodeOptions []oauth2.AuthCodeOption
+	// Options configure the API that is returned.
+	Options []Option
+}
+
+type loginCallback struct {
+	code string
+	err  error
+}
+
+// Login opens the authorize URL, waits for the UAA to redirect back with a
+// code, and returns an API that uses the token obtained by exchanging it.
+// Login returns ctx's error if ctx is done first.
+func (l *BrowserLogin) Login(ctx context.Context) (*API, error) {
+	return l.login(ctx, func(authorizeURL string) (string, error) {
+		return authorizeURL, nil
+	})
+}
+
+// login is Login, opening the URL that page returns for the authorize URL.
+func (l *BrowserLogin) login(ctx context.Context, page func(authorizeURL string) (string, error)) (*API, error) {
+	target, err := BuildTargetURL(l.Target)
+	if err != nil {
+		return nil, err
+	}
+	state, err := loginState()
+	if err != nil {
+		return nil, err
+	}
+	addr := l.ListenAddr
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+
+	path := l.callbackPath()
+	redirectURI := (&url.URL{Scheme: "http", Host: ln.Addr().String(), Path: path}).String()
+
+	callbacks := make(chan loginCallback, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+		code, err := codeFromLoginRedirect(req.URL, state)
+		if err != nil {
+			http.Error(w, "Login failed: "+err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprint(w, "Login succeeded. You may close this window.")
+		}
+		select {
+		case callbacks <- loginCallback{code: code, err: err}:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(ln)
+	defer server.Close()
+
+	authorizeURL := (&API{TargetURL: target}).AuthorizeURL(l.ClientID, redirectURI, state, l.Scopes, l.AuthCodeOptions...)
+	browser := l.Browser
+	if browser == nil {
+		browser = SystemBrowser
+	}
+	pageURL, err := page(authorizeURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := browser.Open(pageURL); err != nil {
+		return nil, err
+	}
+
+	select {
+	case callback := <-callbacks:
+		if callback.err != nil {
+			return nil, callback.err
+		}
+		return l.exchange(callback.code, redirectURI)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// LoginHeadless logs the user in without opening a browser or listening for
+// the redirect, for environments such as SSH sessions: the authorize URL is
+// written to out, and the user opens it on another machine, logs in, and
+// pastes the URL to which they were redirected, or just its code, into in.
+// The redirected page is not expected to load.
+func (l *BrowserLogin) LoginHeadless(in io.Reader, out io.Writer) (*API, error) {
+	target, err := BuildTargetURL(l.Target)
+	if err != nil {
+		return nil, err
+	}
+	state, err := loginState()
+	if err != nil {
+		return nil, err
+	}
+	redirectURI := l.RedirectURI
+	if redirectURI == "" {
+		redirectURI = "http://127.0.0.1" + l.callbackPath()
+	}
+
+	authorizeURL := (&API{TargetURL: target}).AuthorizeURL(l.ClientID, redirectURI, state, l.Scopes, l.AuthCodeOptions...)
+	if err := PrintURL(out).Open(authorizeURL); err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Fprint(out, "Paste the URL you were redirected to, or its code: "); err != nil {
+		return nil, err
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return nil, err
+	}
+	code, err := pastedCode(strings.TrimSpace(line), state)
+	if err != nil {
+		return nil, err
+	}
+	return l.exchange(code, redirectURI)
+}
+
+func (l *BrowserLogin) callbackPath() string {
+	if l.CallbackPath == "" {
+		return DefaultLoginCallbackPath
+	}
+	return l.CallbackPath
+}
+
+// pastedCode returns the code from a pasted redirect URL, or the pasted text
+// itself if it is a bare code.
+func pastedCode(pasted string, state string) (string, error) {
+	if pasted == "" {
+		return "", errors.New("no code was entered")
+	}
+	if u, err := url.Parse(pasted); err == nil && u.RawQuery != "" {
+		return codeFromLoginRedirect(u, state)
+	}
+	return pasted, nil
+}
+
+// exchange exchanges the code for a token. The UAA requires the redirect URI
+// that was sent to the authorize endpoint to be sent with the code.
+func (l *BrowserLogin) exchange(code string, redirectURI string) (*API, error) {
+	opts := append([]Option{WithTokenParams(url.Values{"redirect_uri": {redirectURI}})}, l.Options...)
+	return NewWithAuthorizationCode(l.Target, l.ZoneID, l.ClientID, l.ClientSecret, code, l.SkipSSLValidation, l.TokenFormat, opts...)
+}
+
+// codeFromLoginRedirect returns the code from the URL to which the UAA
+// redirected the browser, checking that it carries the expected state.
+func codeFromLoginRedirect(u *url.URL, state string) (string, error) {
+	query := u.Query()
+	if e := query.Get("error"); e != "" {
+		if description := query.Get("error_description"); description != "" {
+			return "", fmt.Errorf("%s: %s", e, description)
+		}
+		return "", errors.New(e)
+	}
+	if query.Get("state") != state {
+		return "", errors.New("the state parameter does not match the login request")
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", errors.New("the redirect does not include a code")
+	}
+	return code, nil
+}
+
+func loginState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,185 @@
+package uaa_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestBrowserLogin(t *testing.T) {
+	spec.Run(t, "BrowserLogin", testBrowserLogin, spec.Report(report.Terminal{}))
+}
+
+func testBrowserLogin(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s           *httptest.Server
+		login       *uaa.BrowserLogin
+		authorize   url.Values
+		exchanged   url.Values
+		redirectErr string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		redirectErr = ""
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/oauth/authorize":
+				authorize = req.URL.Query()
+				redirect, _ := url.Parse(authorize.Get("redirect_uri"))
+				query := url.Values{"state": {authorize.Get("state")}, "code": {"the-code"}}
+				if redirectErr != "" {
+					query = url.Values{"error": {redirectErr}}
+				}
+				redirect.RawQuery = query.Encode()
+				http.Redirect(w, req, redirect.String(), http.StatusFound)
+			case "/oauth/token":
+				req.ParseForm()
+				exchanged = req.Form
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"access_token":  "the-token",
+					"token_type":    "bearer",
+					"refresh_token": "the-refresh-token",
+					"expires_in":    3600,
+				})
+			}
+		}))
+		login = &uaa.BrowserLogin{
+			Target:   s.URL,
+			ClientID: "cli",
+			Scopes:   []string{"openid"},
+			Browser: uaa.BrowserFunc(func(u string) error {
+				go func() {
+					resp, err := http.Get(u)
+					if err == nil {
+						resp.Body.Close()
+					}
+				}()
+				return nil
+			}),
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("exchanges the code received on the loopback interface", func() {
+		api, err := login.Login(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		token, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("the-token"))
+
+		Expect(authorize.Get("client_id")).To(Equal("cli"))
+		Expect(authorize.Get("scope")).To(Equal("openid"))
+		redirect, err := url.Parse(authorize.Get("redirect_uri"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redirect.Hostname()).To(Equal("127.0.0.1"))
+		Expect(redirect.Path).To(Equal(uaa.DefaultLoginCallbackPath))
+		Expect(exchanged.Get("code")).To(Equal("the-code"))
+		Expect(exchanged.Get("redirect_uri")).To(Equal(authorize.Get("redirect_uri")))
+	})
+
+	it("returns the error the UAA redirects with", func() {
+		redirectErr = "access_denied"
+		_, err := login.Login(context.Background())
+		Expect(err).To(MatchError("access_denied"))
+	})
+
+	it("returns the browser's error", func() {
+		login.Browser = uaa.BrowserFunc(func(string) error { return context.DeadlineExceeded })
+		_, err := login.Login(context.Background())
+		Expect(err).To(Equal(context.DeadlineExceeded))
+	})
+
+	it("stops waiting when the context is done", func() {
+		login.Browser = uaa.BrowserFunc(func(string) error { return nil })
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := login.Login(ctx)
+		Expect(err).To(Equal(context.DeadlineExceeded))
+	})
+
+	when("LoginHeadless()", func() {
+		var out bytes.Buffer
+
+		it.Before(func() {
+			out.Reset()
+		})
+
+		printedURL := func() *url.URL {
+			for _, line := range strings.Split(out.String(), "\n") {
+				if strings.HasPrefix(line, "  ") {
+					u, err := url.Parse(strings.TrimSpace(line))
+					Expect(err).NotTo(HaveOccurred())
+					return u
+				}
+			}
+			t.Fatal("no URL was printed")
+			return nil
+		}
+
+		it("exchanges a pasted code", func() {
+			api, err := login.LoginHeadless(strings.NewReader("pasted-code\n"), &out)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(api).NotTo(BeNil())
+			query := printedURL().Query()
+			Expect(query.Get("redirect_uri")).To(Equal("http://127.0.0.1/callback"))
+			Expect(exchanged.Get("code")).To(Equal("pasted-code"))
+			Expect(exchanged.Get("redirect_uri")).To(Equal("http://127.0.0.1/callback"))
+		})
+
+		it("exchanges the code from a pasted redirect URL", func() {
+			login.RedirectURI = "https://login.example.com/done"
+			in := &lazyReader{read: func() string {
+				state := printedURL().Query().Get("state")
+				return "https://login.example.com/done?code=redirected-code&state=" + state + "\n"
+			}}
+			_, err := login.LoginHeadless(in, &out)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exchanged.Get("code")).To(Equal("redirected-code"))
+			Expect(exchanged.Get("redirect_uri")).To(Equal("https://login.example.com/done"))
+		})
+
+		it("rejects a redirect URL with the wrong state", func() {
+			_, err := login.LoginHeadless(strings.NewReader("http://127.0.0.1/callback?code=c&state=other"), &out)
+			Expect(err).To(MatchError(ContainSubstring("state")))
+		})
+	})
+
+	when("PrintURL()", func() {
+		it("writes the URL", func() {
+			var b bytes.Buffer
+			Expect(uaa.PrintURL(&b).Open("https://uaa.example.com/oauth/authorize")).To(Succeed())
+			Expect(b.String()).To(ContainSubstring("  https://uaa.example.com/oauth/authorize\n"))
+		})
+	})
+}
+
+// lazyReader produces its contents when it is first read, after the prompt
+// has been written.
+type lazyReader struct {
+	read func() string
+	r    io.Reader
+}
+
+func (l *lazyReader) Read(p []byte) (int, error) {
+	if l.r == nil {
+		l.r = strings.NewReader(l.read())
+	}
+	return l.r.Read(p)
+}
@@ -0,0 +1,140 @@
+package uaa
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is the cause of the error returned when a request is not
+// sent because the API's circuit breaker is open. Use errors.Cause from
+// github.com/pkg/errors to compare against it.
+var ErrCircuitOpen = errors.New("the circuit breaker is open")
+
+// Default circuit breaker settings.
+const (
+	DefaultCircuitBreakerThreshold = 5
+	DefaultCircuitBreakerCooldown  = 30 * time.Second
+)
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+// Valid CircuitState values.
+const (
+	// CircuitClosed lets requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails requests without sending them.
+	CircuitOpen
+	// CircuitHalfOpen lets a single trial request through to test whether
+	// the UAA has recovered.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return ""
+}
+
+// CircuitBreaker stops requests to the UAA after consecutive failures, so that
+// callers on hot paths such as token validation fail fast during an outage
+// instead of waiting on timeouts. A request fails if it cannot be sent or the
+// UAA responds with a 5xx status. Once Threshold consecutive requests have
+// failed, requests fail immediately with ErrCircuitOpen until Cooldown has
+// passed; then a single trial request is sent, and the breaker closes if it
+// succeeds or opens again if it fails.
+//
+// The cooldown is timed with the clock of the API that sends the request, set
+// by WithClock. A CircuitBreaker is safe for concurrent use and may be shared
+// by several APIs that talk to the same UAA.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	clock    Clock
+}
+
+// NewCircuitBreaker returns a CircuitBreaker with the default threshold and
+// cooldown.
+func NewCircuitBreaker() *CircuitBreaker {
+	return &CircuitBreaker{
+		Threshold: DefaultCircuitBreakerThreshold,
+		Cooldown:  DefaultCircuitBreakerCooldown,
+	}
+}
+
+// WithCircuitBreaker returns an Option that sends the API's requests through
+// the given circuit breaker.
+func WithCircuitBreaker(b *CircuitBreaker) Option {
+	return func(a *API) {
+		a.circuitBreaker = b
+	}
+}
+
+// State returns the current state of the breaker, judged by the clock of the
+// API that last used it.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	clock := b.clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+	if b.state == CircuitOpen && clock.Now().Sub(b.openedAt) >= b.Cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// allow returns ErrCircuitOpen if the request should not be sent.
+func (b *CircuitBreaker) allow(clock Clock) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clock = clock
+	switch b.state {
+	case CircuitOpen:
+		if clock.Now().Sub(b.openedAt) < b.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		return nil
+	case CircuitHalfOpen:
+		// A trial request is already in flight.
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// record records the outcome of a request that was allowed.
+func (b *CircuitBreaker) record(clock Clock, resp *http.Response, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clock = clock
+	if err == nil && resp.StatusCode < http.StatusInternalServerError {
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	threshold := b.Threshold
+	if threshold <= 0 {
+		threshold = DefaultCircuitBreakerThreshold
+	}
+	if b.state == CircuitHalfOpen || b.failures >= threshold {
+		b.state = CircuitOpen
+		b.openedAt = clock.Now()
+	}
+}
@@ -0,0 +1,107 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	spec.Run(t, "CircuitBreaker", testCircuitBreaker, spec.Report(report.Terminal{}))
+}
+
+func testCircuitBreaker(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s      *httptest.Server
+		a      *uaa.API
+		b      *uaa.CircuitBreaker
+		status int
+		called int
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		status = http.StatusServiceUnavailable
+		called = 0
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			called++
+			w.WriteHeader(status)
+			w.Write([]byte(MarcusUserResponse))
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+		b = &uaa.CircuitBreaker{Threshold: 2, Cooldown: 20 * time.Millisecond}
+		uaa.WithCircuitBreaker(b)(a)
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("opens after consecutive failures", func() {
+		for i := 0; i < 2; i++ {
+			_, err := a.GetUser("id")
+			Expect(errors.Cause(err)).NotTo(Equal(uaa.ErrCircuitOpen))
+		}
+		Expect(b.State()).To(Equal(uaa.CircuitOpen))
+
+		_, err := a.GetUser("id")
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrCircuitOpen))
+		Expect(called).To(Equal(2))
+	})
+
+	it("closes after a successful trial request", func() {
+		a.GetUser("id")
+		a.GetUser("id")
+		time.Sleep(30 * time.Millisecond)
+		Expect(b.State()).To(Equal(uaa.CircuitHalfOpen))
+
+		status = http.StatusOK
+		_, err := a.GetUser("id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(b.State()).To(Equal(uaa.CircuitClosed))
+	})
+
+	it("opens again if the trial request fails", func() {
+		a.GetUser("id")
+		a.GetUser("id")
+		time.Sleep(30 * time.Millisecond)
+		a.GetUser("id")
+		Expect(called).To(Equal(3))
+		Expect(b.State()).To(Equal(uaa.CircuitOpen))
+	})
+
+	it("times the cooldown with the API's clock", func() {
+		clock := uaa.NewFakeClock()
+		uaa.WithClock(clock)(a)
+		b.Cooldown = time.Hour
+		a.GetUser("id")
+		a.GetUser("id")
+		Expect(b.State()).To(Equal(uaa.CircuitOpen))
+
+		clock.Advance(time.Hour)
+		Expect(b.State()).To(Equal(uaa.CircuitHalfOpen))
+		status = http.StatusOK
+		_, err := a.GetUser("id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(called).To(Equal(3))
+	})
+
+	it("does not count client errors as failures", func() {
+		status = http.StatusNotFound
+		for i := 0; i < 3; i++ {
+			a.GetUser("id")
+		}
+		Expect(b.State()).To(Equal(uaa.CircuitClosed))
+		Expect(called).To(Equal(3))
+	})
+}
@@ -0,0 +1,143 @@
+package uaa
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Claims are the claims carried by a UAA JSON Web Token.
+type Claims struct {
+	JTI         string   `json:"jti,omitempty"`
+	Subject     string   `json:"sub,omitempty"`
+	Scope       []string `json:"scope,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+	CID         string   `json:"cid,omitempty"`
+	AZP         string   `json:"azp,omitempty"`
+	GrantType   string   `json:"grant_type,omitempty"`
+	UserID      string   `json:"user_id,omitempty"`
+	Origin      string   `json:"origin,omitempty"`
+	Username    string   `json:"user_name,omitempty"`
+	Email       string   `json:"email,omitempty"`
+	AuthTime    int64    `json:"auth_time,omitempty"`
+	RevSig      string   `json:"rev_sig,omitempty"`
+	IssuedAt    int64    `json:"iat,omitempty"`
+	Expiry      int64    `json:"exp,omitempty"`
+	Issuer      string   `json:"iss,omitempty"`
+	ZoneID      string   `json:"zid,omitempty"`
+	Audience    []string `json:"aud,omitempty"`
+	Revocable   bool     `json:"revocable,omitempty"`
+	Authorities []string `json:"authorities,omitempty"`
+
+	UserAttributes map[string][]string `json:"user_attributes,omitempty"`
+	Roles          []string            `json:"roles,omitempty"`
+}
+
+// DecodeClaims decodes the claims of the given JSON Web Token. The signature
+// of the token is not verified.
+func DecodeClaims(token string) (*Claims, error) {
+	segments, ok := splitJWT(token)
+	if !ok {
+		return nil, errors.New("token is not a JSON Web Token")
+	}
+	return decodeClaims(segments.payload)
+}
+
+// decodeClaims decodes the payload segment of a JSON Web Token.
+func decodeClaims(segment string) (*Claims, error) {
+	payload, err := decodeSegment(segment)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ExpiresAt returns the time at which the token expires.
+func (c *Claims) ExpiresAt() time.Time {
+	return time.Unix(c.Expiry, 0)
+}
+
+// HasScope returns true if the token was granted the given scope. If the
+// scope contains a wildcard (e.g. "spaces.*.developer"), HasScope returns true
+// if any granted scope matches it.
+func (c *Claims) HasScope(scope string) bool {
+	for _, granted := range c.Scope {
+		if ScopeMatches(scope, granted) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRole returns true if the token carries the given role, one of the
+// user's external groups. Roles are only included in tokens requested with
+// the roles scope.
+func (c *Claims) HasRole(role string) bool {
+	return contains(c.Roles, role)
+}
+
+// HasAnyScope returns true if the token was granted at least one of the given
+// scopes.
+func (c *Claims) HasAnyScope(scopes ...string) bool {
+	for _, scope := range scopes {
+		if c.HasScope(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAllScopes returns true if the token was granted every one of the given
+// scopes.
+func (c *Claims) HasAllScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !c.HasScope(scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// ScopeMatches returns true if the scope matches the pattern. A "*" in the
+// pattern matches one or more characters within a single dot-separated
+// segment, so "cloud_controller.*" matches "cloud_controller.admin" and
+// "spaces.*.developer" matches "spaces.a1b2.developer".
+func ScopeMatches(pattern string, scope string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == scope
+	}
+	patternParts := strings.Split(pattern, ".")
+	scopeParts := strings.Split(scope, ".")
+	if len(patternParts) != len(scopeParts) {
+		return false
+	}
+	for i := range patternParts {
+		if !segmentMatches(patternParts[i], scopeParts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func segmentMatches(pattern string, segment string) bool {
+	star := strings.Index(pattern, "*")
+	if star == -1 {
+		return pattern == segment
+	}
+	prefix, rest := pattern[:star], pattern[star+1:]
+	if !strings.HasPrefix(segment, prefix) {
+		return false
+	}
+	segment = segment[len(prefix):]
+	for i := 1; i <= len(segment); i++ {
+		if segmentMatches(rest, segment[i:]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package uaa_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestClaims(t *testing.T) {
+	spec.Run(t, "Claims", testClaims, spec.Report(report.Terminal{}))
+}
+
+func unsignedJWT(claims interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	payload, _ := json.Marshal(claims)
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+}
+
+func testClaims(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	when("DecodeClaims()", func() {
+		it("decodes the token payload", func() {
+			expiry := time.Now().Add(time.Hour).Unix()
+			token := unsignedJWT(map[string]interface{}{
+				"sub":       "user-id",
+				"user_name": "marcus",
+				"scope":     []string{"openid", "scim.read"},
+				"zid":       "uaa",
+				"exp":       expiry,
+			})
+			claims, err := uaa.DecodeClaims(token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims.Subject).To(Equal("user-id"))
+			Expect(claims.Username).To(Equal("marcus"))
+			Expect(claims.Scope).To(ConsistOf("openid", "scim.read"))
+			Expect(claims.ZoneID).To(Equal("uaa"))
+			Expect(claims.ExpiresAt().Unix()).To(Equal(expiry))
+		})
+
+		it("decodes roles and user attributes", func() {
+			token := unsignedJWT(map[string]interface{}{
+				"roles":           []string{"finance", "admins"},
+				"user_attributes": map[string][]string{"cost_center": {"1234"}},
+			})
+			claims, err := uaa.DecodeClaims(token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims.HasRole("finance")).To(BeTrue())
+			Expect(claims.HasRole("sales")).To(BeFalse())
+			Expect(claims.UserAttributes["cost_center"]).To(Equal([]string{"1234"}))
+		})
+
+		it("returns an error for an opaque token", func() {
+			claims, err := uaa.DecodeClaims("f5a7a4b3d8d04c9a8f1e5c3f2e6a7b8c")
+			Expect(err).To(HaveOccurred())
+			Expect(claims).To(BeNil())
+		})
+
+		it("returns an error when the payload is not valid JSON", func() {
+			claims, err := uaa.DecodeClaims("a." + base64.RawURLEncoding.EncodeToString([]byte("{nope")) + ".c")
+			Expect(err).To(HaveOccurred())
+			Expect(claims).To(BeNil())
+		})
+	})
+
+	when("checking scopes", func() {
+		var claims *uaa.Claims
+
+		it.Before(func() {
+			claims = &uaa.Claims{Scope: []string{"cloud_controller.admin", "spaces.a1b2.developer", "openid"}}
+		})
+
+		it("matches exact scopes", func() {
+			Expect(claims.HasScope("openid")).To(BeTrue())
+			Expect(claims.HasScope("cloud_controller.admin")).To(BeTrue())
+			Expect(claims.HasScope("cloud_controller.read")).To(BeFalse())
+		})
+
+		it("matches wildcard scopes", func() {
+			Expect(claims.HasScope("cloud_controller.*")).To(BeTrue())
+			Expect(claims.HasScope("spaces.*.developer")).To(BeTrue())
+			Expect(claims.HasScope("spaces.*.manager")).To(BeFalse())
+			Expect(claims.HasScope("*.admin")).To(BeTrue())
+		})
+
+		it("does not let a wildcard span segments", func() {
+			Expect(claims.HasScope("spaces.*")).To(BeFalse())
+			Expect(claims.HasScope("*")).To(BeTrue())
+		})
+
+		it("checks any and all scopes", func() {
+			Expect(claims.HasAnyScope("scim.read", "openid")).To(BeTrue())
+			Expect(claims.HasAnyScope("scim.read", "scim.write")).To(BeFalse())
+			Expect(claims.HasAllScopes("openid", "cloud_controller.admin")).To(BeTrue())
+			Expect(claims.HasAllScopes("openid", "scim.write")).To(BeFalse())
+			Expect(claims.HasAllScopes()).To(BeTrue())
+			Expect(claims.HasAnyScope()).To(BeFalse())
+		})
+	})
+
+	when("ScopeMatches()", func() {
+		it("supports partial segment wildcards", func() {
+			Expect(uaa.ScopeMatches("zones.*.admin", "zones.abc.admin")).To(BeTrue())
+			Expect(uaa.ScopeMatches("zones.abc-*.admin", "zones.abc-123.admin")).To(BeTrue())
+			Expect(uaa.ScopeMatches("zones.abc-*.admin", "zones.xyz-123.admin")).To(BeFalse())
+			Expect(uaa.ScopeMatches("zones.*.admin", "zones..admin")).To(BeFalse())
+		})
+	})
+}
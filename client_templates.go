@@ -0,0 +1,101 @@
+package uaa
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// Token validities, in seconds, used by the client templates.
+const (
+	cfAccessTokenValidity         = 2 * 60 * 60
+	cfRefreshTokenValidity        = 14 * 24 * 60 * 60
+	dashboardAccessTokenValidity  = 10 * 60
+	dashboardRefreshTokenValidity = 24 * 60 * 60
+	serviceAccessTokenValidity    = 60 * 60
+)
+
+// NewCFClientTemplate returns the definition of a public client, like the cf
+// CLI's, that obtains tokens for users with the password grant and keeps them
+// fresh with refresh tokens. It has no secret, so Validate, which expects
+// password grant clients to be confidential, refuses it. The client is
+// created in the zone targeted by the API that creates it.
+func NewCFClientTemplate(clientID string) Client {
+	return Client{
+		ClientID:             clientID,
+		AuthorizedGrantTypes: []string{string(PASSWORD), string(REFRESHTOKEN)},
+		Scope:                []string{"openid", "uaa.user", "cloud_controller.read", "cloud_controller.write", "password.write"},
+		Authorities:          []string{"uaa.none"},
+		AccessTokenValidity:  cfAccessTokenValidity,
+		RefreshTokenValidity: cfRefreshTokenValidity,
+	}
+}
+
+// NewDashboardClientTemplate returns the definition of a confidential web
+// application, such as a service broker dashboard, that logs users in with
+// the authorization code grant. Each redirect URI must be an absolute https
+// URL, or an http URL on localhost, and its host may not contain wildcards,
+// so that the client cannot be used to redirect users to other sites. The
+// client is created in the zone targeted by the API that creates it.
+func NewDashboardClientTemplate(clientID, clientSecret string, redirectURIs []string) (Client, error) {
+	if len(redirectURIs) == 0 {
+		return Client{}, errorMissingValueForGrantType("redirect_uri", AUTHCODE)
+	}
+	for _, uri := range redirectURIs {
+		if err := checkTemplateRedirectURI(uri); err != nil {
+			return Client{}, err
+		}
+	}
+	return Client{
+		ClientID:             clientID,
+		ClientSecret:         clientSecret,
+		AuthorizedGrantTypes: []string{string(AUTHCODE), string(REFRESHTOKEN)},
+		RedirectURI:          redirectURIs,
+		Scope:                []string{"openid", "cloud_controller_service_permissions.read"},
+		Authorities:          []string{"uaa.none"},
+		AccessTokenValidity:  dashboardAccessTokenValidity,
+		RefreshTokenValidity: dashboardRefreshTokenValidity,
+	}, nil
+}
+
+// NewServiceClientTemplate returns the definition of a client that acts on
+// its own behalf with the client credentials grant, holding the given
+// authorities. It cannot obtain tokens for users. The client is created in
+// the zone targeted by the API that creates it.
+func NewServiceClientTemplate(clientID, clientSecret string, authorities []string) Client {
+	if len(authorities) == 0 {
+		authorities = []string{"uaa.none"}
+	}
+	return Client{
+		ClientID:             clientID,
+		ClientSecret:         clientSecret,
+		AuthorizedGrantTypes: []string{string(CLIENTCREDENTIALS)},
+		Scope:                []string{"uaa.none"},
+		Authorities:          authorities,
+		AccessTokenValidity:  serviceAccessTokenValidity,
+	}
+}
+
+func checkTemplateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("invalid redirect URI %q: %v", uri, err)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("redirect URI %q must be an absolute URL", uri)
+	}
+	host := u.Hostname()
+	if strings.Contains(host, "*") {
+		return fmt.Errorf("redirect URI %q may not contain a wildcard in its host", uri)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if host == "localhost" || net.ParseIP(host).IsLoopback() {
+			return nil
+		}
+	}
+	return fmt.Errorf("redirect URI %q must use https", uri)
+}
@@ -0,0 +1,49 @@
+package uaa_test
+
+import (
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestClientTemplates(t *testing.T) {
+	spec.Run(t, "ClientTemplates", testClientTemplates, spec.Report(report.Terminal{}))
+}
+
+func testClientTemplates(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("builds a public cf client", func() {
+		client := uaa.NewCFClientTemplate("cf")
+		Expect(client.AuthorizedGrantTypes).To(Equal([]string{"password", "refresh_token"}))
+		Expect(client.ClientSecret).To(BeEmpty())
+		Expect(client.Validate()).To(MatchError("client_secret must be specified for password grant type"))
+	})
+
+	it("builds a valid dashboard client", func() {
+		client, err := uaa.NewDashboardClientTemplate("dashboard", "secret", []string{"https://dashboard.example.com/callback", "http://localhost:8080/callback"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Validate()).To(Succeed())
+		Expect(client.RefreshTokenValidity).To(BeNumerically(">", client.AccessTokenValidity))
+	})
+
+	it("refuses unsafe dashboard redirect URIs", func() {
+		for _, uri := range []string{"http://dashboard.example.com/callback", "https://*.example.com/callback", "/callback"} {
+			_, err := uaa.NewDashboardClientTemplate("dashboard", "secret", []string{uri})
+			Expect(err).To(HaveOccurred(), uri)
+		}
+		_, err := uaa.NewDashboardClientTemplate("dashboard", "secret", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	it("builds a valid service client", func() {
+		client := uaa.NewServiceClientTemplate("broker", "secret", []string{"clients.read"})
+		Expect(client.Validate()).To(Succeed())
+		Expect(client.Authorities).To(Equal([]string{"clients.read"}))
+	})
+}
@@ -21,20 +21,91 @@ type paginatedClientList struct {
 // Client is a UAA client
 // http://docs.cloudfoundry.org/api/uaa/version/4.14.0/index.html#clients.
 type Client struct {
-	ClientID             string   `json:"client_id,omitempty" generator:"id"`
-	ClientSecret         string   `json:"client_secret,omitempty"`
-	Scope                []string `json:"scope,omitempty"`
-	ResourceIDs          []string `json:"resource_ids,omitempty"`
-	AuthorizedGrantTypes []string `json:"authorized_grant_types,omitempty"`
-	RedirectURI          []string `json:"redirect_uri,omitempty"`
-	Authorities          []string `json:"authorities,omitempty"`
-	TokenSalt            string   `json:"token_salt,omitempty"`
-	AllowedProviders     []string `json:"allowedproviders,omitempty"`
-	DisplayName          string   `json:"name,omitempty"`
-	LastModified         int64    `json:"lastModified,omitempty"`
-	RequiredUserGroups   []string `json:"required_user_groups,omitempty"`
-	AccessTokenValidity  int64    `json:"access_token_validity,omitempty"`
-	RefreshTokenValidity int64    `json:"refresh_token_validity,omitempty"`
+	ClientID             string      `json:"client_id,omitempty" generator:"id"`
+	ClientSecret         string      `json:"client_secret,omitempty"`
+	Scope                []string    `json:"scope,omitempty"`
+	ResourceIDs          []string    `json:"resource_ids,omitempty"`
+	AuthorizedGrantTypes []string    `json:"authorized_grant_types,omitempty"`
+	RedirectURI          []string    `json:"redirect_uri,omitempty"`
+	Authorities          []string    `json:"authorities,omitempty"`
+	TokenSalt            string      `json:"token_salt,omitempty"`
+	AllowedProviders     []string    `json:"allowedproviders,omitempty"`
+	DisplayName          string      `json:"name,omitempty"`
+	LastModified         int64       `json:"lastModified,omitempty"`
+	RequiredUserGroups   []string    `json:"required_user_groups,omitempty"`
+	AccessTokenValidity  int64       `json:"access_token_validity,omitempty"`
+	RefreshTokenValidity int64       `json:"refresh_token_validity,omitempty"`
+	AutoApprove          AutoApprove `json:"autoapprove,omitempty"`
+	CreatedBy            string      `json:"created_by,omitempty"`
+
+	// Extra holds the fields returned by the UAA that the struct does not
+	// model, so that they are sent back unchanged when it is updated.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// AutoApprove is the list of scopes that users are not asked to approve when
+// they authorize the client. UAA represents approving every scope with the
+// single scope "true", and accepts and returns autoapprove either as a list
+// of scopes or as a boolean, so AutoApprove unmarshals from either and
+// marshals as true when it approves every scope.
+type AutoApprove []string
+
+// MarshalJSON returns true if every scope is approved, or the list of scopes.
+func (a AutoApprove) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 && a[0] == "true" {
+		return []byte("true"), nil
+	}
+	return json.Marshal([]string(a))
+}
+
+// UnmarshalJSON accepts a boolean, a string or a list of scopes.
+func (a *AutoApprove) UnmarshalJSON(data []byte) error {
+	var all bool
+	if err := json.Unmarshal(data, &all); err == nil {
+		*a = nil
+		if all {
+			*a = AutoApprove{"true"}
+		}
+		return nil
+	}
+	var scope string
+	if err := json.Unmarshal(data, &scope); err == nil {
+		*a = nil
+		if scope != "" && scope != "false" {
+			*a = AutoApprove{scope}
+		}
+		return nil
+	}
+	var scopes []string
+	if err := json.Unmarshal(data, &scopes); err != nil {
+		return fmt.Errorf("autoapprove must be a boolean or a list of scopes: %v", err)
+	}
+	*a = scopes
+	return nil
+}
+
+// AutoApproveAll returns an AutoApprove that approves every scope.
+func AutoApproveAll() AutoApprove {
+	return AutoApprove{"true"}
+}
+
+// All returns true if every scope is approved.
+func (a AutoApprove) All() bool {
+	return contains(a, "true")
+}
+
+// Approves returns true if users are not asked to approve the scope. The
+// scopes in the list may contain wildcards, as described for ScopeMatches.
+func (a AutoApprove) Approves(scope string) bool {
+	if a.All() {
+		return true
+	}
+	for _, approved := range a {
+		if ScopeMatches(approved, scope) {
+			return true
+		}
+	}
+	return false
 }
 
 // GrantType is a type of oauth2 grant.
@@ -47,8 +118,56 @@ const (
 	IMPLICIT          = GrantType("implicit")
 	PASSWORD          = GrantType("password")
 	CLIENTCREDENTIALS = GrantType("client_credentials")
+	USERTOKEN         = GrantType("user_token")
+	SAML2BEARER       = GrantType("urn:ietf:params:oauth:grant-type:saml2-bearer")
+	JWTBEARER         = GrantType("urn:ietf:params:oauth:grant-type:jwt-bearer")
 )
 
+var validGrantTypes = []GrantType{REFRESHTOKEN, AUTHCODE, IMPLICIT, PASSWORD, CLIENTCREDENTIALS, USERTOKEN, SAML2BEARER, JWTBEARER}
+
+// Validate returns an error if the grant type is not supported by the UAA.
+func (g GrantType) Validate() error {
+	for _, valid := range validGrantTypes {
+		if g == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown grant type %q, must be one of %v", string(g), validGrantTypes)
+}
+
+// MarshalJSON sends the fields in Extra along with the modeled ones. Grant
+// types are not checked, so that a client with a grant type added in a newer
+// UAA can still be encoded; CreateClient, UpdateClient and Validate check
+// them.
+func (c Client) MarshalJSON() ([]byte, error) {
+	type client Client
+	return marshalWithExtra(client(c), c.Extra)
+}
+
+// UnmarshalJSON keeps the fields that Client does not model in Extra.
+func (c *Client) UnmarshalJSON(data []byte) error {
+	type client Client
+	var decoded client
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*c = Client(decoded)
+	c.Extra = extra
+	return nil
+}
+
+// checkWrite returns an error if any of the client's grant types is not
+// supported by the UAA.
+func (c Client) checkWrite() error {
+	for _, grantType := range c.AuthorizedGrantTypes {
+		if err := GrantType(grantType).Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func errorMissingValueForGrantType(value string, grantType GrantType) error {
 	return fmt.Errorf("%v must be specified for %v grant type", value, grantType)
 }
@@ -95,6 +214,10 @@ func (c *Client) Validate() error {
 		return errorMissingValue("client_id")
 	}
 
+	if err := c.checkWrite(); err != nil {
+		return err
+	}
+
 	if err := requireRedirectURIForGrantType(c, AUTHCODE); err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package uaa_test
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -235,4 +236,74 @@ func testClientExtra(t *testing.T, when spec.G, it spec.S) {
 			Expect(err).NotTo(BeNil())
 		})
 	})
+
+	when("validating grant types", func() {
+		it("refuses unknown grant types", func() {
+			client := uaa.Client{ClientID: "peanuts_client", ClientSecret: "secret", AuthorizedGrantTypes: []string{"client_credential"}}
+			err := client.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`unknown grant type "client_credential"`))
+		})
+
+		it("accepts known grant types", func() {
+			client := uaa.Client{ClientID: "peanuts_client", AuthorizedGrantTypes: []string{string(uaa.JWTBEARER), string(uaa.USERTOKEN)}}
+			Expect(client.Validate()).To(Succeed())
+		})
+
+		it("marshals grant types it does not know", func() {
+			j, err := json.Marshal(uaa.Client{ClientID: "peanuts_client", AuthorizedGrantTypes: []string{"urn:ietf:params:oauth:grant-type:token-exchange"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(j)).To(ContainSubstring("token-exchange"))
+		})
+	})
+
+	when("the client's autoapprove", func() {
+		it("unmarshals from a boolean", func() {
+			var c uaa.Client
+			Expect(json.Unmarshal([]byte(`{"autoapprove":true}`), &c)).To(Succeed())
+			Expect(c.AutoApprove).To(Equal(uaa.AutoApprove{"true"}))
+			Expect(json.Unmarshal([]byte(`{"autoapprove":false}`), &c)).To(Succeed())
+			Expect(c.AutoApprove).To(BeEmpty())
+		})
+
+		it("unmarshals from a string", func() {
+			var c uaa.Client
+			Expect(json.Unmarshal([]byte(`{"autoapprove":"true"}`), &c)).To(Succeed())
+			Expect(c.AutoApprove).To(Equal(uaa.AutoApprove{"true"}))
+		})
+
+		it("unmarshals from a list of scopes", func() {
+			var c uaa.Client
+			Expect(json.Unmarshal([]byte(`{"autoapprove":["openid","profile"]}`), &c)).To(Succeed())
+			Expect(c.AutoApprove).To(Equal(uaa.AutoApprove{"openid", "profile"}))
+		})
+
+		it("refuses other values", func() {
+			var c uaa.Client
+			Expect(json.Unmarshal([]byte(`{"autoapprove":{}}`), &c)).NotTo(Succeed())
+		})
+
+		it("round-trips along with the other client fields", func() {
+			response := `{
+				"client_id": "app",
+				"autoapprove": true,
+				"allowedproviders": ["uaa", "ldap"],
+				"required_user_groups": ["admins"],
+				"created_by": "4fb47c4b-1a4e-4b8c-8a0c-bd0a0b1c9bd5",
+				"lastModified": 1536864473000
+			}`
+			var c uaa.Client
+			Expect(json.Unmarshal([]byte(response), &c)).To(Succeed())
+			Expect(c.CreatedBy).To(Equal("4fb47c4b-1a4e-4b8c-8a0c-bd0a0b1c9bd5"))
+			j, err := json.Marshal(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j).To(MatchJSON(response))
+		})
+
+		it("marshals a list of scopes", func() {
+			j, err := json.Marshal(uaa.Client{ClientID: "app", AutoApprove: uaa.AutoApprove{"openid"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(j).To(MatchJSON(`{"client_id":"app","autoapprove":["openid"]}`))
+		})
+	})
 }
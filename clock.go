@@ -0,0 +1,106 @@
+package uaa
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time and waits. The API uses its Clock to decide when its
+// token has expired, to wait between retries and to schedule the keep-alive,
+// so that tests can use a FakeClock to simulate the passage of time without
+// sleeping.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	// After returns a channel that receives the time once d has passed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// WithClock returns an Option that makes the API use the given clock for
+// token expiry and the keep-alive, and for the delays of the pacer, of
+// CheckClientSecrets and of the Create*AndGet and Update*AndGet retries. It
+// must be passed to the constructor to affect the tokens that the API obtains
+// itself.
+func WithClock(clock Clock) Option {
+	return func(a *API) {
+		a.clock = clock
+	}
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// getClock returns the API's clock, or the system clock if it has none.
+func (a *API) getClock() Clock {
+	if a.clock == nil {
+		return systemClock{}
+	}
+	return a.clock
+}
+
+// FakeClock is a Clock whose time only changes when it is advanced. Sleep
+// advances the clock rather than waiting. It is safe for concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewFakeClock returns a FakeClock set to the current time, which keeps it
+// consistent with the expiry times of tokens obtained from a UAA.
+func NewFakeClock() *FakeClock {
+	return &FakeClock{now: time.Now()}
+}
+
+// Now returns the clock's time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep advances the clock by d and returns immediately.
+func (c *FakeClock) Sleep(d time.Duration) {
+	c.Advance(d)
+}
+
+// After returns a channel that receives the clock's time once the clock has
+// been advanced by d, by Advance or Sleep.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), c: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.now = c.now.Add(d)
+	}
+	waiting := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiting = append(waiting, w)
+			continue
+		}
+		w.c <- c.now
+	}
+	c.waiters = waiting
+}
@@ -0,0 +1,104 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestClock(t *testing.T) {
+	spec.Run(t, "Clock", testClock, spec.Report(report.Terminal{}))
+}
+
+func testClock(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		handler http.Handler
+		clock   *uaa.FakeClock
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		clock = uaa.NewFakeClock()
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			handler.ServeHTTP(w, req)
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("advances when slept", func() {
+		start := clock.Now()
+		clock.Sleep(time.Hour)
+		Expect(clock.Now().Sub(start)).To(Equal(time.Hour))
+	})
+
+	it("delivers After once the clock has advanced far enough", func() {
+		after := clock.After(time.Hour)
+		clock.Advance(30 * time.Minute)
+		Consistently(after).ShouldNot(Receive())
+		clock.Advance(30 * time.Minute)
+		Eventually(after).Should(Receive(Equal(clock.Now())))
+		Eventually(clock.After(0)).Should(Receive())
+	})
+
+	it("refreshes the token when the clock passes its expiry", func() {
+		issued := 0
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			issued++
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"token-` + strconv.Itoa(issued) + `","token_type":"bearer","expires_in":3600}`))
+		})
+		api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.OpaqueToken, uaa.WithClock(clock))
+		Expect(err).NotTo(HaveOccurred())
+
+		token, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("token-1"))
+		clock.Advance(30 * time.Minute)
+		token, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("token-1"))
+
+		clock.Advance(time.Hour)
+		token, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("token-2"))
+	})
+
+	it("paces retries without sleeping", func() {
+		calls := 0
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls++
+			if calls < 3 {
+				w.Header().Set("Retry-After", "600")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"id":"marcus-id"}`))
+		})
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a := &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+		uaa.WithClock(clock)(a)
+		uaa.WithPacer(uaa.NewPacer())(a)
+
+		start := clock.Now()
+		began := time.Now()
+		_, err := a.GetUser("marcus-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal(3))
+		Expect(clock.Now().Sub(start)).To(Equal(20 * time.Minute))
+		Expect(time.Since(began)).To(BeNumerically("<", time.Second))
+	})
+}
@@ -0,0 +1,142 @@
+package uaa_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+// These tests are most useful when run with -race.
+
+func TestConcurrency(t *testing.T) {
+	spec.Run(t, "Concurrency", testConcurrency, spec.Report(report.Terminal{}))
+}
+
+func testConcurrency(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s      *httptest.Server
+		tokens int32
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		atomic.StoreInt32(&tokens, 0)
+		s = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch req.URL.Path {
+			case "/oauth/token":
+				atomic.AddInt32(&tokens, 1)
+				w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":2}`))
+			case "/Users":
+				if req.Method == http.MethodPost {
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte(`{"id":"00000000-0000-0000-0000-000000000001","userName":"marcus"}`))
+					return
+				}
+				start, _ := strconv.Atoi(req.URL.Query().Get("startIndex"))
+				if start == 0 {
+					start = 1
+				}
+				fmt.Fprintf(w, `{"resources":[{"id":"user-%d","userName":"user-%d"}],"startIndex":%d,"itemsPerPage":1,"totalResults":5}`, start, start, start)
+			default:
+				w.Write([]byte(`{"app":{"version":"74.4.0"}}`))
+			}
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	stress := func(n int, fn func(i int)) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				fn(i)
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	it("makes requests and refreshes tokens from many goroutines", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var events, responses int32
+		a, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken,
+			uaa.WithSkipSSLValidation(true),
+			uaa.WithExpiryLeeway(time.Second),
+			uaa.WithKeepAlive(ctx, time.Second),
+			uaa.WithCircuitBreaker(uaa.NewCircuitBreaker()),
+			uaa.WithConnectionStats(),
+			uaa.WithTokenEvents(func(uaa.TokenEvent) { atomic.AddInt32(&events, 1) }),
+			uaa.WithResponseHook(func(*http.Response, error, time.Duration) { atomic.AddInt32(&responses, 1) }))
+		Expect(err).NotTo(HaveOccurred())
+
+		stress(60, func(i int) {
+			switch i % 4 {
+			case 0:
+				users, err := a.ListAllUsers("", "", "", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(users).To(HaveLen(5))
+			case 1:
+				token, err := a.Token()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(token.AccessToken).To(Equal("token"))
+			case 2:
+				_, err := a.GetInfo()
+				Expect(err).NotTo(HaveOccurred())
+			default:
+				a.SetVerbose(false)
+			}
+		})
+		Expect(atomic.LoadInt32(&tokens)).To(BeNumerically(">=", 1))
+		Expect(atomic.LoadInt32(&events)).To(BeNumerically(">=", 1))
+		Expect(atomic.LoadInt32(&responses)).To(BeNumerically(">=", 15*5+15))
+	})
+
+	it("plans writes and fetches pages concurrently", func() {
+		a, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken,
+			uaa.WithSkipSSLValidation(true), uaa.WithDryRun(), uaa.WithLazyAuthentication(), uaa.WithPageConcurrency(4), uaa.WithPacer(uaa.NewPacer()))
+		Expect(err).NotTo(HaveOccurred())
+
+		stress(40, func(i int) {
+			if i%2 == 0 {
+				_, err := a.CreateUser(uaa.User{Username: fmt.Sprintf("user-%d", i)})
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			users, err := a.ListAllUsers("", "", "", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(users[4].ID).To(Equal("user-5"))
+		})
+		Expect(a.PlannedActions()).To(HaveLen(20))
+	})
+
+	it("shares a client between APIs", func() {
+		u, _ := url.Parse(s.URL)
+		c := &http.Client{Transport: &http.Transport{}}
+		apis := make([]*uaa.API, 4)
+		for i := range apis {
+			apis[i] = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c, SkipSSLValidation: true}
+		}
+
+		stress(40, func(i int) {
+			_, err := apis[i%len(apis)].GetInfo()
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+}
@@ -0,0 +1,263 @@
+package uaa
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// AuthMethod is the way an API described by a Config authenticates.
+type AuthMethod string
+
+// Valid AuthMethod values.
+const (
+	// AuthClientCredentials uses the client credentials grant, as
+	// NewWithClientCredentials does.
+	AuthClientCredentials AuthMethod = "client_credentials"
+	// AuthPassword uses the password grant, as NewWithPasswordCredentials
+	// does.
+	AuthPassword AuthMethod = "password"
+	// AuthToken uses an access token obtained elsewhere, as NewWithToken
+	// does.
+	AuthToken AuthMethod = "token"
+)
+
+// SecretRef refers to a secret kept outside a Config, so that the Config can
+// be stored and passed between processes without exposing the secret. It is
+// either "env:NAME", for the value of the environment variable NAME, or
+// "file:PATH", for the contents of the file at PATH with surrounding
+// whitespace removed.
+type SecretRef string
+
+// Resolve returns the secret that the reference refers to.
+func (r SecretRef) Resolve() (string, error) {
+	switch {
+	case strings.HasPrefix(string(r), "env:"):
+		name := strings.TrimPrefix(string(r), "env:")
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("%v is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(string(r), "file:"):
+		data, err := ioutil.ReadFile(strings.TrimPrefix(string(r), "file:"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", fmt.Errorf("unknown secret reference %q, must start with env: or file:", string(r))
+}
+
+// AuthConfig describes how an API authenticates. Secrets are given by
+// reference.
+type AuthConfig struct {
+	Method       AuthMethod  `json:"method"`
+	ClientID     string      `json:"clientId,omitempty"`
+	ClientSecret SecretRef   `json:"clientSecret,omitempty"`
+	Username     string      `json:"username,omitempty"`
+	Password     SecretRef   `json:"password,omitempty"`
+	Token        SecretRef   `json:"token,omitempty"`
+	TokenFormat  TokenFormat `json:"tokenFormat"`
+}
+
+// Config is a serializable description of an API, from which NewFromConfig
+// builds an equivalent API, for example in a worker process. It never
+// contains secrets.
+type Config struct {
+	Target            string     `json:"target"`
+	ZoneID            string     `json:"zoneId,omitempty"`
+	SkipSSLValidation bool       `json:"skipSslValidation,omitempty"`
+	Auth              AuthConfig `json:"auth"`
+	// TokenParams are the parameters sent with token requests, as set by
+	// WithScopes and WithTokenParams.
+	TokenParams   url.Values        `json:"tokenParams,omitempty"`
+	BasePath      string            `json:"basePath,omitempty"`
+	EndpointPaths map[string]string `json:"endpointPaths,omitempty"`
+	ReadOnly      bool              `json:"readOnly,omitempty"`
+	// Proxy, FallbackTargets and PinnedAddresses are the arguments of
+	// WithProxy, WithFallbackTargets and WithPinnedAddresses.
+	Proxy           string   `json:"proxy,omitempty"`
+	FallbackTargets []string `json:"fallbackTargets,omitempty"`
+	PinnedAddresses []string `json:"pinnedAddresses,omitempty"`
+	// The remaining fields are the arguments of the options of the same
+	// names. Durations are encoded as nanoseconds.
+	MaxResponseSize      int64         `json:"maxResponseSize,omitempty"`
+	ExpiryLeeway         time.Duration `json:"expiryLeeway,omitempty"`
+	MaxClockSkew         time.Duration `json:"maxClockSkew,omitempty"`
+	RestrictedScopeCheck bool          `json:"restrictedScopeCheck,omitempty"`
+	ScopePreflight       bool          `json:"scopePreflight,omitempty"`
+	PageConcurrency      int           `json:"pageConcurrency,omitempty"`
+	ListDeadline         time.Duration `json:"listDeadline,omitempty"`
+}
+
+// Config describes the API so that NewFromConfig can build an equivalent
+// one. Only APIs built with NewWithClientCredentials,
+// NewWithPasswordCredentials, NewWithToken or NewFromConfig can be described.
+// The secrets the API was built with are not included: unless the API was
+// built by NewFromConfig, set the references to them in the returned
+// Config's Auth before using it. Options that are functions, such as hooks,
+// are not included either and must be passed to NewFromConfig again. An API
+// whose proxy URL includes a password cannot be described.
+func (a *API) Config() (*Config, error) {
+	if a.TargetURL == nil {
+		return nil, errors.New("the API does not have a target")
+	}
+	if a.auth == nil {
+		return nil, errors.New("the API's authentication method cannot be described by a Config")
+	}
+	cfg := &Config{
+		Target:               a.TargetURL.String(),
+		ZoneID:               a.ZoneID,
+		SkipSSLValidation:    a.SkipSSLValidation,
+		Auth:                 *a.auth,
+		ReadOnly:             a.readOnly,
+		PinnedAddresses:      append([]string(nil), a.pinnedAddresses...),
+		MaxResponseSize:      a.maxResponseSize,
+		ExpiryLeeway:         a.expiryLeeway,
+		MaxClockSkew:         a.maxClockSkew,
+		RestrictedScopeCheck: a.restrictedScopeCheck,
+		ScopePreflight:       a.scopePreflight,
+		PageConcurrency:      a.pageConcurrency,
+		ListDeadline:         a.listTimeout,
+	}
+	if a.proxyURL != nil {
+		if _, ok := a.proxyURL.User.Password(); ok {
+			return nil, errors.New("the API's proxy URL includes a password, which a Config cannot contain")
+		}
+		cfg.Proxy = a.proxyURL.String()
+	}
+	for _, target := range a.fallbackTargets {
+		cfg.FallbackTargets = append(cfg.FallbackTargets, target.String())
+	}
+	for key, values := range a.tokenParams {
+		if key == "token_format" {
+			continue
+		}
+		if cfg.TokenParams == nil {
+			cfg.TokenParams = url.Values{}
+		}
+		cfg.TokenParams[key] = append([]string(nil), values...)
+	}
+	if a.paths != nil {
+		a.paths.mu.RLock()
+		cfg.BasePath = a.paths.base
+		for endpoint, path := range a.paths.endpoints {
+			if cfg.EndpointPaths == nil {
+				cfg.EndpointPaths = map[string]string{}
+			}
+			cfg.EndpointPaths[endpoint] = path
+		}
+		a.paths.mu.RUnlock()
+	}
+	return cfg, nil
+}
+
+// NewFromConfig builds the API that the Config describes, resolving the
+// secrets it refers to. The options are applied after those from the
+// Config. A token given by reference must be a JSON Web Token, so that its
+// expiry is known.
+func NewFromConfig(cfg Config, opts ...Option) (*API, error) {
+	var configured []Option
+	if len(cfg.TokenParams) > 0 {
+		configured = append(configured, WithTokenParams(cfg.TokenParams))
+	}
+	if cfg.BasePath != "" {
+		configured = append(configured, WithBasePath(cfg.BasePath))
+	}
+	for endpoint, path := range cfg.EndpointPaths {
+		configured = append(configured, WithEndpointPath(endpoint, path))
+	}
+	if cfg.ReadOnly {
+		configured = append(configured, WithReadOnly())
+	}
+	if len(cfg.PinnedAddresses) > 0 {
+		configured = append(configured, WithPinnedAddresses(cfg.PinnedAddresses...))
+	}
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		configured = append(configured, WithProxy(proxyURL))
+	}
+	if len(cfg.FallbackTargets) > 0 {
+		targets := make([]*url.URL, len(cfg.FallbackTargets))
+		for i, target := range cfg.FallbackTargets {
+			u, err := url.Parse(target)
+			if err != nil {
+				return nil, err
+			}
+			targets[i] = u
+		}
+		configured = append(configured, WithFallbackTargets(targets...))
+	}
+	if cfg.RestrictedScopeCheck {
+		configured = append(configured, WithRestrictedScopeCheck())
+	}
+	if cfg.ScopePreflight {
+		configured = append(configured, WithScopePreflight())
+	}
+	configured = append(configured,
+		WithMaxResponseSize(cfg.MaxResponseSize),
+		WithExpiryLeeway(cfg.ExpiryLeeway),
+		WithMaxClockSkew(cfg.MaxClockSkew),
+		WithPageConcurrency(cfg.PageConcurrency),
+		WithListDeadline(cfg.ListDeadline),
+		WithSkipSSLValidation(cfg.SkipSSLValidation))
+	opts = append(configured, opts...)
+
+	resolve := func(name string, ref SecretRef) (string, error) {
+		if ref == "" {
+			return "", fmt.Errorf("the config has no reference to the %s", name)
+		}
+		return ref.Resolve()
+	}
+
+	var (
+		a                             *API
+		secret, password, accessToken string
+		err                           error
+	)
+	switch cfg.Auth.Method {
+	case AuthClientCredentials:
+		if secret, err = resolve("client secret", cfg.Auth.ClientSecret); err != nil {
+			return nil, err
+		}
+		a, err = NewWithClientCredentials(cfg.Target, cfg.ZoneID, cfg.Auth.ClientID, secret, cfg.Auth.TokenFormat, opts...)
+	case AuthPassword:
+		if secret, err = resolve("client secret", cfg.Auth.ClientSecret); err != nil {
+			return nil, err
+		}
+		if password, err = resolve("password", cfg.Auth.Password); err != nil {
+			return nil, err
+		}
+		a, err = NewWithPasswordCredentials(cfg.Target, cfg.ZoneID, cfg.Auth.ClientID, secret, cfg.Auth.Username, password, cfg.Auth.TokenFormat, opts...)
+	case AuthToken:
+		if accessToken, err = resolve("token", cfg.Auth.Token); err != nil {
+			return nil, err
+		}
+		claims, err := DecodeClaims(accessToken)
+		if err != nil {
+			return nil, err
+		}
+		token := oauth2.Token{AccessToken: accessToken, TokenType: "bearer", Expiry: claims.ExpiresAt()}
+		if a, err = NewWithToken(cfg.Target, cfg.ZoneID, token, opts...); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown authentication method %q", string(cfg.Auth.Method))
+	}
+	if err != nil {
+		return nil, err
+	}
+	auth := cfg.Auth
+	a.auth = &auth
+	return a, nil
+}
@@ -0,0 +1,114 @@
+package uaa
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// ConnectionStats describe how the connection for a request to the UAA was
+// obtained, to help diagnose slow requests. The durations are zero for steps
+// that did not happen, such as when a connection was reused.
+type ConnectionStats struct {
+	// DNSLookup is the time spent resolving the UAA's host name.
+	DNSLookup time.Duration
+	// Connect is the time spent establishing the TCP connection.
+	Connect time.Duration
+	// TLSHandshake is the time spent on the TLS handshake.
+	TLSHandshake time.Duration
+	// Reused is true if the request was sent on a connection that had been
+	// used before, and WasIdle if that connection had been idle, for IdleTime.
+	Reused   bool
+	WasIdle  bool
+	IdleTime time.Duration
+	// TimeToFirstByte is the time from asking for a connection to receiving
+	// the first byte of the response.
+	TimeToFirstByte time.Duration
+}
+
+// WithConnectionStats returns an Option that traces how the connection for
+// each request is obtained, so that a response hook can get the
+// ConnectionStats for the response with ConnectionStatsFor. Frequent DNS
+// lookups and TLS handshakes, or few reused connections, suggest that
+// connections are not being kept alive between requests.
+func WithConnectionStats() Option {
+	return func(a *API) {
+		a.connectionStats = true
+	}
+}
+
+// ConnectionStatsFor returns the connection stats for the response, and
+// false if the API that made the request was not created with
+// WithConnectionStats.
+func ConnectionStatsFor(resp *http.Response) (ConnectionStats, bool) {
+	if resp == nil || resp.Request == nil {
+		return ConnectionStats{}, false
+	}
+	trace, ok := resp.Request.Context().Value(connectionTraceKey{}).(*connectionTrace)
+	if !ok {
+		return ConnectionStats{}, false
+	}
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	return trace.stats, true
+}
+
+type connectionTraceKey struct{}
+
+// connectionTrace collects the stats for a request. The transport may call
+// its hooks from other goroutines, such as for a dial that loses a race with
+// another, so they are guarded by a mutex.
+type connectionTrace struct {
+	mu                       sync.Mutex
+	stats                    ConnectionStats
+	start, dns, connect, tls time.Time
+}
+
+// traceConnection returns a copy of the request that collects its connection
+// stats.
+func traceConnection(req *http.Request) *http.Request {
+	t := &connectionTrace{}
+	record := func(f func()) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		f()
+	}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			record(func() { t.start = time.Now() })
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			record(func() { t.dns = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			record(func() { t.stats.DNSLookup = time.Since(t.dns) })
+		},
+		ConnectStart: func(string, string) {
+			record(func() { t.connect = time.Now() })
+		},
+		ConnectDone: func(string, string, error) {
+			record(func() { t.stats.Connect = time.Since(t.connect) })
+		},
+		TLSHandshakeStart: func() {
+			record(func() { t.tls = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			record(func() { t.stats.TLSHandshake = time.Since(t.tls) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			record(func() {
+				t.stats.Reused = info.Reused
+				t.stats.WasIdle = info.WasIdle
+				t.stats.IdleTime = info.IdleTime
+			})
+		},
+		GotFirstResponseByte: func() {
+			record(func() { t.stats.TimeToFirstByte = time.Since(t.start) })
+		},
+	}
+	ctx := context.WithValue(req.Context(), connectionTraceKey{}, t)
+	return req.WithContext(httptrace.WithClientTrace(ctx, trace))
+}
@@ -0,0 +1,69 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestConnectionStats(t *testing.T) {
+	spec.Run(t, "ConnectionStats", testConnectionStats, spec.Report(report.Terminal{}))
+}
+
+func testConnectionStats(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s     *httptest.Server
+		a     *uaa.API
+		stats []uaa.ConnectionStats
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		stats = nil
+		s = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"app":{"version":"74.4.0"}}`))
+		}))
+		u, _ := url.Parse(s.URL)
+		c := s.Client()
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+		uaa.WithResponseHook(func(resp *http.Response, err error, elapsed time.Duration) {
+			if s, ok := uaa.ConnectionStatsFor(resp); ok {
+				stats = append(stats, s)
+			}
+		})(a)
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("reports a new connection and then a reused one", func() {
+		uaa.WithConnectionStats()(a)
+		_, err := a.GetInfo()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = a.GetInfo()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(stats).To(HaveLen(2))
+		Expect(stats[0].Reused).To(BeFalse())
+		Expect(stats[0].Connect).To(BeNumerically(">", 0))
+		Expect(stats[0].TLSHandshake).To(BeNumerically(">", 0))
+		Expect(stats[0].TimeToFirstByte).To(BeNumerically(">", 0))
+		Expect(stats[1].Reused).To(BeTrue())
+		Expect(stats[1].TLSHandshake).To(BeZero())
+	})
+
+	it("reports nothing unless enabled", func() {
+		_, err := a.GetInfo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stats).To(BeEmpty())
+	})
+}
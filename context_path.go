@@ -0,0 +1,123 @@
+package uaa
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// DefaultContextPath is the path under which BOSH Lite and some other
+// distributions mount the UAA.
+const DefaultContextPath = "/uaa"
+
+// WithoutContextPathDetection returns an Option that turns off the detection
+// of a UAA mounted under DefaultContextPath. By default, when the target
+// responds 404 Not Found to a request for /info or /oauth/token, an API built
+// by one of the New* constructors checks whether the UAA answers at
+// /uaa/info instead, and if it does, sends that request and all later ones
+// under /uaa, as if WithBasePath("/uaa") had been given. Detection is tried
+// once per API, and not at all when a base path has been given.
+func WithoutContextPathDetection() Option {
+	return func(a *API) {
+		a.noContextPathDetection = true
+	}
+}
+
+// contextPathTriggers are the paths whose 404 responses start detection; they
+// are the requests that a misconfigured target fails on first.
+var contextPathTriggers = map[string]bool{
+	"/info":        true,
+	"/oauth/token": true,
+}
+
+// installContextPathDetection makes the API's clients detect a UAA mounted
+// under DefaultContextPath unless the caller opted out.
+func (a *API) installContextPathDetection() {
+	if a.noContextPathDetection || a.TargetURL == nil {
+		return
+	}
+	paths := a.installPaths()
+	paths.mu.Lock()
+	defer paths.mu.Unlock()
+	paths.detect = true
+}
+
+// detectContextPath decides, after a request for path was answered 404 Not
+// Found, whether the UAA is mounted under DefaultContextPath, using probe to
+// check. It returns true if the base path was changed, in which case the
+// request should be sent again.
+func (r *pathRewriter) detectContextPath(path string, probe func(path string) bool) bool {
+	r.mu.RLock()
+	candidate := r.detect && r.base == "" && contextPathTriggers[path]
+	for endpoint := range r.endpoints {
+		if path == endpoint {
+			candidate = false
+		}
+	}
+	r.mu.RUnlock()
+	if !candidate {
+		return false
+	}
+
+	found := false
+	r.detectOnce.Do(func() {
+		found = probe(DefaultContextPath + "/info")
+		if found {
+			r.setBase(DefaultContextPath)
+		}
+	})
+	return found
+}
+
+// retryUnderContextPath sends the request, which was for path, once more if
+// the UAA answered it 404 Not Found and turns out to be mounted under
+// DefaultContextPath. The context path may also have been detected while the
+// request was in flight, by the token request made for it.
+func (a *API) retryUnderContextPath(client *http.Client, path string, req *http.Request, resp *http.Response) (*http.Response, error) {
+	if a.paths == nil || resp.StatusCode != http.StatusNotFound {
+		return resp, nil
+	}
+	prober := a.UnauthenticatedClient
+	if prober == nil {
+		prober = client
+	}
+	probe := func(p string) bool {
+		return probeInfo(prober.Do, req, p)
+	}
+	a.paths.detectContextPath(path, probe)
+	rewritten := a.paths.rewrite(path)
+	if rewritten == req.URL.Path || !rewindBody(req) {
+		return resp, nil
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	u := *req.URL
+	u.Path = rewritten
+	req.URL = &u
+	return a.send(client, req)
+}
+
+// probeInfo returns true if the UAA's server information is served at path
+// on the request's host, using roundTrip to make the request.
+func probeInfo(roundTrip func(*http.Request) (*http.Response, error), req *http.Request, path string) bool {
+	u := url.URL{Scheme: req.URL.Scheme, Host: req.URL.Host, Path: path}
+	probe, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false
+	}
+	probe.Header.Set("Accept", "application/json")
+	probe = probe.WithContext(context.WithValue(req.Context(), pathsRewrittenKey{}, true))
+	resp, err := roundTrip(probe)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	info := Info{}
+	return json.NewDecoder(resp.Body).Decode(&info) == nil && info.App.Version != ""
+}
@@ -0,0 +1,106 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/oauth2"
+)
+
+func TestContextPath(t *testing.T) {
+	spec.Run(t, "ContextPath", testContextPath, spec.Report(report.Terminal{}))
+}
+
+func testContextPath(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s     *httptest.Server
+		calls []string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		calls = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, req.Method+" "+req.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			switch req.URL.Path {
+			case "/uaa/info":
+				w.Write([]byte(`{"app":{"version":"4.30.0"},"zone_name":"uaa"}`))
+			case "/uaa/oauth/token":
+				w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":3600}`))
+			case "/uaa/Users/00000000-0000-0000-0000-000000000001":
+				w.Write([]byte(MarcusUserResponse))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("detects the context path when the token request is not found", func() {
+		a, err := uaa.NewWithClientCredentials(s.URL, "", "admin", "secret", uaa.JSONWebToken)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = a.GetUser("00000000-0000-0000-0000-000000000001")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal([]string{
+			"POST /oauth/token",
+			"GET /uaa/info",
+			"POST /uaa/oauth/token",
+			// The request was addressed before its token was obtained.
+			"GET /Users/00000000-0000-0000-0000-000000000001",
+			"GET /uaa/Users/00000000-0000-0000-0000-000000000001",
+		}))
+		Expect(a.AuthorizeURL("app", "", "", nil)).To(HavePrefix(s.URL + "/uaa/oauth/authorize"))
+	})
+
+	it("detects the context path when the server information is not found", func() {
+		a, err := uaa.NewWithToken(s.URL, "", oauth2.Token{AccessToken: "token", Expiry: time.Now().Add(time.Hour)})
+		Expect(err).NotTo(HaveOccurred())
+		info, err := a.GetInfo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.App.Version).To(Equal("4.30.0"))
+		Expect(calls).To(Equal([]string{"GET /info", "GET /uaa/info", "GET /uaa/info"}))
+	})
+
+	it("only tries to detect the context path once", func() {
+		s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, req.Method+" "+req.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		})
+		a, err := uaa.NewWithToken(s.URL, "", oauth2.Token{AccessToken: "token", Expiry: time.Now().Add(time.Hour)})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = a.GetInfo()
+		Expect(err).To(HaveOccurred())
+		_, err = a.GetInfo()
+		Expect(err).To(HaveOccurred())
+		Expect(calls).To(Equal([]string{"GET /info", "GET /uaa/info", "GET /info"}))
+	})
+
+	it("does not probe for other requests that are not found", func() {
+		a, err := uaa.NewWithToken(s.URL, "", oauth2.Token{AccessToken: "token", Expiry: time.Now().Add(time.Hour)})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = a.GetUser("missing")
+		Expect(err).To(HaveOccurred())
+		Expect(calls).To(Equal([]string{"GET /Users/missing"}))
+	})
+
+	it("can be turned off", func() {
+		a, err := uaa.NewWithClientCredentials(s.URL, "", "admin", "secret", uaa.JSONWebToken, uaa.WithoutContextPathDetection())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = a.GetUser("00000000-0000-0000-0000-000000000001")
+		Expect(err).To(HaveOccurred())
+		for _, call := range calls {
+			Expect(strings.HasPrefix(call, "GET /uaa")).To(BeFalse())
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package uaa
+
+// CountUsers returns the number of users matching the filter. Only the first
+// user's ID is transferred, so counting is cheap even when there are many
+// users.
+func (a *API) CountUsers(filter string) (int, error) {
+	_, page, err := a.ListUsers(filter, "", "id", "", 1, 1)
+	if err != nil {
+		return 0, err
+	}
+	return page.TotalResults, nil
+}
+
+// CountClients returns the number of clients matching the filter. Only the
+// first client is transferred, so counting is cheap even when there are many
+// clients.
+func (a *API) CountClients(filter string) (int, error) {
+	_, page, err := a.ListClients(filter, "", "", 1, 1)
+	if err != nil {
+		return 0, err
+	}
+	return page.TotalResults, nil
+}
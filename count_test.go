@@ -0,0 +1,73 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestCount(t *testing.T) {
+	spec.Run(t, "Count", testCount, spec.Report(report.Terminal{}))
+}
+
+func testCount(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		handler http.Handler
+		a       *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			handler.ServeHTTP(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("counts users with a single-item page", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal(uaa.UsersEndpoint))
+			query := req.URL.Query()
+			Expect(query.Get("filter")).To(Equal(`origin eq "ldap"`))
+			Expect(query.Get("count")).To(Equal("1"))
+			Expect(query.Get("attributes")).To(Equal("id"))
+			w.Write([]byte(MultiPaginatedResponse(1, 1, 4231, uaa.User{ID: "id"})))
+		})
+		count, err := a.CountUsers(`origin eq "ldap"`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(4231))
+	})
+
+	it("counts clients with a single-item page", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal(uaa.ClientsEndpoint))
+			Expect(req.URL.Query().Get("count")).To(Equal("1"))
+			w.Write([]byte(MultiPaginatedResponse(1, 1, 12, uaa.Client{ClientID: "client"})))
+		})
+		count, err := a.CountClients("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(12))
+	})
+
+	it("returns an error when the request fails", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+		count, err := a.CountUsers("")
+		Expect(err).To(HaveOccurred())
+		Expect(count).To(Equal(0))
+	})
+}
@@ -0,0 +1,145 @@
+package uaa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudfoundry-community/go-uaa/passwordcredentials"
+	"golang.org/x/oauth2"
+)
+
+// PromptedCredentials are what a user logs in with: a username and password,
+// or a one-time passcode from the UAA's passcode page.
+type PromptedCredentials struct {
+	Username string
+	Password string
+	Passcode string
+}
+
+// CredentialsPrompter collects a user's credentials when they are needed,
+// for example by prompting on a terminal.
+type CredentialsPrompter interface {
+	PromptCredentials() (PromptedCredentials, error)
+}
+
+// CredentialsPrompterFunc is a CredentialsPrompter implemented by a function.
+type CredentialsPrompterFunc func() (PromptedCredentials, error)
+
+// PromptCredentials calls f.
+func (f CredentialsPrompterFunc) PromptCredentials() (PromptedCredentials, error) {
+	return f()
+}
+
+// NewWithCredentialsPrompter builds an API that asks prompter for the user's
+// credentials when it first needs a token, rather than when it is built, and
+// uses the password grant to get a token for them. Later tokens are obtained
+// with the refresh token; the user is prompted again only if the UAA rejects
+// the refresh token or there is none. Use WithMFACodePrompt to also prompt
+// for a multi-factor authentication code.
+//
+// No requests are made until the API is used, so a CLI can build the API
+// before it knows whether a command needs to authenticate.
+func NewWithCredentialsPrompter(target string, zoneID string, clientID string, clientSecret string, prompter CredentialsPrompter, tokenFormat TokenFormat, opts ...Option) (*API, error) {
+	if err := tokenFormat.Validate(); err != nil {
+		return nil, err
+	}
+	if prompter == nil {
+		return nil, errors.New("prompter cannot be nil")
+	}
+	u, err := BuildTargetURL(target)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenURL := urlWithPath(*u, "/oauth/token")
+	v := url.Values{}
+	v.Add("token_format", tokenFormat.String())
+	client := &http.Client{Transport: http.DefaultTransport}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+	a := &API{
+		UnauthenticatedClient: client,
+		TargetURL:             u,
+		ZoneID:                zoneID,
+		tokenParams:           v,
+	}
+	source := promptTokenSource{
+		api:      a,
+		ctx:      ctx,
+		prompter: prompter,
+		config: &passwordcredentials.Config{
+			ClientID:       clientID,
+			ClientSecret:   clientSecret,
+			Endpoint:       oauth2.Endpoint{TokenURL: tokenURL.String()},
+			EndpointParams: v,
+		},
+	}
+	refreshURL := tokenURL
+	refreshURL.RawQuery = v.Encode()
+	c := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     oauth2.Endpoint{TokenURL: refreshURL.String()},
+	}
+	a.AuthenticatedClient = oauth2.NewClient(ctx, oauth2.ReuseTokenSource(nil, source))
+	a.requestToken = refreshTokenRequester(ctx, a, c)
+	a.applyOptions(opts)
+	refresh := refreshWithToken(ctx, c)
+	a.installExpiryTokenSource(func(current *oauth2.Token) (*oauth2.Token, error) {
+		if current == nil || current.RefreshToken == "" {
+			return source.Token()
+		}
+		token, err := refresh(current)
+		if err != nil && tokenRejected(err) {
+			return source.Token()
+		}
+		return token, err
+	})
+	return a, nil
+}
+
+// promptTokenSource requests tokens with the password grant, prompting for
+// the credentials each time.
+type promptTokenSource struct {
+	api      *API
+	ctx      context.Context
+	prompter CredentialsPrompter
+	config   *passwordcredentials.Config
+}
+
+func (s promptTokenSource) Token() (*oauth2.Token, error) {
+	credentials, err := s.prompter.PromptCredentials()
+	if err != nil {
+		return nil, err
+	}
+	config := *s.config
+	if credentials.Passcode != "" {
+		params := url.Values{}
+		for key, values := range config.EndpointParams {
+			params[key] = values
+		}
+		params.Set("passcode", credentials.Passcode)
+		config.EndpointParams = params
+	} else {
+		config.Username = credentials.Username
+		config.Password = credentials.Password
+	}
+	token, err := s.api.passwordToken(s.ctx, config)
+	if credentialsErr := badCredentials(err); credentialsErr != nil {
+		return nil, credentialsErr
+	}
+	return token, err
+}
+
+// tokenRejected returns true if err is the UAA's refusal of a token request,
+// as opposed to a failure to reach the UAA.
+func tokenRejected(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	retrieveErr, ok := err.(*oauth2.RetrieveError)
+	return ok && retrieveErr.Response != nil &&
+		retrieveErr.Response.StatusCode >= http.StatusBadRequest &&
+		retrieveErr.Response.StatusCode < http.StatusInternalServerError
+}
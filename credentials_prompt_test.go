@@ -0,0 +1,140 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestCredentialsPrompter(t *testing.T) {
+	spec.Run(t, "CredentialsPrompter", testCredentialsPrompter, spec.Report(report.Terminal{}))
+}
+
+func testCredentialsPrompter(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s            *httptest.Server
+		grants       []string
+		refreshValid bool
+		prompts      int
+		credentials  uaa.PromptedCredentials
+		prompter     uaa.CredentialsPrompter
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		grants = nil
+		refreshValid = true
+		prompts = 0
+		credentials = uaa.PromptedCredentials{Username: "marcus", Password: "meditations"}
+		prompter = uaa.CredentialsPrompterFunc(func() (uaa.PromptedCredentials, error) {
+			prompts++
+			return credentials, nil
+		})
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.ParseForm()).To(Succeed())
+			w.Header().Set("Content-Type", "application/json")
+			switch req.Form.Get("grant_type") {
+			case "password":
+				grants = append(grants, "password "+req.Form.Get("username")+req.Form.Get("passcode"))
+				if req.Form.Get("password") == "wrong" {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error":"unauthorized","error_description":"Bad credentials"}`))
+					return
+				}
+			case "refresh_token":
+				grants = append(grants, "refresh_token "+req.Form.Get("refresh_token"))
+				if !refreshValid {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error":"invalid_token","error_description":"Invalid refresh token (expired)"}`))
+					return
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "refresh_token": "refresh", "token_type": "bearer", "expires_in": 3600})
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("prompts when the first token is needed", func() {
+		api, err := uaa.NewWithCredentialsPrompter(s.URL, "", "cf", "", prompter, uaa.OpaqueToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prompts).To(Equal(0))
+		Expect(grants).To(BeEmpty())
+
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prompts).To(Equal(1))
+		Expect(grants).To(Equal([]string{"password marcus"}))
+	})
+
+	it("refreshes later tokens without prompting", func() {
+		clock := uaa.NewFakeClock()
+		api, err := uaa.NewWithCredentialsPrompter(s.URL, "", "cf", "", prompter, uaa.OpaqueToken, uaa.WithClock(clock))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		clock.Advance(2 * time.Hour)
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prompts).To(Equal(1))
+		Expect(grants).To(Equal([]string{"password marcus", "refresh_token refresh"}))
+	})
+
+	it("prompts again when the refresh token is rejected", func() {
+		clock := uaa.NewFakeClock()
+		api, err := uaa.NewWithCredentialsPrompter(s.URL, "", "cf", "", prompter, uaa.OpaqueToken, uaa.WithClock(clock))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		refreshValid = false
+		clock.Advance(2 * time.Hour)
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prompts).To(Equal(2))
+		Expect(grants).To(ContainElement("refresh_token refresh"))
+		Expect(grants[len(grants)-1]).To(Equal("password marcus"))
+	})
+
+	it("logs in with a passcode", func() {
+		credentials = uaa.PromptedCredentials{Passcode: "a1b2c3"}
+		api, err := uaa.NewWithCredentialsPrompter(s.URL, "", "cf", "", prompter, uaa.OpaqueToken)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grants).To(Equal([]string{"password a1b2c3"}))
+	})
+
+	it("returns wrong credentials as ErrBadCredentials and prompts again next time", func() {
+		credentials.Password = "wrong"
+		api, err := uaa.NewWithCredentialsPrompter(s.URL, "", "cf", "", prompter, uaa.OpaqueToken)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).To(Equal(uaa.ErrBadCredentials{Code: "unauthorized", Description: "Bad credentials"}))
+
+		credentials.Password = "meditations"
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prompts).To(Equal(2))
+	})
+
+	it("does not request a token when the prompt fails", func() {
+		prompter = uaa.CredentialsPrompterFunc(func() (uaa.PromptedCredentials, error) {
+			return uaa.PromptedCredentials{}, errors.New("cancelled")
+		})
+		api, err := uaa.NewWithCredentialsPrompter(s.URL, "", "cf", "", prompter, uaa.OpaqueToken)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).To(MatchError(ContainSubstring("cancelled")))
+		Expect(grants).To(BeEmpty())
+	})
+}
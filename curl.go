@@ -23,13 +23,19 @@ func (a *API) Curl(path string, method string, data string, headers []string) (s
 		return "", "", err
 	}
 
-	if a.Verbose {
+	if a.verbose() {
 		logRequest(req)
 	}
+	if err := a.checkReadOnly(req); err != nil {
+		return "", "", err
+	}
+	if body, planned, err := a.plan(req); planned || err != nil {
+		return "", string(body), err
+	}
 
-	resp, err := a.AuthenticatedClient.Do(req)
+	resp, err := a.send(a.AuthenticatedClient, req)
 	if err != nil {
-		if a.Verbose {
+		if a.verbose() {
 			fmt.Printf("%v\n\n", err)
 		}
 		return "", "", err
@@ -40,12 +46,12 @@ func (a *API) Curl(path string, method string, data string, headers []string) (s
 	resHeaders := string(headerBytes)
 
 	bytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil && a.Verbose {
+	if err != nil && a.verbose() {
 		fmt.Printf("%v\n\n", err)
 	}
 	resBody := string(bytes)
 
-	if a.Verbose {
+	if a.verbose() {
 		logResponse(resp)
 	}
 
@@ -0,0 +1,232 @@
+package uaa
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TokenProblem is a reason that a token is not accepted.
+type TokenProblem string
+
+// The problems reported by DiagnoseToken.
+const (
+	// TokenMalformed is a token that is neither a JSON Web Token nor an
+	// opaque token that the UAA recognizes.
+	TokenMalformed TokenProblem = "malformed"
+	// TokenExpired is a token past its expiry.
+	TokenExpired TokenProblem = "expired"
+	// TokenWrongZone is a token issued by another identity zone than the
+	// API's.
+	TokenWrongZone TokenProblem = "wrong_zone"
+	// TokenWrongIssuer is a token issued by another UAA than the API's
+	// target.
+	TokenWrongIssuer TokenProblem = "wrong_issuer"
+	// TokenUnknownKey is a JSON Web Token signed with a key the UAA does not
+	// publish, typically because the key has been rotated out.
+	TokenUnknownKey TokenProblem = "unknown_key"
+	// TokenBadSignature is a JSON Web Token whose signature does not match.
+	TokenBadSignature TokenProblem = "bad_signature"
+	// TokenRevoked is a token that the UAA reports as no longer active.
+	TokenRevoked TokenProblem = "revoked"
+	// TokenInsufficientScope is a token that lacks scopes that are required.
+	TokenInsufficientScope TokenProblem = "insufficient_scope"
+)
+
+// TokenFinding is a problem found with a token, with a description for
+// people.
+type TokenFinding struct {
+	Problem TokenProblem
+	Detail  string
+}
+
+// TokenDiagnosis is the result of DiagnoseToken.
+type TokenDiagnosis struct {
+	// Opaque is true if the token is not a JSON Web Token.
+	Opaque bool
+	// KeyID is the ID of the key that signed a JSON Web Token.
+	KeyID string
+	// Claims are the token's claims, decoded from a JSON Web Token or
+	// returned by introspection, or nil if they could not be read.
+	Claims *Claims
+	// Findings are the problems found, most fundamental first.
+	Findings []TokenFinding
+	// Unchecked describes the checks that could not be made.
+	Unchecked []string
+}
+
+// OK returns true if no problems were found.
+func (d *TokenDiagnosis) OK() bool {
+	return len(d.Findings) == 0
+}
+
+// Has returns true if the given problem was found.
+func (d *TokenDiagnosis) Has(problem TokenProblem) bool {
+	for _, f := range d.Findings {
+		if f.Problem == problem {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the verdict on the token, one finding per line.
+func (d *TokenDiagnosis) String() string {
+	var lines []string
+	if d.OK() {
+		verdict := "the token is valid"
+		if d.Claims != nil {
+			if who := d.Claims.principal(); who != "" {
+				verdict += " for " + who
+			}
+			if d.Claims.ZoneID != "" {
+				verdict += " in zone " + d.Claims.ZoneID
+			}
+		}
+		lines = append(lines, verdict)
+	} else {
+		for _, f := range d.Findings {
+			lines = append(lines, fmt.Sprintf("%s: %s", f.Problem, f.Detail))
+		}
+	}
+	for _, u := range d.Unchecked {
+		lines = append(lines, "not checked: "+u)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (d *TokenDiagnosis) add(problem TokenProblem, format string, args ...interface{}) {
+	d.Findings = append(d.Findings, TokenFinding{Problem: problem, Detail: fmt.Sprintf(format, args...)})
+}
+
+// principal describes who the token was issued to.
+func (c *Claims) principal() string {
+	if c.Username != "" {
+		return fmt.Sprintf("user %s", c.Username)
+	}
+	if c.UserID != "" {
+		return fmt.Sprintf("user %s", c.UserID)
+	}
+	if c.ClientID != "" {
+		return fmt.Sprintf("client %s", c.ClientID)
+	}
+	return ""
+}
+
+// DiagnoseToken explains why the UAA, or a resource server that trusts it,
+// might reject the given token: because it is malformed, has expired, was
+// issued by another zone or UAA, was signed by a key the UAA no longer
+// publishes, has been revoked, or lacks any of the required scopes. A JSON
+// Web Token is decoded and verified against the UAA's token keys; an opaque
+// token, or a revocable JSON Web Token, is introspected, which needs the
+// uaa.resource authority. An error is returned only if the diagnosis could
+// not be made, for example because the UAA could not be reached.
+func (a *API) DiagnoseToken(token string, requiredScopes ...string) (*TokenDiagnosis, error) {
+	d := &TokenDiagnosis{}
+	if token == "" {
+		d.add(TokenMalformed, "the token is blank")
+		return d, nil
+	}
+
+	if _, ok := splitJWT(token); ok {
+		if done, err := a.diagnoseJWT(d, token); done || err != nil {
+			return d, err
+		}
+	} else {
+		d.Opaque = true
+		introspection, err := a.IntrospectToken(token)
+		if err != nil {
+			return nil, err
+		}
+		if !introspection.Active {
+			d.add(TokenRevoked, "the UAA does not recognize the token as active; it has expired, been revoked, or was never issued by this UAA")
+			return d, nil
+		}
+		claims := introspection.Claims
+		d.Claims = &claims
+	}
+
+	a.diagnoseClaims(d, requiredScopes)
+	return d, nil
+}
+
+// diagnoseJWT decodes and verifies a JSON Web Token, and returns true if
+// there is no point in checking its claims.
+func (a *API) diagnoseJWT(d *TokenDiagnosis, token string) (bool, error) {
+	segments, header, err := parseJWTHeader(token)
+	if err != nil {
+		d.add(TokenMalformed, "the token's header cannot be decoded")
+		return true, nil
+	}
+	d.KeyID = header.Kid
+	claims, err := decodeClaims(segments.payload)
+	if err != nil {
+		d.add(TokenMalformed, "the token's claims cannot be decoded")
+		return true, nil
+	}
+	d.Claims = claims
+
+	keys, err := a.TokenKeys()
+	if err != nil {
+		return true, err
+	}
+	var key *JWK
+	for i := range keys {
+		if header.Kid == "" || keys[i].Kid == header.Kid {
+			key = &keys[i]
+			break
+		}
+	}
+	if key == nil {
+		var kids []string
+		for _, k := range keys {
+			kids = append(kids, k.Kid)
+		}
+		sort.Strings(kids)
+		d.add(TokenUnknownKey, "the token was signed with key %q, but the UAA publishes only %s", header.Kid, strings.Join(kids, ", "))
+	} else if _, err := verifyToken(token, segments, header, key); err != nil {
+		d.add(TokenBadSignature, "the token's signature does not match key %q: %v", key.Kid, err)
+	}
+
+	if claims.Revocable && len(d.Findings) == 0 {
+		introspection, err := a.IntrospectToken(token)
+		if err != nil {
+			d.Unchecked = append(d.Unchecked, fmt.Sprintf("whether the revocable token has been revoked (%v)", err))
+		} else if !introspection.Active {
+			d.add(TokenRevoked, "the token has been revoked")
+		}
+	}
+	return false, nil
+}
+
+// diagnoseClaims checks the expiry, zone, issuer and scopes of the token.
+func (a *API) diagnoseClaims(d *TokenDiagnosis, requiredScopes []string) {
+	claims := d.Claims
+	now := a.getClock().Now()
+	if claims.Expiry != 0 && !now.Before(claims.ExpiresAt()) {
+		d.add(TokenExpired, "the token expired at %s, %v ago", claims.ExpiresAt().UTC().Format(time.RFC3339), now.Sub(claims.ExpiresAt()).Truncate(time.Second))
+	}
+
+	if a.ZoneID != "" && claims.ZoneID != "" && claims.ZoneID != a.ZoneID {
+		d.add(TokenWrongZone, "the token was issued by zone %q, not zone %q", claims.ZoneID, a.ZoneID)
+	}
+	if issuer, err := url.Parse(claims.Issuer); err == nil && claims.Issuer != "" && a.TargetURL != nil {
+		// A zone's tokens are issued by its subdomain of the UAA's host.
+		host, target := strings.ToLower(issuer.Hostname()), strings.ToLower(a.TargetURL.Hostname())
+		if host != target && !strings.HasSuffix(host, "."+target) {
+			d.add(TokenWrongIssuer, "the token was issued by %s, not by the UAA at %s", claims.Issuer, a.TargetURL.Host)
+		}
+	}
+
+	var missing []string
+	for _, scope := range requiredScopes {
+		if !claims.HasScope(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		d.add(TokenInsufficientScope, "the token lacks the scopes %s; it has %s", strings.Join(missing, ", "), strings.Join(claims.Scope, ", "))
+	}
+}
@@ -0,0 +1,140 @@
+package uaa_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestDiagnoseToken(t *testing.T) {
+	spec.Run(t, "DiagnoseToken", testDiagnoseToken, spec.Report(report.Terminal{}))
+}
+
+func testDiagnoseToken(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s          *httptest.Server
+		a          *uaa.API
+		key, other *rsa.PrivateKey
+		active     bool
+		claims     map[string]interface{}
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		other, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		active = true
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch req.URL.Path {
+			case "/token_keys":
+				json.NewEncoder(w).Encode(uaa.Keys{Keys: []uaa.JWK{publicJWK(key, "key-2")}})
+			case "/introspect":
+				json.NewEncoder(w).Encode(map[string]interface{}{"active": active, "zid": "tenant", "client_id": "app", "exp": time.Now().Add(time.Hour).Unix()})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+			ZoneID:                "tenant",
+		}
+		claims = map[string]interface{}{
+			"user_name": "marcus",
+			"zid":       "tenant",
+			"iss":       "http://tenant." + u.Hostname() + ":8080/oauth/token",
+			"exp":       time.Now().Add(time.Hour).Unix(),
+			"scope":     []string{"openid", "scim.read"},
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("finds nothing wrong with a good token", func() {
+		d, err := a.DiagnoseToken(signedJWT(key, "key-2", claims), "scim.read")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.OK()).To(BeTrue())
+		Expect(d.KeyID).To(Equal("key-2"))
+		Expect(d.String()).To(Equal("the token is valid for user marcus in zone tenant"))
+	})
+
+	it("reports an expired token", func() {
+		claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		d, err := a.DiagnoseToken(signedJWT(key, "key-2", claims))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Has(uaa.TokenExpired)).To(BeTrue())
+		Expect(d.String()).To(HavePrefix("expired: the token expired at "))
+	})
+
+	it("reports a token from another zone or UAA", func() {
+		claims["zid"] = "other"
+		claims["iss"] = "https://login.example.com/oauth/token"
+		d, err := a.DiagnoseToken(signedJWT(key, "key-2", claims))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Has(uaa.TokenWrongZone)).To(BeTrue())
+		Expect(d.Has(uaa.TokenWrongIssuer)).To(BeTrue())
+	})
+
+	it("reports a key the UAA no longer publishes", func() {
+		d, err := a.DiagnoseToken(signedJWT(key, "key-1", claims))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Findings).To(HaveLen(1))
+		Expect(d.Findings[0].Problem).To(Equal(uaa.TokenUnknownKey))
+		Expect(d.Findings[0].Detail).To(ContainSubstring(`"key-1", but the UAA publishes only key-2`))
+	})
+
+	it("reports a bad signature", func() {
+		d, err := a.DiagnoseToken(signedJWT(other, "key-2", claims))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Has(uaa.TokenBadSignature)).To(BeTrue())
+	})
+
+	it("reports a revoked token", func() {
+		claims["revocable"] = true
+		active = false
+		d, err := a.DiagnoseToken(signedJWT(key, "key-2", claims))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Has(uaa.TokenRevoked)).To(BeTrue())
+	})
+
+	it("reports missing scopes", func() {
+		d, err := a.DiagnoseToken(signedJWT(key, "key-2", claims), "scim.read", "scim.write")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Findings).To(ConsistOf(uaa.TokenFinding{
+			Problem: uaa.TokenInsufficientScope,
+			Detail:  "the token lacks the scopes scim.write; it has openid, scim.read",
+		}))
+	})
+
+	it("introspects an opaque token", func() {
+		d, err := a.DiagnoseToken("opaque-token")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Opaque).To(BeTrue())
+		Expect(d.OK()).To(BeTrue())
+		Expect(d.String()).To(Equal("the token is valid for client app in zone tenant"))
+
+		active = false
+		d, err = a.DiagnoseToken("opaque-token")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Has(uaa.TokenRevoked)).To(BeTrue())
+	})
+}
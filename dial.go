@@ -0,0 +1,134 @@
+package uaa
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DialContextFunc dials a network connection.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WithDialContext returns an Option that makes the API's connections, including
+// those used to request tokens, with the given dial function. It can be used to
+// reach the UAA through an SSH tunnel or another custom transport.
+//
+// The option must be applied after the API's clients are set; the New*
+// constructors do this. The clients' transports are copied rather than
+// modified, so http.DefaultTransport is not affected.
+func WithDialContext(dial DialContextFunc) Option {
+	return func(a *API) {
+		a.modifyTransports(func(t *http.Transport) {
+			t.DialContext = dial
+		})
+	}
+}
+
+// WithPinnedAddresses returns an Option that connects to the given IP
+// addresses instead of resolving the host name of the API's target, like an
+// entry in /etc/hosts. Each address may include a port; otherwise the port of
+// the target is used. The addresses are tried in order until a connection
+// succeeds. TLS certificates are still verified against the target's host
+// name. Connections to other hosts are made as usual.
+func WithPinnedAddresses(addrs ...string) Option {
+	return func(a *API) {
+		if a.TargetURL == nil || len(addrs) == 0 {
+			return
+		}
+		a.pinnedAddresses = addrs
+		host := a.TargetURL.Hostname()
+		a.modifyTransports(func(t *http.Transport) {
+			t.DialContext = pinnedDialer(host, addrs, t.DialContext)
+		})
+	}
+}
+
+func pinnedDialer(host string, addrs []string, next DialContextFunc) DialContextFunc {
+	if next == nil {
+		next = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		h, port, err := net.SplitHostPort(addr)
+		if err != nil || h != host {
+			return next(ctx, network, addr)
+		}
+		err = errors.New("no pinned addresses")
+		for _, pinned := range addrs {
+			target := pinned
+			if _, _, splitErr := net.SplitHostPort(pinned); splitErr != nil {
+				target = net.JoinHostPort(pinned, port)
+			}
+			var conn net.Conn
+			if conn, err = next(ctx, network, target); err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, err
+	}
+}
+
+// modifyTransports replaces the *http.Transport underneath each of the API's
+// clients with a modified copy.
+func (a *API) modifyTransports(fn func(*http.Transport)) {
+	modified := map[*http.Client]bool{}
+	for _, c := range []*http.Client{a.AuthenticatedClient, a.UnauthenticatedClient} {
+		if c == nil || modified[c] {
+			continue
+		}
+		c.Transport = modifyTransport(c.Transport, fn)
+		modified[c] = true
+	}
+}
+
+func modifyTransport(rt http.RoundTripper, fn func(*http.Transport)) http.RoundTripper {
+	switch t := rt.(type) {
+	case nil:
+		return modifyTransport(http.DefaultTransport, fn)
+	case *http.Transport:
+		c := cloneTransport(t)
+		fn(c)
+		return c
+	case *oauth2.Transport:
+		return &oauth2.Transport{Source: t.Source, Base: modifyTransport(t.Base, fn)}
+	case *tokenTransport:
+		c := cloneTransport(t.underlyingTransport)
+		fn(c)
+		return &tokenTransport{underlyingTransport: c, token: t.token}
+	case *failoverTransport:
+		return &failoverTransport{targets: t.targets, base: modifyTransport(t.base, fn)}
+	case *pathTransport:
+		return &pathTransport{paths: t.paths, base: modifyTransport(t.base, fn)}
+	}
+	return rt
+}
+
+// cloneTransport returns a copy of the transport's configuration.
+func cloneTransport(t *http.Transport) *http.Transport {
+	c := &http.Transport{
+		Proxy:                  t.Proxy,
+		DialContext:            t.DialContext,
+		Dial:                   t.Dial,
+		DialTLS:                t.DialTLS,
+		TLSHandshakeTimeout:    t.TLSHandshakeTimeout,
+		DisableKeepAlives:      t.DisableKeepAlives,
+		DisableCompression:     t.DisableCompression,
+		MaxIdleConns:           t.MaxIdleConns,
+		MaxIdleConnsPerHost:    t.MaxIdleConnsPerHost,
+		IdleConnTimeout:        t.IdleConnTimeout,
+		ResponseHeaderTimeout:  t.ResponseHeaderTimeout,
+		ExpectContinueTimeout:  t.ExpectContinueTimeout,
+		ProxyConnectHeader:     t.ProxyConnectHeader,
+		MaxResponseHeaderBytes: t.MaxResponseHeaderBytes,
+	}
+	if t.TLSClientConfig != nil {
+		c.TLSClientConfig = t.TLSClientConfig.Clone()
+	}
+	return c
+}
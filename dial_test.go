@@ -0,0 +1,63 @@
+package uaa_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestDial(t *testing.T) {
+	spec.Run(t, "Dial", testDial, spec.Report(report.Terminal{}))
+}
+
+func testDial(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s    *httptest.Server
+		port string
+		a    *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Host).To(Equal("uaa.example.invalid:" + port))
+			w.Write([]byte(MarcusUserResponse))
+		}))
+		u, _ := url.Parse(s.URL)
+		port = u.Port()
+		target, _ := url.Parse("http://uaa.example.invalid:" + port)
+		c := &http.Client{Transport: http.DefaultTransport}
+		a = &uaa.API{TargetURL: target, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("connects to pinned addresses in order", func() {
+		uaa.WithPinnedAddresses("127.0.0.1:1", "127.0.0.1")(a)
+		user, err := a.GetUser("id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("marcus@stoicism.com"))
+		Expect(a.AuthenticatedClient.Transport).NotTo(BeIdenticalTo(http.DefaultTransport))
+	})
+
+	it("connects with a custom dial function", func() {
+		var dialed []string
+		uaa.WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = append(dialed, addr)
+			return (&net.Dialer{}).DialContext(ctx, network, s.Listener.Addr().String())
+		})(a)
+		_, err := a.GetUser("id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dialed).To(Equal([]string{"uaa.example.invalid:" + port}))
+	})
+}
@@ -0,0 +1,118 @@
+//go:build go1.18
+// +build go1.18
+
+package uaa_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestDo(t *testing.T) {
+	spec.Run(t, "Do", testDo, spec.Report(report.Terminal{}))
+}
+
+func testDo(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		handler http.HandlerFunc
+		a       *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			handler(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+			ZoneID:                "tenant",
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("decodes the response into the type", func() {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Method).To(Equal(http.MethodGet))
+			Expect(req.URL.Path).To(Equal("/mfa-providers/provider-id"))
+			Expect(req.Header.Get("X-Identity-Zone-Id")).To(Equal("tenant"))
+			w.Write([]byte(`{"id":"provider-id","name":"google"}`))
+		}
+		provider, err := uaa.Do[struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}](a, http.MethodGet, "/mfa-providers/provider-id", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(provider.ID).To(Equal("provider-id"))
+		Expect(provider.Name).To(Equal("google"))
+	})
+
+	it("encodes the body as JSON and keeps the query", func() {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Method).To(Equal(http.MethodPost))
+			Expect(req.URL.Query().Get("force")).To(Equal("true"))
+			Expect(req.Header.Get("Content-Type")).To(Equal("application/json"))
+			body, _ := ioutil.ReadAll(req.Body)
+			Expect(body).To(MatchJSON(`{"userName":"marcus"}`))
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		}
+		user, err := uaa.Do[uaa.User](a, http.MethodPost, "/Users?force=true", uaa.User{Username: "marcus"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("marcus"))
+	})
+
+	it("decodes the resources of a list into a slice", func() {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"resources":[{"id":"a"},{"id":"b"}],"startIndex":1,"itemsPerPage":2,"totalResults":2}`))
+		}
+		groups, err := uaa.Do[[]uaa.Group](a, http.MethodGet, "/Groups", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(HaveLen(2))
+		Expect(groups[1].ID).To(Equal("b"))
+	})
+
+	it("decodes a bare array into a slice", func() {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`[{"id":"a"}]`))
+		}
+		providers, err := uaa.Do[[]uaa.IdentityProvider](a, http.MethodGet, "/identity-providers", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(providers).To(HaveLen(1))
+	})
+
+	it("returns the zero value for an empty response", func() {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		result, err := uaa.Do[*uaa.User](a, http.MethodDelete, "/Users/user-id", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(BeNil())
+	})
+
+	it("returns the usual error for an unsuccessful response", func() {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		_, err := uaa.Do[uaa.User](a, http.MethodGet, "/Users/user-id", nil)
+		unexpected, ok := errors.Cause(err).(uaa.ErrUnexpectedResponse)
+		Expect(ok).To(BeTrue())
+		Expect(unexpected.StatusCode).To(Equal(http.StatusForbidden))
+	})
+}
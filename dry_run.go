@@ -0,0 +1,100 @@
+package uaa
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// nonMutatingPaths are endpoints that are called with POST but do not change
+// anything.
+var nonMutatingPaths = []string{"/introspect", "/check_token", "/oauth/token"}
+
+// isMutating returns true if the request may change the state of the UAA.
+func isMutating(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return false
+	}
+	for _, path := range nonMutatingPaths {
+		if strings.HasSuffix(req.URL.Path, path) {
+			return false
+		}
+	}
+	return true
+}
+
+// PlannedAction is a request that was not sent because the API is in dry-run
+// mode.
+type PlannedAction struct {
+	Method string
+	URL    string
+	Body   string
+}
+
+func (p PlannedAction) String() string {
+	if p.Body == "" {
+		return fmt.Sprintf("%s %s", p.Method, p.URL)
+	}
+	return fmt.Sprintf("%s %s %s", p.Method, p.URL, p.Body)
+}
+
+type dryRunLog struct {
+	mu      sync.Mutex
+	actions []PlannedAction
+}
+
+// WithDryRun returns an Option under which requests that would change the UAA
+// (POST, PUT, PATCH, and DELETE) are recorded instead of being sent. Requests
+// that only read from the UAA are sent as usual. A recorded request succeeds
+// and its request body is returned as the response, so that, for example,
+// CreateUser returns the user that would have been created. The recorded
+// requests are available from PlannedActions and are printed if Verbose is
+// set.
+func WithDryRun() Option {
+	return func(a *API) {
+		a.dryRun = &dryRunLog{}
+	}
+}
+
+// PlannedActions returns the requests recorded in dry-run mode, in the order
+// they were made.
+func (a *API) PlannedActions() []PlannedAction {
+	if a.dryRun == nil {
+		return nil
+	}
+	a.dryRun.mu.Lock()
+	defer a.dryRun.mu.Unlock()
+	return append([]PlannedAction(nil), a.dryRun.actions...)
+}
+
+// plan records the request if the API is in dry-run mode and the request is
+// mutating. It returns the response body to use in place of sending the
+// request, and whether the request was recorded.
+func (a *API) plan(req *http.Request) ([]byte, bool, error) {
+	if a.dryRun == nil || !isMutating(req) {
+		return nil, false, nil
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, false, err
+		}
+		req.Body.Close()
+	}
+	action := PlannedAction{Method: req.Method, URL: req.URL.String(), Body: string(body)}
+	a.dryRun.mu.Lock()
+	a.dryRun.actions = append(a.dryRun.actions, action)
+	a.dryRun.mu.Unlock()
+	if a.verbose() {
+		fmt.Printf("DRY RUN: %v\n\n", action)
+	}
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") || len(body) == 0 {
+		body = []byte("{}")
+	}
+	return body, true, nil
+}
@@ -0,0 +1,78 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestDryRun(t *testing.T) {
+	spec.Run(t, "DryRun", testDryRun, spec.Report(report.Terminal{}))
+}
+
+func testDryRun(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		a        *uaa.API
+		requests []string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		requests = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			w.Write([]byte(PaginatedResponse(uaa.User{ID: "marcus-id", Username: "marcus"})))
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+		uaa.WithDryRun()(a)
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("records mutating requests instead of sending them", func() {
+		user, err := a.CreateUser(uaa.User{Username: "seneca"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("seneca"))
+		_, err = a.DeleteUser("marcus-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a.AddGroupMember("group-id", "marcus-id", "", "")).To(Succeed())
+
+		Expect(requests).To(BeEmpty())
+		actions := a.PlannedActions()
+		Expect(actions).To(HaveLen(3))
+		Expect(actions[0].Method).To(Equal(http.MethodPost))
+		Expect(actions[0].URL).To(Equal(s.URL + uaa.UsersEndpoint))
+		Expect(actions[0].Body).To(MatchJSON(`{"userName":"seneca"}`))
+		Expect(actions[1].String()).To(Equal("DELETE " + s.URL + uaa.UsersEndpoint + "/marcus-id"))
+		Expect(actions[2].URL).To(Equal(s.URL + uaa.GroupsEndpoint + "/group-id/members"))
+	})
+
+	it("sends requests that do not change anything", func() {
+		users, err := a.ListAllUsers("", "", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(1))
+		_, err = a.IntrospectToken("token")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(Equal([]string{"GET /Users", "POST /introspect"}))
+		Expect(a.PlannedActions()).To(BeEmpty())
+	})
+
+	it("records mutating curl requests", func() {
+		_, body, err := a.Curl("/Users/marcus-id", http.MethodDelete, "", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(body).To(Equal("{}"))
+		Expect(requests).To(BeEmpty())
+		Expect(a.PlannedActions()).To(HaveLen(1))
+	})
+}
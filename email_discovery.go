@@ -0,0 +1,145 @@
+package uaa
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrNoProviderForEmail is returned by ProviderForEmail when no active
+// identity provider accepts the email address's domain.
+type ErrNoProviderForEmail struct {
+	Email string
+}
+
+func (e ErrNoProviderForEmail) Error() string {
+	return fmt.Sprintf("no identity provider accepts the email address %s", e.Email)
+}
+
+// ErrMultipleProvidersForEmail is returned by ProviderForEmail when more than
+// one active identity provider accepts the email address's domain, in which
+// case the UAA asks the user to choose between them.
+type ErrMultipleProvidersForEmail struct {
+	Email string
+	// Origins are the origin keys of the providers, sorted.
+	Origins []string
+}
+
+func (e ErrMultipleProvidersForEmail) Error() string {
+	return fmt.Sprintf("the identity providers %s all accept the email address %s", strings.Join(e.Origins, ", "), e.Email)
+}
+
+// EmailDomains returns the email domains of the users the provider
+// authenticates, which the UAA uses to discover the provider from a user's
+// email address when the zone has IDPDiscoveryEnabled. A domain may contain
+// "*" wildcards. A provider with no email domains is only discovered if it is
+// the uaa provider, as a fallback.
+func (c IdentityProviderConfig) EmailDomains() []string {
+	var domains []string
+	list, _ := c["emailDomain"].([]interface{})
+	for _, domain := range list {
+		if d, ok := domain.(string); ok {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// SetEmailDomains sets the email domains of the users the provider
+// authenticates. With no domains, the setting is removed.
+func (c *IdentityProviderConfig) SetEmailDomains(domains ...string) {
+	if *c == nil {
+		*c = IdentityProviderConfig{}
+	}
+	if len(domains) == 0 {
+		delete(*c, "emailDomain")
+		return
+	}
+	list := make([]interface{}, len(domains))
+	for i, domain := range domains {
+		list[i] = domain
+	}
+	(*c)["emailDomain"] = list
+}
+
+// ProvidersForEmail applies the UAA's identity provider discovery rules to
+// the email address, and returns the providers a user with that address is
+// sent to: the active providers with an email domain that matches the
+// address's domain, ignoring case, or if there are none, the uaa provider if
+// it is active and has no email domains.
+func ProvidersForEmail(providers []IdentityProvider, email string) []IdentityProvider {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil
+	}
+	domain := strings.ToLower(email[at+1:])
+
+	var matched []IdentityProvider
+	var fallback *IdentityProvider
+	for i, p := range providers {
+		if !p.Active {
+			continue
+		}
+		domains := p.Config.EmailDomains()
+		if len(domains) == 0 && p.OriginKey == string(UAAOrigin) {
+			fallback = &providers[i]
+		}
+		for _, d := range domains {
+			if emailDomainMatches(strings.ToLower(d), domain) {
+				matched = append(matched, p)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 && fallback != nil {
+		matched = append(matched, *fallback)
+	}
+	return matched
+}
+
+// emailDomainMatches returns true if the domain matches the pattern, in which
+// a "*" matches any characters.
+func emailDomainMatches(pattern string, domain string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == domain
+	}
+	if !strings.HasPrefix(domain, parts[0]) {
+		return false
+	}
+	domain = domain[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(domain, part)
+		if i < 0 {
+			return false
+		}
+		domain = domain[i+len(part):]
+	}
+	return strings.HasSuffix(domain, last)
+}
+
+// ProviderForEmail returns the identity provider that the UAA's login page
+// would send a user with the given email address to, so that custom login
+// pages can route users in the same way. See ProvidersForEmail for the
+// rules. It returns ErrNoProviderForEmail or ErrMultipleProvidersForEmail if
+// there is not exactly one such provider.
+func (a *API) ProviderForEmail(email string) (*IdentityProvider, error) {
+	providers, err := a.ListIdentityProviders()
+	if err != nil {
+		return nil, err
+	}
+	matched := ProvidersForEmail(providers, email)
+	switch len(matched) {
+	case 0:
+		return nil, ErrNoProviderForEmail{Email: email}
+	case 1:
+		return &matched[0], nil
+	}
+	var origins []string
+	for _, p := range matched {
+		origins = append(origins, p.OriginKey)
+	}
+	sort.Strings(origins)
+	return nil, ErrMultipleProvidersForEmail{Email: email, Origins: origins}
+}
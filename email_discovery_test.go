@@ -0,0 +1,100 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestEmailDiscovery(t *testing.T) {
+	spec.Run(t, "EmailDiscovery", testEmailDiscovery, spec.Report(report.Terminal{}))
+}
+
+func testEmailDiscovery(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s         *httptest.Server
+		a         *uaa.API
+		providers []uaa.IdentityProvider
+	)
+
+	provider := func(origin string, active bool, domains ...string) uaa.IdentityProvider {
+		p := uaa.IdentityProvider{Type: uaa.SAMLOrigin, OriginKey: origin, Active: active}
+		if origin == "uaa" {
+			p.Type = uaa.UAAOrigin
+		}
+		p.Config.SetEmailDomains(domains...)
+		return p
+	}
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		providers = []uaa.IdentityProvider{
+			provider("uaa", true),
+			provider("okta", true, "example.com", "*.example.org"),
+			provider("legacy", false, "example.com"),
+		}
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal(uaa.IdentityProvidersEndpoint))
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(providers)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("round-trips the email domains through the provider's config", func() {
+		j, err := json.Marshal(providers[1])
+		Expect(err).NotTo(HaveOccurred())
+		var p uaa.IdentityProvider
+		Expect(json.Unmarshal(j, &p)).To(Succeed())
+		Expect(p.Config.EmailDomains()).To(Equal([]string{"example.com", "*.example.org"}))
+	})
+
+	it("finds the active provider for the email domain", func() {
+		p, err := a.ProviderForEmail("Marcus@EXAMPLE.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.OriginKey).To(Equal("okta"))
+
+		p, err = a.ProviderForEmail("marcus@eu.example.org")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.OriginKey).To(Equal("okta"))
+	})
+
+	it("falls back to the uaa provider", func() {
+		p, err := a.ProviderForEmail("marcus@stoicism.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.OriginKey).To(Equal("uaa"))
+	})
+
+	it("does not fall back to a uaa provider with email domains", func() {
+		providers[0].Config.SetEmailDomains("internal.example.net")
+		_, err := a.ProviderForEmail("marcus@stoicism.com")
+		Expect(err).To(Equal(uaa.ErrNoProviderForEmail{Email: "marcus@stoicism.com"}))
+	})
+
+	it("reports every provider when several match", func() {
+		providers = append(providers, provider("azure", true, "*.com"))
+		_, err := a.ProviderForEmail("marcus@example.com")
+		Expect(err).To(Equal(uaa.ErrMultipleProvidersForEmail{Email: "marcus@example.com", Origins: []string{"azure", "okta"}}))
+	})
+
+	it("finds no provider for an address without a domain", func() {
+		Expect(uaa.ProvidersForEmail(providers, "marcus")).To(BeEmpty())
+	})
+}
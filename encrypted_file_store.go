@@ -0,0 +1,274 @@
+package uaa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultPBKDF2Iterations is the number of PBKDF2 iterations used to derive
+// an EncryptedFileTokenStore's key from its passphrase when none is set.
+const DefaultPBKDF2Iterations = 600000
+
+// ErrWrongPassphrase is returned when a token file cannot be decrypted,
+// because the passphrase is wrong or the file has been tampered with.
+var ErrWrongPassphrase = errors.New("the token file cannot be decrypted with the passphrase")
+
+// PassphraseFunc returns the passphrase that a token file is encrypted with,
+// for example by prompting the user for it.
+type PassphraseFunc func() ([]byte, error)
+
+// PassphraseFromEnv returns a PassphraseFunc that reads the passphrase from
+// the named environment variable, for headless environments that have no one
+// to prompt.
+func PassphraseFromEnv(name string) PassphraseFunc {
+	return func() ([]byte, error) {
+		passphrase := os.Getenv(name)
+		if passphrase == "" {
+			return nil, fmt.Errorf("%v is not set", name)
+		}
+		return []byte(passphrase), nil
+	}
+}
+
+// EncryptedFileTokenStore is a TokenStore that keeps the token in a file,
+// encrypted with AES-256-GCM under a key derived from a passphrase with
+// PBKDF2-HMAC-SHA256. It suits headless environments that have no keychain,
+// where a plaintext file would expose the refresh token to anyone who can
+// read it. The passphrase is asked for once and then remembered.
+//
+// An EncryptedFileTokenStore is safe for concurrent use.
+type EncryptedFileTokenStore struct {
+	Path       string
+	Passphrase PassphraseFunc
+	// Iterations is the number of PBKDF2 iterations for newly created files;
+	// if it is zero, DefaultPBKDF2Iterations is used.
+	Iterations int
+
+	mu  sync.Mutex
+	key *derivedKey
+}
+
+var _ TokenStore = &EncryptedFileTokenStore{}
+
+// NewEncryptedFileTokenStore returns an EncryptedFileTokenStore that keeps
+// the token in the file at path, encrypted with the passphrase.
+func NewEncryptedFileTokenStore(path string, passphrase PassphraseFunc) *EncryptedFileTokenStore {
+	return &EncryptedFileTokenStore{Path: path, Passphrase: passphrase}
+}
+
+// encryptedTokenFile is the format of the token file.
+type encryptedTokenFile struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// derivedKey is a key derived from the passphrase, with the salt and
+// iterations it was derived with.
+type derivedKey struct {
+	passphrase []byte
+	salt       []byte
+	iterations int
+	key        []byte
+}
+
+// encryptedTokenFileAD is authenticated along with the token, so that a file
+// in another format cannot be passed off as a token file.
+var encryptedTokenFileAD = []byte("go-uaa token file v1")
+
+// Load decrypts and returns the token in the file, or ErrTokenNotFound if
+// there is no file.
+func (s *EncryptedFileTokenStore) Load() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := encryptedTokenFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%v is not a token file: %v", s.Path, err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("%v has unsupported version %d", s.Path, file.Version)
+	}
+	key, err := s.deriveKey(file.Salt, file.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key.key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, encryptedTokenFileAD)
+	if err != nil {
+		// Ask for the passphrase again next time.
+		s.key = nil
+		return nil, ErrWrongPassphrase
+	}
+	token := &oauth2.Token{}
+	if err := json.Unmarshal(plaintext, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Save encrypts the token and writes it to the file, replacing the file
+// atomically so that a failed write does not lose the stored token. The file
+// is readable only by its owner.
+func (s *EncryptedFileTokenStore) Save(token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	key := s.key
+	if key == nil {
+		salt := make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return err
+		}
+		iterations := s.Iterations
+		if iterations <= 0 {
+			iterations = DefaultPBKDF2Iterations
+		}
+		if key, err = s.deriveKey(salt, iterations); err != nil {
+			return err
+		}
+	}
+	aead, err := newGCM(key.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	data, err := json.Marshal(encryptedTokenFile{
+		Version:    1,
+		Iterations: key.iterations,
+		Salt:       key.salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, encryptedTokenFileAD),
+	})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(s.Path, data)
+}
+
+// Delete removes the file, if there is one.
+func (s *EncryptedFileTokenStore) Delete() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// deriveKey returns the key for the salt and iterations, asking for the
+// passphrase if it has not been asked for yet, and remembers it.
+func (s *EncryptedFileTokenStore) deriveKey(salt []byte, iterations int) (*derivedKey, error) {
+	if iterations <= 0 || len(salt) == 0 {
+		return nil, errors.New("the token file has no key derivation parameters")
+	}
+	if s.key != nil && hmac.Equal(s.key.salt, salt) && s.key.iterations == iterations {
+		return s.key, nil
+	}
+	var passphrase []byte
+	if s.key != nil {
+		passphrase = s.key.passphrase
+	} else {
+		if s.Passphrase == nil {
+			return nil, errors.New("the token store has no passphrase")
+		}
+		var err error
+		if passphrase, err = s.Passphrase(); err != nil {
+			return nil, err
+		}
+	}
+	s.key = &derivedKey{
+		passphrase: passphrase,
+		salt:       salt,
+		iterations: iterations,
+		key:        pbkdf2SHA256(passphrase, salt, iterations, 32),
+	}
+	return s.key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key of the given length from the password with
+// PBKDF2 (RFC 8018) and HMAC-SHA256.
+func pbkdf2SHA256(password []byte, salt []byte, iterations int, length int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < length; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		var index [4]byte
+		binary.BigEndian.PutUint32(index[:], block)
+		prf.Write(index[:])
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:length]
+}
+
+// writeFileAtomically writes the data to a temporary file, readable only by
+// its owner, and renames it to path.
+func writeFileAtomically(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
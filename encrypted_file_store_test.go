@@ -0,0 +1,124 @@
+package uaa_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/oauth2"
+)
+
+func TestEncryptedFileTokenStore(t *testing.T) {
+	spec.Run(t, "EncryptedFileTokenStore", testEncryptedFileTokenStore, spec.Report(report.Terminal{}))
+}
+
+func testEncryptedFileTokenStore(t *testing.T, when spec.G, it spec.S) {
+	var (
+		dir        string
+		path       string
+		passphrase string
+		asked      int
+		store      *uaa.EncryptedFileTokenStore
+	)
+
+	newStore := func() *uaa.EncryptedFileTokenStore {
+		store := uaa.NewEncryptedFileTokenStore(path, func() ([]byte, error) {
+			asked++
+			return []byte(passphrase), nil
+		})
+		store.Iterations = 1000
+		return store
+	}
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		var err error
+		dir, err = ioutil.TempDir("", "uaa")
+		Expect(err).NotTo(HaveOccurred())
+		path = filepath.Join(dir, "token")
+		passphrase = "meditations"
+		asked = 0
+		store = newStore()
+	})
+
+	it.After(func() {
+		os.RemoveAll(dir)
+	})
+
+	it("saves and loads the token", func() {
+		Expect(store.Save(&oauth2.Token{AccessToken: "access", RefreshToken: "refresh"})).To(Succeed())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("refresh"))
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+		token, err := newStore().Load()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("access"))
+		Expect(token.RefreshToken).To(Equal("refresh"))
+	})
+
+	it("asks for the passphrase once", func() {
+		Expect(store.Save(&oauth2.Token{AccessToken: "first"})).To(Succeed())
+		_, err := store.Load()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.Save(&oauth2.Token{AccessToken: "second"})).To(Succeed())
+		token, err := store.Load()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("second"))
+		Expect(asked).To(Equal(1))
+	})
+
+	it("rejects the wrong passphrase and asks again", func() {
+		Expect(store.Save(&oauth2.Token{AccessToken: "access"})).To(Succeed())
+		passphrase = "discourses"
+		other := newStore()
+		_, err := other.Load()
+		Expect(err).To(Equal(uaa.ErrWrongPassphrase))
+
+		passphrase = "meditations"
+		token, err := other.Load()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("access"))
+	})
+
+	it("returns ErrTokenNotFound when there is no file", func() {
+		_, err := store.Load()
+		Expect(err).To(Equal(uaa.ErrTokenNotFound))
+		Expect(store.Delete()).To(Succeed())
+	})
+
+	it("deletes the file", func() {
+		Expect(store.Save(&oauth2.Token{AccessToken: "access"})).To(Succeed())
+		Expect(store.Delete()).To(Succeed())
+		_, err := os.Stat(path)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	it("returns the passphrase's error", func() {
+		store.Passphrase = func() ([]byte, error) { return nil, errors.New("cancelled") }
+		Expect(store.Save(&oauth2.Token{AccessToken: "access"})).To(MatchError("cancelled"))
+	})
+
+	it("reads the passphrase from the environment", func() {
+		os.Setenv("UAA_TOKEN_PASSPHRASE", "meditations")
+		defer os.Unsetenv("UAA_TOKEN_PASSPHRASE")
+		store.Passphrase = uaa.PassphraseFromEnv("UAA_TOKEN_PASSPHRASE")
+		Expect(store.Save(&oauth2.Token{AccessToken: "access"})).To(Succeed())
+		token, err := newStore().Load()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("access"))
+
+		_, err = uaa.PassphraseFromEnv("UAA_MISSING_PASSPHRASE")()
+		Expect(err).To(MatchError("UAA_MISSING_PASSPHRASE is not set"))
+	})
+}
@@ -0,0 +1,175 @@
+package uaa
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WithBasePath returns an Option for a UAA that is not mounted at the root of
+// its host, for example one that a gateway serves under /uaa. The base path
+// is added to the path of every request the API makes, including token
+// requests, and to the URLs it builds, such as AuthorizeURL.
+//
+// The option must be applied after the API's TargetURL and clients are set;
+// the New* constructors do this.
+func WithBasePath(base string) Option {
+	return func(a *API) {
+		a.installPaths().setBase(base)
+	}
+}
+
+// WithEndpointPath returns an Option that sends requests for the endpoint
+// with the given default path, such as "/oauth/token" or UsersEndpoint, to
+// path instead, for a gateway that exposes the UAA's endpoints at other
+// paths. Requests for paths below the endpoint, such as a user's path below
+// UsersEndpoint, are sent below path. The path replaces the endpoint's
+// whole path, so it is not prefixed with the base path given to WithBasePath.
+//
+// The option must be applied after the API's TargetURL and clients are set;
+// the New* constructors do this.
+func WithEndpointPath(endpoint string, path string) Option {
+	return func(a *API) {
+		a.installPaths().setEndpoint(endpoint, path)
+	}
+}
+
+// installPaths returns the API's path rewriter. The API rewrites the paths of
+// the requests it sends itself; token requests, which golang.org/x/oauth2
+// sends with the API's unauthenticated client, are rewritten by wrapping that
+// client's transport.
+func (a *API) installPaths() *pathRewriter {
+	if a.paths == nil {
+		a.paths = &pathRewriter{}
+	}
+	c := a.UnauthenticatedClient
+	if c == nil {
+		return a.paths
+	}
+	if _, ok := c.Transport.(*pathTransport); !ok {
+		base := c.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		c.Transport = &pathTransport{paths: a.paths, base: base}
+	}
+	return a.paths
+}
+
+// rewritePath returns the request with its path rewritten, marked so that it
+// is not rewritten again on its way through a pathTransport.
+func (a *API) rewritePath(req *http.Request) *http.Request {
+	if a.paths == nil || req.Context().Value(pathsRewrittenKey{}) != nil {
+		return req
+	}
+	return a.paths.rewritten(req)
+}
+
+// endpointPath returns the path to which a request for the given default
+// path is sent.
+func (a *API) endpointPath(path string) string {
+	if a.paths == nil {
+		return path
+	}
+	return a.paths.rewrite(path)
+}
+
+// pathRewriter maps the UAA's default endpoint paths to the paths at which a
+// deployment serves them.
+type pathRewriter struct {
+	mu        sync.RWMutex
+	base      string
+	endpoints map[string]string
+
+	detect     bool
+	detectOnce sync.Once
+}
+
+func (r *pathRewriter) setBase(base string) {
+	base = strings.TrimRight(base, "/")
+	if base != "" && !strings.HasPrefix(base, "/") {
+		base = "/" + base
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.base = base
+}
+
+func (r *pathRewriter) setEndpoint(endpoint string, path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.endpoints == nil {
+		r.endpoints = map[string]string{}
+	}
+	r.endpoints[strings.TrimRight(endpoint, "/")] = strings.TrimRight(path, "/")
+}
+
+// rewrite returns the path to use for the given default path. The longest
+// endpoint that the path is or is below is replaced; otherwise the base path
+// is added.
+func (r *pathRewriter) rewrite(path string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	match := ""
+	for endpoint := range r.endpoints {
+		if len(endpoint) > len(match) && (path == endpoint || strings.HasPrefix(path, endpoint+"/")) {
+			match = endpoint
+		}
+	}
+	if match != "" {
+		return r.endpoints[match] + path[len(match):]
+	}
+	return r.base + path
+}
+
+type pathsRewrittenKey struct{}
+
+// pathTransport rewrites the paths of requests with a pathRewriter. A request
+// is only rewritten once, however many pathTransports it passes through.
+type pathTransport struct {
+	paths *pathRewriter
+	base  http.RoundTripper
+}
+
+func (t *pathTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Context().Value(pathsRewrittenKey{}) != nil {
+		return t.base.RoundTrip(req)
+	}
+	resp, err := t.base.RoundTrip(t.paths.rewritten(req))
+	if err != nil || resp.StatusCode != http.StatusNotFound {
+		return resp, err
+	}
+	probe := func(path string) bool {
+		return probeInfo(t.base.RoundTrip, req, path)
+	}
+	if !t.paths.detectContextPath(req.URL.Path, probe) {
+		return resp, nil
+	}
+	retry := t.paths.rewritten(req)
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, nil
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return t.base.RoundTrip(retry)
+}
+
+// rewritten returns a copy of the request with its path rewritten.
+func (r *pathRewriter) rewritten(req *http.Request) *http.Request {
+	copied := req.WithContext(context.WithValue(req.Context(), pathsRewrittenKey{}, true))
+	u := *req.URL
+	u.Path = r.rewrite(u.Path)
+	u.RawPath = ""
+	copied.URL = &u
+	return copied
+}
@@ -0,0 +1,86 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestEndpoints(t *testing.T) {
+	spec.Run(t, "Endpoints", testEndpoints, spec.Report(report.Terminal{}))
+}
+
+func testEndpoints(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s     *httptest.Server
+		calls []string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		calls = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, req.Method+" "+req.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			switch req.URL.Path {
+			case "/uaa/oauth/token", "/auth/token":
+				w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":3600}`))
+			case "/uaa/Users/00000000-0000-0000-0000-000000000001", "/scim/Users/00000000-0000-0000-0000-000000000001":
+				w.Write([]byte(MarcusUserResponse))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("adds the base path to every request", func() {
+		a, err := uaa.NewWithClientCredentials(s.URL, "", "admin", "secret", uaa.JSONWebToken, uaa.WithBasePath("/uaa/"))
+		Expect(err).NotTo(HaveOccurred())
+		user, err := a.GetUser("00000000-0000-0000-0000-000000000001")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("marcus@stoicism.com"))
+		Expect(calls).To(Equal([]string{
+			"POST /uaa/oauth/token",
+			"GET /uaa/Users/00000000-0000-0000-0000-000000000001",
+		}))
+	})
+
+	it("sends overridden endpoints to their paths", func() {
+		a, err := uaa.NewWithClientCredentials(s.URL, "", "admin", "secret", uaa.JSONWebToken,
+			uaa.WithBasePath("uaa"),
+			uaa.WithEndpointPath("/oauth/token", "/auth/token"),
+			uaa.WithEndpointPath(uaa.UsersEndpoint, "/scim/Users"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = a.GetUser("00000000-0000-0000-0000-000000000001")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal([]string{
+			"POST /auth/token",
+			"GET /scim/Users/00000000-0000-0000-0000-000000000001",
+		}))
+	})
+
+	it("rewrites the token exchange of the authorization code grant", func() {
+		_, err := uaa.NewWithAuthorizationCode(s.URL, "", "app", "secret", "code", false, uaa.JSONWebToken, uaa.WithBasePath("/uaa"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal([]string{"POST /uaa/oauth/token"}))
+	})
+
+	it("rewrites the URLs it builds", func() {
+		u, _ := url.Parse(s.URL)
+		a := &uaa.API{TargetURL: u}
+		uaa.WithBasePath("/uaa")(a)
+		authorize, err := url.Parse(a.AuthorizeURL("app", "https://app.example.com/callback", "state", nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(authorize.Path).To(Equal("/uaa/oauth/authorize"))
+	})
+}
@@ -0,0 +1,81 @@
+package uaa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EnsureUser creates the user if no user with its username exists in its
+// origin, or otherwise updates the existing user to match it. If the user's
+// origin is blank and users with the username exist in more than one origin,
+// EnsureUser returns an error rather than guess which to update. The user's
+// password is only set when the user is created.
+func (a *API) EnsureUser(user User) (*User, error) {
+	if user.Username == "" {
+		return nil, errors.New("username cannot be blank")
+	}
+	filter := fmt.Sprintf(`userName eq "%v"`, user.Username)
+	if user.Origin != "" {
+		filter = fmt.Sprintf(`%s and origin eq "%v"`, filter, user.Origin)
+	}
+	existing, err := a.ListAllUsers(filter, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	switch len(existing) {
+	case 0:
+		return a.CreateUser(user)
+	case 1:
+		user.ID = existing[0].ID
+		user.Meta = existing[0].Meta
+		return a.UpdateUser(user)
+	default:
+		var origins []string
+		for _, u := range existing {
+			origins = append(origins, u.Origin)
+		}
+		return nil, fmt.Errorf("Found users with username %v in multiple origins [%v].", user.Username, strings.Join(origins, ", "))
+	}
+}
+
+// EnsureGroup creates the group if no group with its display name exists, or
+// otherwise updates the existing group to match it. If the group's members
+// are nil, the existing group's members are kept.
+func (a *API) EnsureGroup(group Group) (*Group, error) {
+	if group.DisplayName == "" {
+		return nil, errors.New("group name may not be blank")
+	}
+	existing, err := a.ListAllGroups(fmt.Sprintf(`displayName eq "%v"`, group.DisplayName), "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		return a.CreateGroup(group)
+	}
+	group.ID = existing[0].ID
+	group.Meta = existing[0].Meta
+	if group.Members == nil {
+		group.Members = existing[0].Members
+	}
+	return a.UpdateGroup(group)
+}
+
+// EnsureClient creates the client if no client with its client ID exists, or
+// otherwise updates the existing client to match it. The client's secret is
+// only set when the client is created; use ChangeClientSecret to change the
+// secret of an existing client.
+func (a *API) EnsureClient(client Client) (*Client, error) {
+	if client.ClientID == "" {
+		return nil, errors.New("client ID cannot be blank")
+	}
+	existing, err := a.ListAllClients(fmt.Sprintf(`client_id eq "%v"`, client.ClientID), "", "")
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		return a.CreateClient(client)
+	}
+	client.ClientSecret = ""
+	return a.UpdateClient(client)
+}
@@ -0,0 +1,140 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestEnsure(t *testing.T) {
+	spec.Run(t, "Ensure", testEnsure, spec.Report(report.Terminal{}))
+}
+
+func testEnsure(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		handler  http.Handler
+		a        *uaa.API
+		requests []string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		requests = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			handler.ServeHTTP(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	when("EnsureUser()", func() {
+		it("creates a missing user", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Method == http.MethodGet {
+					Expect(req.URL.Query().Get("filter")).To(Equal(`userName eq "marcus" and origin eq "uaa"`))
+					w.Write([]byte(PaginatedResponse()))
+					return
+				}
+				body, _ := ioutil.ReadAll(req.Body)
+				w.Write(body)
+			})
+			user, err := a.EnsureUser(uaa.User{Username: "marcus", Origin: "uaa"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.Username).To(Equal("marcus"))
+			Expect(requests).To(Equal([]string{"GET /Users", "POST /Users"}))
+		})
+
+		it("updates an existing user", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Method == http.MethodGet {
+					w.Write([]byte(PaginatedResponse(uaa.User{ID: "marcus-id", Username: "marcus", Meta: &uaa.Meta{Version: 3}})))
+					return
+				}
+				body, _ := ioutil.ReadAll(req.Body)
+				w.Write(body)
+			})
+			user, err := a.EnsureUser(uaa.User{Username: "marcus", ExternalID: "m1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.ID).To(Equal("marcus-id"))
+			Expect(user.Meta.Version).To(Equal(3))
+			Expect(user.ExternalID).To(Equal("m1"))
+			Expect(requests).To(Equal([]string{"GET /Users", "PUT /Users"}))
+		})
+
+		it("refuses to guess between origins", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte(PaginatedResponse(uaa.User{Username: "marcus", Origin: "uaa"}, uaa.User{Username: "marcus", Origin: "ldap"})))
+			})
+			_, err := a.EnsureUser(uaa.User{Username: "marcus"})
+			Expect(err).To(MatchError("Found users with username marcus in multiple origins [uaa, ldap]."))
+			Expect(requests).To(HaveLen(1))
+		})
+	})
+
+	when("EnsureGroup()", func() {
+		it("keeps the existing members when none are given", func() {
+			var updated uaa.Group
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Method == http.MethodGet {
+					w.Write([]byte(PaginatedResponse(uaa.Group{ID: "stoics-id", DisplayName: "stoics", Members: []uaa.GroupMember{{Value: "marcus-id"}}})))
+					return
+				}
+				body, _ := ioutil.ReadAll(req.Body)
+				Expect(json.Unmarshal(body, &updated)).To(Succeed())
+				w.Write(body)
+			})
+			_, err := a.EnsureGroup(uaa.Group{DisplayName: "stoics", Description: "Stoic philosophers"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.ID).To(Equal("stoics-id"))
+			Expect(updated.Members).To(Equal([]uaa.GroupMember{{Value: "marcus-id"}}))
+		})
+	})
+
+	when("EnsureClient()", func() {
+		it("creates a missing client with its secret", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Method == http.MethodGet {
+					Expect(req.URL.Query().Get("filter")).To(Equal(`client_id eq "shinyclient"`))
+					w.Write([]byte(PaginatedResponse()))
+					return
+				}
+				body, _ := ioutil.ReadAll(req.Body)
+				Expect(body).To(ContainSubstring(`"client_secret":"secret"`))
+				w.Write(body)
+			})
+			_, err := a.EnsureClient(uaa.Client{ClientID: "shinyclient", ClientSecret: "secret"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests).To(Equal([]string{"GET /oauth/clients", "POST /oauth/clients"}))
+		})
+
+		it("updates an existing client without its secret", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if req.Method == http.MethodGet {
+					w.Write([]byte(PaginatedResponse(uaa.Client{ClientID: "shinyclient"})))
+					return
+				}
+				body, _ := ioutil.ReadAll(req.Body)
+				Expect(body).NotTo(ContainSubstring("client_secret"))
+				w.Write(body)
+			})
+			_, err := a.EnsureClient(uaa.Client{ClientID: "shinyclient", ClientSecret: "secret"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests).To(Equal([]string{"GET /oauth/clients", "PUT /oauth/clients"}))
+		})
+	})
+}
@@ -0,0 +1,172 @@
+package uaa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// maxErrorBodySize is how much of an error response's body is read.
+	maxErrorBodySize = 64 << 10
+	// maxErrorSnippet is the number of characters of a body that are kept
+	// in an error.
+	maxErrorSnippet = 200
+)
+
+// ErrUnexpectedResponse describes an unsuccessful response from the UAA, or
+// from a proxy in front of it. It is the cause, according to errors.Cause from
+// github.com/pkg/errors, of the errors returned for such responses.
+type ErrUnexpectedResponse struct {
+	StatusCode  int
+	Status      string
+	ContentType string
+	// Code and Description are the error and its description from a JSON
+	// error body, such as scim_resource_already_exists.
+	Code        string
+	Description string
+	// Snippet is the start of any other body, such as a proxy's HTML error
+	// page, reduced to its text with control characters removed.
+	Snippet string
+}
+
+func (e ErrUnexpectedResponse) Error() string {
+	msg := "the UAA responded " + e.Status
+	if e.ContentType != "" && e.Code == "" && e.Snippet != "" {
+		msg += " (" + e.ContentType + ")"
+	}
+	switch {
+	case e.Code != "" && e.Description != "":
+		msg += ": " + e.Code + ": " + e.Description
+	case e.Code != "":
+		msg += ": " + e.Code
+	case e.Snippet != "":
+		msg += ": " + e.Snippet
+	}
+	return msg
+}
+
+// unexpectedResponse reads the start of the response's body and describes
+// the response.
+func unexpectedResponse(resp *http.Response) ErrUnexpectedResponse {
+	e := ErrUnexpectedResponse{StatusCode: resp.StatusCode, Status: resp.Status}
+	if e.Status == "" {
+		e.Status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	e.ContentType = mediaType
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return e
+	}
+
+	if body[0] == '{' {
+		var uaaErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+			Message     string `json:"message"`
+		}
+		if json.Unmarshal(body, &uaaErr) == nil && uaaErr.Error != "" {
+			e.Code = sanitize(uaaErr.Error)
+			e.Description = sanitize(uaaErr.Description)
+			if e.Description == "" {
+				e.Description = sanitize(uaaErr.Message)
+			}
+			return e
+		}
+	}
+	if mediaType == "text/html" || body[0] == '<' {
+		e.Snippet = sanitize(htmlText(body))
+	} else {
+		e.Snippet = sanitize(string(body))
+	}
+	return e
+}
+
+// htmlText returns the text of an HTML page, preferring its title, and
+// leaving out scripts and styles.
+func htmlText(page []byte) string {
+	var (
+		text    []string
+		title   string
+		skip    int
+		inTitle bool
+	)
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if title != "" {
+				return title
+			}
+			return strings.Join(text, " ")
+		case html.StartTagToken:
+			switch name, _ := z.TagName(); string(name) {
+			case "script", "style":
+				skip++
+			case "title":
+				inTitle = true
+			}
+		case html.EndTagToken:
+			switch name, _ := z.TagName(); string(name) {
+			case "script", "style":
+				if skip > 0 {
+					skip--
+				}
+			case "title":
+				inTitle = false
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			t := strings.TrimSpace(string(z.Text()))
+			if t == "" {
+				continue
+			}
+			if inTitle {
+				title = t
+			}
+			text = append(text, t)
+		}
+	}
+}
+
+// sanitize collapses whitespace, removes control characters, which could
+// otherwise affect a terminal that the error is printed to, and truncates the
+// text to maxErrorSnippet characters.
+func sanitize(s string) string {
+	var b bytes.Buffer
+	space := false
+	n := 0
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = b.Len() > 0
+			continue
+		}
+		if !unicode.IsPrint(r) {
+			continue
+		}
+		if n == maxErrorSnippet {
+			b.WriteString("...")
+			break
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+			n++
+		}
+		b.WriteRune(r)
+		n++
+	}
+	return b.String()
+}
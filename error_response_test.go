@@ -0,0 +1,98 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestErrorResponse(t *testing.T) {
+	spec.Run(t, "ErrorResponse", testErrorResponse, spec.Report(report.Terminal{}))
+}
+
+func testErrorResponse(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s           *httptest.Server
+		a           *uaa.API
+		status      int
+		contentType string
+		body        string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("describes a JSON error from the UAA", func() {
+		status = http.StatusConflict
+		contentType = "application/json;charset=UTF-8"
+		body = `{"error":"scim_resource_already_exists","message":"Username already in use: marcus"}`
+		_, err := a.CreateUser(uaa.User{Username: "marcus"})
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrUnexpectedResponse{
+			StatusCode:  409,
+			Status:      "409 Conflict",
+			ContentType: "application/json",
+			Code:        "scim_resource_already_exists",
+			Description: "Username already in use: marcus",
+		}))
+		Expect(err).To(MatchError(ContainSubstring("the UAA responded 409 Conflict: scim_resource_already_exists: Username already in use: marcus")))
+	})
+
+	it("reduces an HTML error page to its title", func() {
+		status = http.StatusBadGateway
+		contentType = "text/html"
+		body = `<html><head><title>502 Bad Gateway</title><style>body { color: red }</style></head>
+			<body><h1>Bad Gateway</h1><script>alert(1)</script>` + strings.Repeat("<p>padding</p>", 10000) + `</body></html>`
+		_, err := a.GetInfo()
+		cause := errors.Cause(err).(uaa.ErrUnexpectedResponse)
+		Expect(cause.Snippet).To(Equal("502 Bad Gateway"))
+		Expect(err).To(MatchError(ContainSubstring("the UAA responded 502 Bad Gateway (text/html): 502 Bad Gateway")))
+	})
+
+	it("keeps the text of an HTML page without a title, leaving out scripts", func() {
+		status = http.StatusServiceUnavailable
+		contentType = "text/html"
+		body = `<html><body><h1>Service   Unavailable</h1><script>alert(1)</script><p>Try again later.</p></body></html>`
+		_, err := a.GetInfo()
+		Expect(errors.Cause(err).(uaa.ErrUnexpectedResponse).Snippet).To(Equal("Service Unavailable Try again later."))
+	})
+
+	it("truncates long bodies and removes control characters", func() {
+		status = http.StatusInternalServerError
+		contentType = "text/plain"
+		body = "\x1b[31mfailure\x1b[0m\n" + strings.Repeat("x", 1000)
+		_, err := a.GetInfo()
+		snippet := errors.Cause(err).(uaa.ErrUnexpectedResponse).Snippet
+		Expect(snippet).To(HavePrefix("[31mfailure[0m xxx"))
+		Expect(snippet).To(HaveSuffix("..."))
+		Expect(len(snippet)).To(Equal(203))
+	})
+
+	it("describes an empty body by its status", func() {
+		status = http.StatusForbidden
+		contentType = ""
+		body = ""
+		_, err := a.GetInfo()
+		Expect(errors.Cause(err).Error()).To(Equal("the UAA responded 403 Forbidden"))
+	})
+}
@@ -0,0 +1,239 @@
+package uaa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultExpiryLeeway is how long before its expiry a token is replaced when
+// no leeway is set. It is the leeway that golang.org/x/oauth2 uses.
+const DefaultExpiryLeeway = 10 * time.Second
+
+// ErrClockSkew is returned, when the API was created with WithMaxClockSkew,
+// for a token whose expiry according to the UAA differs from its expiry
+// according to the local clock by more than the maximum. A local clock that
+// is far enough off makes fresh tokens look expired, or expired tokens look
+// fresh, so that requests fail in a loop.
+type ErrClockSkew struct {
+	// Skew is how far the local clock is ahead of the UAA's, or behind it if
+	// negative.
+	Skew    time.Duration
+	MaxSkew time.Duration
+}
+
+func (e ErrClockSkew) Error() string {
+	direction := "ahead of"
+	skew := e.Skew
+	if skew < 0 {
+		direction = "behind"
+		skew = -skew
+	}
+	return fmt.Sprintf("the local clock is %v %s the UAA's, more than the %v allowed; check that the clock is synchronized", skew, direction, e.MaxSkew)
+}
+
+// WithExpiryLeeway returns an Option that makes the API replace its token
+// when the token expires within leeway, rather than within
+// DefaultExpiryLeeway, so that a token does not expire while a request is in
+// flight or because the local clock is slightly behind. It applies to APIs
+// that obtain their own tokens and must be passed to the constructor.
+func WithExpiryLeeway(leeway time.Duration) Option {
+	return func(a *API) {
+		a.expiryLeeway = leeway
+	}
+}
+
+// WithMaxClockSkew returns an Option that refuses tokens with an ErrClockSkew
+// if the local clock and the UAA's differ by more than maxSkew, judged by
+// comparing the token's exp claim with the expiry computed locally from the
+// token response. Only JSON Web Tokens carry an exp claim; opaque tokens are
+// not checked. It applies to APIs that obtain their own tokens and must be
+// passed to the constructor.
+func WithMaxClockSkew(maxSkew time.Duration) Option {
+	return func(a *API) {
+		a.maxClockSkew = maxSkew
+	}
+}
+
+// installExpiryTokenSource replaces the authenticated client's token source
+// with one that applies the expiry leeway, clock skew, and clock options, and
+// that can replace a token the UAA rejects. refresh obtains a new token given
+// the current one.
+func (a *API) installExpiryTokenSource(refresh func(current *oauth2.Token) (*oauth2.Token, error)) {
+	if a.AuthenticatedClient == nil {
+		return
+	}
+	t, ok := unwrapTransport(a.AuthenticatedClient.Transport).(*oauth2.Transport)
+	if !ok {
+		return
+	}
+	leeway := a.expiryLeeway
+	if leeway <= 0 {
+		leeway = DefaultExpiryLeeway
+	}
+	source := &expiryTokenSource{
+		base:    t.Source,
+		refresh: refresh,
+		leeway:  leeway,
+		maxSkew: a.maxClockSkew,
+		clock:   a.getClock(),
+		events:  a.tokenEvents,
+		rotates: a.secretRotation != nil,
+		store:   a.tokenStore,
+
+		requested: a.requestedScopes(),
+		narrowed:  a.narrowedScopes,
+	}
+	if a.tokenStore != nil {
+		if token, err := a.tokenStore.Load(); err == nil && token != nil {
+			source.token = token
+		}
+	}
+	t.Source = source
+	if a.keepAlive != nil {
+		go source.keepAlive(a.keepAlive.ctx, a.keepAlive.lead)
+	}
+}
+
+// expiryTokenSource caches tokens and replaces them leeway before they
+// expire. The first token comes from base; later tokens come from refresh,
+// because base caches its token and would return it until it is within its
+// own, shorter, leeway.
+type expiryTokenSource struct {
+	base    oauth2.TokenSource
+	refresh func(current *oauth2.Token) (*oauth2.Token, error)
+	leeway  time.Duration
+	maxSkew time.Duration
+	clock   Clock
+	events  func(TokenEvent)
+	rotates bool
+	store   TokenStore
+
+	requested []string
+	narrowed  func(ScopeGrant)
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+func (s *expiryTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != nil && !s.expiresSoon(s.token) {
+		return s.token, nil
+	}
+	token, err := s.fetch(s.token)
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+// fetch obtains a token to replace the current one, which is nil if there is
+// none yet.
+func (s *expiryTokenSource) fetch(current *oauth2.Token) (*oauth2.Token, error) {
+	token, err := s.obtain(current)
+	if err != nil {
+		return nil, err
+	}
+	if s.expiresSoon(token) && s.refresh != nil {
+		return s.obtain(token)
+	}
+	return token, nil
+}
+
+// obtain makes a single token request, from base if there is no current
+// token and by refreshing the current token otherwise.
+func (s *expiryTokenSource) obtain(current *oauth2.Token) (*oauth2.Token, error) {
+	start := s.clock.Now()
+	event := TokenEvent{Type: TokenIssued, RequestedScopes: s.requested}
+	var (
+		token *oauth2.Token
+		err   error
+	)
+	if current == nil || s.refresh == nil {
+		token, err = s.base.Token()
+		// The base source uses the constructor's client secret, so a rotated
+		// secret is only tried through refresh.
+		if err != nil && s.rotates && s.refresh != nil && badCredentials(err) != nil {
+			token, err = s.refresh(nil)
+		}
+	} else {
+		event.Type = TokenRefreshed
+		token, err = s.refresh(current)
+	}
+	if err == nil {
+		err = s.checkSkew(token)
+	}
+	event.Elapsed = s.clock.Now().Sub(start)
+	if err != nil {
+		event.Type = failedTokenEvent[event.Type]
+		event.Err = err
+		s.emit(event)
+		return nil, err
+	}
+	token = s.onClock(token)
+	s.emit(newTokenEvent(event, token))
+	if grant := NewScopeGrant(s.requested, token); s.narrowed != nil && grant.Narrowed() {
+		s.narrowed(grant)
+	}
+	if s.store != nil {
+		// A token that cannot be saved is still good for this process.
+		s.store.Save(token)
+	}
+	return token, nil
+}
+
+func (s *expiryTokenSource) emit(event TokenEvent) {
+	if s.events != nil {
+		s.events(event)
+	}
+}
+
+// onClock returns a copy of the token whose expiry is measured on the
+// source's clock rather than the system clock that oauth2 uses.
+func (s *expiryTokenSource) onClock(token *oauth2.Token) *oauth2.Token {
+	if token.Expiry.IsZero() {
+		return token
+	}
+	copied := *token
+	copied.Expiry = s.clock.Now().Add(time.Until(token.Expiry))
+	return &copied
+}
+
+func (s *expiryTokenSource) expiresSoon(token *oauth2.Token) bool {
+	return !token.Expiry.IsZero() && !s.clock.Now().Add(s.leeway).Before(token.Expiry)
+}
+
+func (s *expiryTokenSource) checkSkew(token *oauth2.Token) error {
+	if s.maxSkew <= 0 {
+		return nil
+	}
+	claims, err := DecodeClaims(token.AccessToken)
+	if err != nil || claims.Expiry == 0 || token.Expiry.IsZero() {
+		return nil
+	}
+	// The token's Expiry was computed from the local clock when the token was
+	// issued, and its exp claim from the UAA's.
+	skew := token.Expiry.Sub(claims.ExpiresAt())
+	if skew > s.maxSkew || -skew > s.maxSkew {
+		return ErrClockSkew{Skew: skew.Truncate(time.Second), MaxSkew: s.maxSkew}
+	}
+	return nil
+}
+
+// refreshWithToken returns a function that obtains a new token with the
+// current token's refresh token.
+func refreshWithToken(ctx context.Context, c *oauth2.Config) func(*oauth2.Token) (*oauth2.Token, error) {
+	return func(current *oauth2.Token) (*oauth2.Token, error) {
+		if current == nil || current.RefreshToken == "" {
+			return nil, errors.New("the API's token cannot be refreshed")
+		}
+		return c.TokenSource(ctx, &oauth2.Token{RefreshToken: current.RefreshToken}).Token()
+	}
+}
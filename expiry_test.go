@@ -0,0 +1,98 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestExpiry(t *testing.T) {
+	spec.Run(t, "Expiry", testExpiry, spec.Report(report.Terminal{}))
+}
+
+func testExpiry(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s         *httptest.Server
+		issued    int
+		expiresIn int
+		exp       time.Time
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		issued = 0
+		expiresIn = 60
+		exp = time.Time{}
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal("/oauth/token"))
+			issued++
+			expiry := exp
+			if expiry.IsZero() {
+				expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": unsignedJWT(map[string]interface{}{"exp": expiry.Unix(), "jti": strconv.Itoa(issued)}),
+				"token_type":   "bearer",
+				"expires_in":   expiresIn,
+			})
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("replaces tokens that expire within the leeway", func() {
+		api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken, uaa.WithExpiryLeeway(2*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		// Each 60 second token expires within the leeway, so every call
+		// obtains a new one.
+		Expect(issued).To(BeNumerically(">=", 3))
+
+		expiresIn = 3600
+		issued = 0
+		api, err = uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken, uaa.WithExpiryLeeway(2*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		first, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		second, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(issued).To(Equal(1))
+	})
+
+	it("refuses tokens when the clocks disagree", func() {
+		expiresIn = 3600
+		exp = time.Now().Add(time.Hour).Add(-10 * time.Minute)
+		api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken, uaa.WithMaxClockSkew(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).To(HaveOccurred())
+		skew, ok := errors.Cause(err).(uaa.ErrClockSkew)
+		Expect(ok).To(BeTrue())
+		Expect(skew.Skew).To(BeNumerically("~", 10*time.Minute, 5*time.Second))
+		Expect(err.Error()).To(ContainSubstring("ahead of the UAA's"))
+	})
+
+	it("accepts tokens when the clocks agree", func() {
+		expiresIn = 3600
+		api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken, uaa.WithMaxClockSkew(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+	})
+}
@@ -0,0 +1,152 @@
+package uaa
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// ExportCheckpoint records how far the exports of each resource type, such as
+// "users", have got, so that an interrupted export can resume rather than
+// start again from the first page.
+type ExportCheckpoint interface {
+	// Load returns the cursor at which the export of the resource type
+	// resumes, or the zero ListCursor if it has not started.
+	Load(resourceType string) (ListCursor, error)
+	// Save records the cursor at which the export of the resource type
+	// resumes.
+	Save(resourceType string, cursor ListCursor) error
+}
+
+// MemoryExportCheckpoint is an ExportCheckpoint held in memory. It allows an
+// export to be retried within a process.
+type MemoryExportCheckpoint struct {
+	mu      sync.Mutex
+	cursors map[string]ListCursor
+}
+
+// NewMemoryExportCheckpoint returns an empty MemoryExportCheckpoint.
+func NewMemoryExportCheckpoint() *MemoryExportCheckpoint {
+	return &MemoryExportCheckpoint{cursors: map[string]ListCursor{}}
+}
+
+// Load returns the cursor for the resource type.
+func (c *MemoryExportCheckpoint) Load(resourceType string) (ListCursor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cursors[resourceType], nil
+}
+
+// Save records the cursor for the resource type.
+func (c *MemoryExportCheckpoint) Save(resourceType string, cursor ListCursor) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cursors[resourceType] = cursor
+	return nil
+}
+
+// FileExportCheckpoint is an ExportCheckpoint stored in a file as a JSON
+// object mapping each resource type to its cursor. The file is replaced
+// atomically on each save, so an interruption leaves the previous
+// checkpoint. It allows an export to be resumed by a later process. Remove
+// the file once the export has completed.
+type FileExportCheckpoint struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Load reads the cursor for the resource type from the file. A missing file
+// has no cursors.
+func (c *FileExportCheckpoint) Load(resourceType string) (ListCursor, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cursors, err := c.read()
+	if err != nil {
+		return ListCursor{}, err
+	}
+	return cursors[resourceType], nil
+}
+
+// Save records the cursor for the resource type in the file.
+func (c *FileExportCheckpoint) Save(resourceType string, cursor ListCursor) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cursors, err := c.read()
+	if err != nil {
+		return err
+	}
+	cursors[resourceType] = cursor
+	j, err := json.Marshal(cursors)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(c.Path, j)
+}
+
+func (c *FileExportCheckpoint) read() (map[string]ListCursor, error) {
+	cursors := map[string]ListCursor{}
+	data, err := ioutil.ReadFile(c.Path)
+	if os.IsNotExist(err) {
+		return cursors, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &cursors); err != nil {
+		return nil, err
+	}
+	return cursors, nil
+}
+
+// exportCheckpointPageSize is the number of results exported between
+// checkpoints.
+const exportCheckpointPageSize = 100
+
+// ExportUsersCSVWithCheckpoint is ExportUsersCSV for exports that may be
+// interrupted. The users are read a page at a time, and after each page has
+// been written and flushed to w the position reached is saved to checkpoint
+// under "users". The export starts at the saved position, so an interrupted
+// export resumes when it is run again with the same filter and with w
+// appending to the same file; the header row is only written by an export
+// that starts at the first user. Users created or deleted while an export is
+// interrupted may shift the results between pages.
+func (a *API) ExportUsersCSVWithCheckpoint(w io.Writer, filter string, checkpoint ExportCheckpoint) error {
+	cursor, err := checkpoint.Load("users")
+	if err != nil {
+		return err
+	}
+	writer := NewUserCSVWriter(w)
+	if cursor.startIndex() > 1 {
+		writer.wroteHeader = true
+	}
+	progress := NewProgressTracker(0, a.progress)
+	start := cursor.startIndex()
+	for {
+		users, page, err := a.ListUsers(filter, "", "", "", start, exportCheckpointPageSize)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err := writer.Write(NewUserRecord(user)); err != nil {
+				return err
+			}
+			progress.Done(nil)
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		start = page.StartIndex + page.ItemsPerPage
+		if len(users) == 0 {
+			start = page.StartIndex
+		}
+		if err := checkpoint.Save("users", ListCursor{StartIndex: start}); err != nil {
+			return err
+		}
+		if len(users) == 0 || start > page.TotalResults {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package uaa_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestExportCheckpoint(t *testing.T) {
+	spec.Run(t, "ExportCheckpoint", testExportCheckpoint, spec.Report(report.Terminal{}))
+}
+
+func testExportCheckpoint(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		failAt  int
+		starts  []int
+		a       *uaa.API
+		tempDir string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		failAt = 0
+		starts = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal(uaa.UsersEndpoint))
+			start, _ := strconv.Atoi(req.URL.Query().Get("startIndex"))
+			count, _ := strconv.Atoi(req.URL.Query().Get("count"))
+			starts = append(starts, start)
+			if start == failAt {
+				failAt = 0
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			var users []uaa.User
+			for i := start; i < start+count && i <= 250; i++ {
+				users = append(users, uaa.User{ID: strconv.Itoa(i), Username: fmt.Sprintf("user%d", i)})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"resources":    users,
+				"startIndex":   start,
+				"itemsPerPage": len(users),
+				"totalResults": 250,
+			})
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		}
+		var err error
+		tempDir, err = ioutil.TempDir("", "export-checkpoint")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		s.Close()
+		os.RemoveAll(tempDir)
+	})
+
+	it("exports every user and records the end", func() {
+		checkpoint := uaa.NewMemoryExportCheckpoint()
+		buf := &bytes.Buffer{}
+		Expect(a.ExportUsersCSVWithCheckpoint(buf, "", checkpoint)).To(Succeed())
+		Expect(strings.Split(strings.TrimSpace(buf.String()), "\n")).To(HaveLen(251))
+		Expect(checkpoint.Load("users")).To(Equal(uaa.ListCursor{StartIndex: 251}))
+	})
+
+	it("resumes an interrupted export from the checkpoint", func() {
+		checkpoint := &uaa.FileExportCheckpoint{Path: filepath.Join(tempDir, "checkpoint.json")}
+		buf := &bytes.Buffer{}
+		failAt = 201
+		Expect(a.ExportUsersCSVWithCheckpoint(buf, "", checkpoint)).NotTo(Succeed())
+		Expect(checkpoint.Load("users")).To(Equal(uaa.ListCursor{StartIndex: 201}))
+		Expect(strings.Split(strings.TrimSpace(buf.String()), "\n")).To(HaveLen(201))
+
+		Expect(a.ExportUsersCSVWithCheckpoint(buf, "", checkpoint)).To(Succeed())
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		Expect(lines).To(HaveLen(251))
+		Expect(lines[0]).To(HavePrefix("userName,"))
+		Expect(lines[250]).To(HavePrefix("user250,"))
+		Expect(starts).To(Equal([]int{1, 101, 201, 201}))
+	})
+
+	it("keeps the cursors of other resource types", func() {
+		checkpoint := &uaa.FileExportCheckpoint{Path: filepath.Join(tempDir, "checkpoint.json")}
+		Expect(checkpoint.Save("groups", uaa.ListCursor{StartIndex: 51})).To(Succeed())
+		Expect(checkpoint.Save("users", uaa.ListCursor{StartIndex: 101})).To(Succeed())
+		Expect(checkpoint.Load("groups")).To(Equal(uaa.ListCursor{StartIndex: 51}))
+		Expect(checkpoint.Load("users")).To(Equal(uaa.ListCursor{StartIndex: 101}))
+		Expect(checkpoint.Load("clients")).To(Equal(uaa.ListCursor{}))
+	})
+}
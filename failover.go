@@ -0,0 +1,180 @@
+package uaa
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultFailoverCooldown is how long a target that failed is avoided.
+const DefaultFailoverCooldown = 30 * time.Second
+
+// WithFallbackTargets returns an Option that fails over to the given targets,
+// for example the routers in other availability zones, when the API's target
+// is unreachable or responds with a 5xx status. Only the scheme and host of
+// each fallback target are used.
+//
+// Reads, including token requests and token introspection, are retried
+// against each target in turn. Requests that change the UAA are sent to the
+// first healthy target but are not retried, because they may already have
+// been applied. A target that fails is avoided for DefaultFailoverCooldown
+// unless every target has failed.
+//
+// The option must be applied after the API's TargetURL and clients are set;
+// the New* constructors do this.
+func WithFallbackTargets(targets ...*url.URL) Option {
+	return func(a *API) {
+		if a.TargetURL == nil {
+			return
+		}
+		a.fallbackTargets = targets
+		set := &targetSet{cooldown: DefaultFailoverCooldown, clock: a.getClock}
+		for _, target := range append([]*url.URL{a.TargetURL}, targets...) {
+			set.targets = append(set.targets, &failoverTarget{scheme: target.Scheme, host: target.Host})
+		}
+		for _, c := range []*http.Client{a.AuthenticatedClient, a.UnauthenticatedClient} {
+			if c == nil {
+				continue
+			}
+			if _, ok := c.Transport.(*failoverTransport); ok {
+				continue
+			}
+			base := c.Transport
+			if base == nil {
+				base = http.DefaultTransport
+			}
+			c.Transport = &failoverTransport{targets: set, base: base}
+		}
+	}
+}
+
+type failoverTarget struct {
+	scheme    string
+	host      string
+	downUntil time.Time
+}
+
+type targetSet struct {
+	mu       sync.Mutex
+	targets  []*failoverTarget
+	cooldown time.Duration
+	clock    func() Clock
+}
+
+// ordered returns the healthy targets followed by the unhealthy ones.
+func (s *targetSet) ordered() []failoverTarget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock().Now()
+	var healthy, unhealthy []failoverTarget
+	for _, t := range s.targets {
+		if now.Before(t.downUntil) {
+			unhealthy = append(unhealthy, *t)
+		} else {
+			healthy = append(healthy, *t)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (s *targetSet) primary(u *url.URL) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return u.Host == s.targets[0].host
+}
+
+func (s *targetSet) mark(host string, healthy bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.targets {
+		if t.host != host {
+			continue
+		}
+		if healthy {
+			t.downUntil = time.Time{}
+		} else {
+			t.downUntil = s.clock().Now().Add(s.cooldown)
+		}
+	}
+}
+
+// failoverTransport sends requests for the primary target to the healthiest
+// of a set of equivalent targets.
+type failoverTransport struct {
+	targets *targetSet
+	base    http.RoundTripper
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.targets.primary(req.URL) {
+		return t.base.RoundTrip(req)
+	}
+	targets := t.targets.ordered()
+	if isMutating(req) {
+		targets = targets[:1]
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for i, target := range targets {
+		if i > 0 && req.Body != nil && req.GetBody == nil {
+			// The body cannot be replayed.
+			break
+		}
+		r, rerr := retarget(req, target, i > 0)
+		if rerr != nil {
+			return nil, rerr
+		}
+		resp, err = t.base.RoundTrip(r)
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+		t.targets.mark(target.host, !failed)
+		if !failed || i == len(targets)-1 {
+			break
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+	return resp, err
+}
+
+// retarget returns a copy of the request addressed to the target.
+func retarget(req *http.Request, target failoverTarget, replayBody bool) (*http.Request, error) {
+	r := new(http.Request)
+	*r = *req
+	u := *req.URL
+	u.Scheme = target.scheme
+	u.Host = target.host
+	r.URL = &u
+	r.Host = ""
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	if replayBody && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// unwrapTransport returns the transport underneath any failover or path
+// transports.
+func unwrapTransport(rt http.RoundTripper) http.RoundTripper {
+	switch t := rt.(type) {
+	case *failoverTransport:
+		return unwrapTransport(t.base)
+	case *pathTransport:
+		return unwrapTransport(t.base)
+	}
+	return rt
+}
@@ -0,0 +1,126 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestFailover(t *testing.T) {
+	spec.Run(t, "Failover", testFailover, spec.Report(report.Terminal{}))
+}
+
+func testFailover(t *testing.T, when spec.G, it spec.S) {
+	var (
+		primary, fallback         *httptest.Server
+		primaryCalls, fallbackHit []string
+		primaryStatus             int
+		primaryTokens             bool
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		primaryCalls, fallbackHit = nil, nil
+		primaryStatus = http.StatusBadGateway
+		primaryTokens = true
+		primary = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			primaryCalls = append(primaryCalls, req.Method+" "+req.URL.Path)
+			if req.URL.Path == "/oauth/token" && primaryTokens {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":3600}`))
+				return
+			}
+			w.WriteHeader(primaryStatus)
+			w.Write([]byte(MarcusUserResponse))
+		}))
+		fallback = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fallbackHit = append(fallbackHit, req.Method+" "+req.URL.Path)
+			if req.URL.Path == "/oauth/token" {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":3600}`))
+				return
+			}
+			Expect(req.Header.Get("Authorization")).To(Equal("Bearer token"))
+			w.Write([]byte(MarcusUserResponse))
+		}))
+	})
+
+	it.After(func() {
+		primary.Close()
+		fallback.Close()
+	})
+
+	newAPI := func(opts ...uaa.Option) *uaa.API {
+		u, _ := url.Parse(fallback.URL)
+		opts = append(opts, uaa.WithFallbackTargets(u))
+		a, err := uaa.NewWithClientCredentials(primary.URL, "", "client", "secret", uaa.JSONWebToken, opts...)
+		Expect(err).NotTo(HaveOccurred())
+		return a
+	}
+
+	it("fails over reads", func() {
+		a := newAPI()
+		user, err := a.GetUser("id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("marcus@stoicism.com"))
+		Expect(primaryCalls).To(Equal([]string{"POST /oauth/token", "GET /Users/id"}))
+		Expect(fallbackHit).To(Equal([]string{"GET /Users/id"}))
+	})
+
+	it("fails over token requests", func() {
+		primaryTokens = false
+		a := newAPI()
+		_, err := a.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(primaryCalls).To(Equal([]string{"POST /oauth/token"}))
+		Expect(fallbackHit).To(Equal([]string{"POST /oauth/token"}))
+	})
+
+	it("avoids a target that recently failed", func() {
+		a := newAPI()
+		a.GetUser("id")
+		a.GetUser("id")
+		Expect(primaryCalls).To(HaveLen(2))
+		Expect(fallbackHit).To(Equal([]string{"GET /Users/id", "GET /Users/id"}))
+	})
+
+	it("tries a failed target again once the cooldown has passed on the API's clock", func() {
+		clock := uaa.NewFakeClock()
+		a := newAPI(uaa.WithClock(clock))
+		a.GetUser("id")
+		a.GetUser("id")
+		Expect(primaryCalls).To(HaveLen(2))
+
+		clock.Advance(uaa.DefaultFailoverCooldown)
+		a.GetUser("id")
+		Expect(primaryCalls).To(Equal([]string{"POST /oauth/token", "GET /Users/id", "GET /Users/id"}))
+	})
+
+	it("fails over when the target is unreachable", func() {
+		primary.Close()
+		a := newAPI()
+		_, err := a.GetUser("id")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it("does not retry requests that change the UAA", func() {
+		a := newAPI()
+		_, err := a.DeleteUser("id")
+		Expect(err).To(HaveOccurred())
+		Expect(primaryCalls).To(Equal([]string{"POST /oauth/token", "DELETE /Users/id"}))
+		Expect(fallbackHit).To(BeEmpty())
+	})
+
+	it("leaves the API's token accessible", func() {
+		a := newAPI()
+		token, err := a.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("token"))
+	})
+}
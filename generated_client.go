@@ -27,6 +27,9 @@ func (a *API) GetClient(clientID string) (*Client, error) {
 func (a *API) CreateClient(client Client) (*Client, error) {
 	u := urlWithPath(*a.TargetURL, ClientsEndpoint)
 	created := &Client{}
+	if err := checkWrite(client); err != nil {
+		return nil, err
+	}
 	j, err := json.Marshal(client)
 	if err != nil {
 		return nil, err
@@ -42,6 +45,9 @@ func (a *API) CreateClient(client Client) (*Client, error) {
 func (a *API) UpdateClient(client Client) (*Client, error) {
 	u := urlWithPath(*a.TargetURL, ClientsEndpoint)
 	created := &Client{}
+	if err := checkWrite(client); err != nil {
+		return nil, err
+	}
 	j, err := json.Marshal(client)
 	if err != nil {
 		return nil, err
@@ -53,6 +59,54 @@ func (a *API) UpdateClient(client Client) (*Client, error) {
 	return created, nil
 }
 
+// CreateClientAndGet creates the given client and then reads it back, so that
+// the server's canonical representation is returned. The read is retried
+// while the new client is not yet visible.
+func (a *API) CreateClientAndGet(client Client) (*Client, error) {
+	created, err := a.CreateClient(client)
+	if err != nil {
+		return nil, err
+	}
+	var got *Client
+	err = a.readAfterWrite(func() (bool, error) {
+		var err error
+		if got, err = a.GetClient(created.ClientID); err != nil {
+			return false, err
+		}
+		return staleRead(got, created), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
+// UpdateClientAndGet updates the given client and then reads it back, so that
+// the server's canonical representation is returned. The read is retried
+// while the server returns the client as it was before the update.
+func (a *API) UpdateClientAndGet(client Client) (*Client, error) {
+	updated, err := a.UpdateClient(client)
+	if err != nil {
+		return nil, err
+	}
+	id := updated.ClientID
+	if id == "" {
+		id = client.ClientID
+	}
+	var got *Client
+	err = a.readAfterWrite(func() (bool, error) {
+		var err error
+		if got, err = a.GetClient(id); err != nil {
+			return false, err
+		}
+		return staleRead(got, updated), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
 // DeleteClient deletes the client with the given client ID.
 func (a *API) DeleteClient(clientID string) (*Client, error) {
 	if clientID == "" {
@@ -106,12 +160,22 @@ func (a *API) ListClients(filter string, sortBy string, sortOrder SortOrder, sta
 	return clients.Resources, page, err
 }
 
-// ListAllClients retrieves UAA clients
+// ListAllClients retrieves UAA clients. It is ListAllClientsFrom from the start of
+// the listing.
 func (a *API) ListAllClients(filter string, sortBy string, sortOrder SortOrder) ([]Client, error) {
+	return a.ListAllClientsFrom(ListCursor{}, filter, sortBy, sortOrder)
+}
+
+// ListAllClientsFrom retrieves UAA clients starting at the cursor, which is the
+// zero ListCursor for the first client. If the API's list deadline (see
+// WithListDeadline) passes before the last page is read, the clients read so far
+// are returned with an ErrPartialResults whose Cursor resumes the listing.
+func (a *API) ListAllClientsFrom(cursor ListCursor, filter string, sortBy string, sortOrder SortOrder) ([]Client, error) {
 	page := Page{
-		StartIndex:   1,
+		StartIndex:   cursor.startIndex(),
 		ItemsPerPage: 100,
 	}
+	deadlinePassed := a.listDeadline()
 	var (
 		results     []Client
 		currentPage []Client
@@ -128,7 +192,57 @@ func (a *API) ListAllClients(filter string, sortBy string, sortOrder SortOrder)
 		if (page.StartIndex + page.ItemsPerPage) > page.TotalResults {
 			break
 		}
+		if a.pageConcurrency > 1 {
+			starts := remainingPages(page)
+			pages := make([][]Client, len(starts))
+			var read int
+			read, err = a.fetchPages(starts, deadlinePassed, func(i int, startIndex int) error {
+				var err error
+				pages[i], _, err = a.ListClients(filter, sortBy, sortOrder, startIndex, page.ItemsPerPage)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range pages[:read] {
+				results = append(results, p...)
+			}
+			if read != len(starts) {
+				return results, ErrPartialResults{Cursor: ListCursor{StartIndex: starts[read]}}
+			}
+			break
+		}
 		page.StartIndex = page.StartIndex + page.ItemsPerPage
+		if deadlinePassed() {
+			return results, ErrPartialResults{Cursor: ListCursor{StartIndex: page.StartIndex}}
+		}
 	}
 	return results, nil
 }
+
+// EachClient calls fn with each client matching the filter. The clients are
+// decoded one at a time as each page is read, so that large numbers of
+// clients are not held in memory. If fn returns an error, EachClient stops
+// and returns it.
+func (a *API) EachClient(filter string, sortBy string, sortOrder SortOrder, fn func(Client) error) error {
+	query := url.Values{}
+	if filter != "" {
+		query.Set("filter", filter)
+	}
+	if sortBy != "" {
+		query.Set("sortBy", sortBy)
+	}
+	if sortOrder != "" {
+		query.Set("sortOrder", string(sortOrder))
+	}
+	return a.eachResource(ClientsEndpoint, query, func(d *json.Decoder) error {
+		var client Client
+		if err := d.Decode(&client); err != nil {
+			return err
+		}
+		if err := fn(client); err != nil {
+			return callbackError{err}
+		}
+		return nil
+	})
+}
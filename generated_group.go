@@ -27,6 +27,9 @@ func (a *API) GetGroup(groupID string) (*Group, error) {
 func (a *API) CreateGroup(group Group) (*Group, error) {
 	u := urlWithPath(*a.TargetURL, GroupsEndpoint)
 	created := &Group{}
+	if err := checkWrite(group); err != nil {
+		return nil, err
+	}
 	j, err := json.Marshal(group)
 	if err != nil {
 		return nil, err
@@ -42,6 +45,9 @@ func (a *API) CreateGroup(group Group) (*Group, error) {
 func (a *API) UpdateGroup(group Group) (*Group, error) {
 	u := urlWithPath(*a.TargetURL, GroupsEndpoint)
 	created := &Group{}
+	if err := checkWrite(group); err != nil {
+		return nil, err
+	}
 	j, err := json.Marshal(group)
 	if err != nil {
 		return nil, err
@@ -53,6 +59,54 @@ func (a *API) UpdateGroup(group Group) (*Group, error) {
 	return created, nil
 }
 
+// CreateGroupAndGet creates the given group and then reads it back, so that
+// the server's canonical representation is returned. The read is retried
+// while the new group is not yet visible.
+func (a *API) CreateGroupAndGet(group Group) (*Group, error) {
+	created, err := a.CreateGroup(group)
+	if err != nil {
+		return nil, err
+	}
+	var got *Group
+	err = a.readAfterWrite(func() (bool, error) {
+		var err error
+		if got, err = a.GetGroup(created.ID); err != nil {
+			return false, err
+		}
+		return staleRead(got, created), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
+// UpdateGroupAndGet updates the given group and then reads it back, so that
+// the server's canonical representation is returned. The read is retried
+// while the server returns the group as it was before the update.
+func (a *API) UpdateGroupAndGet(group Group) (*Group, error) {
+	updated, err := a.UpdateGroup(group)
+	if err != nil {
+		return nil, err
+	}
+	id := updated.ID
+	if id == "" {
+		id = group.ID
+	}
+	var got *Group
+	err = a.readAfterWrite(func() (bool, error) {
+		var err error
+		if got, err = a.GetGroup(id); err != nil {
+			return false, err
+		}
+		return staleRead(got, updated), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
 // DeleteGroup deletes the group with the given group ID.
 func (a *API) DeleteGroup(groupID string) (*Group, error) {
 	if groupID == "" {
@@ -109,12 +163,22 @@ func (a *API) ListGroups(filter string, sortBy string, attributes string, sortOr
 	return groups.Resources, page, err
 }
 
-// ListAllGroups retrieves UAA groups
+// ListAllGroups retrieves UAA groups. It is ListAllGroupsFrom from the start of
+// the listing.
 func (a *API) ListAllGroups(filter string, sortBy string, attributes string, sortOrder SortOrder) ([]Group, error) {
+	return a.ListAllGroupsFrom(ListCursor{}, filter, sortBy, attributes, sortOrder)
+}
+
+// ListAllGroupsFrom retrieves UAA groups starting at the cursor, which is the
+// zero ListCursor for the first group. If the API's list deadline (see
+// WithListDeadline) passes before the last page is read, the groups read so far
+// are returned with an ErrPartialResults whose Cursor resumes the listing.
+func (a *API) ListAllGroupsFrom(cursor ListCursor, filter string, sortBy string, attributes string, sortOrder SortOrder) ([]Group, error) {
 	page := Page{
-		StartIndex:   1,
+		StartIndex:   cursor.startIndex(),
 		ItemsPerPage: 100,
 	}
+	deadlinePassed := a.listDeadline()
 	var (
 		results     []Group
 		currentPage []Group
@@ -131,7 +195,60 @@ func (a *API) ListAllGroups(filter string, sortBy string, attributes string, sor
 		if (page.StartIndex + page.ItemsPerPage) > page.TotalResults {
 			break
 		}
+		if a.pageConcurrency > 1 {
+			starts := remainingPages(page)
+			pages := make([][]Group, len(starts))
+			var read int
+			read, err = a.fetchPages(starts, deadlinePassed, func(i int, startIndex int) error {
+				var err error
+				pages[i], _, err = a.ListGroups(filter, sortBy, attributes, sortOrder, startIndex, page.ItemsPerPage)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range pages[:read] {
+				results = append(results, p...)
+			}
+			if read != len(starts) {
+				return results, ErrPartialResults{Cursor: ListCursor{StartIndex: starts[read]}}
+			}
+			break
+		}
 		page.StartIndex = page.StartIndex + page.ItemsPerPage
+		if deadlinePassed() {
+			return results, ErrPartialResults{Cursor: ListCursor{StartIndex: page.StartIndex}}
+		}
 	}
 	return results, nil
 }
+
+// EachGroup calls fn with each group matching the filter. The groups are
+// decoded one at a time as each page is read, so that large numbers of
+// groups are not held in memory. If fn returns an error, EachGroup stops
+// and returns it.
+func (a *API) EachGroup(filter string, sortBy string, attributes string, sortOrder SortOrder, fn func(Group) error) error {
+	query := url.Values{}
+	if filter != "" {
+		query.Set("filter", filter)
+	}
+	if attributes != "" {
+		query.Set("attributes", attributes)
+	}
+	if sortBy != "" {
+		query.Set("sortBy", sortBy)
+	}
+	if sortOrder != "" {
+		query.Set("sortOrder", string(sortOrder))
+	}
+	return a.eachResource(GroupsEndpoint, query, func(d *json.Decoder) error {
+		var group Group
+		if err := d.Decode(&group); err != nil {
+			return err
+		}
+		if err := fn(group); err != nil {
+			return callbackError{err}
+		}
+		return nil
+	})
+}
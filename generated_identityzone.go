@@ -25,6 +25,9 @@ func (a *API) GetIdentityZone(identityzoneID string) (*IdentityZone, error) {
 func (a *API) CreateIdentityZone(identityzone IdentityZone) (*IdentityZone, error) {
 	u := urlWithPath(*a.TargetURL, IdentityZonesEndpoint)
 	created := &IdentityZone{}
+	if err := checkWrite(identityzone); err != nil {
+		return nil, err
+	}
 	j, err := json.Marshal(identityzone)
 	if err != nil {
 		return nil, err
@@ -40,6 +43,9 @@ func (a *API) CreateIdentityZone(identityzone IdentityZone) (*IdentityZone, erro
 func (a *API) UpdateIdentityZone(identityzone IdentityZone) (*IdentityZone, error) {
 	u := urlWithPath(*a.TargetURL, IdentityZonesEndpoint)
 	created := &IdentityZone{}
+	if err := checkWrite(identityzone); err != nil {
+		return nil, err
+	}
 	j, err := json.Marshal(identityzone)
 	if err != nil {
 		return nil, err
@@ -51,6 +57,54 @@ func (a *API) UpdateIdentityZone(identityzone IdentityZone) (*IdentityZone, erro
 	return created, nil
 }
 
+// CreateIdentityZoneAndGet creates the given identityzone and then reads it back, so that
+// the server's canonical representation is returned. The read is retried
+// while the new identityzone is not yet visible.
+func (a *API) CreateIdentityZoneAndGet(identityzone IdentityZone) (*IdentityZone, error) {
+	created, err := a.CreateIdentityZone(identityzone)
+	if err != nil {
+		return nil, err
+	}
+	var got *IdentityZone
+	err = a.readAfterWrite(func() (bool, error) {
+		var err error
+		if got, err = a.GetIdentityZone(created.ID); err != nil {
+			return false, err
+		}
+		return staleRead(got, created), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
+// UpdateIdentityZoneAndGet updates the given identityzone and then reads it back, so that
+// the server's canonical representation is returned. The read is retried
+// while the server returns the identityzone as it was before the update.
+func (a *API) UpdateIdentityZoneAndGet(identityzone IdentityZone) (*IdentityZone, error) {
+	updated, err := a.UpdateIdentityZone(identityzone)
+	if err != nil {
+		return nil, err
+	}
+	id := updated.ID
+	if id == "" {
+		id = identityzone.ID
+	}
+	var got *IdentityZone
+	err = a.readAfterWrite(func() (bool, error) {
+		var err error
+		if got, err = a.GetIdentityZone(id); err != nil {
+			return false, err
+		}
+		return staleRead(got, updated), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
 // DeleteIdentityZone deletes the identityzone with the given identityzone ID.
 func (a *API) DeleteIdentityZone(identityzoneID string) (*IdentityZone, error) {
 	if identityzoneID == "" {
@@ -27,6 +27,9 @@ func (a *API) GetUser(userID string) (*User, error) {
 func (a *API) CreateUser(user User) (*User, error) {
 	u := urlWithPath(*a.TargetURL, UsersEndpoint)
 	created := &User{}
+	if err := checkWrite(user); err != nil {
+		return nil, err
+	}
 	j, err := json.Marshal(user)
 	if err != nil {
 		return nil, err
@@ -42,6 +45,9 @@ func (a *API) CreateUser(user User) (*User, error) {
 func (a *API) UpdateUser(user User) (*User, error) {
 	u := urlWithPath(*a.TargetURL, UsersEndpoint)
 	created := &User{}
+	if err := checkWrite(user); err != nil {
+		return nil, err
+	}
 	j, err := json.Marshal(user)
 	if err != nil {
 		return nil, err
@@ -53,6 +59,54 @@ func (a *API) UpdateUser(user User) (*User, error) {
 	return created, nil
 }
 
+// CreateUserAndGet creates the given user and then reads it back, so that
+// the server's canonical representation is returned. The read is retried
+// while the new user is not yet visible.
+func (a *API) CreateUserAndGet(user User) (*User, error) {
+	created, err := a.CreateUser(user)
+	if err != nil {
+		return nil, err
+	}
+	var got *User
+	err = a.readAfterWrite(func() (bool, error) {
+		var err error
+		if got, err = a.GetUser(created.ID); err != nil {
+			return false, err
+		}
+		return staleRead(got, created), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
+// UpdateUserAndGet updates the given user and then reads it back, so that
+// the server's canonical representation is returned. The read is retried
+// while the server returns the user as it was before the update.
+func (a *API) UpdateUserAndGet(user User) (*User, error) {
+	updated, err := a.UpdateUser(user)
+	if err != nil {
+		return nil, err
+	}
+	id := updated.ID
+	if id == "" {
+		id = user.ID
+	}
+	var got *User
+	err = a.readAfterWrite(func() (bool, error) {
+		var err error
+		if got, err = a.GetUser(id); err != nil {
+			return false, err
+		}
+		return staleRead(got, updated), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
 // DeleteUser deletes the user with the given user ID.
 func (a *API) DeleteUser(userID string) (*User, error) {
 	if userID == "" {
@@ -109,12 +163,22 @@ func (a *API) ListUsers(filter string, sortBy string, attributes string, sortOrd
 	return users.Resources, page, err
 }
 
-// ListAllUsers retrieves UAA users
+// ListAllUsers retrieves UAA users. It is ListAllUsersFrom from the start of
+// the listing.
 func (a *API) ListAllUsers(filter string, sortBy string, attributes string, sortOrder SortOrder) ([]User, error) {
+	return a.ListAllUsersFrom(ListCursor{}, filter, sortBy, attributes, sortOrder)
+}
+
+// ListAllUsersFrom retrieves UAA users starting at the cursor, which is the
+// zero ListCursor for the first user. If the API's list deadline (see
+// WithListDeadline) passes before the last page is read, the users read so far
+// are returned with an ErrPartialResults whose Cursor resumes the listing.
+func (a *API) ListAllUsersFrom(cursor ListCursor, filter string, sortBy string, attributes string, sortOrder SortOrder) ([]User, error) {
 	page := Page{
-		StartIndex:   1,
+		StartIndex:   cursor.startIndex(),
 		ItemsPerPage: 100,
 	}
+	deadlinePassed := a.listDeadline()
 	var (
 		results     []User
 		currentPage []User
@@ -131,7 +195,60 @@ func (a *API) ListAllUsers(filter string, sortBy string, attributes string, sort
 		if (page.StartIndex + page.ItemsPerPage) > page.TotalResults {
 			break
 		}
+		if a.pageConcurrency > 1 {
+			starts := remainingPages(page)
+			pages := make([][]User, len(starts))
+			var read int
+			read, err = a.fetchPages(starts, deadlinePassed, func(i int, startIndex int) error {
+				var err error
+				pages[i], _, err = a.ListUsers(filter, sortBy, attributes, sortOrder, startIndex, page.ItemsPerPage)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range pages[:read] {
+				results = append(results, p...)
+			}
+			if read != len(starts) {
+				return results, ErrPartialResults{Cursor: ListCursor{StartIndex: starts[read]}}
+			}
+			break
+		}
 		page.StartIndex = page.StartIndex + page.ItemsPerPage
+		if deadlinePassed() {
+			return results, ErrPartialResults{Cursor: ListCursor{StartIndex: page.StartIndex}}
+		}
 	}
 	return results, nil
 }
+
+// EachUser calls fn with each user matching the filter. The users are
+// decoded one at a time as each page is read, so that large numbers of
+// users are not held in memory. If fn returns an error, EachUser stops
+// and returns it.
+func (a *API) EachUser(filter string, sortBy string, attributes string, sortOrder SortOrder, fn func(User) error) error {
+	query := url.Values{}
+	if filter != "" {
+		query.Set("filter", filter)
+	}
+	if attributes != "" {
+		query.Set("attributes", attributes)
+	}
+	if sortBy != "" {
+		query.Set("sortBy", sortBy)
+	}
+	if sortOrder != "" {
+		query.Set("sortOrder", string(sortOrder))
+	}
+	return a.eachResource(UsersEndpoint, query, func(d *json.Decoder) error {
+		var user User
+		if err := d.Decode(&user); err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return callbackError{err}
+		}
+		return nil
+	})
+}
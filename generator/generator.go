@@ -22,6 +22,7 @@ var typesToProcess = []interface{}{
 	uaa.Group{},
 	uaa.User{},
 	uaa.IdentityZone{},
+	uaa.IdentityProvider{},
 }
 
 func main() {
@@ -46,7 +47,7 @@ func main() {
 			t.SupportsAttributes = false
 		}
 
-		if typeName == "IdentityZone" {
+		if typeName == "IdentityZone" || typeName == "IdentityProvider" {
 			t.SupportsPaging = false
 		}
 
@@ -185,6 +186,9 @@ func (a *API) Get{{.ModelTypeName}}({{tolower .ModelTypeName}}ID string) (*{{.Mo
 func (a *API) Create{{.ModelTypeName}}({{tolower .ModelTypeName}} {{.ModelTypeName}}) (*{{.ModelTypeName}}, error) {
 	u := urlWithPath(*a.TargetURL, {{.ModelPluralTypeName}}Endpoint)
 	created := &{{.ModelTypeName}}{}
+	if err := checkWrite({{tolower .ModelTypeName}}); err != nil {
+		return nil, err
+	}
 	j, err := json.Marshal({{tolower .ModelTypeName}})
 	if err != nil {
 		return nil, err
@@ -200,6 +204,9 @@ func (a *API) Create{{.ModelTypeName}}({{tolower .ModelTypeName}} {{.ModelTypeNa
 func (a *API) Update{{.ModelTypeName}}({{tolower .ModelTypeName}} {{.ModelTypeName}}) (*{{.ModelTypeName}}, error) {
 	u := urlWithPath(*a.TargetURL, {{.ModelPluralTypeName}}Endpoint)
 	created := &{{.ModelTypeName}}{}
+	if err := checkWrite({{tolower .ModelTypeName}}); err != nil {
+		return nil, err
+	}
 	j, err := json.Marshal({{tolower .ModelTypeName}})
 	if err != nil {
 		return nil, err
@@ -211,6 +218,54 @@ func (a *API) Update{{.ModelTypeName}}({{tolower .ModelTypeName}} {{.ModelTypeNa
 	return created, nil
 }
 
+// Create{{.ModelTypeName}}AndGet creates the given {{tolower .ModelTypeName}} and then reads it back, so that
+// the server's canonical representation is returned. The read is retried
+// while the new {{tolower .ModelTypeName}} is not yet visible.
+func (a *API) Create{{.ModelTypeName}}AndGet({{tolower .ModelTypeName}} {{.ModelTypeName}}) (*{{.ModelTypeName}}, error) {
+	created, err := a.Create{{.ModelTypeName}}({{tolower .ModelTypeName}})
+	if err != nil {
+		return nil, err
+	}
+	var got *{{.ModelTypeName}}
+	err = a.readAfterWrite(func() (bool, error) {
+		var err error
+		if got, err = a.Get{{.ModelTypeName}}(created.{{.IDFieldName}}); err != nil {
+			return false, err
+		}
+		return staleRead(got, created), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
+// Update{{.ModelTypeName}}AndGet updates the given {{tolower .ModelTypeName}} and then reads it back, so that
+// the server's canonical representation is returned. The read is retried
+// while the server returns the {{tolower .ModelTypeName}} as it was before the update.
+func (a *API) Update{{.ModelTypeName}}AndGet({{tolower .ModelTypeName}} {{.ModelTypeName}}) (*{{.ModelTypeName}}, error) {
+	updated, err := a.Update{{.ModelTypeName}}({{tolower .ModelTypeName}})
+	if err != nil {
+		return nil, err
+	}
+	id := updated.{{.IDFieldName}}
+	if id == "" {
+		id = {{tolower .ModelTypeName}}.{{.IDFieldName}}
+	}
+	var got *{{.ModelTypeName}}
+	err = a.readAfterWrite(func() (bool, error) {
+		var err error
+		if got, err = a.Get{{.ModelTypeName}}(id); err != nil {
+			return false, err
+		}
+		return staleRead(got, updated), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return got, nil
+}
+
 // Delete{{.ModelTypeName}} deletes the {{tolower .ModelTypeName}} with the given {{tolower .ModelTypeName}} ID.
 func (a *API) Delete{{.ModelTypeName}}({{tolower .ModelTypeName}}ID string) (*{{.ModelTypeName}}, error) {
 	if {{tolower .ModelTypeName}}ID == "" {
@@ -267,12 +322,22 @@ func (a *API) List{{.ModelPluralTypeName}}(filter string, sortBy string{{if .Sup
 	return {{tolower .ModelPluralTypeName}}.Resources, page, err
 }
 
-// ListAll{{.ModelPluralTypeName}} retrieves UAA {{tolower .ModelPluralTypeName}}
+// ListAll{{.ModelPluralTypeName}} retrieves UAA {{tolower .ModelPluralTypeName}}. It is ListAll{{.ModelPluralTypeName}}From from the start of
+// the listing.
 func (a *API) ListAll{{.ModelPluralTypeName}}(filter string, sortBy string{{if .SupportsAttributes}}, attributes string{{end}}, sortOrder SortOrder) ([]{{.ModelTypeName}}, error) {
+	return a.ListAll{{.ModelPluralTypeName}}From(ListCursor{}, filter, sortBy{{if .SupportsAttributes}}, attributes{{end}}, sortOrder)
+}
+
+// ListAll{{.ModelPluralTypeName}}From retrieves UAA {{tolower .ModelPluralTypeName}} starting at the cursor, which is the
+// zero ListCursor for the first {{tolower .ModelTypeName}}. If the API's list deadline (see
+// WithListDeadline) passes before the last page is read, the {{tolower .ModelPluralTypeName}} read so far
+// are returned with an ErrPartialResults whose Cursor resumes the listing.
+func (a *API) ListAll{{.ModelPluralTypeName}}From(cursor ListCursor, filter string, sortBy string{{if .SupportsAttributes}}, attributes string{{end}}, sortOrder SortOrder) ([]{{.ModelTypeName}}, error) {
 	page := Page{
-		StartIndex:   1,
+		StartIndex:   cursor.startIndex(),
 		ItemsPerPage: 100,
 	}
+	deadlinePassed := a.listDeadline()
 	var (
 		results     []{{.ModelTypeName}}
 		currentPage []{{.ModelTypeName}}
@@ -289,9 +354,62 @@ func (a *API) ListAll{{.ModelPluralTypeName}}(filter string, sortBy string{{if .
 		if (page.StartIndex + page.ItemsPerPage) > page.TotalResults {
 			break
 		}
+		if a.pageConcurrency > 1 {
+			starts := remainingPages(page)
+			pages := make([][]{{.ModelTypeName}}, len(starts))
+			var read int
+			read, err = a.fetchPages(starts, deadlinePassed, func(i int, startIndex int) error {
+				var err error
+				pages[i], _, err = a.List{{.ModelPluralTypeName}}(filter, sortBy{{if .SupportsAttributes}}, attributes{{end}}, sortOrder, startIndex, page.ItemsPerPage)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range pages[:read] {
+				results = append(results, p...)
+			}
+			if read != len(starts) {
+				return results, ErrPartialResults{Cursor: ListCursor{StartIndex: starts[read]}}
+			}
+			break
+		}
 		page.StartIndex = page.StartIndex + page.ItemsPerPage
+		if deadlinePassed() {
+			return results, ErrPartialResults{Cursor: ListCursor{StartIndex: page.StartIndex}}
+		}
 	}
 	return results, nil
+}
+
+// Each{{.ModelTypeName}} calls fn with each {{tolower .ModelTypeName}} matching the filter. The {{tolower .ModelPluralTypeName}} are
+// decoded one at a time as each page is read, so that large numbers of
+// {{tolower .ModelPluralTypeName}} are not held in memory. If fn returns an error, Each{{.ModelTypeName}} stops
+// and returns it.
+func (a *API) Each{{.ModelTypeName}}(filter string, sortBy string{{if .SupportsAttributes}}, attributes string{{end}}, sortOrder SortOrder, fn func({{.ModelTypeName}}) error) error {
+	query := url.Values{}
+	if filter != "" {
+		query.Set("filter", filter)
+	}
+	{{if .SupportsAttributes}}if attributes != "" {
+		query.Set("attributes", attributes)
+	}
+	{{end}}if sortBy != "" {
+		query.Set("sortBy", sortBy)
+	}
+	if sortOrder != "" {
+		query.Set("sortOrder", string(sortOrder))
+	}
+	return a.eachResource({{.ModelPluralTypeName}}Endpoint, query, func(d *json.Decoder) error {
+		var {{tolower .ModelTypeName}} {{.ModelTypeName}}
+		if err := d.Decode(&{{tolower .ModelTypeName}}); err != nil {
+			return err
+		}
+		if err := fn({{tolower .ModelTypeName}}); err != nil {
+			return callbackError{err}
+		}
+		return nil
+	})
 }{{else}}// List{{.ModelPluralTypeName}} fetches all of the {{.ModelTypeName}} records.
 // If successful, List{{.ModelPluralTypeName}} returns the {{tolower .ModelPluralTypeName}}
 // If unsuccessful, List{{.ModelPluralTypeName}} returns the error.
@@ -0,0 +1,63 @@
+package uaa
+
+import "errors"
+
+// ExpandGroup returns the users who are members of the group with the given
+// ID, either directly or through nested groups, each listed once. Cycles of
+// groups that are members of each other are followed only once.
+func (a *API) ExpandGroup(groupID string) ([]GroupMember, error) {
+	if groupID == "" {
+		return nil, errors.New("groupID cannot be blank")
+	}
+	var (
+		users   []GroupMember
+		seen    = map[string]bool{}
+		visited = map[string]bool{}
+		pending = []string{groupID}
+	)
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+
+		group, err := a.GetGroup(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range group.Members {
+			if member.Type == "GROUP" {
+				pending = append(pending, member.Value)
+				continue
+			}
+			if !seen[member.Value] {
+				seen[member.Value] = true
+				users = append(users, member)
+			}
+		}
+	}
+	return users, nil
+}
+
+// GroupsForUser returns the groups that the user with the given ID belongs
+// to. If transitive is false only the groups the user is a direct member of
+// are returned; otherwise the groups the user belongs to through nested
+// groups are included too.
+func (a *API) GroupsForUser(userID string, transitive bool) ([]UserGroup, error) {
+	if userID == "" {
+		return nil, errors.New("userID cannot be blank")
+	}
+	user, err := a.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	var groups []UserGroup
+	for _, group := range user.Groups {
+		if transitive || group.Type != "INDIRECT" {
+			groups = append(groups, group)
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,83 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestGroupTree(t *testing.T) {
+	spec.Run(t, "GroupTree", testGroupTree, spec.Report(report.Terminal{}))
+}
+
+func testGroupTree(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s      *httptest.Server
+		a      *uaa.API
+		groups map[string]uaa.Group
+		gets   int
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		gets = 0
+		groups = map[string]uaa.Group{
+			"philosophers": {ID: "philosophers", Members: []uaa.GroupMember{
+				{Type: "USER", Value: "socrates-id"},
+				{Type: "GROUP", Value: "stoics"},
+			}},
+			"stoics": {ID: "stoics", Members: []uaa.GroupMember{
+				{Type: "USER", Value: "marcus-id"},
+				{Type: "USER", Value: "socrates-id"},
+				{Type: "GROUP", Value: "philosophers"},
+			}},
+		}
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			gets++
+			if strings.HasPrefix(req.URL.Path, uaa.UsersEndpoint) {
+				json.NewEncoder(w).Encode(uaa.User{ID: "marcus-id", Groups: []uaa.UserGroup{
+					{Value: "stoics", Display: "stoics", Type: "DIRECT"},
+					{Value: "philosophers", Display: "philosophers", Type: "INDIRECT"},
+				}})
+				return
+			}
+			json.NewEncoder(w).Encode(groups[strings.TrimPrefix(req.URL.Path, uaa.GroupsEndpoint+"/")])
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("expands nested groups once each", func() {
+		members, err := a.ExpandGroup("philosophers")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(members).To(Equal([]uaa.GroupMember{
+			{Type: "USER", Value: "socrates-id"},
+			{Type: "USER", Value: "marcus-id"},
+		}))
+		Expect(gets).To(Equal(2))
+	})
+
+	it("returns direct or transitive groups for a user", func() {
+		direct, err := a.GroupsForUser("marcus-id", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(direct).To(HaveLen(1))
+		Expect(direct[0].Display).To(Equal("stoics"))
+
+		all, err := a.GroupsForUser("marcus-id", true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(all).To(HaveLen(2))
+	})
+}
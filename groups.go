@@ -60,6 +60,13 @@ func (a *API) AddGroupMember(groupID string, memberID string, entityType string,
 	return nil
 }
 
+// RemoveGroupMember removes the entity with the given memberID from the group
+// with the given ID.
+func (a *API) RemoveGroupMember(groupID string, memberID string) error {
+	u := urlWithPath(*a.TargetURL, fmt.Sprintf("%s/%s/members/%s", GroupsEndpoint, groupID, memberID))
+	return a.doJSON(http.MethodDelete, &u, nil, nil, true)
+}
+
 // GetGroupByName gets the group with the given name
 // http://docs.cloudfoundry.org/api/uaa/version/4.14.0/index.html#list-4.
 func (a *API) GetGroupByName(name string, attributes string) (*Group, error) {
@@ -195,4 +195,17 @@ func testGroupsExtra(t *testing.T, when spec.G, it spec.S) {
 			Expect(called).To(Equal(1))
 		})
 	})
+
+	when("RemoveGroupMember()", func() {
+		it("removes a membership", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.Method).To(Equal(http.MethodDelete))
+				Expect(req.URL.Path).To(Equal(fmt.Sprintf("%s/%s/members/%s", uaa.GroupsEndpoint, "group-id-1", "user-id-1")))
+				w.WriteHeader(http.StatusOK)
+			})
+			err := a.RemoveGroupMember("group-id-1", "user-id-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(called).To(Equal(1))
+		})
+	})
 }
@@ -0,0 +1,78 @@
+package uaa
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WithRequestHook returns an Option that calls hook with each request just
+// before it is sent to the UAA. The hook may modify the request, for example
+// to add headers. Hooks are called in the order they were added.
+func WithRequestHook(hook func(*http.Request)) Option {
+	return func(a *API) {
+		a.requestHooks = append(a.requestHooks, hook)
+	}
+}
+
+// WithResponseHook returns an Option that calls hook after each request to
+// the UAA completes, with the response or the error and the time the request
+// took. The hook must not read or close the response body. Hooks are called in
+// the order they were added. See WithConnectionStats for diagnosing slow
+// requests from a hook.
+func WithResponseHook(hook func(*http.Response, error, time.Duration)) Option {
+	return func(a *API) {
+		a.responseHooks = append(a.responseHooks, hook)
+	}
+}
+
+// send sends the request with the given client, calling the request and
+// response hooks around it, recording the outcome with the circuit breaker,
+// and retrying it through the pacer if it is throttled. If the UAA rejects
+// the API's token as invalid, the request is sent once more with a new token.
+// The request's path is rewritten as configured by WithBasePath and
+// WithEndpointPath, and the request is sent once more under a detected
+// context path (see WithoutContextPathDetection).
+func (a *API) send(client *http.Client, req *http.Request) (*http.Response, error) {
+	path := req.URL.Path
+	req = a.rewritePath(req)
+	send := func() (*http.Response, error) {
+		return a.sendPaced(req, func() (*http.Response, error) {
+			return a.sendOnce(client, req)
+		})
+	}
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+	resp, err = a.retryWithNewToken(client, req, resp, send)
+	if err != nil {
+		return nil, err
+	}
+	return a.retryUnderContextPath(client, path, req, resp)
+}
+
+func (a *API) sendOnce(client *http.Client, req *http.Request) (*http.Response, error) {
+	if a.circuitBreaker != nil {
+		if err := a.circuitBreaker.allow(a.getClock()); err != nil {
+			return nil, errors.Wrapf(err, "not calling %s", req.URL)
+		}
+	}
+	for _, hook := range a.requestHooks {
+		hook(req)
+	}
+	if a.connectionStats {
+		req = traceConnection(req)
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	elapsed := time.Since(start)
+	if a.circuitBreaker != nil {
+		a.circuitBreaker.record(a.getClock(), resp, err)
+	}
+	for _, hook := range a.responseHooks {
+		hook(resp, err, elapsed)
+	}
+	return resp, err
+}
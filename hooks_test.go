@@ -0,0 +1,80 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestHooks(t *testing.T) {
+	spec.Run(t, "Hooks", testHooks, spec.Report(report.Terminal{}))
+}
+
+func testHooks(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s *httptest.Server
+		a *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("X-Audit") != "yes" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(MarcusUserResponse))
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("calls the hooks around each request", func() {
+		var (
+			events   []string
+			status   int
+			duration time.Duration
+		)
+		uaa.WithRequestHook(func(req *http.Request) {
+			events = append(events, "request "+req.URL.Path)
+			req.Header.Set("X-Audit", "yes")
+		})(a)
+		uaa.WithResponseHook(func(resp *http.Response, err error, d time.Duration) {
+			Expect(err).NotTo(HaveOccurred())
+			events = append(events, "response")
+			status = resp.StatusCode
+			duration = d
+		})(a)
+
+		user, err := a.GetUser("00000000-0000-0000-0000-000000000001")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("marcus@stoicism.com"))
+		Expect(events).To(Equal([]string{"request /Users/00000000-0000-0000-0000-000000000001", "response"}))
+		Expect(status).To(Equal(http.StatusOK))
+		Expect(duration).To(BeNumerically(">", 0))
+	})
+
+	it("passes transport errors to the response hook", func() {
+		var hookErr error
+		uaa.WithResponseHook(func(resp *http.Response, err error, d time.Duration) {
+			Expect(resp).To(BeNil())
+			hookErr = err
+		})(a)
+		s.Close()
+		_, err := a.GetUser("id")
+		Expect(err).To(HaveOccurred())
+		Expect(hookErr).To(HaveOccurred())
+	})
+}
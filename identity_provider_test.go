@@ -0,0 +1,133 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+var identityproviderResponse string = `{
+  "type" : "saml",
+  "config" : "{\"emailDomain\":null,\"additionalConfiguration\":null,\"providerDescription\":null,\"attributeMappings\":{},\"addShadowUserOnLogin\":true,\"storeCustomAttributes\":true}",
+  "id" : "00000000-0000-0000-0000-000000000001",
+  "originKey" : "SAML",
+  "name" : "SAML name",
+  "version" : 0,
+  "created" : 1527032707746,
+  "active" : true,
+  "identityZoneId" : "uaa",
+  "last_modified" : 1527032707746
+}`
+
+var identityproviderListResponse string = `[{
+  "type" : "saml",
+  "config" : "{\"addShadowUserOnLogin\":true}",
+  "id" : "00000000-0000-0000-0000-000000000001",
+  "originKey" : "SAML",
+  "name" : "SAML name",
+  "active" : true,
+  "identityZoneId" : "uaa"
+}, {
+  "type" : "ldap",
+  "config" : {"baseUrl":"ldap://localhost:389/"},
+  "id" : "00000000-0000-0000-0000-000000000002",
+  "originKey" : "ldap",
+  "name" : "UAA LDAP Provider",
+  "active" : true,
+  "identityZoneId" : "uaa"
+}]`
+
+var testIdentityProviderValue uaa.IdentityProvider = uaa.IdentityProvider{
+	ID:        "00000000-0000-0000-0000-000000000001",
+	Type:      uaa.SAMLOrigin,
+	OriginKey: "SAML",
+	Name:      "SAML name",
+	Active:    true,
+}
+
+var testIdentityProviderJSON string = `{
+  "id" : "00000000-0000-0000-0000-000000000001",
+  "type" : "saml",
+  "originKey" : "SAML",
+  "name" : "SAML name",
+  "active" : true
+}`
+
+func TestIdentityProviderJSON(t *testing.T) {
+	spec.Run(t, "IdentityProviderJSON", testIdentityProviderJSONEncoding, spec.Report(report.Terminal{}))
+}
+
+func testIdentityProviderJSONEncoding(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("decodes the config from a string", func() {
+		var provider uaa.IdentityProvider
+		Expect(json.Unmarshal([]byte(identityproviderResponse), &provider)).To(Succeed())
+		Expect(provider.Type).To(Equal(uaa.SAMLOrigin))
+		Expect(provider.Config["addShadowUserOnLogin"]).To(Equal(true))
+	})
+
+	it("decodes the config from an object", func() {
+		var providers []uaa.IdentityProvider
+		Expect(json.Unmarshal([]byte(identityproviderListResponse), &providers)).To(Succeed())
+		Expect(providers[1].Config["baseUrl"]).To(Equal("ldap://localhost:389/"))
+	})
+
+	it("encodes the config as a string", func() {
+		provider := testIdentityProviderValue
+		provider.Config = uaa.IdentityProviderConfig{"addShadowUserOnLogin": true}
+		j, err := json.Marshal(provider)
+		Expect(err).NotTo(HaveOccurred())
+		var raw map[string]interface{}
+		Expect(json.Unmarshal(j, &raw)).To(Succeed())
+		Expect(raw["config"]).To(Equal(`{"addShadowUserOnLogin":true}`))
+	})
+
+	it("encodes a type it does not know", func() {
+		provider := testIdentityProviderValue
+		provider.Type = uaa.Origin("saml2.0")
+		j, err := json.Marshal(provider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(j)).To(ContainSubstring(`"type":"saml2.0"`))
+	})
+
+	it("maps custom user attributes", func() {
+		var config uaa.IdentityProviderConfig
+		config.SetAttributeMapping("given_name", "firstName")
+		config.SetUserAttributeMapping("cost_center", "costCenter")
+		config.SetStoreCustomAttributes(true)
+		Expect(config.AttributeMappings()).To(Equal(map[string]string{
+			"given_name":                 "firstName",
+			"user.attribute.cost_center": "costCenter",
+		}))
+		Expect(config.UserAttributeMappings()).To(Equal(map[string]string{"cost_center": "costCenter"}))
+		Expect(config.StoreCustomAttributes()).To(BeTrue())
+
+		j, err := json.Marshal(config)
+		Expect(err).NotTo(HaveOccurred())
+		var decoded uaa.IdentityProviderConfig
+		Expect(json.Unmarshal(j, &decoded)).To(Succeed())
+		Expect(decoded.UserAttributeMappings()).To(Equal(map[string]string{"cost_center": "costCenter"}))
+		Expect(decoded.StoreCustomAttributes()).To(BeTrue())
+	})
+
+	it("configures the external groups whitelist", func() {
+		var config uaa.IdentityProviderConfig
+		config.SetExternalGroupsWhitelist("finance", "admins-*")
+		config.SetExternalGroupsMapping("memberOf")
+		Expect(config.ExternalGroupsWhitelist()).To(Equal([]string{"finance", "admins-*"}))
+		Expect(config.AttributeMappings()).To(HaveKeyWithValue("external_groups", "memberOf"))
+
+		j, err := json.Marshal(config)
+		Expect(err).NotTo(HaveOccurred())
+		var decoded uaa.IdentityProviderConfig
+		Expect(json.Unmarshal(j, &decoded)).To(Succeed())
+		Expect(decoded.ExternalGroupsWhitelist()).To(Equal([]string{"finance", "admins-*"}))
+	})
+}
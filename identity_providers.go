@@ -0,0 +1,228 @@
+package uaa
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IdentityProvidersEndpoint is the path to the identity providers resource.
+const IdentityProvidersEndpoint string = "/identity-providers"
+
+// Origin is a type of identity provider. Users authenticated by an identity
+// provider have the provider's origin key as their origin; the origin keys of
+// the built-in providers are the same as their types.
+type Origin string
+
+// Valid Origin values.
+const (
+	UAAOrigin      = Origin("uaa")
+	LDAPOrigin     = Origin("ldap")
+	SAMLOrigin     = Origin("saml")
+	OIDCOrigin     = Origin("oidc1.0")
+	OAuth2Origin   = Origin("oauth2.0")
+	KeystoneOrigin = Origin("keystone")
+)
+
+var knownOrigins = []Origin{UAAOrigin, LDAPOrigin, SAMLOrigin, OIDCOrigin, OAuth2Origin, KeystoneOrigin}
+
+// Validate returns an error if the origin is not a known identity provider
+// type.
+func (o Origin) Validate() error {
+	for _, known := range knownOrigins {
+		if o == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown identity provider type %q, must be one of %v", string(o), knownOrigins)
+}
+
+// IdentityProvider is a UAA identity provider
+// http://docs.cloudfoundry.org/api/uaa/version/4.14.0/index.html#identity-providers.
+type IdentityProvider struct {
+	ID             string                 `json:"id,omitempty"`
+	Type           Origin                 `json:"type"`
+	OriginKey      string                 `json:"originKey"`
+	Name           string                 `json:"name"`
+	Config         IdentityProviderConfig `json:"config,omitempty"`
+	Active         bool                   `json:"active"`
+	IdentityZoneID string                 `json:"identityZoneId,omitempty"`
+	Version        int                    `json:"version,omitempty"`
+	Created        int64                  `json:"created,omitempty"`
+	LastModified   int64                  `json:"last_modified,omitempty"`
+
+	// Extra holds the fields returned by the UAA that the struct does not
+	// model, so that they are sent back unchanged when it is updated.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// MarshalJSON sends the fields in Extra along with the modeled ones. The type
+// is not checked, so that an identity provider with a type added in a newer
+// UAA can still be encoded; CreateIdentityProvider and UpdateIdentityProvider
+// check it.
+func (p IdentityProvider) MarshalJSON() ([]byte, error) {
+	type identityProvider IdentityProvider
+	return marshalWithExtra(identityProvider(p), p.Extra)
+}
+
+// checkWrite returns an error if the identity provider's type is not known.
+func (p IdentityProvider) checkWrite() error {
+	return p.Type.Validate()
+}
+
+// UnmarshalJSON keeps the fields that IdentityProvider does not model in
+// Extra.
+func (p *IdentityProvider) UnmarshalJSON(data []byte) error {
+	type identityProvider IdentityProvider
+	var decoded identityProvider
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*p = IdentityProvider(decoded)
+	p.Extra = extra
+	return nil
+}
+
+// IdentityProviderConfig is the type-specific configuration of an identity
+// provider. The UAA sends and expects it as a string containing a JSON
+// object; IdentityProviderConfig handles the conversion.
+type IdentityProviderConfig map[string]interface{}
+
+// MarshalJSON encodes the config as a string containing a JSON object.
+func (c IdentityProviderConfig) MarshalJSON() ([]byte, error) {
+	if c == nil {
+		return []byte("null"), nil
+	}
+	j, err := json.Marshal(map[string]interface{}(c))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(j))
+}
+
+// UnmarshalJSON decodes the config from either a string containing a JSON
+// object or a JSON object.
+func (c *IdentityProviderConfig) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		s, err := strconv.Unquote(string(data))
+		if err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*c = m
+	return nil
+}
+
+// userAttributePrefix prefixes the names of custom user attributes in an
+// identity provider's attribute mappings.
+const userAttributePrefix = "user.attribute."
+
+// AttributeMappings returns the provider's attribute mappings, from UAA user
+// attributes (e.g. given_name, or user.attribute.cost_center for a custom
+// attribute) to the names of the SAML attributes or OIDC claims they are
+// read from.
+func (c IdentityProviderConfig) AttributeMappings() map[string]string {
+	mappings := map[string]string{}
+	m, _ := c["attributeMappings"].(map[string]interface{})
+	for attribute, value := range m {
+		if name, ok := value.(string); ok {
+			mappings[attribute] = name
+		}
+	}
+	return mappings
+}
+
+// SetAttributeMapping maps the UAA user attribute to the SAML attribute or
+// OIDC claim with the given name.
+func (c *IdentityProviderConfig) SetAttributeMapping(attribute string, providerAttribute string) {
+	if *c == nil {
+		*c = IdentityProviderConfig{}
+	}
+	m, ok := (*c)["attributeMappings"].(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{}
+		(*c)["attributeMappings"] = m
+	}
+	m[attribute] = providerAttribute
+}
+
+// UserAttributeMappings returns the provider's custom user attribute
+// mappings, from the names of the custom attributes to the names of the SAML
+// attributes or OIDC claims they are read from.
+func (c IdentityProviderConfig) UserAttributeMappings() map[string]string {
+	mappings := map[string]string{}
+	for attribute, name := range c.AttributeMappings() {
+		if strings.HasPrefix(attribute, userAttributePrefix) {
+			mappings[strings.TrimPrefix(attribute, userAttributePrefix)] = name
+		}
+	}
+	return mappings
+}
+
+// SetUserAttributeMapping maps the custom user attribute with the given name
+// to the SAML attribute or OIDC claim with the given name. Users
+// authenticated by the provider then have the attribute in the
+// user_attributes of their user info and ID tokens, if the provider stores
+// custom attributes (see SetStoreCustomAttributes).
+func (c *IdentityProviderConfig) SetUserAttributeMapping(name string, providerAttribute string) {
+	c.SetAttributeMapping(userAttributePrefix+name, providerAttribute)
+}
+
+// StoreCustomAttributes returns true if the UAA stores the custom user
+// attributes read from the provider when users log in.
+func (c IdentityProviderConfig) StoreCustomAttributes() bool {
+	store, _ := c["storeCustomAttributes"].(bool)
+	return store
+}
+
+// SetStoreCustomAttributes sets whether the UAA stores the custom user
+// attributes read from the provider when users log in, which it must for them
+// to be returned from /userinfo.
+func (c *IdentityProviderConfig) SetStoreCustomAttributes(store bool) {
+	if *c == nil {
+		*c = IdentityProviderConfig{}
+	}
+	(*c)["storeCustomAttributes"] = store
+}
+
+// ExternalGroupsWhitelist returns the groups from the provider that are
+// passed through to tokens and user info as roles. A pattern such as "*"
+// matches many groups.
+func (c IdentityProviderConfig) ExternalGroupsWhitelist() []string {
+	var groups []string
+	list, _ := c["externalGroupsWhitelist"].([]interface{})
+	for _, group := range list {
+		if g, ok := group.(string); ok {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// SetExternalGroupsWhitelist sets the groups from the provider that are passed
+// through to tokens and user info as roles.
+func (c *IdentityProviderConfig) SetExternalGroupsWhitelist(groups ...string) {
+	if *c == nil {
+		*c = IdentityProviderConfig{}
+	}
+	list := make([]interface{}, len(groups))
+	for i, group := range groups {
+		list[i] = group
+	}
+	(*c)["externalGroupsWhitelist"] = list
+}
+
+// SetExternalGroupsMapping reads the user's external groups from the SAML
+// attribute or OIDC claim with the given name. The external groups that
+// match the whitelist become the roles in tokens requested with the roles
+// scope.
+func (c *IdentityProviderConfig) SetExternalGroupsMapping(providerAttribute string) {
+	c.SetAttributeMapping("external_groups", providerAttribute)
+}
@@ -1,5 +1,7 @@
 package uaa
 
+import "encoding/json"
+
 // IdentityZonesEndpoint is the path to the users resource.
 const IdentityZonesEndpoint string = "/identity-zones"
 
@@ -14,6 +16,29 @@ type IdentityZone struct {
 	Description  string             `json:"description,omitempty"`
 	Created      int                `json:"created,omitempty"`
 	LastModified int                `json:"last_modified,omitempty"`
+
+	// Extra holds the fields returned by the UAA that the struct does not
+	// model, so that they are sent back unchanged when it is updated.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// MarshalJSON includes the fields in Extra.
+func (z IdentityZone) MarshalJSON() ([]byte, error) {
+	type identityZone IdentityZone
+	return marshalWithExtra(identityZone(z), z.Extra)
+}
+
+// UnmarshalJSON keeps the fields that IdentityZone does not model in Extra.
+func (z *IdentityZone) UnmarshalJSON(data []byte) error {
+	type identityZone IdentityZone
+	var decoded identityZone
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*z = IdentityZone(decoded)
+	z.Extra = extra
+	return nil
 }
 
 // ClientSecretPolicy is an identity zone client secret policy.
@@ -34,16 +59,31 @@ type TokenPolicy struct {
 	RefreshTokenUnique   bool   `json:"refreshTokenUnique,omitempty"`
 	RefreshTokenFormat   string `json:"refreshTokenFormat,omitempty"`
 	ActiveKeyID          string `json:"activeKeyId,omitempty"`
+
+	Keys map[string]TokenPolicyKey `json:"keys,omitempty"`
+}
+
+// TokenPolicyKey is a key that an identity zone signs tokens with. The UAA
+// never returns signing keys, so they are blank in zones read from it.
+type TokenPolicyKey struct {
+	SigningKey string `json:"signingKey,omitempty"`
 }
 
-// SAMLKey is an identity zone SAML key.
+// SAMLKey is a key pair that an identity zone signs SAML messages with. Key
+// and Certificate are PEM encoded, and Passphrase decrypts Key if it is
+// encrypted. The UAA never returns keys or passphrases, so they are blank in
+// zones read from it.
 type SAMLKey struct {
 	Key         string `json:"key,omitempty"`
 	Passphrase  string `json:"passphrase,omitempty"`
 	Certificate string `json:"certificate,omitempty"`
 }
 
-// SAMLConfig is an identity zone SAMLConfig.
+// SAMLConfig is the configuration of an identity zone as a SAML service
+// provider: which messages it signs and expects to be signed, and the key
+// pairs it signs them with, keyed by ID. ActiveKeyID selects the key pair used
+// for signing; the others stay in the zone's metadata so that identity
+// providers can move to a new certificate before it becomes active.
 type SAMLConfig struct {
 	AssertionSigned            bool               `json:"assertionSigned,omitempty"`
 	RequestSigned              bool               `json:"requestSigned,omitempty"`
@@ -81,18 +121,26 @@ type CORSPolicy struct {
 
 // IdentityZoneLinks is an identity zone link.
 type IdentityZoneLinks struct {
-	Logout struct {
-		RedirectURL              string   `json:"redirectUrl,omitempty"`
-		RedirectParameterName    string   `json:"redirectParameterName,omitempty"`
-		DisableRedirectParameter bool     `json:"disableRedirectParameter,omitempty"`
-		Whitelist                []string `json:"whitelist,omitempty"`
-	} `json:"logout,omitempty"`
-	HomeRedirect string `json:"homeRedirect,omitempty"`
-	SelfService  struct {
-		SelfServiceLinksEnabled bool   `json:"selfServiceLinksEnabled,omitempty"`
-		Signup                  string `json:"signup,omitempty"`
-		Passwd                  string `json:"passwd,omitempty"`
-	} `json:"selfService,omitempty"`
+	Logout       IdentityZoneLogoutLinks      `json:"logout,omitempty"`
+	HomeRedirect string                       `json:"homeRedirect,omitempty"`
+	SelfService  IdentityZoneSelfServiceLinks `json:"selfService,omitempty"`
+}
+
+// IdentityZoneLogoutLinks configures where users are sent after logging out
+// of an identity zone.
+type IdentityZoneLogoutLinks struct {
+	RedirectURL              string   `json:"redirectUrl,omitempty"`
+	RedirectParameterName    string   `json:"redirectParameterName,omitempty"`
+	DisableRedirectParameter bool     `json:"disableRedirectParameter,omitempty"`
+	Whitelist                []string `json:"whitelist,omitempty"`
+}
+
+// IdentityZoneSelfServiceLinks configures the sign up and password reset
+// links shown on an identity zone's login page.
+type IdentityZoneSelfServiceLinks struct {
+	SelfServiceLinksEnabled bool   `json:"selfServiceLinksEnabled,omitempty"`
+	Signup                  string `json:"signup,omitempty"`
+	Passwd                  string `json:"passwd,omitempty"`
 }
 
 // Prompt is a UAA prompt.
@@ -102,11 +150,33 @@ type Prompt struct {
 	Text string `json:"text,omitempty"`
 }
 
-// Branding is the branding for a UAA identity zone.
+// Branding is the branding for a UAA identity zone. The logos are base64
+// encoded PNG images.
 type Branding struct {
-	CompanyName string `json:"companyName,omitempty"`
-	ProductLogo string `json:"productLogo,omitempty"`
-	SquareLogo  string `json:"squareLogo,omitempty"`
+	CompanyName     string            `json:"companyName,omitempty"`
+	ProductLogo     string            `json:"productLogo,omitempty"`
+	SquareLogo      string            `json:"squareLogo,omitempty"`
+	FooterLegalText string            `json:"footerLegalText,omitempty"`
+	FooterLinks     map[string]string `json:"footerLinks,omitempty"`
+	Banner          *BrandingBanner   `json:"banner,omitempty"`
+	Consent         *BrandingConsent  `json:"consent,omitempty"`
+}
+
+// BrandingBanner is a banner shown at the top of an identity zone's pages.
+// The colors are hexadecimal, such as #0071bc.
+type BrandingBanner struct {
+	Logo            string `json:"logo,omitempty"`
+	Text            string `json:"text,omitempty"`
+	TextColor       string `json:"textColor,omitempty"`
+	BackgroundColor string `json:"backgroundColor,omitempty"`
+	Link            string `json:"link,omitempty"`
+}
+
+// BrandingConsent is a statement that users must accept, linking to the
+// terms they consent to, before creating an account in an identity zone.
+type BrandingConsent struct {
+	Text string `json:"text,omitempty"`
+	Link string `json:"link,omitempty"`
 }
 
 // IdentityZoneUserConfig is the user configuration for an identity zone.
@@ -121,6 +191,11 @@ type IdentityZoneMFAConfig struct {
 }
 
 // IdentityZoneConfig is the configuration for an identity zone.
+//
+// The UAA has no per-zone email settings: the SMTP server and the from
+// address used for invitation, verification and password reset emails are
+// configured for the whole server in uaa.yml. Emails sent for a zone are
+// branded with its Branding.CompanyName.
 type IdentityZoneConfig struct {
 	ClientSecretPolicy    *ClientSecretPolicy     `json:"clientSecretPolicy,omitempty"`
 	TokenPolicy           *TokenPolicy            `json:"tokenPolicy,omitempty"`
@@ -133,4 +208,28 @@ type IdentityZoneConfig struct {
 	AccountChooserEnabled *bool                   `json:"accountChooserEnabled,omitempty"`
 	UserConfig            *IdentityZoneUserConfig `json:"userConfig,omitempty"`
 	MFAConfig             *IdentityZoneMFAConfig  `json:"mfaConfig,omitempty"`
+
+	// Extra holds the fields returned by the UAA that the struct does not
+	// model, so that they are sent back unchanged when it is updated.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// MarshalJSON includes the fields in Extra.
+func (c IdentityZoneConfig) MarshalJSON() ([]byte, error) {
+	type identityZoneConfig IdentityZoneConfig
+	return marshalWithExtra(identityZoneConfig(c), c.Extra)
+}
+
+// UnmarshalJSON keeps the fields that IdentityZoneConfig does not model in
+// Extra.
+func (c *IdentityZoneConfig) UnmarshalJSON(data []byte) error {
+	type identityZoneConfig IdentityZoneConfig
+	var decoded identityZoneConfig
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*c = IdentityZoneConfig(decoded)
+	c.Extra = extra
+	return nil
 }
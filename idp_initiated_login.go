@@ -0,0 +1,57 @@
+package uaa
+
+import (
+	"context"
+	"errors"
+	"net/url"
+)
+
+// DefaultRelayStateParam is the query parameter with which most SAML identity
+// providers accept the RelayState for an IdP-initiated login.
+const DefaultRelayStateParam = "RelayState"
+
+// IdPInitiatedLogin logs a user in from a command line program in zones
+// where the SAML identity provider only supports IdP-initiated logins, so
+// that automation can still obtain tokens. It opens the identity provider's
+// login URL in a browser with the UAA's authorize URL as the RelayState.
+// After the identity provider posts the assertion to the UAA, the UAA
+// establishes a session and sends the browser to the RelayState, where the
+// session is used to issue a code to a server listening on the loopback
+// interface, as with BrowserLogin.
+//
+// The client must allow the loopback redirect URI and should be set to
+// auto-approve its scopes, so that no approval page interrupts the flow.
+type IdPInitiatedLogin struct {
+	BrowserLogin
+
+	// IdPURL is the identity provider's URL that starts a login to the UAA,
+	// such as the application's embed link.
+	IdPURL string
+	// RelayStateParam is the query parameter of IdPURL that carries the
+	// RelayState. Defaults to DefaultRelayStateParam.
+	RelayStateParam string
+}
+
+// Login opens the identity provider's login URL and returns an API that uses
+// the token obtained once the UAA redirects back with a code. It returns
+// ctx's error if ctx is done first.
+func (l *IdPInitiatedLogin) Login(ctx context.Context) (*API, error) {
+	idpURL, err := url.Parse(l.IdPURL)
+	if err != nil {
+		return nil, err
+	}
+	if !idpURL.IsAbs() {
+		return nil, errors.New("the identity provider's URL must be absolute")
+	}
+	param := l.RelayStateParam
+	if param == "" {
+		param = DefaultRelayStateParam
+	}
+	return l.BrowserLogin.login(ctx, func(authorizeURL string) (string, error) {
+		u := *idpURL
+		query := u.Query()
+		query.Set(param, authorizeURL)
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	})
+}
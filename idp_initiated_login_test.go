@@ -0,0 +1,102 @@
+package uaa_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestIdPInitiatedLogin(t *testing.T) {
+	spec.Run(t, "IdPInitiatedLogin", testIdPInitiatedLogin, spec.Report(report.Terminal{}))
+}
+
+func testIdPInitiatedLogin(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s         *httptest.Server
+		idp       *httptest.Server
+		session   bool
+		exchanged url.Values
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		session = false
+		// The fake UAA only issues codes to browsers with a session, which
+		// is established by the assertion the identity provider posts.
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			req.ParseForm()
+			switch req.URL.Path {
+			case "/saml/SSO/alias/uaa.example.com":
+				session = true
+				http.Redirect(w, req, req.PostForm.Get("RelayState"), http.StatusFound)
+			case "/oauth/authorize":
+				if !session {
+					http.Redirect(w, req, "/login", http.StatusFound)
+					return
+				}
+				query := req.URL.Query()
+				redirect, _ := url.Parse(query.Get("redirect_uri"))
+				redirect.RawQuery = url.Values{"code": {"the-code"}, "state": {query.Get("state")}}.Encode()
+				http.Redirect(w, req, redirect.String(), http.StatusFound)
+			case "/oauth/token":
+				exchanged = req.PostForm
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "token_type": "bearer", "expires_in": 3600})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		// The fake identity provider posts an assertion to the UAA with the
+		// RelayState it was given, as the browser would.
+		idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			form := url.Values{"SAMLResponse": {"assertion"}, "RelayState": {req.URL.Query().Get("RelayState")}}
+			resp, err := http.PostForm(s.URL+"/saml/SSO/alias/uaa.example.com", form)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+		idp.Close()
+	})
+
+	it("captures the code issued after the identity provider logs the user in", func() {
+		login := &uaa.IdPInitiatedLogin{
+			BrowserLogin: uaa.BrowserLogin{
+				Target:   s.URL,
+				ClientID: "automation",
+				Browser: uaa.BrowserFunc(func(u string) error {
+					go func() {
+						if resp, err := http.Get(u); err == nil {
+							resp.Body.Close()
+						}
+					}()
+					return nil
+				}),
+			},
+			IdPURL: idp.URL + "/app/uaa/sso/saml",
+		}
+		api, err := login.Login(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		token, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("token"))
+		Expect(exchanged.Get("code")).To(Equal("the-code"))
+	})
+
+	it("requires an absolute identity provider URL", func() {
+		login := &uaa.IdPInitiatedLogin{BrowserLogin: uaa.BrowserLogin{Target: s.URL}, IdPURL: "/sso"}
+		_, err := login.Login(context.Background())
+		Expect(err).To(HaveOccurred())
+	})
+}
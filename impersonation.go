@@ -0,0 +1,120 @@
+package uaa
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+// ErrImpersonationDisabled is the cause of the error returned by
+// TokenForUser when the API was not created with WithImpersonation. Use
+// errors.Cause from github.com/pkg/errors to compare against it.
+var ErrImpersonationDisabled = errors.New("impersonation is not enabled for the API")
+
+// WithImpersonation returns an Option that allows TokenForUser to obtain
+// tokens for other users. It is off by default so that a credential able to
+// impersonate users is not used that way by accident.
+func WithImpersonation() Option {
+	return func(a *API) {
+		a.impersonation = true
+	}
+}
+
+// TokenForUser obtains a token for the user with the given ID, issued to the
+// client with the given ID and limited to the given scopes (or the client's
+// scopes that the user has, if none are given), without the user's password.
+// It is intended for support tooling that must reproduce a user's
+// permissions.
+//
+// The API's token must have the oauth.login scope, as UAA only grants tokens
+// this way to trusted login servers, and the client must be allowed the
+// implicit grant; UAA records the login in its audit log. TokenForUser
+// returns an error caused by ErrImpersonationDisabled unless the API was
+// created with WithImpersonation.
+func (a *API) TokenForUser(userID string, clientID string, scopes []string) (*oauth2.Token, error) {
+	if !a.impersonation {
+		return nil, errors.Wrapf(ErrImpersonationDisabled, "refusing to get a token for user %s", userID)
+	}
+	if userID == "" || clientID == "" {
+		return nil, errors.New("userID and clientID cannot be blank")
+	}
+	if a.AuthenticatedClient == nil {
+		return nil, errors.New("the API does not have an authenticated client")
+	}
+
+	form := url.Values{}
+	form.Set("client_id", clientID)
+	form.Set("response_type", "token")
+	form.Set("source", "login")
+	form.Set("user_id", userID)
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+	u := urlWithPath(*a.TargetURL, "/oauth/authorize")
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Identity-Zone-Id", a.ZoneID)
+	if a.verbose() {
+		logRequest(req)
+	}
+
+	// UAA returns the token in the fragment of the redirect to the client,
+	// which must not be followed.
+	a.ensureTimeout()
+	a.ensureTransport(a.AuthenticatedClient)
+	client := *a.AuthenticatedClient
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := a.send(&client, req)
+	if err != nil {
+		return nil, requestError(u.String())
+	}
+	defer resp.Body.Close()
+	if a.verbose() {
+		logResponse(resp)
+	}
+
+	location, err := resp.Location()
+	if err != nil || resp.StatusCode < 300 || resp.StatusCode > 399 {
+		return nil, fmt.Errorf("UAA did not issue a token for user %s: the response status was %v", userID, resp.Status)
+	}
+	return tokenFromFragment(location.Fragment)
+}
+
+// tokenFromFragment parses a token from the fragment of a redirect made by
+// the implicit grant.
+func tokenFromFragment(fragment string) (*oauth2.Token, error) {
+	values, err := url.ParseQuery(fragment)
+	if err != nil {
+		return nil, err
+	}
+	if e := values.Get("error"); e != "" {
+		return nil, fmt.Errorf("UAA did not issue a token: %s: %s", e, values.Get("error_description"))
+	}
+	if values.Get("access_token") == "" {
+		return nil, errors.New("UAA did not issue a token: the redirect has no access_token")
+	}
+	token := &oauth2.Token{
+		AccessToken: values.Get("access_token"),
+		TokenType:   values.Get("token_type"),
+	}
+	if expiresIn, err := strconv.Atoi(values.Get("expires_in")); err == nil && expiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(expiresIn) * time.Second)
+	}
+	extra := map[string]interface{}{}
+	for key := range values {
+		extra[key] = values.Get(key)
+	}
+	return token.WithExtra(extra), nil
+}
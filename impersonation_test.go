@@ -0,0 +1,77 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestImpersonation(t *testing.T) {
+	spec.Run(t, "Impersonation", testImpersonation, spec.Report(report.Terminal{}))
+}
+
+func testImpersonation(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		a        *uaa.API
+		called   int
+		location string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		called = 0
+		location = "https://app.example.com/callback#access_token=user-token&token_type=bearer&expires_in=3600&scope=openid+cloud_controller.read&jti=abc"
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			called++
+			Expect(req.Method).To(Equal(http.MethodPost))
+			Expect(req.URL.Path).To(Equal("/oauth/authorize"))
+			Expect(req.ParseForm()).To(Succeed())
+			Expect(req.PostForm.Get("client_id")).To(Equal("cf"))
+			Expect(req.PostForm.Get("user_id")).To(Equal("user-id"))
+			Expect(req.PostForm.Get("source")).To(Equal("login"))
+			Expect(req.PostForm.Get("response_type")).To(Equal("token"))
+			Expect(req.PostForm.Get("scope")).To(Equal("openid cloud_controller.read"))
+			http.Redirect(w, req, location, http.StatusFound)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("is disabled by default", func() {
+		token, err := a.TokenForUser("user-id", "cf", []string{"openid", "cloud_controller.read"})
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrImpersonationDisabled))
+		Expect(token).To(BeNil())
+		Expect(called).To(Equal(0))
+	})
+
+	it("returns the token from the redirect without following it", func() {
+		uaa.WithImpersonation()(a)
+		token, err := a.TokenForUser("user-id", "cf", []string{"openid", "cloud_controller.read"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(called).To(Equal(1))
+		Expect(token.AccessToken).To(Equal("user-token"))
+		Expect(token.Valid()).To(BeTrue())
+		Expect(uaa.GrantedScopes(token)).To(Equal([]string{"openid", "cloud_controller.read"}))
+	})
+
+	it("returns the error from the redirect", func() {
+		uaa.WithImpersonation()(a)
+		location = "https://app.example.com/callback#error=access_denied&error_description=User+not+approved"
+		_, err := a.TokenForUser("user-id", "cf", []string{"openid", "cloud_controller.read"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("access_denied"))
+	})
+}
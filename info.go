@@ -14,6 +14,7 @@ type Info struct {
 	CommitID       string              `json:"commit_id"`
 	Timestamp      string              `json:"timestamp"`
 	IdpDefinitions map[string]string   `json:"idpDefinitions"`
+	OAuthLinks     map[string]string   `json:"oauthLinks"`
 }
 
 type uaaApp struct {
@@ -0,0 +1,29 @@
+package uaa
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TokenIntrospection is the result of introspecting a token.
+type TokenIntrospection struct {
+	Claims
+	Active bool `json:"active"`
+}
+
+// IntrospectToken asks the UAA whether the given token is active and returns
+// its claims. The authenticated client must have the uaa.resource authority
+// http://docs.cloudfoundry.org/api/uaa/version/4.14.0/index.html#introspect-token.
+func (a *API) IntrospectToken(token string) (*TokenIntrospection, error) {
+	u := urlWithPath(*a.TargetURL, "/introspect")
+	body := url.Values{"token": {token}}.Encode()
+	headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+
+	introspection := &TokenIntrospection{}
+	err := a.doJSONWithHeaders(http.MethodPost, &u, headers, strings.NewReader(body), introspection, true)
+	if err != nil {
+		return nil, err
+	}
+	return introspection, nil
+}
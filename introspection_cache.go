@@ -0,0 +1,156 @@
+package uaa
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// DefaultIntrospectionCacheMaxEntries is the number of introspection results
+// an IntrospectionCache holds if no maximum is configured.
+const DefaultIntrospectionCacheMaxEntries = 10000
+
+// IntrospectionCache caches the results of token introspection so that
+// services that accept opaque tokens do not call the UAA for every request.
+// Entries expire after the TTL, so a revoked token is rejected at most TTL
+// after its revocation; keep the TTL short.
+type IntrospectionCache struct {
+	// TTL is how long an active token's introspection result is cached. An
+	// entry never outlives the token's expiry.
+	TTL time.Duration
+	// NegativeTTL is how long an inactive token's introspection result is
+	// cached. Inactive results are not cached if NegativeTTL is zero.
+	NegativeTTL time.Duration
+	// MaxEntries is the maximum number of cached results. Defaults to
+	// DefaultIntrospectionCacheMaxEntries.
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List
+}
+
+type introspectionEntry struct {
+	key           [sha256.Size]byte
+	introspection TokenIntrospection
+	expires       time.Time
+}
+
+// NewIntrospectionCache returns an IntrospectionCache that caches active
+// tokens for the given TTL.
+func NewIntrospectionCache(ttl time.Duration) *IntrospectionCache {
+	return &IntrospectionCache{TTL: ttl}
+}
+
+// Get returns the cached introspection result for the token, if any.
+// Expiry is judged by the system clock; a TokenValidator uses its own.
+func (c *IntrospectionCache) Get(token string) (*TokenIntrospection, bool) {
+	return c.get(token, time.Now())
+}
+
+func (c *IntrospectionCache) get(token string, now time.Time) (*TokenIntrospection, bool) {
+	key := sha256.Sum256([]byte(token))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		return nil, false
+	}
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*introspectionEntry)
+	if !now.Before(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	introspection := entry.introspection
+	return &introspection, true
+}
+
+// Add caches the introspection result for the token.
+func (c *IntrospectionCache) Add(token string, introspection *TokenIntrospection) {
+	c.add(token, introspection, time.Now())
+}
+
+func (c *IntrospectionCache) add(token string, introspection *TokenIntrospection, now time.Time) {
+	ttl := c.TTL
+	if !introspection.Active {
+		ttl = c.NegativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	expires := now.Add(ttl)
+	if introspection.Active && introspection.Expiry != 0 && introspection.ExpiresAt().Before(expires) {
+		expires = introspection.ExpiresAt()
+	}
+
+	key := sha256.Sum256([]byte(token))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[[sha256.Size]byte]*list.Element)
+		c.order = list.New()
+	}
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	c.entries[key] = c.order.PushFront(&introspectionEntry{
+		key:           key,
+		introspection: *introspection,
+		expires:       expires,
+	})
+	for c.order.Len() > c.maxEntries() {
+		c.remove(c.order.Back())
+	}
+}
+
+// Invalidate removes the cached introspection result for the token.
+func (c *IntrospectionCache) Invalidate(token string) {
+	key := sha256.Sum256([]byte(token))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+}
+
+// Len returns the number of cached introspection results.
+func (c *IntrospectionCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.order == nil {
+		return 0
+	}
+	return c.order.Len()
+}
+
+// lookup and store allow a nil cache to be used by the TokenValidator, and
+// judge expiry by the validator's clock.
+func (c *IntrospectionCache) lookup(token string, now time.Time) (*TokenIntrospection, bool) {
+	if c == nil {
+		return nil, false
+	}
+	return c.get(token, now)
+}
+
+func (c *IntrospectionCache) store(token string, introspection *TokenIntrospection, now time.Time) {
+	if c != nil {
+		c.add(token, introspection, now)
+	}
+}
+
+func (c *IntrospectionCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*introspectionEntry).key)
+}
+
+func (c *IntrospectionCache) maxEntries() int {
+	if c.MaxEntries <= 0 {
+		return DefaultIntrospectionCacheMaxEntries
+	}
+	return c.MaxEntries
+}
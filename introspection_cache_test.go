@@ -0,0 +1,139 @@
+package uaa_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestIntrospectionCache(t *testing.T) {
+	spec.Run(t, "IntrospectionCache", testIntrospectionCache, spec.Report(report.Terminal{}))
+}
+
+func testIntrospectionCache(t *testing.T, when spec.G, it spec.S) {
+	var cache *uaa.IntrospectionCache
+
+	active := func(expiresIn time.Duration) *uaa.TokenIntrospection {
+		return &uaa.TokenIntrospection{
+			Active: true,
+			Claims: uaa.Claims{ClientID: "app", Expiry: time.Now().Add(expiresIn).Unix()},
+		}
+	}
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		cache = uaa.NewIntrospectionCache(time.Minute)
+	})
+
+	it("returns cached results", func() {
+		cache.Add("token", active(time.Hour))
+		introspection, ok := cache.Get("token")
+		Expect(ok).To(BeTrue())
+		Expect(introspection.ClientID).To(Equal("app"))
+		_, ok = cache.Get("other")
+		Expect(ok).To(BeFalse())
+	})
+
+	it("expires results after the TTL", func() {
+		cache.TTL = time.Millisecond
+		cache.Add("token", active(time.Hour))
+		time.Sleep(5 * time.Millisecond)
+		_, ok := cache.Get("token")
+		Expect(ok).To(BeFalse())
+		Expect(cache.Len()).To(Equal(0))
+	})
+
+	it("does not cache results past the token expiry", func() {
+		cache.Add("token", active(0))
+		_, ok := cache.Get("token")
+		Expect(ok).To(BeFalse())
+	})
+
+	it("only caches inactive results when negative caching is enabled", func() {
+		cache.Add("revoked", &uaa.TokenIntrospection{Active: false})
+		_, ok := cache.Get("revoked")
+		Expect(ok).To(BeFalse())
+
+		cache.NegativeTTL = time.Minute
+		cache.Add("revoked", &uaa.TokenIntrospection{Active: false})
+		introspection, ok := cache.Get("revoked")
+		Expect(ok).To(BeTrue())
+		Expect(introspection.Active).To(BeFalse())
+	})
+
+	it("evicts the least recently used result when full", func() {
+		cache.MaxEntries = 2
+		cache.Add("a", active(time.Hour))
+		cache.Add("b", active(time.Hour))
+		cache.Get("a")
+		cache.Add("c", active(time.Hour))
+		Expect(cache.Len()).To(Equal(2))
+		_, ok := cache.Get("b")
+		Expect(ok).To(BeFalse())
+		_, ok = cache.Get("a")
+		Expect(ok).To(BeTrue())
+	})
+
+	it("invalidates results", func() {
+		cache.Add("token", active(time.Hour))
+		cache.Invalidate("token")
+		_, ok := cache.Get("token")
+		Expect(ok).To(BeFalse())
+	})
+
+	when("used by a TokenValidator", func() {
+		var (
+			s      *httptest.Server
+			called int
+		)
+
+		it.Before(func() {
+			called = 0
+			s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.URL.Path).To(Equal("/introspect"))
+				called++
+				fmt.Fprintf(w, `{"active":true,"exp":%d}`, time.Now().Add(time.Hour).Unix())
+			}))
+		})
+
+		it.After(func() {
+			s.Close()
+		})
+
+		it("introspects each token once", func() {
+			c := &http.Client{Transport: http.DefaultTransport}
+			u, _ := url.Parse(s.URL)
+			a := &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+			v := &uaa.TokenValidator{API: a, IntrospectionCache: cache}
+
+			for i := 0; i < 3; i++ {
+				_, err := v.Validate("opaque-token")
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(called).To(Equal(1))
+		})
+
+		it("expires results by the validator's clock", func() {
+			clock := uaa.NewFakeClock()
+			c := &http.Client{Transport: http.DefaultTransport}
+			u, _ := url.Parse(s.URL)
+			a := &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+			v := &uaa.TokenValidator{API: a, IntrospectionCache: cache, Clock: clock}
+
+			_, err := v.Validate("opaque-token")
+			Expect(err).NotTo(HaveOccurred())
+			clock.Advance(cache.TTL)
+			_, err = v.Validate("opaque-token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(called).To(Equal(2))
+		})
+	})
+}
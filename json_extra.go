@@ -0,0 +1,85 @@
+package uaa
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// knownFields caches the lower-cased JSON field names of struct types.
+var knownFields sync.Map
+
+// jsonFieldNames returns the lower-cased JSON names of the struct type's
+// fields, which encoding/json matches case-insensitively.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	if names, ok := knownFields.Load(t); ok {
+		return names.(map[string]bool)
+	}
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for embedded := range jsonFieldNames(f.Type) {
+				names[embedded] = true
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names[strings.ToLower(name)] = true
+	}
+	knownFields.Store(t, names)
+	return names
+}
+
+// unmarshalWithExtra decodes the JSON object into v, a pointer to a struct,
+// and returns the object's fields that the struct does not have, or nil if
+// there are none.
+func unmarshalWithExtra(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	names := jsonFieldNames(reflect.TypeOf(v).Elem())
+	var extra map[string]json.RawMessage
+	for name, value := range fields {
+		if names[strings.ToLower(name)] {
+			continue
+		}
+		if extra == nil {
+			extra = map[string]json.RawMessage{}
+		}
+		extra[name] = value
+	}
+	return extra, nil
+}
+
+// marshalWithExtra encodes v, a struct, as a JSON object along with the extra
+// fields. With extra fields, the object's keys are sorted, so that the
+// encoding does not depend on the order in which they were received.
+func marshalWithExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range extra {
+		if _, ok := fields[name]; !ok {
+			fields[name] = value
+		}
+	}
+	return json.Marshal(fields)
+}
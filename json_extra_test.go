@@ -0,0 +1,74 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestJSONExtra(t *testing.T) {
+	spec.Run(t, "JSONExtra", testJSONExtra, spec.Report(report.Terminal{}))
+}
+
+func testJSONExtra(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("round-trips the zone fields it does not model", func() {
+		data := `{"id":"tenant","subdomain":"tenant","name":"Tenant","active":true,
+			"config":{"tokenPolicy":{"accessTokenValidity":3600},"issuer":"https://tenant.example.com","defaultUserGroups":["openid"]}}`
+		zone := uaa.IdentityZone{}
+		Expect(json.Unmarshal([]byte(data), &zone)).To(Succeed())
+		Expect(zone.Extra).To(Equal(map[string]json.RawMessage{"active": json.RawMessage("true")}))
+		Expect(zone.Config.Extra).To(HaveKey("issuer"))
+		Expect(zone.Config.Extra).To(HaveKey("defaultUserGroups"))
+
+		zone.Name = "Renamed"
+		out, err := json.Marshal(zone)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(MatchJSON(`{"id":"tenant","subdomain":"tenant","name":"Renamed","active":true,
+			"config":{"tokenPolicy":{"accessTokenValidity":3600},"issuer":"https://tenant.example.com","defaultUserGroups":["openid"]}}`))
+	})
+
+	it("marshals the same way whatever order the fields arrived in", func() {
+		first, second := uaa.Client{}, uaa.Client{}
+		Expect(json.Unmarshal([]byte(`{"client_id":"app","b":2,"a":1}`), &first)).To(Succeed())
+		Expect(json.Unmarshal([]byte(`{"a":1,"b":2,"client_id":"app"}`), &second)).To(Succeed())
+		firstOut, err := json.Marshal(first)
+		Expect(err).NotTo(HaveOccurred())
+		secondOut, err := json.Marshal(second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(firstOut)).To(Equal(`{"a":1,"b":2,"client_id":"app"}`))
+		Expect(secondOut).To(Equal(firstOut))
+	})
+
+	it("leaves Extra nil when every field is modeled", func() {
+		client := uaa.Client{}
+		Expect(json.Unmarshal([]byte(`{"client_id":"app","scope":["openid"],"autoapprove":true}`), &client)).To(Succeed())
+		Expect(client.Extra).To(BeNil())
+		Expect(client.AutoApprove.All()).To(BeTrue())
+	})
+
+	it("round-trips the identity provider fields it does not model", func() {
+		provider := uaa.IdentityProvider{}
+		Expect(json.Unmarshal([]byte(`{"type":"saml","originKey":"okta","name":"Okta","active":true,"aliasZid":"other"}`), &provider)).To(Succeed())
+		Expect(provider.Extra).To(Equal(map[string]json.RawMessage{"aliasZid": json.RawMessage(`"other"`)}))
+		out, err := json.Marshal(provider)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(ContainSubstring(`"aliasZid":"other"`))
+	})
+
+	it("round-trips a client with a grant type it does not know", func() {
+		client := uaa.Client{}
+		Expect(json.Unmarshal([]byte(`{"client_id":"app","authorized_grant_types":["urn:ietf:params:oauth:grant-type:token-exchange"],"a":1}`), &client)).To(Succeed())
+		out, err := json.Marshal(client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(ContainSubstring("token-exchange"))
+		Expect(out).To(ContainSubstring(`"a":1`))
+	})
+}
@@ -0,0 +1,149 @@
+package uaa
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// JWKThumbprint returns the RFC 7638 SHA-256 thumbprint of the RSA or EC
+// public key, which is stable for a given key and so makes a good key ID.
+func JWKThumbprint(key crypto.PublicKey) (string, error) {
+	var members string
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		members = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, encodeInt(big.NewInt(int64(k.E))), encodeInt(k.N))
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		members = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Curve.Params().Name, encodeFixed(k.X, size), encodeFixed(k.Y, size))
+	default:
+		return "", fmt.Errorf("unsupported public key type %T, must be RSA or EC", key)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// NewJWK returns the public JWK for the private key, in the form the UAA
+// publishes at /token_keys: with the key's thumbprint as its key ID unless
+// kid is given, and with the public key in PEM form as its value.
+func NewJWK(key crypto.Signer, kid string) (*JWK, error) {
+	pub := key.Public()
+	if kid == "" {
+		var err error
+		if kid, err = JWKThumbprint(pub); err != nil {
+			return nil, err
+		}
+	}
+	value, err := PublicKeyPEM(pub)
+	if err != nil {
+		return nil, err
+	}
+	jwk := &JWK{Kid: kid, Use: "sig", Value: value}
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.Alg = "RS256"
+		jwk.N = encodeInt(k.N)
+		jwk.E = encodeInt(big.NewInt(int64(k.E)))
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = k.Curve.Params().Name
+		jwk.X = encodeFixed(k.X, size)
+		jwk.Y = encodeFixed(k.Y, size)
+		switch jwk.Crv {
+		case "P-256":
+			jwk.Alg = "ES256"
+		case "P-384":
+			jwk.Alg = "ES384"
+		case "P-521":
+			jwk.Alg = "ES512"
+		}
+	}
+	return jwk, nil
+}
+
+// NewJWKS returns the JWK set publishing the public keys of the private keys,
+// each identified by its thumbprint.
+func NewJWKS(keys ...crypto.Signer) (*Keys, error) {
+	set := &Keys{Keys: []JWK{}}
+	for _, key := range keys {
+		jwk, err := NewJWK(key, "")
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, *jwk)
+	}
+	return set, nil
+}
+
+// PrivateKeyPEM encodes the RSA or EC private key as PEM, in the PKCS #1 or
+// SEC 1 form that the UAA expects for zone signing keys.
+func PrivateKeyPEM(key crypto.Signer) (string, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)})), nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return "", err
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+	}
+	return "", fmt.Errorf("unsupported private key type %T, must be RSA or EC", key)
+}
+
+// PublicKeyPEM encodes the public key as a PEM "PUBLIC KEY" block.
+func PublicKeyPEM(key crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// AddSigningKey adds the RSA private key to the token policy's signing keys
+// under its thumbprint, and returns the key ID. If the policy has no active
+// key, the new key becomes active; otherwise set ActiveKeyID to the returned
+// ID once every token verifier has fetched the new key.
+func (p *TokenPolicy) AddSigningKey(key crypto.Signer) (string, error) {
+	if _, ok := key.(*rsa.PrivateKey); !ok {
+		return "", fmt.Errorf("unsupported signing key type %T, must be RSA", key)
+	}
+	kid, err := JWKThumbprint(key.Public())
+	if err != nil {
+		return "", err
+	}
+	signingKey, err := PrivateKeyPEM(key)
+	if err != nil {
+		return "", err
+	}
+	if p.Keys == nil {
+		p.Keys = map[string]TokenPolicyKey{}
+	}
+	p.Keys[kid] = TokenPolicyKey{SigningKey: signingKey}
+	if p.ActiveKeyID == "" {
+		p.ActiveKeyID = kid
+	}
+	return kid, nil
+}
+
+func encodeInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+// encodeFixed encodes an EC coordinate padded to the curve's size, as RFC
+// 7518 requires.
+func encodeFixed(i *big.Int, size int) string {
+	b := i.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
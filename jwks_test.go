@@ -0,0 +1,93 @@
+package uaa_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestJWKS(t *testing.T) {
+	spec.Run(t, "JWKS", testJWKS, spec.Report(report.Terminal{}))
+}
+
+func testJWKS(t *testing.T, when spec.G, it spec.S) {
+	var rsaKey *rsa.PrivateKey
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		var err error
+		rsaKey, err = rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it("computes the RFC 7638 thumbprint", func() {
+		// The example key from RFC 7638, section 3.1.
+		n, _ := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}
+		thumbprint, err := uaa.JWKThumbprint(key)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(thumbprint).To(Equal("NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"))
+	})
+
+	it("publishes a JWK that verifies with the original key", func() {
+		jwk, err := uaa.NewJWK(rsaKey, "")
+		Expect(err).NotTo(HaveOccurred())
+		kid, _ := uaa.JWKThumbprint(&rsaKey.PublicKey)
+		Expect(jwk.Kid).To(Equal(kid))
+		Expect(jwk.Alg).To(Equal("RS256"))
+		pub, err := jwk.PublicKey()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub).To(Equal(&rsaKey.PublicKey))
+
+		jwk.N, jwk.E = "", ""
+		pub, err = jwk.PublicKey()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub).To(Equal(&rsaKey.PublicKey))
+	})
+
+	it("builds a JWK set of RSA and EC keys", func() {
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		set, err := uaa.NewJWKS(rsaKey, ecKey)
+		Expect(err).NotTo(HaveOccurred())
+		j, err := json.Marshal(set)
+		Expect(err).NotTo(HaveOccurred())
+
+		var decoded uaa.Keys
+		Expect(json.Unmarshal(j, &decoded)).To(Succeed())
+		Expect(decoded.Keys).To(HaveLen(2))
+		Expect(decoded.Keys[1].Kty).To(Equal("EC"))
+		Expect(decoded.Keys[1].Crv).To(Equal("P-256"))
+		Expect(decoded.Keys[1].Alg).To(Equal("ES256"))
+	})
+
+	it("adds signing keys to a zone's token policy", func() {
+		policy := uaa.TokenPolicy{}
+		kid, err := policy.AddSigningKey(rsaKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policy.ActiveKeyID).To(Equal(kid))
+
+		pem, _ := uaa.PrivateKeyPEM(rsaKey)
+		Expect(policy.Keys[kid].SigningKey).To(Equal(pem))
+		parsed, err := uaa.ParsePrivateKeyPEM([]byte(pem), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(parsed.(*rsa.PrivateKey).D).To(Equal(rsaKey.D))
+
+		other, _ := rsa.GenerateKey(rand.Reader, 1024)
+		otherKid, err := policy.AddSigningKey(other)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policy.Keys).To(HaveLen(2))
+		Expect(policy.ActiveKeyID).To(Equal(kid))
+		Expect(otherKid).NotTo(Equal(kid))
+	})
+}
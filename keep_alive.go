@@ -0,0 +1,92 @@
+package uaa
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const (
+	// keepAliveRetryDelay is how long the keep-alive waits to try again after
+	// failing to obtain a token.
+	keepAliveRetryDelay = 5 * time.Second
+	// minKeepAliveWait keeps the keep-alive from refreshing in a loop when
+	// the UAA issues tokens that have expired or are about to.
+	minKeepAliveWait = 100 * time.Millisecond
+)
+
+type keepAlive struct {
+	ctx  context.Context
+	lead time.Duration
+}
+
+// WithKeepAlive returns an Option that makes the API obtain its first token
+// straight away and then replace each token in the background, between lead
+// and one and a half times lead before it expires, so that requests do not
+// wait for a token to be refreshed. The random spread keeps many processes
+// started together from refreshing at the same moment. If a refresh fails it
+// is tried again after a few seconds, and requests refresh the token
+// themselves if it expires in the meantime. The keep-alive stops when ctx is
+// done. It applies to APIs that obtain their own tokens and must be passed to
+// the constructor.
+func WithKeepAlive(ctx context.Context, lead time.Duration) Option {
+	return func(a *API) {
+		a.keepAlive = &keepAlive{ctx: ctx, lead: lead}
+	}
+}
+
+// keepAlive replaces the source's token shortly before it expires until ctx
+// is done. The token is obtained without holding the lock, so that requests
+// can keep using the current token in the meantime.
+func (s *expiryTokenSource) keepAlive(ctx context.Context, lead time.Duration) {
+	var failed bool
+	for {
+		s.mu.Lock()
+		current := s.token
+		s.mu.Unlock()
+
+		var wait time.Duration
+		switch {
+		case failed:
+			wait = keepAliveRetryDelay
+		case current == nil:
+			wait = 0
+		case current.Expiry.IsZero():
+			return
+		default:
+			wait = s.keepAliveWait(current.Expiry, lead)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.clock.After(wait):
+		}
+
+		token, err := s.fetch(current)
+		if failed = err != nil; failed {
+			continue
+		}
+		s.mu.Lock()
+		s.token = token
+		s.mu.Unlock()
+	}
+}
+
+// keepAliveWait returns how long to wait before replacing a token that
+// expires at expiry. A token that lives for less than lead is replaced
+// halfway through its life.
+func (s *expiryTokenSource) keepAliveWait(expiry time.Time, lead time.Duration) time.Duration {
+	remaining := expiry.Sub(s.clock.Now())
+	wait := remaining - lead
+	if spread := int64(lead / 2); spread > 0 {
+		wait -= time.Duration(rand.Int63n(spread))
+	}
+	if wait < remaining/2 {
+		wait = remaining / 2
+	}
+	if wait < minKeepAliveWait {
+		wait = minKeepAliveWait
+	}
+	return wait
+}
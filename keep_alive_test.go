@@ -0,0 +1,88 @@
+package uaa_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestKeepAlive(t *testing.T) {
+	spec.Run(t, "KeepAlive", testKeepAlive, spec.Report(report.Terminal{}))
+}
+
+func testKeepAlive(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s      *httptest.Server
+		issued int32
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		atomic.StoreInt32(&issued, 0)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			n := atomic.AddInt32(&issued, 1)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "token-" + strconv.Itoa(int(n)),
+				"token_type":   "bearer",
+				"expires_in":   1,
+			})
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("refreshes the token in the background until the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.OpaqueToken,
+			uaa.WithExpiryLeeway(time.Millisecond),
+			uaa.WithKeepAlive(ctx, 300*time.Millisecond))
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() int32 { return atomic.LoadInt32(&issued) }, time.Second).Should(BeNumerically(">=", 1))
+		Eventually(func() int32 { return atomic.LoadInt32(&issued) }, 2*time.Second).Should(BeNumerically(">=", 2))
+
+		before := atomic.LoadInt32(&issued)
+		token, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(HavePrefix("token-"))
+		Expect(token.Expiry).To(BeTemporally(">", time.Now().Add(200*time.Millisecond)))
+		Expect(atomic.LoadInt32(&issued)).To(Equal(before))
+
+		cancel()
+		time.Sleep(100 * time.Millisecond)
+		stopped := atomic.LoadInt32(&issued)
+		Consistently(func() int32 { return atomic.LoadInt32(&issued) }, 1500*time.Millisecond).Should(Equal(stopped))
+	})
+
+	it("waits on the API's clock", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		clock := uaa.NewFakeClock()
+		_, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.OpaqueToken,
+			uaa.WithClock(clock),
+			uaa.WithExpiryLeeway(time.Millisecond),
+			uaa.WithKeepAlive(ctx, 300*time.Millisecond))
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() int32 { return atomic.LoadInt32(&issued) }, time.Second).Should(Equal(int32(1)))
+		Consistently(func() int32 { return atomic.LoadInt32(&issued) }, 800*time.Millisecond).Should(Equal(int32(1)))
+		Eventually(func() int32 {
+			clock.Advance(time.Second)
+			return atomic.LoadInt32(&issued)
+		}, time.Second).Should(BeNumerically(">=", 2))
+	})
+}
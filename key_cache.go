@@ -0,0 +1,191 @@
+package uaa
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultKeyRefreshInterval is the interval at which a KeyCache refreshes its
+// keys if no interval is configured.
+const DefaultKeyRefreshInterval = 15 * time.Minute
+
+// DefaultUnknownKeyRefreshInterval is the minimum time between refreshes
+// forced by tokens signed with an unknown key if no interval is configured.
+const DefaultUnknownKeyRefreshInterval = 10 * time.Second
+
+// KeyCache is a KeySource that caches the UAA's token keys. Cached keys are
+// served while they are refreshed in the background, a token signed with an
+// unknown key forces a refresh so that key rotation is picked up, and keys
+// are kept when a refresh fails.
+type KeyCache struct {
+	// API is used to fetch the token keys.
+	API *API
+	// RefreshInterval is the age after which the cached keys are refreshed.
+	// Defaults to DefaultKeyRefreshInterval.
+	RefreshInterval time.Duration
+	// UnknownKeyRefreshInterval is the minimum time between refreshes forced
+	// by unknown key IDs, and between attempts to fetch the keys while none
+	// are cached and the last attempt failed. Defaults to
+	// DefaultUnknownKeyRefreshInterval.
+	UnknownKeyRefreshInterval time.Duration
+
+	mu          sync.RWMutex
+	keys        []JWK
+	fetchedAt   time.Time
+	forcedAt    time.Time
+	refreshMu   sync.Mutex
+	refreshing  int32
+	lastRefresh error
+	failedAt    time.Time
+}
+
+// NewKeyCache returns a KeyCache that fetches keys with the given API.
+func NewKeyCache(api *API) *KeyCache {
+	return &KeyCache{API: api}
+}
+
+// Key returns the cached key with the given ID. The keys are fetched if they
+// have not been fetched yet, or if no cached key has the given ID. While no
+// keys are cached and the last attempt to fetch them failed, that error is
+// returned rather than fetching again until UnknownKeyRefreshInterval has
+// passed.
+func (c *KeyCache) Key(kid string) (*JWK, error) {
+	keys, fetchedAt := c.snapshot()
+	if keys == nil {
+		if err := c.backingOff(); err != nil {
+			return nil, err
+		}
+		if err := c.Refresh(); err != nil {
+			return nil, err
+		}
+		keys, fetchedAt = c.snapshot()
+	}
+
+	key, err := findKey(keys, kid)
+	if err == nil {
+		if c.clock().Now().Sub(fetchedAt) > c.refreshInterval() {
+			c.refreshAsync()
+		}
+		return key, nil
+	}
+
+	if c.shouldForceRefresh() {
+		if err := c.Refresh(); err != nil {
+			return nil, err
+		}
+		keys, _ = c.snapshot()
+		return findKey(keys, kid)
+	}
+	return nil, err
+}
+
+// Refresh fetches the keys from the UAA. If the keys cannot be fetched, the
+// previously cached keys are kept.
+func (c *KeyCache) Refresh() error {
+	started := c.clock().Now()
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	c.mu.RLock()
+	fresh := c.fetchedAt.After(started)
+	c.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	keys, err := c.API.TokenKeys()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastRefresh = err
+	if err != nil {
+		c.failedAt = c.clock().Now()
+		return err
+	}
+	c.keys = keys
+	c.fetchedAt = c.clock().Now()
+	return nil
+}
+
+// Start refreshes the keys in the background at the refresh interval until
+// the context is done.
+func (c *KeyCache) Start(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.clock().After(c.refreshInterval()):
+				c.Refresh()
+			}
+		}
+	}()
+}
+
+// LastRefreshError returns the error from the most recent refresh, if any.
+func (c *KeyCache) LastRefreshError() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lastRefresh
+}
+
+func (c *KeyCache) snapshot() ([]JWK, time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.keys, c.fetchedAt
+}
+
+func (c *KeyCache) refreshAsync() {
+	if !atomic.CompareAndSwapInt32(&c.refreshing, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&c.refreshing, 0)
+		c.Refresh()
+	}()
+}
+
+func (c *KeyCache) shouldForceRefresh() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock().Now()
+	if now.Sub(c.forcedAt) < c.unknownKeyRefreshInterval() {
+		return false
+	}
+	c.forcedAt = now
+	return true
+}
+
+// backingOff returns the error of the last refresh if it failed less than
+// UnknownKeyRefreshInterval ago.
+func (c *KeyCache) backingOff() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.lastRefresh != nil && c.clock().Now().Sub(c.failedAt) < c.unknownKeyRefreshInterval() {
+		return c.lastRefresh
+	}
+	return nil
+}
+
+// clock returns the clock of the API used to fetch the keys.
+func (c *KeyCache) clock() Clock {
+	if c.API != nil {
+		return c.API.getClock()
+	}
+	return systemClock{}
+}
+
+func (c *KeyCache) unknownKeyRefreshInterval() time.Duration {
+	if c.UnknownKeyRefreshInterval == 0 {
+		return DefaultUnknownKeyRefreshInterval
+	}
+	return c.UnknownKeyRefreshInterval
+}
+
+func (c *KeyCache) refreshInterval() time.Duration {
+	if c.RefreshInterval == 0 {
+		return DefaultKeyRefreshInterval
+	}
+	return c.RefreshInterval
+}
@@ -0,0 +1,184 @@
+package uaa_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestKeyCache(t *testing.T) {
+	spec.Run(t, "KeyCache", testKeyCache, spec.Report(report.Terminal{}))
+}
+
+func testKeyCache(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s      *httptest.Server
+		mu     sync.Mutex
+		keys   []uaa.JWK
+		failed bool
+		called int
+		cache  *uaa.KeyCache
+	)
+
+	requests := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return called
+	}
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		called = 0
+		failed = false
+		keys = []uaa.JWK{{Kid: "key-1", Kty: "RSA"}}
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			called++
+			if failed {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			json.NewEncoder(w).Encode(uaa.Keys{Keys: keys})
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		cache = uaa.NewKeyCache(&uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		})
+	})
+
+	it.After(func() {
+		if s != nil {
+			s.Close()
+		}
+	})
+
+	it("fetches the keys once and serves them from the cache", func() {
+		key, err := cache.Key("key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Kid).To(Equal("key-1"))
+		_, err = cache.Key("key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests()).To(Equal(1))
+	})
+
+	it("refreshes once when a token is signed with an unknown key", func() {
+		_, err := cache.Key("key-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		mu.Lock()
+		keys = append(keys, uaa.JWK{Kid: "key-2", Kty: "RSA"})
+		mu.Unlock()
+		key, err := cache.Key("key-2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Kid).To(Equal("key-2"))
+		Expect(requests()).To(Equal(2))
+
+		_, err = cache.Key("key-3")
+		Expect(err).To(BeAssignableToTypeOf(&uaa.InvalidTokenError{}))
+		Expect(requests()).To(Equal(2))
+	})
+
+	it("serves stale keys while refreshing in the background", func() {
+		cache.RefreshInterval = time.Millisecond
+		_, err := cache.Key("key-1")
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		failed = true
+		mu.Unlock()
+		key, err := cache.Key("key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Kid).To(Equal("key-1"))
+		Eventually(cache.LastRefreshError).Should(HaveOccurred())
+
+		key, err = cache.Key("key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Kid).To(Equal("key-1"))
+	})
+
+	it("refreshes on an interval after it is started", func() {
+		cache.RefreshInterval = 10 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		cache.Start(ctx)
+		Eventually(requests).Should(BeNumerically(">=", 2))
+	})
+
+	it("refreshes on an interval of the API's clock", func() {
+		clock := uaa.NewFakeClock()
+		uaa.WithClock(clock)(cache.API)
+		cache.RefreshInterval = time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		cache.Start(ctx)
+		Consistently(requests).Should(Equal(0))
+		Eventually(func() int {
+			clock.Advance(time.Hour)
+			return requests()
+		}).Should(BeNumerically(">=", 1))
+	})
+
+	it("returns an error when the keys have never been fetched", func() {
+		s.Close()
+		_, err := cache.Key("key-1")
+		Expect(err).To(HaveOccurred())
+	})
+
+	it("backs off while the keys cannot be fetched", func() {
+		failed = true
+		cache.UnknownKeyRefreshInterval = 50 * time.Millisecond
+		_, err := cache.Key("key-1")
+		Expect(err).To(HaveOccurred())
+		attempted := requests()
+		_, again := cache.Key("key-1")
+		Expect(again).To(Equal(err))
+		Expect(again).To(Equal(cache.LastRefreshError()))
+		Expect(requests()).To(Equal(attempted))
+
+		mu.Lock()
+		failed = false
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		key, err := cache.Key("key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Kid).To(Equal("key-1"))
+		Expect(requests()).To(Equal(attempted + 1))
+	})
+
+	it("backs off by the API's clock", func() {
+		clock := uaa.NewFakeClock()
+		uaa.WithClock(clock)(cache.API)
+		failed = true
+		cache.UnknownKeyRefreshInterval = time.Hour
+		_, err := cache.Key("key-1")
+		Expect(err).To(HaveOccurred())
+		attempted := requests()
+
+		mu.Lock()
+		failed = false
+		mu.Unlock()
+		_, again := cache.Key("key-1")
+		Expect(again).To(Equal(err))
+		Expect(requests()).To(Equal(attempted))
+
+		clock.Advance(time.Hour)
+		key, err := cache.Key("key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Kid).To(Equal("key-1"))
+	})
+}
@@ -0,0 +1,45 @@
+package uaa
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// WithLazyAuthentication makes the constructors return without requesting a
+// token, so that an API can be built while the UAA is unavailable; the token
+// is requested by the first call that needs one. The client credentials and
+// password constructors always authenticate lazily, so the option only
+// changes NewWithAuthorizationCode, which otherwise exchanges the code
+// immediately and returns any error. If the exchange fails it is attempted
+// again by the next call, for as long as the UAA accepts the code.
+func WithLazyAuthentication() Option {
+	return func(a *API) {
+		a.lazyAuthentication = true
+	}
+}
+
+// exchangeTokenSource exchanges an authorization code for a token when a
+// token is first needed, and then refreshes the token as needed.
+type exchangeTokenSource struct {
+	config *oauth2.Config
+	ctx    context.Context
+	code   string
+
+	mu     sync.Mutex
+	source oauth2.TokenSource
+}
+
+func (s *exchangeTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.source == nil {
+		t, err := s.config.Exchange(s.ctx, s.code)
+		if err != nil {
+			return nil, err
+		}
+		s.source = s.config.TokenSource(s.ctx, t)
+	}
+	return s.source.Token()
+}
@@ -0,0 +1,72 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestLazyAuthentication(t *testing.T) {
+	spec.Run(t, "LazyAuthentication", testLazyAuthentication, spec.Report(report.Terminal{}))
+}
+
+func testLazyAuthentication(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s         *httptest.Server
+		available bool
+		exchanges int
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		available = false
+		exchanges = 0
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if !available {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			switch req.URL.Path {
+			case "/oauth/token":
+				exchanges++
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"lazy-token","token_type":"bearer","expires_in":3600}`))
+			case "/userinfo":
+				Expect(req.Header.Get("Authorization")).To(Equal("Bearer lazy-token"))
+				w.Write([]byte(`{"user_name":"charlieb"}`))
+			}
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("fails to build an API with an authorization code while the UAA is down", func() {
+		api, err := uaa.NewWithAuthorizationCode(s.URL, "", "client", "secret", "code", false, uaa.OpaqueToken)
+		Expect(err).To(HaveOccurred())
+		Expect(api).To(BeNil())
+	})
+
+	it("exchanges the code on the first call that needs a token", func() {
+		api, err := uaa.NewWithAuthorizationCode(s.URL, "", "client", "secret", "code", false, uaa.OpaqueToken, uaa.WithLazyAuthentication())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(api).NotTo(BeNil())
+
+		_, err = api.GetMe()
+		Expect(err).To(HaveOccurred())
+
+		available = true
+		me, err := api.GetMe()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(me.Username).To(Equal("charlieb"))
+		_, err = api.GetMe()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exchanges).To(Equal(1))
+	})
+}
@@ -0,0 +1,60 @@
+package uaa
+
+import (
+	"fmt"
+	"time"
+)
+
+// ListCursor is the position in a listing at which a ListAll*From method
+// resumes. The zero ListCursor is the start of the listing. A ListCursor can
+// be stored as JSON between runs, so that, for example, a nightly export that
+// runs out of time continues the next night where it stopped.
+type ListCursor struct {
+	// StartIndex is the 1-based index of the next result to read.
+	StartIndex int `json:"startIndex"`
+}
+
+func (c ListCursor) startIndex() int {
+	if c.StartIndex < 1 {
+		return 1
+	}
+	return c.StartIndex
+}
+
+// ErrPartialResults is returned, along with the results read so far, by the
+// ListAll* methods when the API's list deadline passes before the last page
+// is read. Pass Cursor to the matching ListAll*From method, with the same
+// filter and sort order, to read the rest.
+type ErrPartialResults struct {
+	Cursor ListCursor
+}
+
+func (e ErrPartialResults) Error() string {
+	return fmt.Sprintf("the list deadline passed; the results from index %d were not read", e.Cursor.StartIndex)
+}
+
+// WithListDeadline returns an Option that limits each call to a ListAll*
+// method to about timeout: once timeout has passed since the call began, no
+// further pages are requested, and the results read so far are returned with
+// an ErrPartialResults. A page that is being read when the deadline passes is
+// finished. With WithPageConcurrency, the deadline is checked before each page
+// is requested, and the pages requested before it passed are all read, so
+// that the results always end at the cursor.
+func WithListDeadline(timeout time.Duration) Option {
+	return func(a *API) {
+		a.listTimeout = timeout
+	}
+}
+
+// listDeadline returns a function that reports whether the list deadline of a
+// call starting now has passed. Without a deadline, it never has.
+func (a *API) listDeadline() func() bool {
+	if a.listTimeout <= 0 {
+		return func() bool { return false }
+	}
+	clock := a.getClock()
+	deadline := clock.Now().Add(a.listTimeout)
+	return func() bool {
+		return !clock.Now().Before(deadline)
+	}
+}
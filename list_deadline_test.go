@@ -0,0 +1,100 @@
+package uaa_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestListDeadline(t *testing.T) {
+	spec.Run(t, "ListDeadline", testListDeadline, spec.Report(report.Terminal{}))
+}
+
+func testListDeadline(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s      *httptest.Server
+		clock  *uaa.FakeClock
+		mu     sync.Mutex
+		starts []string
+		a      *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		clock = uaa.NewFakeClock()
+		starts = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := req.URL.Query().Get("startIndex")
+			mu.Lock()
+			starts = append(starts, start)
+			mu.Unlock()
+			clock.Advance(time.Minute)
+			index, _ := strconv.Atoi(start)
+			fmt.Fprintf(w, `{"resources":[{"id":"user-%d"}],"startIndex":%d,"itemsPerPage":1,"totalResults":4}`, index, index)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		}
+		uaa.WithClock(clock)(a)
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("reads every page without a deadline", func() {
+		users, err := a.ListAllUsers("", "", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(4))
+	})
+
+	it("returns partial results and a cursor when the deadline passes", func() {
+		uaa.WithListDeadline(90 * time.Second)(a)
+		users, err := a.ListAllUsers("", "", "", "")
+		Expect(users).To(HaveLen(2))
+		Expect(err).To(Equal(uaa.ErrPartialResults{Cursor: uaa.ListCursor{StartIndex: 3}}))
+		Expect(starts).To(Equal([]string{"1", "2"}))
+
+		cursor := err.(uaa.ErrPartialResults).Cursor
+		rest, err := a.ListAllUsersFrom(cursor, "", "", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rest).To(HaveLen(2))
+		Expect(rest[0].ID).To(Equal("user-3"))
+		Expect(starts).To(Equal([]string{"1", "2", "3", "4"}))
+	})
+
+	it("does not request pages concurrently once the deadline has passed", func() {
+		uaa.WithPageConcurrency(4)(a)
+		uaa.WithListDeadline(30 * time.Second)(a)
+		users, err := a.ListAllUsers("", "", "", "")
+		Expect(users).To(HaveLen(1))
+		Expect(err).To(Equal(uaa.ErrPartialResults{Cursor: uaa.ListCursor{StartIndex: 2}}))
+		Expect(starts).To(Equal([]string{"1"}))
+	})
+
+	it("ends concurrently read results at the cursor", func() {
+		uaa.WithPageConcurrency(2)(a)
+		uaa.WithListDeadline(90 * time.Second)(a)
+		users, err := a.ListAllUsers("", "", "", "")
+		Expect(err).To(BeAssignableToTypeOf(uaa.ErrPartialResults{}))
+		Expect(len(users)).To(BeNumerically("<", 4))
+		for i, user := range users {
+			Expect(user.ID).To(Equal(fmt.Sprintf("user-%d", i+1)))
+		}
+		Expect(err.(uaa.ErrPartialResults).Cursor.StartIndex).To(Equal(len(users) + 1))
+	})
+}
@@ -0,0 +1,69 @@
+package uaa
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// SingleLogoutURL returns the URL of the UAA's /logout.do endpoint, to which
+// a web application sends the user's browser to end their UAA session. After
+// logging the user out, UAA redirects the browser to the given redirect URL,
+// which must match one of the client's registered redirect URIs.
+func (a *API) SingleLogoutURL(clientID string, redirect string) string {
+	u := urlWithPath(*a.TargetURL, a.endpointPath("/logout.do"))
+	query := url.Values{}
+	if clientID != "" {
+		query.Set("client_id", clientID)
+	}
+	if redirect != "" {
+		query.Set("redirect", redirect)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// LogoutURL returns an OpenID Connect RP-initiated logout URL for the UAA,
+// to which a relying party sends the user's browser to end their UAA session.
+// The idToken is the ID token the relying party was issued for the user, sent
+// as the id_token_hint so that UAA can identify the client and check the
+// redirect. After logging the user out, UAA redirects the browser to
+// postLogoutRedirect with the given state.
+func (a *API) LogoutURL(idToken string, postLogoutRedirect string, state string) string {
+	u := urlWithPath(*a.TargetURL, a.endpointPath("/logout.do"))
+	query := url.Values{}
+	if idToken != "" {
+		query.Set("id_token_hint", idToken)
+	}
+	if postLogoutRedirect != "" {
+		query.Set("post_logout_redirect_uri", postLogoutRedirect)
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// RevokeToken revokes the token with the given ID (the jti claim of a JWT, or
+// the value of an opaque token), so that a server-side session built on it
+// can no longer use it. The token must be revocable; see
+// http://docs.cloudfoundry.org/api/uaa/version/4.14.0/index.html#revoke-a-single-token.
+func (a *API) RevokeToken(tokenID string) error {
+	if tokenID == "" {
+		return errors.New("tokenID cannot be blank")
+	}
+	u := urlWithPath(*a.TargetURL, fmt.Sprintf("/oauth/token/revoke/%s", tokenID))
+	return a.doJSON(http.MethodDelete, &u, nil, nil, true)
+}
+
+// RevokeUserTokens revokes all of the tokens issued to the user with the
+// given ID, ending every session the user has with clients of the UAA.
+func (a *API) RevokeUserTokens(userID string) error {
+	if userID == "" {
+		return errors.New("userID cannot be blank")
+	}
+	u := urlWithPath(*a.TargetURL, fmt.Sprintf("/oauth/token/revoke/user/%s", userID))
+	return a.doJSON(http.MethodDelete, &u, nil, nil, true)
+}
@@ -0,0 +1,108 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestLogout(t *testing.T) {
+	spec.Run(t, "Logout", testLogout, spec.Report(report.Terminal{}))
+}
+
+func testLogout(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		handler http.Handler
+		called  int
+		a       *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		called = 0
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			called++
+			handler.ServeHTTP(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	when("SingleLogoutURL()", func() {
+		it("includes the client and redirect", func() {
+			u, err := url.Parse(a.SingleLogoutURL("app", "https://app.example.com/bye"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u.Path).To(Equal("/logout.do"))
+			Expect(u.Query().Get("client_id")).To(Equal("app"))
+			Expect(u.Query().Get("redirect")).To(Equal("https://app.example.com/bye"))
+		})
+
+		it("omits empty parameters", func() {
+			Expect(a.SingleLogoutURL("", "")).To(Equal(s.URL + "/logout.do"))
+		})
+	})
+
+	when("LogoutURL()", func() {
+		it("includes the id token hint, redirect and state", func() {
+			u, err := url.Parse(a.LogoutURL("id.token.jwt", "https://app.example.com/bye", "xyz"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u.Path).To(Equal("/logout.do"))
+			query := u.Query()
+			Expect(query.Get("id_token_hint")).To(Equal("id.token.jwt"))
+			Expect(query.Get("post_logout_redirect_uri")).To(Equal("https://app.example.com/bye"))
+			Expect(query.Get("state")).To(Equal("xyz"))
+		})
+
+		it("omits empty parameters", func() {
+			Expect(a.LogoutURL("", "", "")).To(Equal(s.URL + "/logout.do"))
+		})
+	})
+
+	when("RevokeToken()", func() {
+		it("deletes the token", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.Method).To(Equal(http.MethodDelete))
+				Expect(req.URL.Path).To(Equal("/oauth/token/revoke/token-id"))
+				w.WriteHeader(http.StatusOK)
+			})
+			Expect(a.RevokeToken("token-id")).To(Succeed())
+			Expect(called).To(Equal(1))
+		})
+
+		it("requires a token ID", func() {
+			Expect(a.RevokeToken("")).NotTo(Succeed())
+			Expect(called).To(Equal(0))
+		})
+
+		it("returns an error when the UAA refuses", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+			Expect(a.RevokeToken("token-id")).NotTo(Succeed())
+		})
+	})
+
+	when("RevokeUserTokens()", func() {
+		it("deletes the user's tokens", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.Method).To(Equal(http.MethodDelete))
+				Expect(req.URL.Path).To(Equal("/oauth/token/revoke/user/user-id"))
+				w.WriteHeader(http.StatusOK)
+			})
+			Expect(a.RevokeUserTokens("user-id")).To(Succeed())
+			Expect(called).To(Equal(1))
+		})
+	})
+}
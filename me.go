@@ -16,6 +16,21 @@ type UserInfo struct {
 	PhoneNumber       []string `json:"phone_number"`
 	PreviousLoginTime int64    `json:"previous_logon_time"`
 	Name              string   `json:"name"`
+
+	// UserAttributes are the custom attributes read from the user's
+	// identity provider. They are only returned if the token has the
+	// user_attributes scope.
+	UserAttributes map[string][]string `json:"user_attributes,omitempty"`
+
+	// Roles are the user's external groups from their identity provider
+	// that match the provider's whitelist. They are only returned if the
+	// token has the roles scope.
+	Roles []string `json:"roles,omitempty"`
+}
+
+// HasRole returns true if the user has the given role.
+func (u *UserInfo) HasRole(role string) bool {
+	return contains(u.Roles, role)
 }
 
 // GetMe retrieves the UserInfo for the current user.
@@ -78,6 +78,33 @@ func testMe(t *testing.T, when spec.G, it spec.S) {
 		Expect(userinfo.GivenName).To(Equal("Charlie"))
 		Expect(userinfo.FamilyName).To(Equal("Brown"))
 		Expect(userinfo.Email).To(Equal("charlieb@peanuts.com"))
+		Expect(userinfo.UserAttributes).To(BeEmpty())
+	})
+
+	it("returns the user's custom attributes", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"user_id":"d6ef6c2e","user_attributes":{"cost_center":["1234"],"entitlements":["reports","billing"]}}`))
+		})
+
+		userinfo, err := a.GetMe()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userinfo.UserAttributes).To(Equal(map[string][]string{
+			"cost_center":  {"1234"},
+			"entitlements": {"reports", "billing"},
+		}))
+	})
+
+	it("returns the user's roles", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"user_id":"d6ef6c2e","roles":["finance"]}`))
+		})
+
+		userinfo, err := a.GetMe()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userinfo.HasRole("finance")).To(BeTrue())
+		Expect(userinfo.HasRole("admins")).To(BeFalse())
 	})
 
 	it("returns helpful error when /userinfo request fails", func() {
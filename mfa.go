@@ -0,0 +1,63 @@
+package uaa
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/cloudfoundry-community/go-uaa/passwordcredentials"
+	"golang.org/x/oauth2"
+)
+
+// MFACodeFunc returns the current code from the user's authenticator app,
+// for example by prompting the user for it.
+type MFACodeFunc func() (string, error)
+
+// WithMFACode returns an Option that sends the given multi-factor
+// authentication code as the mfaCode parameter of password grant token
+// requests, which UAA requires when it enforces MFA for the zone. Because
+// codes expire after a short time, the code is only good for the first
+// token; use WithMFACodePrompt for an API that lives longer than that.
+func WithMFACode(code string) Option {
+	return WithMFACodePrompt(func() (string, error) {
+		return code, nil
+	})
+}
+
+// WithMFACodePrompt returns an Option that calls prompt for a multi-factor
+// authentication code before each password grant token request, and sends
+// the code as the request's mfaCode parameter. If prompt returns an error,
+// the token request is not made.
+func WithMFACodePrompt(prompt MFACodeFunc) Option {
+	return func(a *API) {
+		a.mfaCode = prompt
+	}
+}
+
+// passwordToken requests a token with the password grant, adding an MFA code
+// if the API has a way to obtain one.
+func (a *API) passwordToken(ctx context.Context, config passwordcredentials.Config) (*oauth2.Token, error) {
+	if a.mfaCode != nil {
+		code, err := a.mfaCode()
+		if err != nil {
+			return nil, err
+		}
+		params := url.Values{}
+		for key, values := range config.EndpointParams {
+			params[key] = values
+		}
+		params.Set("mfaCode", code)
+		config.EndpointParams = params
+	}
+	return config.TokenSource(ctx).Token()
+}
+
+// passwordTokenSource requests tokens with the password grant.
+type passwordTokenSource struct {
+	api    *API
+	ctx    context.Context
+	config *passwordcredentials.Config
+}
+
+func (s passwordTokenSource) Token() (*oauth2.Token, error) {
+	return s.api.passwordToken(s.ctx, *s.config)
+}
@@ -0,0 +1,90 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestMFA(t *testing.T) {
+	spec.Run(t, "MFA", testMFA, spec.Report(report.Terminal{}))
+}
+
+func testMFA(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s     *httptest.Server
+		codes []string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		codes = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.ParseForm()).To(Succeed())
+			Expect(req.Form.Get("grant_type")).To(Equal("password"))
+			codes = append(codes, req.Form.Get("mfaCode"))
+			w.Header().Set("Content-Type", "application/json")
+			if req.Form.Get("mfaCode") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_request","error_description":"A multi-factor authentication code is required to complete the request"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "token_type": "bearer", "expires_in": 3600})
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("sends the MFA code", func() {
+		api, err := uaa.NewWithPasswordCredentials(s.URL, "", "cf", "", "marcus", "meditations", uaa.OpaqueToken, uaa.WithMFACode("123456"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(codes).To(Equal([]string{"123456"}))
+	})
+
+	it("prompts for a code for each token request", func() {
+		clock := uaa.NewFakeClock()
+		prompts := 0
+		api, err := uaa.NewWithPasswordCredentials(s.URL, "", "cf", "", "marcus", "meditations", uaa.OpaqueToken,
+			uaa.WithClock(clock),
+			uaa.WithMFACodePrompt(func() (string, error) {
+				prompts++
+				return strconv.Itoa(100000 + prompts), nil
+			}))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		clock.Advance(2 * time.Hour)
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(codes).To(Equal([]string{"100001", "100002"}))
+	})
+
+	it("does not request a token when the prompt fails", func() {
+		api, err := uaa.NewWithPasswordCredentials(s.URL, "", "cf", "", "marcus", "meditations", uaa.OpaqueToken,
+			uaa.WithMFACodePrompt(func() (string, error) { return "", errors.New("cancelled") }))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).To(MatchError("cancelled"))
+		Expect(codes).To(BeEmpty())
+	})
+
+	it("fails without a code when MFA is enforced", func() {
+		api, err := uaa.NewWithPasswordCredentials(s.URL, "", "cf", "", "marcus", "meditations", uaa.OpaqueToken)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).To(MatchError(ContainSubstring("multi-factor authentication code is required")))
+	})
+}
@@ -0,0 +1,125 @@
+package uaa
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notices are the rate-limit, deprecation and warning headers of a response
+// from the UAA, or from a proxy in front of it.
+type Notices struct {
+	Method     string
+	URL        string
+	StatusCode int
+
+	// RateLimit, RateLimitRemaining and RateLimitReset are read from the
+	// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset
+	// headers; they are -1 and the zero time if a header is absent.
+	RateLimit          int
+	RateLimitRemaining int
+	RateLimitReset     time.Time
+	// RetryAfter is read from the Retry-After header.
+	RetryAfter time.Duration
+
+	// Deprecated is true if the response has a Deprecation header, and
+	// Sunset is the time from its Sunset header at which the endpoint will
+	// be removed.
+	Deprecated bool
+	Sunset     time.Time
+
+	// Warnings are read from the X-Cf-Warnings and Warning headers.
+	Warnings []string
+}
+
+// Empty returns true if the response had none of the headers.
+func (n Notices) Empty() bool {
+	return n.RateLimit < 0 && n.RateLimitRemaining < 0 && n.RateLimitReset.IsZero() &&
+		n.RetryAfter == 0 && !n.Deprecated && n.Sunset.IsZero() && len(n.Warnings) == 0
+}
+
+// WithNoticeHandler returns an Option that calls handler with the notices of
+// each response from the UAA that has rate-limit, deprecation or warning
+// headers, so that operators learn about throttling and deprecated endpoints.
+func WithNoticeHandler(handler func(Notices)) Option {
+	return WithResponseHook(func(resp *http.Response, err error, elapsed time.Duration) {
+		if resp == nil {
+			return
+		}
+		if notices := NoticesFromResponse(resp); !notices.Empty() {
+			handler(notices)
+		}
+	})
+}
+
+// NoticesFromResponse reads the notices from the headers of a response.
+func NoticesFromResponse(resp *http.Response) Notices {
+	n := Notices{
+		StatusCode:         resp.StatusCode,
+		RateLimit:          headerInt(resp.Header, "X-RateLimit-Limit"),
+		RateLimitRemaining: headerInt(resp.Header, "X-RateLimit-Remaining"),
+	}
+	if resp.Request != nil {
+		n.Method = resp.Request.Method
+		n.URL = resp.Request.URL.String()
+	}
+	if reset := headerInt(resp.Header, "X-RateLimit-Reset"); reset >= 0 {
+		n.RateLimitReset = time.Unix(int64(reset), 0)
+	}
+	n.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+
+	if resp.Header.Get("Deprecation") != "" {
+		n.Deprecated = true
+	}
+	if sunset, err := http.ParseTime(resp.Header.Get("Sunset")); err == nil {
+		n.Sunset = sunset
+	}
+
+	for _, header := range resp.Header["X-Cf-Warnings"] {
+		for _, warning := range strings.Split(header, ",") {
+			if w, err := url.QueryUnescape(strings.TrimSpace(warning)); err == nil && w != "" {
+				n.Warnings = append(n.Warnings, w)
+			}
+		}
+	}
+	for _, header := range resp.Header["Warning"] {
+		// Warning: 299 - "text" ["date"]
+		if start := strings.Index(header, `"`); start >= 0 {
+			if end := strings.Index(header[start+1:], `"`); end >= 0 {
+				n.Warnings = append(n.Warnings, header[start+1:start+1+end])
+				continue
+			}
+		}
+		n.Warnings = append(n.Warnings, header)
+	}
+	return n
+}
+
+func headerInt(h http.Header, key string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(h.Get(key)))
+	if err != nil || v < 0 {
+		return -1
+	}
+	return v
+}
+
+// parseRetryAfter returns the delay given by a Retry-After header, which is
+// either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
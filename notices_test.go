@@ -0,0 +1,88 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestNotices(t *testing.T) {
+	spec.Run(t, "Notices", testNotices, spec.Report(report.Terminal{}))
+}
+
+func testNotices(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		headers http.Header
+		a       *uaa.API
+		notices []uaa.Notices
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		headers = http.Header{}
+		notices = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			for key, values := range headers {
+				w.Header()[key] = values
+			}
+			w.Write([]byte(InfoResponseJSON))
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+		uaa.WithNoticeHandler(func(n uaa.Notices) {
+			notices = append(notices, n)
+		})(a)
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("does not call the handler for responses without notices", func() {
+		_, err := a.GetInfo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notices).To(BeEmpty())
+	})
+
+	it("reports rate-limit headers", func() {
+		reset := time.Now().Add(time.Minute).Unix()
+		headers.Set("X-RateLimit-Limit", "100")
+		headers.Set("X-RateLimit-Remaining", "3")
+		headers.Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		headers.Set("Retry-After", "7")
+		a.GetInfo()
+		Expect(notices).To(HaveLen(1))
+		n := notices[0]
+		Expect(n.Method).To(Equal(http.MethodGet))
+		Expect(n.URL).To(Equal(s.URL + "/info"))
+		Expect(n.RateLimit).To(Equal(100))
+		Expect(n.RateLimitRemaining).To(Equal(3))
+		Expect(n.RateLimitReset.Unix()).To(Equal(reset))
+		Expect(n.RetryAfter).To(Equal(7 * time.Second))
+	})
+
+	it("reports deprecations and warnings", func() {
+		sunset := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+		headers.Set("Deprecation", "true")
+		headers.Set("Sunset", sunset.Format(http.TimeFormat))
+		headers.Set("X-Cf-Warnings", url.QueryEscape("This endpoint is deprecated")+","+url.QueryEscape("Use /v2"))
+		headers.Set("Warning", `299 - "Scope will be removed"`)
+		a.GetInfo()
+		Expect(notices).To(HaveLen(1))
+		n := notices[0]
+		Expect(n.Deprecated).To(BeTrue())
+		Expect(n.Sunset.Equal(sunset)).To(BeTrue())
+		Expect(n.Warnings).To(Equal([]string{"This endpoint is deprecated", "Use /v2", "Scope will be removed"}))
+		Expect(n.RateLimit).To(Equal(-1))
+	})
+}
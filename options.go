@@ -0,0 +1,12 @@
+package uaa
+
+// Option configures an API. Options are passed to the New* constructors, or
+// can be applied to an existing API by calling them.
+type Option func(*API)
+
+func (a *API) applyOptions(opts []Option) {
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.installContextPathDetection()
+}
@@ -0,0 +1,205 @@
+package uaa
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// OriginMigration describes the migration of users from one identity
+// provider to another, for example from the uaa provider to LDAP.
+type OriginMigration struct {
+	// From and To are the origin keys of the source and target providers.
+	From string
+	To   string
+	// Filter is a SCIM filter that further selects the source users to
+	// migrate, e.g. `userName sw "a"`. If blank, every user of From is
+	// migrated.
+	Filter string
+	// DeactivateSource deactivates each source user once their group
+	// memberships have been moved, so that they can no longer log in
+	// through the source provider.
+	DeactivateSource bool
+	// DryRun reports what the migration would do without changing the UAA.
+	DryRun bool
+}
+
+// OriginMigrationResult is the outcome of migrating one user.
+type OriginMigrationResult struct {
+	Username string `json:"userName"`
+	SourceID string `json:"sourceId"`
+	// TargetID is the ID of the user in the target origin, or "" if the
+	// user would be created in a dry run.
+	TargetID string `json:"targetId,omitempty"`
+	// Created and Updated report whether the target user was, or in a dry
+	// run would be, created or updated.
+	Created bool `json:"created,omitempty"`
+	Updated bool `json:"updated,omitempty"`
+	// Groups are the display names of the groups whose direct membership
+	// was moved from the source user to the target user.
+	Groups      []string `json:"groups,omitempty"`
+	Deactivated bool     `json:"deactivated,omitempty"`
+	// Err is the error that stopped the user's migration, in which case the
+	// other fields describe what had been done.
+	Err error `json:"-"`
+}
+
+// OriginMigrationReport is the outcome of MigrateOrigin.
+type OriginMigrationReport struct {
+	DryRun bool                    `json:"dryRun,omitempty"`
+	Users  []OriginMigrationResult `json:"users"`
+}
+
+// Failed returns the results of the users whose migration failed.
+func (r *OriginMigrationReport) Failed() []OriginMigrationResult {
+	var failed []OriginMigrationResult
+	for _, result := range r.Users {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// MigrateOrigin migrates the users of one origin to another. For each source
+// user, a user with the same username is created in the target origin, or
+// the existing target user is updated with the source user's name, emails
+// and phone numbers. The source user's direct group memberships are then
+// moved to the target user, and the source user is deactivated if
+// DeactivateSource is set. Target users are created verified and without a
+// password, so users migrated to the uaa origin must reset their passwords.
+// A user whose migration fails is reported in the result and does not stop
+// the others; an error is returned only if the source users cannot be
+// listed. Migrating again after a partial failure completes the migration.
+func (a *API) MigrateOrigin(migration OriginMigration) (*OriginMigrationReport, error) {
+	if migration.From == "" || migration.To == "" {
+		return nil, errors.New("the source and target origins cannot be blank")
+	}
+	if migration.From == migration.To {
+		return nil, fmt.Errorf("cannot migrate users from origin %s to itself", migration.From)
+	}
+	filter := fmt.Sprintf(`origin eq "%v"`, migration.From)
+	if migration.Filter != "" {
+		filter = fmt.Sprintf("%s and (%s)", filter, migration.Filter)
+	}
+	sources, err := a.ListAllUsers(filter, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OriginMigrationReport{DryRun: migration.DryRun}
+	progress := NewProgressTracker(len(sources), a.progress)
+	for _, source := range sources {
+		result := a.migrateUser(migration, source)
+		report.Users = append(report.Users, result)
+		progress.Done(result.Err)
+	}
+	return report, nil
+}
+
+func (a *API) migrateUser(migration OriginMigration, source User) OriginMigrationResult {
+	result := OriginMigrationResult{Username: source.Username, SourceID: source.ID}
+	fail := func(err error) OriginMigrationResult {
+		result.Err = err
+		return result
+	}
+
+	existing, err := a.ListAllUsers(fmt.Sprintf(`userName eq "%v" and origin eq "%v"`, source.Username, migration.To), "", "", "")
+	if err != nil {
+		return fail(err)
+	}
+	var target *User
+	if len(existing) == 0 {
+		result.Created = true
+		if !migration.DryRun {
+			if target, err = a.CreateUser(migratedUser(source, migration.To)); err != nil {
+				return fail(err)
+			}
+		}
+	} else {
+		target = &existing[0]
+		if updated, changed := mergeMigratedUser(*target, source); changed {
+			result.Updated = true
+			if !migration.DryRun {
+				if _, err = a.UpdateUser(updated); err != nil {
+					return fail(err)
+				}
+			}
+		}
+	}
+	// The target user's groups include the default groups it was created
+	// in, which it must not be added to again.
+	member := map[string]bool{}
+	if target != nil {
+		result.TargetID = target.ID
+		for _, g := range target.Groups {
+			member[g.Value] = true
+		}
+	}
+	for _, g := range source.Groups {
+		if g.Type != "" && g.Type != "DIRECT" {
+			continue
+		}
+		if !migration.DryRun {
+			if !member[g.Value] {
+				if err := a.AddGroupMember(g.Value, target.ID, "USER", migration.To); err != nil {
+					return fail(err)
+				}
+			}
+			if err := a.RemoveGroupMember(g.Value, source.ID); err != nil {
+				return fail(err)
+			}
+		}
+		result.Groups = append(result.Groups, g.Display)
+	}
+
+	if migration.DeactivateSource && (source.Active == nil || *source.Active) {
+		if !migration.DryRun {
+			version := 0
+			if source.Meta != nil {
+				version = source.Meta.Version
+			}
+			if err := a.DeactivateUser(source.ID, version); err != nil {
+				return fail(err)
+			}
+		}
+		result.Deactivated = true
+	}
+	return result
+}
+
+// migratedUser returns the user to create in the target origin.
+func migratedUser(source User, origin string) User {
+	active, verified := true, true
+	return User{
+		Username:     source.Username,
+		Origin:       origin,
+		Name:         source.Name,
+		Emails:       source.Emails,
+		PhoneNumbers: source.PhoneNumbers,
+		Active:       &active,
+		Verified:     &verified,
+	}
+}
+
+// mergeMigratedUser copies the source user's name, emails and phone numbers
+// to the target user, and reports whether any of them changed.
+func mergeMigratedUser(target User, source User) (User, bool) {
+	merged := target
+	merged.Groups = nil
+	merged.Approvals = nil
+	changed := false
+	if source.Name != nil && !reflect.DeepEqual(source.Name, target.Name) {
+		merged.Name = source.Name
+		changed = true
+	}
+	if len(source.Emails) > 0 && !reflect.DeepEqual(source.Emails, target.Emails) {
+		merged.Emails = source.Emails
+		changed = true
+	}
+	if len(source.PhoneNumbers) > 0 && !reflect.DeepEqual(source.PhoneNumbers, target.PhoneNumbers) {
+		merged.PhoneNumbers = source.PhoneNumbers
+		changed = true
+	}
+	return merged, changed
+}
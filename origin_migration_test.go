@@ -0,0 +1,135 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestOriginMigration(t *testing.T) {
+	spec.Run(t, "OriginMigration", testOriginMigration, spec.Report(report.Terminal{}))
+}
+
+func testOriginMigration(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		a        *uaa.API
+		users    map[string]uaa.User
+		requests []string
+		fail     map[string]bool
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		requests = nil
+		fail = map[string]bool{}
+		active := true
+		users = map[string]uaa.User{
+			"marcus-uaa": {ID: "marcus-uaa", Username: "marcus", Origin: "uaa", Active: &active, Meta: &uaa.Meta{Version: 3},
+				Name:   &uaa.UserName{GivenName: "Marcus"},
+				Groups: []uaa.UserGroup{{Value: "stoics-id", Display: "stoics", Type: "DIRECT"}, {Value: "uaa.user-id", Display: "uaa.user", Type: "DIRECT"}, {Value: "philosophers-id", Display: "philosophers", Type: "INDIRECT"}}},
+			"seneca-uaa":  {ID: "seneca-uaa", Username: "seneca", Origin: "uaa", Name: &uaa.UserName{GivenName: "Seneca"}},
+			"seneca-ldap": {ID: "seneca-ldap", Username: "seneca", Origin: "ldap"},
+		}
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			request := req.Method + " " + req.URL.Path
+			if req.Method != http.MethodGet {
+				requests = append(requests, request)
+			}
+			if fail[request] {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			body, _ := ioutil.ReadAll(req.Body)
+			switch req.Method {
+			case http.MethodGet:
+				filter := req.URL.Query().Get("filter")
+				matched := []uaa.User{}
+				for _, id := range []string{"marcus-uaa", "seneca-uaa", "seneca-ldap"} {
+					user, ok := users[id]
+					if ok && strings.Contains(filter, `origin eq "`+user.Origin+`"`) && (!strings.Contains(filter, "userName") || strings.Contains(filter, `"`+user.Username+`"`)) {
+						matched = append(matched, user)
+					}
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"resources": matched, "startIndex": 1, "itemsPerPage": len(matched), "totalResults": len(matched)})
+			case http.MethodPost:
+				if req.URL.Path == uaa.UsersEndpoint {
+					var user uaa.User
+					json.Unmarshal(body, &user)
+					user.ID = user.Username + "-" + user.Origin
+					user.Groups = []uaa.UserGroup{{Value: "uaa.user-id", Display: "uaa.user", Type: "DIRECT"}}
+					users[user.ID] = user
+					json.NewEncoder(w).Encode(user)
+					return
+				}
+				w.Write(body)
+			default:
+				w.Write([]byte("{}"))
+			}
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("creates or updates target users, moves groups, and deactivates the source", func() {
+		report, err := a.MigrateOrigin(uaa.OriginMigration{From: "uaa", To: "ldap", DeactivateSource: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Failed()).To(BeEmpty())
+		Expect(report.Users).To(Equal([]uaa.OriginMigrationResult{
+			{Username: "marcus", SourceID: "marcus-uaa", TargetID: "marcus-ldap", Created: true, Groups: []string{"stoics", "uaa.user"}, Deactivated: true},
+			{Username: "seneca", SourceID: "seneca-uaa", TargetID: "seneca-ldap", Updated: true, Deactivated: true},
+		}))
+		Expect(requests).To(Equal([]string{
+			"POST /Users",
+			"POST /Groups/stoics-id/members",
+			"DELETE /Groups/stoics-id/members/marcus-uaa",
+			"DELETE /Groups/uaa.user-id/members/marcus-uaa",
+			"PATCH /Users/marcus-uaa",
+			"PUT /Users",
+			"PATCH /Users/seneca-uaa",
+		}))
+		Expect(users["marcus-ldap"].Name).To(Equal(&uaa.UserName{GivenName: "Marcus"}))
+	})
+
+	it("reports what it would do in a dry run", func() {
+		report, err := a.MigrateOrigin(uaa.OriginMigration{From: "uaa", To: "ldap", Filter: `userName eq "marcus"`, DryRun: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.DryRun).To(BeTrue())
+		Expect(report.Users[0]).To(Equal(uaa.OriginMigrationResult{Username: "marcus", SourceID: "marcus-uaa", Created: true, Groups: []string{"stoics", "uaa.user"}}))
+		Expect(requests).To(BeEmpty())
+	})
+
+	it("reports a failed user and continues", func() {
+		fail["POST /Users"] = true
+		report, err := a.MigrateOrigin(uaa.OriginMigration{From: "uaa", To: "ldap"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Users).To(HaveLen(2))
+		Expect(report.Failed()).To(HaveLen(1))
+		Expect(report.Failed()[0].Username).To(Equal("marcus"))
+		Expect(report.Users[1].Updated).To(BeTrue())
+	})
+
+	it("refuses to migrate an origin to itself", func() {
+		_, err := a.MigrateOrigin(uaa.OriginMigration{From: "uaa", To: "uaa"})
+		Expect(err).To(MatchError("cannot migrate users from origin uaa to itself"))
+	})
+}
@@ -0,0 +1,163 @@
+package uaa
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRateLimited is the cause of the error returned when the UAA responds
+// with 429 Too Many Requests, after any retries made by the API's Pacer. Use
+// errors.Cause from github.com/pkg/errors to compare against it.
+var ErrRateLimited = errors.New("the UAA is rate limiting requests")
+
+// Default pacer settings.
+const (
+	DefaultPacerMaxRetries   = 5
+	DefaultPacerInitialDelay = time.Second
+	DefaultPacerMaxDelay     = time.Minute
+)
+
+// Pacer slows requests down when the UAA's rate limiting responds with 429
+// Too Many Requests, so that bulk jobs pace themselves rather than failing
+// part way through. A throttled request is retried up to MaxRetries times,
+// after the delay given by the response's Retry-After header or, if there is
+// none, after a delay that starts at InitialDelay and doubles with each retry
+// up to MaxDelay. While the delay lasts, other requests made through the
+// Pacer wait too. Requests whose bodies cannot be replayed are not retried.
+//
+// A Pacer is safe for concurrent use and may be shared by several APIs that
+// talk to the same UAA.
+type Pacer struct {
+	MaxRetries   int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+
+	mu    sync.Mutex
+	until time.Time
+	stats PacerStats
+}
+
+// PacerStats counts the work done by a Pacer.
+type PacerStats struct {
+	// Throttled is the number of 429 responses.
+	Throttled int
+	// Retried is the number of requests that were retried.
+	Retried int
+	// Waited is the total time requests have waited.
+	Waited time.Duration
+}
+
+// NewPacer returns a Pacer with the default settings.
+func NewPacer() *Pacer {
+	return &Pacer{
+		MaxRetries:   DefaultPacerMaxRetries,
+		InitialDelay: DefaultPacerInitialDelay,
+		MaxDelay:     DefaultPacerMaxDelay,
+	}
+}
+
+// WithPacer returns an Option that sends the API's requests through the given
+// pacer.
+func WithPacer(p *Pacer) Option {
+	return func(a *API) {
+		a.pacer = p
+	}
+}
+
+// Stats returns the pacer's counters.
+func (p *Pacer) Stats() PacerStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// wait blocks until any delay imposed by a throttled response has passed.
+func (p *Pacer) wait(clock Clock) {
+	p.mu.Lock()
+	delay := p.until.Sub(clock.Now())
+	if delay > 0 {
+		p.stats.Waited += delay
+	}
+	p.mu.Unlock()
+	if delay > 0 {
+		clock.Sleep(delay)
+	}
+}
+
+// throttled records a 429 response to the given attempt and returns whether
+// the request should be retried. If so, the requests that follow wait for the
+// delay.
+func (p *Pacer) throttled(resp *http.Response, attempt int, clock Clock) bool {
+	delay := parseRetryAfter(resp.Header.Get("Retry-After"), clock.Now())
+	if delay == 0 {
+		delay = p.InitialDelay << uint(attempt)
+		if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+			delay = p.MaxDelay
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Throttled++
+	if attempt >= p.MaxRetries {
+		return false
+	}
+	p.stats.Retried++
+	if until := clock.Now().Add(delay); until.After(p.until) {
+		p.until = until
+	}
+	return true
+}
+
+// sendPaced sends the request with send, retrying it through the pacer while
+// the UAA responds with 429.
+func (a *API) sendPaced(req *http.Request, send func() (*http.Response, error)) (*http.Response, error) {
+	if a.pacer == nil {
+		return send()
+	}
+	for attempt := 0; ; attempt++ {
+		a.pacer.wait(a.getClock())
+		resp, err := send()
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+		if !a.pacer.throttled(resp, attempt, a.getClock()) || !rewindBody(req) {
+			return resp, nil
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// rewindBody resets the request's body so that it can be sent again, and
+// returns false if it cannot be.
+func rewindBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	req.Body = body
+	return true
+}
+
+// statusError returns the error for an unsuccessful response.
+func statusError(req *http.Request, resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return errors.Wrapf(ErrRateLimited, "An error occurred while calling %s", req.URL.String())
+	}
+	if isInvalidToken(resp) {
+		return errors.Wrapf(ErrInvalidToken, "An error occurred while calling %s", req.URL.String())
+	}
+	return errors.Wrapf(unexpectedResponse(resp), "An unknown error occurred while calling %s", req.URL.String())
+}
@@ -0,0 +1,112 @@
+package uaa_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestPacer(t *testing.T) {
+	spec.Run(t, "Pacer", testPacer, spec.Report(report.Terminal{}))
+}
+
+func testPacer(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s          *httptest.Server
+		a          *uaa.API
+		p          *uaa.Pacer
+		throttle   int
+		retryAfter string
+		called     int
+		bodies     []string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		throttle, called, retryAfter, bodies = 0, 0, "", nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			called++
+			body, _ := ioutil.ReadAll(req.Body)
+			bodies = append(bodies, string(body))
+			if called <= throttle {
+				if retryAfter != "" {
+					w.Header().Set("Retry-After", retryAfter)
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte(`{"error":"too_many_requests"}`))
+				return
+			}
+			w.Write([]byte(`{"id":"user-id","userName":"marcus"}`))
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+		p = &uaa.Pacer{MaxRetries: 3, InitialDelay: 5 * time.Millisecond, MaxDelay: 20 * time.Millisecond}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("returns ErrRateLimited without a pacer", func() {
+		throttle = 1
+		_, err := a.GetUser("user-id")
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrRateLimited))
+		Expect(called).To(Equal(1))
+	})
+
+	it("retries throttled requests after backing off", func() {
+		throttle = 2
+		uaa.WithPacer(p)(a)
+		start := time.Now()
+		user, err := a.GetUser("user-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("marcus"))
+		Expect(called).To(Equal(3))
+		Expect(time.Since(start)).To(BeNumerically(">=", 15*time.Millisecond))
+		stats := p.Stats()
+		Expect(stats.Throttled).To(Equal(2))
+		Expect(stats.Retried).To(Equal(2))
+		Expect(stats.Waited).To(BeNumerically(">", 0))
+	})
+
+	it("honors Retry-After", func() {
+		throttle = 1
+		retryAfter = "1"
+		p.MaxDelay = time.Millisecond
+		uaa.WithPacer(p)(a)
+		start := time.Now()
+		_, err := a.GetUser("user-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically(">=", 900*time.Millisecond))
+	})
+
+	it("replays request bodies", func() {
+		throttle = 1
+		uaa.WithPacer(p)(a)
+		_, err := a.CreateUser(uaa.User{Username: "marcus"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bodies).To(HaveLen(2))
+		Expect(bodies[1]).To(Equal(bodies[0]))
+		Expect(bodies[0]).To(ContainSubstring("marcus"))
+	})
+
+	it("gives up after the maximum retries", func() {
+		throttle = 10
+		uaa.WithPacer(p)(a)
+		_, err := a.GetUser("user-id")
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrRateLimited))
+		Expect(called).To(Equal(4))
+		Expect(p.Stats().Throttled).To(Equal(4))
+		Expect(p.Stats().Retried).To(Equal(3))
+	})
+}
@@ -1,8 +1,80 @@
 package uaa
 
+import "sync"
+
 // Page represents a page of information returned from the UAA API.
 type Page struct {
 	StartIndex   int `json:"startIndex"`
 	ItemsPerPage int `json:"itemsPerPage"`
 	TotalResults int `json:"totalResults"`
 }
+
+// WithPageConcurrency makes the ListAll* methods fetch the pages after the
+// first concurrently, using up to n requests at a time, once the first page
+// has reported the total number of results. The results are returned in the
+// same order as when the pages are fetched one at a time. An n of 1 or less
+// fetches the pages one at a time, which is the default.
+func WithPageConcurrency(n int) Option {
+	return func(a *API) {
+		a.pageConcurrency = n
+	}
+}
+
+// remainingPages returns the start indexes of the pages that follow the given
+// page.
+func remainingPages(page Page) []int {
+	var starts []int
+	if page.ItemsPerPage <= 0 {
+		return starts
+	}
+	for start := page.StartIndex + page.ItemsPerPage; start <= page.TotalResults; start += page.ItemsPerPage {
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+// fetchPages calls fetch with the position and start index of each of the
+// pages, using up to a.pageConcurrency goroutines at a time. Once a fetch
+// fails or the deadline has passed no further pages are started. It returns
+// the number of pages that were started, all of which have finished, and the
+// first error.
+func (a *API) fetchPages(starts []int, deadlinePassed func() bool, fetch func(i int, startIndex int) error) (int, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		started  int
+	)
+	sem := make(chan struct{}, a.pageConcurrency)
+	for i, start := range starts {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+
+		sem <- struct{}{}
+		if deadlinePassed() {
+			<-sem
+			break
+		}
+		started++
+		wg.Add(1)
+		go func(i int, start int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fetch(i, start); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(i, start)
+	}
+	wg.Wait()
+	return started, firstErr
+}
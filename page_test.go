@@ -0,0 +1,99 @@
+package uaa_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestPageConcurrency(t *testing.T) {
+	spec.Run(t, "PageConcurrency", testPageConcurrency, spec.Report(report.Terminal{}))
+}
+
+func testPageConcurrency(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		a        *uaa.API
+		mu       sync.Mutex
+		inFlight int
+		maxSeen  int
+		failAt   string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		inFlight, maxSeen, failAt = 0, 0, ""
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxSeen {
+				maxSeen = inFlight
+			}
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			}()
+
+			start, _ := strconv.Atoi(req.URL.Query().Get("startIndex"))
+			if req.URL.Query().Get("startIndex") == failAt {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			// Later pages respond sooner, so that they complete out of order.
+			time.Sleep(time.Duration(10-start) * 5 * time.Millisecond)
+
+			// The server caps the page size at 2.
+			var users []interface{}
+			for i := start; i < start+2 && i <= 9; i++ {
+				users = append(users, uaa.User{ID: fmt.Sprintf("user-%d", i)})
+			}
+			w.Write([]byte(MultiPaginatedResponse(start, 2, 9, users...)))
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("fetches the remaining pages concurrently and keeps their order", func() {
+		uaa.WithPageConcurrency(3)(a)
+		users, err := a.ListAllUsers("", "", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(9))
+		for i, user := range users {
+			Expect(user.ID).To(Equal(fmt.Sprintf("user-%d", i+1)))
+		}
+		Expect(maxSeen).To(BeNumerically(">", 1))
+		Expect(maxSeen).To(BeNumerically("<=", 3))
+	})
+
+	it("fetches one page at a time by default", func() {
+		users, err := a.ListAllUsers("", "", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(9))
+		Expect(maxSeen).To(Equal(1))
+	})
+
+	it("returns the error when a page fails", func() {
+		failAt = "5"
+		uaa.WithPageConcurrency(4)(a)
+		users, err := a.ListAllUsers("", "", "", "")
+		Expect(err).To(HaveOccurred())
+		Expect(users).To(BeNil())
+	})
+}
@@ -0,0 +1,206 @@
+package uaa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Pagination is the way that a list endpoint divides its results into pages.
+// SCIMPagination covers the UAA's SCIM endpoints, such as UsersEndpoint;
+// LinkPagination and TokenPagination cover endpoints that point to their next
+// page in a Link header or a field of the response.
+type Pagination interface {
+	// first sets the query parameters for the first page.
+	first(query url.Values)
+	// next returns the URL of the page after the one at current, given the
+	// page's response, or nil if it was the last page. If asGiven is true,
+	// the URL is the UAA's own and its path is not rewritten.
+	next(current *url.URL, header http.Header, body []byte) (next *url.URL, asGiven bool, err error)
+}
+
+// SCIMPagination returns the Pagination of the UAA's SCIM lists, which are
+// paged with the startIndex and count parameters, count results at a time.
+// A count of zero or less requests 100.
+func SCIMPagination(count int) Pagination {
+	if count <= 0 {
+		count = 100
+	}
+	return scimPagination{count: count}
+}
+
+type scimPagination struct {
+	count int
+}
+
+func (p scimPagination) first(query url.Values) {
+	query.Set("startIndex", "1")
+	query.Set("count", strconv.Itoa(p.count))
+}
+
+func (p scimPagination) next(current *url.URL, header http.Header, body []byte) (*url.URL, bool, error) {
+	list := struct {
+		Page
+		Resources []json.RawMessage `json:"resources"`
+	}{}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, false, err
+	}
+	if len(list.Resources) == 0 || list.StartIndex+list.ItemsPerPage > list.TotalResults {
+		return nil, false, nil
+	}
+	next := *current
+	query := next.Query()
+	query.Set("startIndex", strconv.Itoa(list.StartIndex+list.ItemsPerPage))
+	next.RawQuery = query.Encode()
+	return &next, false, nil
+}
+
+// LinkPagination returns the Pagination of endpoints that give the URL of the
+// next page in a Link header with the relation "next", as described in RFC
+// 8288. The next page must be on the same host as the first.
+func LinkPagination() Pagination {
+	return linkPagination{}
+}
+
+type linkPagination struct{}
+
+func (linkPagination) first(url.Values) {}
+
+func (linkPagination) next(current *url.URL, header http.Header, body []byte) (*url.URL, bool, error) {
+	link := nextLink(header)
+	if link == "" {
+		return nil, false, nil
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "the next page link %q is invalid", link)
+	}
+	next := current.ResolveReference(ref)
+	if next.Scheme != current.Scheme || next.Host != current.Host {
+		return nil, false, fmt.Errorf("the next page %s is not on the UAA at %s", next.String(), current.Host)
+	}
+	return next, true, nil
+}
+
+// nextLink returns the target of the Link header's "next" relation, or "" if
+// there is none.
+func nextLink(header http.Header) string {
+	for _, value := range header["Link"] {
+		for _, link := range strings.Split(value, ",") {
+			link = strings.TrimSpace(link)
+			end := strings.Index(link, ">")
+			if !strings.HasPrefix(link, "<") || end < 0 {
+				continue
+			}
+			for _, param := range strings.Split(link[end+1:], ";") {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(strings.ToLower(param), "rel=") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(param[len("rel="):], `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return link[1:end]
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// TokenPagination returns the Pagination of endpoints that return a token for
+// the next page in the given top-level field of the response, and expect it
+// back in the given query parameter. The last page has no token.
+func TokenPagination(field string, param string) Pagination {
+	return tokenPagination{field: field, param: param}
+}
+
+type tokenPagination struct {
+	field string
+	param string
+}
+
+func (tokenPagination) first(url.Values) {}
+
+func (p tokenPagination) next(current *url.URL, header http.Header, body []byte) (*url.URL, bool, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, false, err
+	}
+	var token string
+	if raw, ok := fields[p.field]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &token); err != nil {
+			return nil, false, errors.Wrapf(err, "the %s field is not a string", p.field)
+		}
+	}
+	if token == "" {
+		return nil, false, nil
+	}
+	next := *current
+	query := next.Query()
+	query.Set(p.param, token)
+	next.RawQuery = query.Encode()
+	return &next, false, nil
+}
+
+// EachPage calls fn with the body of each page of the list at path, reading
+// the pages one at a time in the way given by pagination, or with
+// SCIMPagination(100) if pagination is nil. The query, which may be nil, is
+// sent with the first page. EachPage stops at the first error, including one
+// returned by fn, and returns it.
+//
+// EachPage lets callers page through endpoints that the API has no method
+// for; the API's ListAll* and Each* methods page through the SCIM lists.
+func (a *API) EachPage(path string, query url.Values, pagination Pagination, fn func(page []byte) error) error {
+	if pagination == nil {
+		pagination = SCIMPagination(0)
+	}
+	params := url.Values{}
+	for key, values := range query {
+		params[key] = values
+	}
+	pagination.first(params)
+	u := urlWithPath(*a.TargetURL, path)
+	u.RawQuery = params.Encode()
+
+	current, asGiven := &u, false
+	for {
+		req, err := http.NewRequest(http.MethodGet, current.String(), nil)
+		if err != nil {
+			return err
+		}
+		if asGiven {
+			req = req.WithContext(context.WithValue(req.Context(), pathsRewrittenKey{}, true))
+		}
+		header, body, err := a.doAndReadWithHeader(req, true)
+		if err != nil {
+			return err
+		}
+		if err := fn(body); err != nil {
+			return err
+		}
+		if header == nil {
+			// The request was recorded in dry-run mode.
+			return nil
+		}
+
+		next, nextAsGiven, err := pagination.next(current, header, body)
+		if err != nil {
+			return errors.Wrapf(err, "An unknown error occurred while parsing response from %s", current.String())
+		}
+		if next == nil {
+			return nil
+		}
+		if next.String() == current.String() {
+			return fmt.Errorf("the UAA returned %s as the page after itself", current.String())
+		}
+		current, asGiven = next, nextAsGiven
+	}
+}
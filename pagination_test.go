@@ -0,0 +1,153 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestPagination(t *testing.T) {
+	spec.Run(t, "Pagination", testPagination, spec.Report(report.Terminal{}))
+}
+
+func testPagination(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		handler http.HandlerFunc
+		queries []string
+		a       *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		queries = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			queries = append(queries, req.URL.RawQuery)
+			handler(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	collect := func(path string, query url.Values, pagination uaa.Pagination) ([]string, error) {
+		var pages []string
+		err := a.EachPage(path, query, pagination, func(page []byte) error {
+			pages = append(pages, string(page))
+			return nil
+		})
+		return pages, err
+	}
+
+	when("the endpoint is a SCIM list", func() {
+		it.Before(func() {
+			handler = func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.URL.Path).To(Equal("/Groups"))
+				start := req.URL.Query().Get("startIndex")
+				if start == "1" {
+					w.Write([]byte(`{"resources":[{"id":"a"},{"id":"b"}],"startIndex":1,"itemsPerPage":2,"totalResults":3}`))
+					return
+				}
+				w.Write([]byte(`{"resources":[{"id":"c"}],"startIndex":3,"itemsPerPage":1,"totalResults":3}`))
+			}
+		})
+
+		it("pages with startIndex and count", func() {
+			pages, err := collect("/Groups", url.Values{"filter": {`displayName sw "a"`}}, uaa.SCIMPagination(2))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pages).To(HaveLen(2))
+			Expect(queries).To(Equal([]string{
+				"count=2&filter=displayName+sw+%22a%22&startIndex=1",
+				"count=2&filter=displayName+sw+%22a%22&startIndex=3",
+			}))
+		})
+
+		it("uses SCIM pagination by default", func() {
+			pages, err := collect("/Groups", nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pages).To(HaveLen(2))
+			Expect(queries[0]).To(Equal("count=100&startIndex=1"))
+		})
+	})
+
+	when("the endpoint links to its next page", func() {
+		it("follows the links", func() {
+			handler = func(w http.ResponseWriter, req *http.Request) {
+				switch req.URL.Query().Get("page") {
+				case "":
+					w.Header().Add("Link", `</oauth/token/list?page=2>; rel="next", </oauth/token/list>; rel="first"`)
+				case "2":
+					w.Header().Add("Link", fmt.Sprintf(`<%s/oauth/token/list?page=3>; rel="prev next"`, s.URL))
+				}
+				json.NewEncoder(w).Encode([]string{req.URL.Query().Get("page")})
+			}
+			pages, err := collect("/oauth/token/list", nil, uaa.LinkPagination())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pages).To(HaveLen(3))
+			Expect(queries).To(Equal([]string{"", "page=2", "page=3"}))
+		})
+
+		it("does not follow links to other hosts", func() {
+			handler = func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Add("Link", `<https://evil.example.com/steal>; rel="next"`)
+				w.Write([]byte(`[]`))
+			}
+			_, err := collect("/oauth/token/list", nil, uaa.LinkPagination())
+			Expect(err).To(MatchError(ContainSubstring("is not on the UAA")))
+			Expect(queries).To(HaveLen(1))
+		})
+	})
+
+	when("the endpoint returns a token for its next page", func() {
+		it("sends the token back", func() {
+			handler = func(w http.ResponseWriter, req *http.Request) {
+				switch req.URL.Query().Get("page_token") {
+				case "":
+					w.Write([]byte(`{"items":[1],"next_page_token":"abc"}`))
+				case "abc":
+					w.Write([]byte(`{"items":[2],"next_page_token":null}`))
+				}
+			}
+			pages, err := collect("/list", url.Values{"size": {"1"}}, uaa.TokenPagination("next_page_token", "page_token"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pages).To(HaveLen(2))
+			Expect(queries).To(Equal([]string{"size=1", "page_token=abc&size=1"}))
+		})
+
+		it("stops when the same page comes back", func() {
+			handler = func(w http.ResponseWriter, req *http.Request) {
+				w.Write([]byte(`{"next_page_token":"abc"}`))
+			}
+			_, err := collect("/list", nil, uaa.TokenPagination("next_page_token", "page_token"))
+			Expect(err).To(MatchError(ContainSubstring("as the page after itself")))
+			Expect(queries).To(HaveLen(2))
+		})
+	})
+
+	it("returns the callback's error", func() {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"resources":[{"id":"a"}],"startIndex":1,"itemsPerPage":1,"totalResults":2}`))
+		}
+		err := a.EachPage("/Groups", nil, nil, func([]byte) error {
+			return fmt.Errorf("stop")
+		})
+		Expect(err).To(MatchError("stop"))
+		Expect(queries).To(HaveLen(1))
+	})
+}
@@ -0,0 +1,78 @@
+package uaa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudfoundry-community/go-uaa/passwordcredentials"
+	"golang.org/x/oauth2"
+)
+
+// PasscodeURL returns the URL of the UAA page on which users who log in with
+// a single sign-on provider obtain a one-time passcode.
+func (a *API) PasscodeURL() string {
+	u := urlWithPath(*a.TargetURL, "/passcode")
+	return u.String()
+}
+
+// NewWithPasscode builds an API that uses a one-time passcode, which a user
+// obtains from the UAA's passcode page after logging in with any identity
+// provider, to get a token for use with the UAA API. The passcode is
+// exchanged for a token immediately, since it can only be used once; the
+// token is then refreshed with its refresh token.
+func NewWithPasscode(target string, zoneID string, clientID string, clientSecret string, passcode string, tokenFormat TokenFormat, opts ...Option) (*API, error) {
+	if err := tokenFormat.Validate(); err != nil {
+		return nil, err
+	}
+	if passcode == "" {
+		return nil, errors.New("passcode cannot be blank")
+	}
+	u, err := BuildTargetURL(target)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Transport: http.DefaultTransport}
+	a := &API{
+		UnauthenticatedClient: client,
+		TargetURL:             u,
+		ZoneID:                zoneID,
+	}
+	a.applyOptions(opts)
+
+	tokenURL := urlWithPath(*u, "/oauth/token")
+	params := url.Values{}
+	for key, values := range a.tokenParams {
+		params[key] = values
+	}
+	params.Set("token_format", tokenFormat.String())
+	params.Set("passcode", passcode)
+	p := &passwordcredentials.Config{
+		ClientID:       clientID,
+		ClientSecret:   clientSecret,
+		Endpoint:       oauth2.Endpoint{TokenURL: tokenURL.String()},
+		EndpointParams: params,
+	}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, a.UnauthenticatedClient)
+	t, err := p.TokenSource(ctx).Token()
+	if err != nil {
+		if credentialsErr := badCredentials(err); credentialsErr != nil {
+			return nil, credentialsErr
+		}
+		return nil, err
+	}
+
+	refreshURL := tokenURL
+	refreshURL.RawQuery = url.Values{"token_format": {tokenFormat.String()}}.Encode()
+	c := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     oauth2.Endpoint{TokenURL: refreshURL.String()},
+	}
+	a.AuthenticatedClient = c.Client(ctx, t)
+	a.requestToken = refreshTokenRequester(ctx, a, c)
+	a.installExpiryTokenSource(refreshWithToken(ctx, c))
+	return a, nil
+}
@@ -0,0 +1,80 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestPasscode(t *testing.T) {
+	spec.Run(t, "Passcode", testPasscode, spec.Report(report.Terminal{}))
+}
+
+func testPasscode(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s     *httptest.Server
+		forms []url.Values
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		forms = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.ParseForm()).To(Succeed())
+			forms = append(forms, req.PostForm)
+			w.Header().Set("Content-Type", "application/json")
+			if req.PostForm.Get("passcode") == "expired" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"unauthorized","error_description":"Invalid passcode"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "token-" + req.PostForm.Get("grant_type"),
+				"refresh_token": "refresh-token",
+				"token_type":    "bearer",
+				"expires_in":    3600,
+			})
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("exchanges the passcode and refreshes with the refresh token", func() {
+		clock := uaa.NewFakeClock()
+		api, err := uaa.NewWithPasscode(s.URL, "", "cf", "", "abc123", uaa.OpaqueToken, uaa.WithClock(clock))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(forms).To(HaveLen(1))
+		Expect(forms[0].Get("grant_type")).To(Equal("password"))
+		Expect(forms[0].Get("passcode")).To(Equal("abc123"))
+		Expect(forms[0]).NotTo(HaveKey("username"))
+		Expect(forms[0]).NotTo(HaveKey("password"))
+
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		clock.Advance(2 * time.Hour)
+		token, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("token-refresh_token"))
+		Expect(forms[1].Get("refresh_token")).To(Equal("refresh-token"))
+	})
+
+	it("rejects an invalid passcode", func() {
+		_, err := uaa.NewWithPasscode(s.URL, "", "cf", "", "expired", uaa.OpaqueToken)
+		Expect(err).To(Equal(uaa.ErrBadCredentials{Code: "unauthorized", Description: "Invalid passcode"}))
+	})
+
+	it("returns the passcode page", func() {
+		u, _ := url.Parse("https://login.example.com")
+		Expect((&uaa.API{TargetURL: u}).PasscodeURL()).To(Equal("https://login.example.com/passcode"))
+	})
+}
@@ -52,7 +52,7 @@ func retrieveToken(ctx context.Context, ClientID, ClientSecret, TokenURL string,
 		return nil, fmt.Errorf("oauth2: cannot fetch token: %v", err)
 	}
 	if code := r.StatusCode; code < 200 || code > 299 {
-		return nil, fmt.Errorf("oauth2: cannot fetch token: %v\nResponse: %s", r.Status, body)
+		return nil, &oauth2.RetrieveError{Response: r, Body: body}
 	}
 
 	var token *internalToken
@@ -252,6 +252,12 @@ func (c *tokenSource) Token() (*oauth2.Token, error) {
 		"username":   {c.conf.Username},
 		"password":   {c.conf.Password},
 	}
+	// Servers such as the UAA accept other credentials in place of a
+	// username and password, supplied with EndpointParams.
+	if c.conf.Username == "" && c.conf.Password == "" {
+		v.Del("username")
+		v.Del("password")
+	}
 	if len(c.conf.Scopes) > 0 {
 		v.Set("scope", strings.Join(c.conf.Scopes, " "))
 	}
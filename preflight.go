@@ -0,0 +1,165 @@
+package uaa
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// PreflightStage is the step of a preflight check that failed.
+type PreflightStage string
+
+// Valid PreflightStage values.
+const (
+	PreflightDNS            = PreflightStage("dns")
+	PreflightConnect        = PreflightStage("connect")
+	PreflightTLS            = PreflightStage("tls")
+	PreflightInfo           = PreflightStage("info")
+	PreflightAuthentication = PreflightStage("authentication")
+)
+
+var preflightHints = map[PreflightStage]string{
+	PreflightDNS:            "check the target's host name",
+	PreflightConnect:        "check the target's port and that the UAA is running and reachable",
+	PreflightTLS:            "check the UAA's certificate, or skip SSL validation if it is self-signed",
+	PreflightInfo:           "check that the target is a UAA",
+	PreflightAuthentication: "check the client and user credentials",
+}
+
+// PreflightError is returned by Validate, identifying the step that failed
+// and the underlying error.
+type PreflightError struct {
+	Stage PreflightStage
+	URL   string
+	Err   error
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("%s check of %s failed (%s): %v", e.Stage, e.URL, preflightHints[e.Stage], e.Err)
+}
+
+// Cause returns the underlying error.
+func (e *PreflightError) Cause() error {
+	return e.Err
+}
+
+// Validate checks that the API can reach and authenticate to the UAA, so that
+// tools can report a helpful error when a UAA is targeted rather than on the
+// first real call. It requests the UAA's /info and then a token, and returns
+// a *PreflightError classifying the first failure as a DNS, connection, TLS,
+// /info or authentication failure.
+func (a *API) Validate(ctx context.Context) error {
+	u := urlWithPath(*a.TargetURL, "/info")
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if a.ZoneID != "" {
+		req.Header.Set("X-Identity-Zone-Id", a.ZoneID)
+	}
+
+	client := a.unauthenticatedClient()
+	resp, err := a.send(client, req)
+	if err != nil {
+		return &PreflightError{Stage: classifyNetworkError(err), URL: u.String(), Err: err}
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &PreflightError{Stage: PreflightConnect, URL: u.String(), Err: err}
+	}
+	if !is2XX(resp.StatusCode) {
+		return &PreflightError{Stage: PreflightInfo, URL: u.String(), Err: fmt.Errorf("the response status was %v", resp.Status)}
+	}
+	if err := json.Unmarshal(body, &Info{}); err != nil {
+		return &PreflightError{Stage: PreflightInfo, URL: u.String(), Err: parseError(err, u.String(), body)}
+	}
+
+	tokenURL := urlWithPath(*a.TargetURL, "/oauth/token")
+	token, err := a.tokenWithContext(ctx)
+	if err != nil {
+		stage := classifyNetworkError(err)
+		if stage == PreflightConnect && !isNetworkError(err) {
+			stage = PreflightAuthentication
+		}
+		return &PreflightError{Stage: stage, URL: tokenURL.String(), Err: err}
+	}
+	if !token.Valid() {
+		return &PreflightError{Stage: PreflightAuthentication, URL: tokenURL.String(), Err: fmt.Errorf("the token expired at %v", token.Expiry.Format(time.RFC3339))}
+	}
+	return nil
+}
+
+// tokenWithContext returns the API's token, giving up when the context is
+// done.
+func (a *API) tokenWithContext(ctx context.Context) (*oauth2.Token, error) {
+	type result struct {
+		token *oauth2.Token
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		token, err := a.Token()
+		done <- result{token, err}
+	}()
+	select {
+	case r := <-done:
+		return r.token, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// classifyNetworkError returns the stage at which a request failed, looking
+// through the errors wrapped by err. Errors that are not recognized are
+// classified as connection failures.
+func classifyNetworkError(err error) PreflightStage {
+	for err != nil {
+		switch e := err.(type) {
+		case *net.DNSError:
+			return PreflightDNS
+		case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError, tls.RecordHeaderError:
+			return PreflightTLS
+		case *url.Error:
+			err = e.Err
+		case *net.OpError:
+			err = e.Err
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			return PreflightConnect
+		}
+	}
+	return PreflightConnect
+}
+
+// isNetworkError returns true if err was returned by the transport rather
+// than describing a response.
+func isNetworkError(err error) bool {
+	for err != nil {
+		switch e := err.(type) {
+		case *url.Error, *net.OpError, net.Error:
+			return true
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Cause() error }:
+			err = e.Cause()
+		default:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package uaa_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestPreflight(t *testing.T) {
+	spec.Run(t, "Preflight", testPreflight, spec.Report(report.Terminal{}))
+}
+
+func testPreflight(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s          *httptest.Server
+		infoStatus int
+		tokenBody  string
+	)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/info":
+			w.WriteHeader(infoStatus)
+			w.Write([]byte(InfoResponseJSON))
+		case "/oauth/token":
+			w.Header().Set("Content-Type", "application/json")
+			if tokenBody == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"invalid_client","error_description":"Bad credentials"}`))
+				return
+			}
+			w.Write([]byte(tokenBody))
+		}
+	})
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		infoStatus = http.StatusOK
+		tokenBody = `{"access_token":"token","token_type":"bearer","expires_in":3600}`
+		s = httptest.NewServer(handler)
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	stageOf := func(err error) uaa.PreflightStage {
+		Expect(err).To(BeAssignableToTypeOf(&uaa.PreflightError{}))
+		return err.(*uaa.PreflightError).Stage
+	}
+
+	it("succeeds when the UAA is reachable and the credentials are good", func() {
+		api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.OpaqueToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(api.Validate(context.Background())).To(Succeed())
+	})
+
+	it("classifies bad credentials", func() {
+		tokenBody = ""
+		api, _ := uaa.NewWithClientCredentials(s.URL, "", "client", "wrong", uaa.OpaqueToken)
+		err := api.Validate(context.Background())
+		Expect(stageOf(err)).To(Equal(uaa.PreflightAuthentication))
+		Expect(err.Error()).To(ContainSubstring("check the client and user credentials"))
+	})
+
+	it("classifies a target that is not a UAA", func() {
+		infoStatus = http.StatusNotFound
+		api, _ := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.OpaqueToken)
+		Expect(stageOf(api.Validate(context.Background()))).To(Equal(uaa.PreflightInfo))
+	})
+
+	it("classifies a refused connection", func() {
+		closed := httptest.NewServer(handler)
+		closed.Close()
+		api, _ := uaa.NewWithClientCredentials(closed.URL, "", "client", "secret", uaa.OpaqueToken)
+		Expect(stageOf(api.Validate(context.Background()))).To(Equal(uaa.PreflightConnect))
+	})
+
+	it("classifies an unresolvable host", func() {
+		dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no such host", Name: "uaa.example.invalid"}}
+		}
+		api, _ := uaa.NewWithClientCredentials("https://uaa.example.invalid", "", "client", "secret", uaa.OpaqueToken, uaa.WithDialContext(dial))
+		Expect(stageOf(api.Validate(context.Background()))).To(Equal(uaa.PreflightDNS))
+	})
+
+	it("classifies an untrusted certificate", func() {
+		tlsServer := httptest.NewTLSServer(handler)
+		defer tlsServer.Close()
+		api, _ := uaa.NewWithClientCredentials(tlsServer.URL, "", "client", "secret", uaa.OpaqueToken)
+		Expect(stageOf(api.Validate(context.Background()))).To(Equal(uaa.PreflightTLS))
+	})
+}
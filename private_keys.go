@@ -0,0 +1,179 @@
+package uaa
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+)
+
+// LoadPrivateKey reads an RSA or EC private key from the file at path, which
+// may hold either a PEM block or a JWK. See ParsePrivateKeyPEM and
+// ParsePrivateKeyJWK.
+func LoadPrivateKey(path string, passphrase []byte) (crypto.Signer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return ParsePrivateKeyJWK(trimmed)
+	}
+	return ParsePrivateKeyPEM(data, passphrase)
+}
+
+// ParsePrivateKeyPEM parses the first PEM block in data as an RSA or EC
+// private key, in PKCS #1, SEC 1, or PKCS #8 form. A block encrypted with the
+// legacy OpenSSL scheme ("Proc-Type: 4,ENCRYPTED") is decrypted with the
+// passphrase. That scheme is insecure: it derives the key from the passphrase
+// with a single round of MD5 and has no integrity check, so a wrong passphrase
+// is not always detected and may instead surface as an error parsing the key.
+// Prefer an unencrypted key kept in a protected file or secret store.
+// Encrypted PKCS #8 ("ENCRYPTED PRIVATE KEY") is not supported; convert such a
+// key with "openssl pkcs8 -in key.pem -out plain.pem" first.
+func ParsePrivateKeyPEM(data []byte, passphrase []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	der := block.Bytes
+	if x509.IsEncryptedPEMBlock(block) {
+		if len(passphrase) == 0 {
+			return nil, errors.New("the private key is encrypted and no passphrase was given")
+		}
+		var err error
+		if der, err = x509.DecryptPEMBlock(block, passphrase); err != nil {
+			return nil, fmt.Errorf("cannot decrypt the private key: %v", err)
+		}
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(der)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(der)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		switch signer.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey:
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported private key type %T, must be RSA or EC", key)
+	case "ENCRYPTED PRIVATE KEY":
+		return nil, errors.New("encrypted PKCS #8 private keys are not supported; decrypt the key with openssl pkcs8 first")
+	}
+	return nil, fmt.Errorf("unsupported PEM block type %q, must be a private key", block.Type)
+}
+
+// privateJWK holds the members of an RSA or EC private JWK
+// (https://tools.ietf.org/html/rfc7518#section-6).
+type privateJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N  string `json:"n,omitempty"`
+	E  string `json:"e,omitempty"`
+	D  string `json:"d,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// ParsePrivateKeyJWK parses an RSA or EC private key from a JWK. Encrypted
+// (JWE) keys are not supported.
+func ParsePrivateKeyJWK(data []byte) (crypto.Signer, error) {
+	if bytes.Count(bytes.TrimSpace(data), []byte(".")) == 4 {
+		return nil, errors.New("encrypted (JWE) keys are not supported")
+	}
+	var jwk privateJWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("the key is not a JWK: %v", err)
+	}
+	if jwk.D == "" {
+		return nil, errors.New("the JWK is not a private key")
+	}
+	ints := func(values ...string) ([]*big.Int, error) {
+		result := make([]*big.Int, len(values))
+		for i, value := range values {
+			b, err := decodeSegment(value)
+			if err != nil {
+				return nil, fmt.Errorf("malformed JWK: %v", err)
+			}
+			result[i] = new(big.Int).SetBytes(b)
+		}
+		return result, nil
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		v, err := ints(jwk.N, jwk.E, jwk.D)
+		if err != nil {
+			return nil, err
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: v[0], E: int(v[1].Int64())},
+			D:         v[2],
+		}
+		if jwk.P == "" || jwk.Q == "" {
+			return nil, errors.New("RSA JWKs without the primes p and q are not supported")
+		}
+		primes, err := ints(jwk.P, jwk.Q)
+		if err != nil {
+			return nil, err
+		}
+		key.Primes = primes
+		if err := key.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid RSA JWK: %v", err)
+		}
+		key.Precompute()
+		return key, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve %q", jwk.Crv)
+		}
+		v, err := ints(jwk.X, jwk.Y, jwk.D)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(v[0], v[1]) {
+			return nil, errors.New("invalid EC JWK: the point is not on the curve")
+		}
+		return &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: curve, X: v[0], Y: v[1]},
+			D:         v[2],
+		}, nil
+	case "":
+		return nil, errors.New("the JWK has no kty")
+	}
+	return nil, fmt.Errorf("unsupported JWK key type %q, must be RSA or EC", jwk.Kty)
+}
@@ -0,0 +1,138 @@
+package uaa_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestPrivateKeys(t *testing.T) {
+	spec.Run(t, "PrivateKeys", testPrivateKeys, spec.Report(report.Terminal{}))
+}
+
+func testPrivateKeys(t *testing.T, when spec.G, it spec.S) {
+	var (
+		rsaKey *rsa.PrivateKey
+		ecKey  *ecdsa.PrivateKey
+	)
+
+	b64 := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		var err error
+		rsaKey, err = rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	when("ParsePrivateKeyPEM()", func() {
+		it("parses PKCS #1, SEC 1, and PKCS #8 keys", func() {
+			key, err := uaa.ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.(*rsa.PrivateKey).D).To(Equal(rsaKey.D))
+
+			der, err := x509.MarshalECPrivateKey(ecKey)
+			Expect(err).NotTo(HaveOccurred())
+			key, err = uaa.ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.(*ecdsa.PrivateKey).D).To(Equal(ecKey.D))
+
+			der, err = x509.MarshalPKCS8PrivateKey(ecKey)
+			Expect(err).NotTo(HaveOccurred())
+			key, err = uaa.ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.(*ecdsa.PrivateKey).D).To(Equal(ecKey.D))
+		})
+
+		it("decrypts keys with the passphrase", func() {
+			block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), []byte("secret"), x509.PEMCipherAES256)
+			Expect(err).NotTo(HaveOccurred())
+			data := pem.EncodeToMemory(block)
+
+			_, err = uaa.ParsePrivateKeyPEM(data, nil)
+			Expect(err).To(MatchError("the private key is encrypted and no passphrase was given"))
+			_, err = uaa.ParsePrivateKeyPEM(data, []byte("wrong"))
+			Expect(err).To(HaveOccurred())
+			key, err := uaa.ParsePrivateKeyPEM(data, []byte("secret"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.(*rsa.PrivateKey).D).To(Equal(rsaKey.D))
+		})
+
+		it("explains unsupported formats", func() {
+			_, err := uaa.ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte("x")}), nil)
+			Expect(err).To(MatchError(ContainSubstring("openssl pkcs8")))
+			_, err = uaa.ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("x")}), nil)
+			Expect(err).To(MatchError(ContainSubstring(`unsupported PEM block type "CERTIFICATE"`)))
+			_, err = uaa.ParsePrivateKeyPEM([]byte("not a key"), nil)
+			Expect(err).To(MatchError("no PEM block found"))
+		})
+	})
+
+	when("ParsePrivateKeyJWK()", func() {
+		it("parses RSA and EC keys", func() {
+			j, _ := json.Marshal(map[string]string{
+				"kty": "RSA",
+				"n":   b64(rsaKey.N),
+				"e":   b64(big.NewInt(int64(rsaKey.E))),
+				"d":   b64(rsaKey.D),
+				"p":   b64(rsaKey.Primes[0]),
+				"q":   b64(rsaKey.Primes[1]),
+			})
+			key, err := uaa.ParsePrivateKeyJWK(j)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.(*rsa.PrivateKey).D).To(Equal(rsaKey.D))
+
+			j, _ = json.Marshal(map[string]string{"kty": "EC", "crv": "P-256", "x": b64(ecKey.X), "y": b64(ecKey.Y), "d": b64(ecKey.D)})
+			key, err = uaa.ParsePrivateKeyJWK(j)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key.(*ecdsa.PrivateKey).D).To(Equal(ecKey.D))
+		})
+
+		it("refuses public and unsupported keys", func() {
+			_, err := uaa.ParsePrivateKeyJWK([]byte(`{"kty":"RSA","n":"AQAB","e":"AQAB"}`))
+			Expect(err).To(MatchError("the JWK is not a private key"))
+			_, err = uaa.ParsePrivateKeyJWK([]byte(`{"kty":"oct","d":"AQAB"}`))
+			Expect(err).To(MatchError(ContainSubstring(`unsupported JWK key type "oct"`)))
+			_, err = uaa.ParsePrivateKeyJWK([]byte("a.b.c.d.e"))
+			Expect(err).To(MatchError("encrypted (JWE) keys are not supported"))
+		})
+	})
+
+	it("loads a key from a PEM or JWK file", func() {
+		dir, err := ioutil.TempDir("", "keys")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "key.pem")
+		Expect(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), 0600)).To(Succeed())
+		key, err := uaa.LoadPrivateKey(path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Public()).To(Equal(&rsaKey.PublicKey))
+
+		path = filepath.Join(dir, "key.json")
+		j, _ := json.Marshal(map[string]string{"kty": "EC", "crv": "P-256", "x": b64(ecKey.X), "y": b64(ecKey.Y), "d": b64(ecKey.D)})
+		Expect(ioutil.WriteFile(path, j, 0600)).To(Succeed())
+		key, err = uaa.LoadPrivateKey(path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.(*ecdsa.PrivateKey).D).To(Equal(ecKey.D))
+	})
+}
@@ -0,0 +1,82 @@
+package uaa
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress is the progress of a long-running bulk operation.
+type Progress struct {
+	// Processed is the number of items processed so far, including those
+	// that failed.
+	Processed int
+	// Failed is the number of items that failed.
+	Failed int
+	// Total is the number of items to process, or 0 if it is not known in
+	// advance.
+	Total int
+	// Elapsed is the time since the operation started.
+	Elapsed time.Duration
+}
+
+// Remaining estimates the time until the operation completes from the rate at
+// which items have been processed so far. It returns 0 if the total is not
+// known or no items have been processed.
+func (p Progress) Remaining() time.Duration {
+	if p.Total <= 0 || p.Processed <= 0 || p.Processed >= p.Total {
+		return 0
+	}
+	perItem := p.Elapsed / time.Duration(p.Processed)
+	return perItem * time.Duration(p.Total-p.Processed)
+}
+
+// ProgressFunc is called with the progress of a bulk operation after each
+// item is processed.
+type ProgressFunc func(Progress)
+
+// WithProgress returns an Option that reports the progress of the API's bulk
+// operations, ImportUsersCSV, ExportUsersCSV and ExportUsersCSVWithCheckpoint,
+// to fn.
+func WithProgress(fn ProgressFunc) Option {
+	return func(a *API) {
+		a.progress = fn
+	}
+}
+
+// ProgressTracker counts the items processed by a bulk operation and reports
+// its progress. It is safe for concurrent use. A nil *ProgressTracker does
+// nothing, so that operations need not check whether progress is wanted.
+type ProgressTracker struct {
+	fn    ProgressFunc
+	start time.Time
+
+	mu       sync.Mutex
+	progress Progress
+}
+
+// NewProgressTracker returns a tracker for an operation on total items (0 if
+// not known) that reports to fn. It returns nil if fn is nil.
+func NewProgressTracker(total int, fn ProgressFunc) *ProgressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &ProgressTracker{fn: fn, start: time.Now(), progress: Progress{Total: total}}
+}
+
+// Done records that an item was processed, and failed if err is not nil, and
+// reports the progress.
+func (t *ProgressTracker) Done(err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.progress.Processed++
+	if err != nil {
+		t.progress.Failed++
+	}
+	t.progress.Elapsed = time.Since(t.start)
+	p := t.progress
+	// Report while holding the lock so that reports arrive in order.
+	t.fn(p)
+	t.mu.Unlock()
+}
@@ -0,0 +1,47 @@
+package uaa_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestProgress(t *testing.T) {
+	spec.Run(t, "Progress", testProgress, spec.Report(report.Terminal{}))
+}
+
+func testProgress(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("estimates the time remaining", func() {
+		p := uaa.Progress{Processed: 2, Total: 6, Elapsed: 4 * time.Second}
+		Expect(p.Remaining()).To(Equal(8 * time.Second))
+		Expect(uaa.Progress{Processed: 2, Elapsed: time.Second}.Remaining()).To(BeZero())
+		Expect(uaa.Progress{Total: 2}.Remaining()).To(BeZero())
+	})
+
+	it("counts processed and failed items", func() {
+		var reports []uaa.Progress
+		tracker := uaa.NewProgressTracker(3, func(p uaa.Progress) { reports = append(reports, p) })
+		tracker.Done(nil)
+		tracker.Done(errors.New("failed"))
+		tracker.Done(nil)
+		Expect(reports).To(HaveLen(3))
+		Expect(reports[2].Processed).To(Equal(3))
+		Expect(reports[2].Failed).To(Equal(1))
+		Expect(reports[2].Total).To(Equal(3))
+	})
+
+	it("does nothing without a function", func() {
+		tracker := uaa.NewProgressTracker(3, nil)
+		Expect(tracker).To(BeNil())
+		tracker.Done(nil)
+	})
+}
@@ -0,0 +1,150 @@
+package uaa_test
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+// serveSOCKS5 runs a minimal SOCKS5 proxy that supports unauthenticated
+// CONNECT requests, and sends the address of each connection to connects.
+func serveSOCKS5(l net.Listener, connects chan<- string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			buf := make([]byte, 262)
+			if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+				return
+			}
+			io.ReadFull(conn, buf[:buf[1]])
+			conn.Write([]byte{5, 0})
+
+			io.ReadFull(conn, buf[:4])
+			var host string
+			switch buf[3] {
+			case 1:
+				io.ReadFull(conn, buf[:4])
+				host = net.IP(buf[:4]).String()
+			case 3:
+				io.ReadFull(conn, buf[:1])
+				n := int(buf[0])
+				io.ReadFull(conn, buf[:n])
+				host = string(buf[:n])
+			}
+			io.ReadFull(conn, buf[:2])
+			addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2]))))
+			connects <- addr
+
+			target, err := net.Dial("tcp", addr)
+			if err != nil {
+				conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+				return
+			}
+			defer target.Close()
+			conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+			go io.Copy(target, conn)
+			io.Copy(conn, target)
+		}(conn)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	spec.Run(t, "Proxy", testProxy, spec.Report(report.Terminal{}))
+}
+
+func testProxy(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s *httptest.Server
+		a *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(MarcusUserResponse))
+		}))
+		u, _ := url.Parse(s.URL)
+		c := &http.Client{Transport: http.DefaultTransport}
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("connects through a SOCKS5 proxy", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+		connects := make(chan string, 1)
+		go serveSOCKS5(l, connects)
+
+		uaa.WithProxy(&url.URL{Scheme: "socks5", Host: l.Addr().String()})(a)
+		user, err := a.GetUser("id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("marcus@stoicism.com"))
+		Expect(<-connects).To(Equal(s.Listener.Addr().String()))
+	})
+
+	it("connects through the SOCKS5 proxy in ALL_PROXY instead of an HTTP proxy", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+		connects := make(chan string, 1)
+		go serveSOCKS5(l, connects)
+		os.Setenv("ALL_PROXY", "socks5://"+l.Addr().String())
+		defer os.Unsetenv("ALL_PROXY")
+
+		proxied := false
+		p := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			proxied = true
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer p.Close()
+		proxyURL, _ := url.Parse(p.URL)
+		c := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+		a.AuthenticatedClient, a.UnauthenticatedClient = c, c
+
+		uaa.WithProxyFromEnvironment()(a)
+		_, err = a.GetUser("id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(<-connects).To(Equal(s.Listener.Addr().String()))
+		Expect(proxied).To(BeFalse())
+	})
+
+	it("sends requests through an HTTP proxy", func() {
+		var proxied string
+		p := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			proxied = req.URL.String()
+			w.Write([]byte(MarcusUserResponse))
+		}))
+		defer p.Close()
+
+		proxyURL, _ := url.Parse(p.URL)
+		uaa.WithProxy(proxyURL)(a)
+		_, err := a.GetUser("id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proxied).To(Equal(s.URL + "/Users/id"))
+	})
+
+	it("fails requests when the proxy is not supported", func() {
+		uaa.WithProxy(&url.URL{Scheme: "gopher", Host: "jumpbox:70"})(a)
+		_, err := a.GetUser("id")
+		Expect(err).To(HaveOccurred())
+	})
+}
@@ -0,0 +1,129 @@
+package uaa
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrInvalidRawJSON is returned when the body passed to one of the Raw
+// methods is not valid JSON.
+var ErrInvalidRawJSON = errors.New("the raw body is not valid JSON")
+
+// The Raw methods read and write resources as the JSON that the UAA sends
+// and receives, for callers who need fields that the typed structs lack.
+// Requests are made in the same way as by the typed methods, with the same
+// authentication, zone headers, and error handling.
+
+// GetUserRaw returns the JSON of the user with the given userID.
+func (a *API) GetUserRaw(userID string) (json.RawMessage, error) {
+	return a.getRaw(UsersEndpoint, userID)
+}
+
+// CreateUserRaw creates the user described by the given JSON and returns the
+// JSON of the created user.
+func (a *API) CreateUserRaw(user json.RawMessage) (json.RawMessage, error) {
+	return a.sendRaw(http.MethodPost, UsersEndpoint, user)
+}
+
+// UpdateUserRaw updates the user described by the given JSON and returns the
+// JSON of the updated user.
+func (a *API) UpdateUserRaw(user json.RawMessage) (json.RawMessage, error) {
+	return a.sendRaw(http.MethodPut, UsersEndpoint, user)
+}
+
+// GetGroupRaw returns the JSON of the group with the given groupID.
+func (a *API) GetGroupRaw(groupID string) (json.RawMessage, error) {
+	return a.getRaw(GroupsEndpoint, groupID)
+}
+
+// CreateGroupRaw creates the group described by the given JSON and returns
+// the JSON of the created group.
+func (a *API) CreateGroupRaw(group json.RawMessage) (json.RawMessage, error) {
+	return a.sendRaw(http.MethodPost, GroupsEndpoint, group)
+}
+
+// UpdateGroupRaw updates the group described by the given JSON and returns
+// the JSON of the updated group.
+func (a *API) UpdateGroupRaw(group json.RawMessage) (json.RawMessage, error) {
+	return a.sendRaw(http.MethodPut, GroupsEndpoint, group)
+}
+
+// GetClientRaw returns the JSON of the client with the given clientID.
+func (a *API) GetClientRaw(clientID string) (json.RawMessage, error) {
+	return a.getRaw(ClientsEndpoint, clientID)
+}
+
+// CreateClientRaw creates the client described by the given JSON and returns
+// the JSON of the created client.
+func (a *API) CreateClientRaw(client json.RawMessage) (json.RawMessage, error) {
+	return a.sendRaw(http.MethodPost, ClientsEndpoint, client)
+}
+
+// UpdateClientRaw updates the client described by the given JSON and returns
+// the JSON of the updated client.
+func (a *API) UpdateClientRaw(client json.RawMessage) (json.RawMessage, error) {
+	return a.sendRaw(http.MethodPut, ClientsEndpoint, client)
+}
+
+// GetIdentityZoneRaw returns the JSON of the identity zone with the given
+// identityzoneID.
+func (a *API) GetIdentityZoneRaw(identityzoneID string) (json.RawMessage, error) {
+	return a.getRaw(IdentityZonesEndpoint, identityzoneID)
+}
+
+// CreateIdentityZoneRaw creates the identity zone described by the given JSON
+// and returns the JSON of the created identity zone.
+func (a *API) CreateIdentityZoneRaw(identityzone json.RawMessage) (json.RawMessage, error) {
+	return a.sendRaw(http.MethodPost, IdentityZonesEndpoint, identityzone)
+}
+
+// UpdateIdentityZoneRaw updates the identity zone described by the given JSON
+// and returns the JSON of the updated identity zone.
+func (a *API) UpdateIdentityZoneRaw(identityzone json.RawMessage) (json.RawMessage, error) {
+	return a.sendRaw(http.MethodPut, IdentityZonesEndpoint, identityzone)
+}
+
+// GetIdentityProviderRaw returns the JSON of the identity provider with the
+// given identityproviderID.
+func (a *API) GetIdentityProviderRaw(identityproviderID string) (json.RawMessage, error) {
+	return a.getRaw(IdentityProvidersEndpoint, identityproviderID)
+}
+
+// CreateIdentityProviderRaw creates the identity provider described by the
+// given JSON and returns the JSON of the created identity provider.
+func (a *API) CreateIdentityProviderRaw(identityprovider json.RawMessage) (json.RawMessage, error) {
+	return a.sendRaw(http.MethodPost, IdentityProvidersEndpoint, identityprovider)
+}
+
+// UpdateIdentityProviderRaw updates the identity provider described by the
+// given JSON and returns the JSON of the updated identity provider.
+func (a *API) UpdateIdentityProviderRaw(identityprovider json.RawMessage) (json.RawMessage, error) {
+	return a.sendRaw(http.MethodPut, IdentityProvidersEndpoint, identityprovider)
+}
+
+func (a *API) getRaw(endpoint string, id string) (json.RawMessage, error) {
+	if id == "" {
+		return nil, errors.New("id cannot be blank")
+	}
+	u := urlWithPath(*a.TargetURL, fmt.Sprintf("%s/%s", endpoint, id))
+	var raw json.RawMessage
+	if err := a.doJSON(http.MethodGet, &u, nil, &raw, true); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (a *API) sendRaw(method string, endpoint string, body json.RawMessage) (json.RawMessage, error) {
+	if !json.Valid(body) {
+		return nil, ErrInvalidRawJSON
+	}
+	u := urlWithPath(*a.TargetURL, endpoint)
+	var raw json.RawMessage
+	if err := a.doJSON(method, &u, bytes.NewReader(body), &raw, true); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
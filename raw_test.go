@@ -0,0 +1,110 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestRaw(t *testing.T) {
+	spec.Run(t, "Raw", testRaw, spec.Report(report.Terminal{}))
+}
+
+func testRaw(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		handler http.HandlerFunc
+		a       *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			handler(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+			ZoneID:                "tenant",
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("returns a user's JSON, including unmodeled fields", func() {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Method).To(Equal(http.MethodGet))
+			Expect(req.URL.Path).To(Equal(uaa.UsersEndpoint + "/user-id"))
+			Expect(req.Header.Get("X-Identity-Zone-Id")).To(Equal("tenant"))
+			w.Write([]byte(`{"id":"user-id","userName":"marcus","futureField":{"a":1}}`))
+		}
+		raw, err := a.GetUserRaw("user-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(raw).To(MatchJSON(`{"id":"user-id","userName":"marcus","futureField":{"a":1}}`))
+	})
+
+	it("sends a client's JSON unchanged", func() {
+		body := `{"client_id":"app","futureField":true}`
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Method).To(Equal(http.MethodPut))
+			Expect(req.URL.Path).To(Equal(uaa.ClientsEndpoint))
+			Expect(req.Header.Get("Content-Type")).To(Equal("application/json"))
+			sent, _ := ioutil.ReadAll(req.Body)
+			Expect(sent).To(MatchJSON(body))
+			w.Write(sent)
+		}
+		raw, err := a.UpdateClientRaw(json.RawMessage(body))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(raw).To(MatchJSON(body))
+	})
+
+	it("posts to the collection when creating", func() {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Method).To(Equal(http.MethodPost))
+			Expect(req.URL.Path).To(Equal(uaa.GroupsEndpoint))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"group-id"}`))
+		}
+		raw, err := a.CreateGroupRaw(json.RawMessage(`{"displayName":"admins"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(raw).To(MatchJSON(`{"id":"group-id"}`))
+	})
+
+	it("rejects invalid JSON without making a request", func() {
+		called := false
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			called = true
+		}
+		_, err := a.CreateIdentityZoneRaw(json.RawMessage(`{"id":`))
+		Expect(err).To(Equal(uaa.ErrInvalidRawJSON))
+		Expect(called).To(BeFalse())
+	})
+
+	it("returns the usual error for an unsuccessful response", func() {
+		handler = func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"scim_resource_not_found","error_description":"Provider not found"}`))
+		}
+		_, err := a.GetIdentityProviderRaw("missing")
+		Expect(err).To(HaveOccurred())
+		unexpected, ok := errors.Cause(err).(uaa.ErrUnexpectedResponse)
+		Expect(ok).To(BeTrue())
+		Expect(unexpected.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(unexpected.Code).To(Equal("scim_resource_not_found"))
+	})
+}
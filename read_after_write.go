@@ -0,0 +1,104 @@
+package uaa
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Defaults for the retries of the Create*AndGet and Update*AndGet methods.
+const (
+	DefaultReadAfterWriteAttempts = 3
+	DefaultReadAfterWriteDelay    = 250 * time.Millisecond
+)
+
+// ErrStaleRead is returned by the Create*AndGet and Update*AndGet methods when
+// every attempt to read a resource back returned an older version than the
+// one written.
+var ErrStaleRead = errors.New("the UAA returned an older version of the resource than was written")
+
+// WithReadAfterWriteRetry returns an Option that makes the Create*AndGet and
+// Update*AndGet methods try to read a resource back up to attempts times,
+// waiting delay between attempts, before giving up. Values of 0 or less use
+// DefaultReadAfterWriteAttempts and DefaultReadAfterWriteDelay.
+func WithReadAfterWriteRetry(attempts int, delay time.Duration) Option {
+	return func(a *API) {
+		a.readAfterWriteAttempts = attempts
+		a.readAfterWriteDelay = delay
+	}
+}
+
+// readAfterWrite calls get until it reads the written resource or the
+// attempts are exhausted. get reports whether the resource it read is older
+// than the one written. UAA servers behind a load balancer may briefly answer
+// a read with a replica that has not yet seen a write, so only reads that
+// find no resource or a stale one are retried; other errors are returned
+// immediately.
+func (a *API) readAfterWrite(get func() (stale bool, err error)) error {
+	attempts := a.readAfterWriteAttempts
+	if attempts <= 0 {
+		attempts = DefaultReadAfterWriteAttempts
+	}
+	delay := a.readAfterWriteDelay
+	if delay <= 0 {
+		delay = DefaultReadAfterWriteDelay
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			a.getClock().Sleep(delay)
+		}
+		stale, getErr := get()
+		switch {
+		case getErr == nil && !stale:
+			return nil
+		case getErr == nil:
+			err = ErrStaleRead
+		case isNotFound(getErr):
+			err = getErr
+		default:
+			return getErr
+		}
+	}
+	return err
+}
+
+// isNotFound reports whether the error is for a 404 response.
+func isNotFound(err error) bool {
+	e, ok := errors.Cause(err).(ErrUnexpectedResponse)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+// staleRead reports whether the resource that was read is older than the one
+// that was written, according to their versions.
+func staleRead(read interface{}, written interface{}) bool {
+	readVersion, ok := resourceVersion(read)
+	if !ok {
+		return false
+	}
+	writtenVersion, ok := resourceVersion(written)
+	return ok && readVersion < writtenVersion
+}
+
+// resourceVersion returns the version of a resource, or false if it has none.
+// Clients have no version, so the time they were last modified is used.
+func resourceVersion(resource interface{}) (int64, bool) {
+	switch r := resource.(type) {
+	case *User:
+		if r.Meta != nil {
+			return int64(r.Meta.Version), true
+		}
+	case *Group:
+		if r.Meta != nil {
+			return int64(r.Meta.Version), true
+		}
+	case *IdentityZone:
+		return int64(r.Version), true
+	case *IdentityProvider:
+		return int64(r.Version), true
+	case *Client:
+		return r.LastModified, r.LastModified != 0
+	}
+	return 0, false
+}
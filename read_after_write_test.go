@@ -0,0 +1,146 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestReadAfterWrite(t *testing.T) {
+	spec.Run(t, "ReadAfterWrite", testReadAfterWrite, spec.Report(report.Terminal{}))
+}
+
+func testReadAfterWrite(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		handler http.Handler
+		a       *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			handler.ServeHTTP(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+		uaa.WithReadAfterWriteRetry(3, time.Millisecond)(a)
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("returns the user as read back from the server", func() {
+		gets := 0
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.Method {
+			case http.MethodPost:
+				w.Write([]byte(`{"id":"marcus-id","userName":"marcus"}`))
+			case http.MethodGet:
+				Expect(req.URL.Path).To(Equal(uaa.UsersEndpoint + "/marcus-id"))
+				gets++
+				if gets == 1 {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Write([]byte(`{"id":"marcus-id","userName":"marcus","origin":"uaa","meta":{"version":0}}`))
+			}
+		})
+		user, err := a.CreateUserAndGet(uaa.User{Username: "marcus"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gets).To(Equal(2))
+		Expect(user.Origin).To(Equal("uaa"))
+	})
+
+	it("reads an updated client back by its client ID", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.Method {
+			case http.MethodPut:
+				body, _ := ioutil.ReadAll(req.Body)
+				w.Write(body)
+			case http.MethodGet:
+				Expect(req.URL.Path).To(Equal(uaa.ClientsEndpoint + "/shinyclient"))
+				json.NewEncoder(w).Encode(uaa.Client{ClientID: "shinyclient", LastModified: 1})
+			}
+		})
+		client, err := a.UpdateClientAndGet(uaa.Client{ClientID: "shinyclient"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.LastModified).To(Equal(int64(1)))
+	})
+
+	it("retries while a replica returns the version before the update", func() {
+		gets := 0
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.Method {
+			case http.MethodPut:
+				w.Write([]byte(`{"id":"marcus-id","userName":"marcus","meta":{"version":2}}`))
+			case http.MethodGet:
+				gets++
+				if gets == 1 {
+					w.Write([]byte(`{"id":"marcus-id","userName":"marcus","meta":{"version":1}}`))
+					return
+				}
+				w.Write([]byte(`{"id":"marcus-id","userName":"marcus","meta":{"version":2}}`))
+			}
+		})
+		user, err := a.UpdateUserAndGet(uaa.User{ID: "marcus-id", Username: "marcus"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gets).To(Equal(2))
+		Expect(user.Meta.Version).To(Equal(2))
+	})
+
+	it("does not return a stale resource", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodPut {
+				w.Write([]byte(`{"id":"stoics-id","displayName":"stoics","meta":{"version":3}}`))
+				return
+			}
+			w.Write([]byte(`{"id":"stoics-id","displayName":"stoics","meta":{"version":2}}`))
+		})
+		group, err := a.UpdateGroupAndGet(uaa.Group{ID: "stoics-id", DisplayName: "stoics"})
+		Expect(err).To(Equal(uaa.ErrStaleRead))
+		Expect(group).To(BeNil())
+	})
+
+	it("does not retry errors other than not found", func() {
+		gets := 0
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodGet {
+				gets++
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"id":"marcus-id","userName":"marcus"}`))
+		})
+		_, err := a.CreateUserAndGet(uaa.User{Username: "marcus"})
+		Expect(err).To(HaveOccurred())
+		Expect(gets).To(Equal(1))
+	})
+
+	it("gives up after the configured attempts", func() {
+		gets := 0
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodGet {
+				gets++
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"id":"stoics-id","displayName":"stoics"}`))
+		})
+		group, err := a.CreateGroupAndGet(uaa.Group{DisplayName: "stoics"})
+		Expect(err).To(HaveOccurred())
+		Expect(group).To(BeNil())
+		Expect(gets).To(Equal(3))
+	})
+}
@@ -0,0 +1,29 @@
+package uaa
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ErrReadOnly is the cause of the error returned when a request that would
+// change the UAA is made by an API created with WithReadOnly. Use
+// errors.Cause from github.com/pkg/errors to compare against it.
+var ErrReadOnly = errors.New("the API is read-only")
+
+// WithReadOnly returns an Option under which requests that would change the
+// UAA (POST, PUT, PATCH, and DELETE) fail with ErrReadOnly without being
+// sent, including requests made with Curl. Requests that only read from the
+// UAA, such as token introspection, are sent as usual.
+func WithReadOnly() Option {
+	return func(a *API) {
+		a.readOnly = true
+	}
+}
+
+func (a *API) checkReadOnly(req *http.Request) error {
+	if a.readOnly && isMutating(req) {
+		return errors.Wrapf(ErrReadOnly, "refusing to %s %s", req.Method, req.URL)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestReadOnly(t *testing.T) {
+	spec.Run(t, "ReadOnly", testReadOnly, spec.Report(report.Terminal{}))
+}
+
+func testReadOnly(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		a        *uaa.API
+		requests []string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		requests = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			w.Write([]byte(PaginatedResponse(uaa.User{ID: "marcus-id", Username: "marcus"})))
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+		uaa.WithReadOnly()(a)
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("refuses mutating requests", func() {
+		_, err := a.CreateUser(uaa.User{Username: "seneca"})
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrReadOnly))
+		Expect(err.Error()).To(ContainSubstring("refusing to POST " + s.URL + uaa.UsersEndpoint))
+		Expect(errors.Cause(a.DeactivateUser("marcus-id", 0))).To(Equal(uaa.ErrReadOnly))
+		_, _, err = a.Curl("/Users/marcus-id", http.MethodDelete, "", nil)
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrReadOnly))
+		Expect(requests).To(BeEmpty())
+	})
+
+	it("sends requests that do not change anything", func() {
+		_, err := a.ListAllUsers("", "", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = a.IntrospectToken("token")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(Equal([]string{"GET /Users", "POST /introspect"}))
+	})
+}
@@ -0,0 +1,194 @@
+package uaa
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ValidateRedirectURI returns nil if the UAA would accept uri as a redirect
+// for a client registered with the redirect URI pattern, or an error
+// explaining why it would not. The pattern is matched the way the UAA matches
+// it, so that a redirect can be checked before a client is registered or a
+// login is started:
+//
+//   - The scheme and host are compared case-insensitively. A "*" in the
+//     scheme matches any characters, so "http*" matches http and https.
+//   - A "*" in the host matches a single label, so "*.example.com" matches
+//     "app.example.com" but not "a.b.example.com" or "example.com".
+//   - The port must match, unless the pattern's port is "*".
+//   - In the path, "*" matches any characters within a segment, "?" matches
+//     one character, and "**" matches any number of segments. A pattern
+//     ending in "/**" also accepts any query.
+//   - Otherwise the query must be the same as the pattern's.
+//
+// To prevent open redirects, a uri with user information, such as
+// "https://example.com@evil.com", or a fragment is always rejected.
+func ValidateRedirectURI(pattern, uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return fmt.Errorf("invalid redirect URI %q: %v", uri, err)
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("redirect URI %q must be an absolute URL", uri)
+	}
+	if u.User != nil || strings.Contains(u.Host, "@") {
+		return fmt.Errorf("redirect URI %q may not contain user information", uri)
+	}
+	if u.Fragment != "" {
+		return fmt.Errorf("redirect URI %q may not contain a fragment", uri)
+	}
+
+	p, err := parseRedirectPattern(pattern)
+	if err != nil {
+		return err
+	}
+	mismatch := func(part string) error {
+		return fmt.Errorf("redirect URI %q does not match the %s of %q", uri, part, pattern)
+	}
+	if !wildcardMatch(strings.ToLower(p.scheme), strings.ToLower(u.Scheme)) {
+		return mismatch("scheme")
+	}
+	if !hostMatches(strings.ToLower(p.host), strings.ToLower(u.Hostname())) {
+		return mismatch("host")
+	}
+	if !portMatches(p.port, u.Scheme, u.Port()) {
+		return mismatch("port")
+	}
+	uriPath := u.EscapedPath()
+	if uriPath == "" {
+		uriPath = "/"
+	}
+	if path.Clean(uriPath) != uriPath && !(strings.HasSuffix(uriPath, "/") && path.Clean(uriPath)+"/" == uriPath) {
+		return fmt.Errorf("redirect URI %q may not contain relative path segments", uri)
+	}
+	if !antMatch(splitPath(p.path), splitPath(uriPath)) {
+		return mismatch("path")
+	}
+	if !strings.HasSuffix(p.path, "/**") && u.RawQuery != p.query {
+		return mismatch("query")
+	}
+	return nil
+}
+
+type redirectPattern struct {
+	scheme, host, port, path, query string
+}
+
+// parseRedirectPattern splits a pattern by hand, because wildcards make many
+// patterns invalid URLs.
+func parseRedirectPattern(original string) (redirectPattern, error) {
+	var p redirectPattern
+	i := strings.Index(original, "://")
+	if i <= 0 {
+		return p, fmt.Errorf("redirect URI pattern %q must be an absolute URL", original)
+	}
+	p.scheme = original[:i]
+	pattern := original[i+3:]
+	if j := strings.IndexAny(pattern, "/?"); j >= 0 {
+		p.host, pattern = pattern[:j], pattern[j:]
+	} else {
+		p.host, pattern = pattern, ""
+	}
+	if j := strings.Index(pattern, "?"); j >= 0 {
+		p.path, p.query = pattern[:j], pattern[j+1:]
+	} else {
+		p.path = pattern
+	}
+	if p.path == "" {
+		p.path = "/"
+	}
+	if j := strings.LastIndex(p.host, ":"); j >= 0 && !strings.Contains(p.host[j:], "]") {
+		p.host, p.port = p.host[:j], p.host[j+1:]
+	}
+	p.host = strings.Trim(p.host, "[]")
+	if p.host == "" || strings.Contains(p.host, "@") {
+		return p, fmt.Errorf("redirect URI pattern %q has an invalid host", original)
+	}
+	return p, nil
+}
+
+// portMatches compares the ports, treating a missing port as the scheme's
+// default port.
+func portMatches(pattern, scheme, port string) bool {
+	defaultPort := map[string]string{"http": "80", "https": "443"}[strings.ToLower(scheme)]
+	if port == "" {
+		port = defaultPort
+	}
+	switch pattern {
+	case "*":
+		return true
+	case "":
+		return port == defaultPort
+	default:
+		return port == pattern
+	}
+}
+
+// hostMatches matches the host label by label, so that a wildcard cannot
+// match a dot.
+func hostMatches(pattern, host string) bool {
+	patternLabels := strings.Split(pattern, ".")
+	hostLabels := strings.Split(host, ".")
+	if len(patternLabels) != len(hostLabels) {
+		return false
+	}
+	for i := range patternLabels {
+		if !wildcardMatch(patternLabels[i], hostLabels[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func splitPath(p string) []string {
+	var segments []string
+	for _, segment := range strings.Split(p, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// antMatch matches path segments against Ant-style pattern segments, in
+// which "**" matches any number of segments.
+func antMatch(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if antMatch(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 || !wildcardMatch(pattern[0], segments[0]) {
+		return false
+	}
+	return antMatch(pattern[1:], segments[1:])
+}
+
+// wildcardMatch matches s against a pattern in which "*" matches any
+// characters and "?" matches a single character.
+func wildcardMatch(pattern, s string) bool {
+	if pattern == "" {
+		return s == ""
+	}
+	switch pattern[0] {
+	case '*':
+		for i := 0; i <= len(s); i++ {
+			if wildcardMatch(pattern[1:], s[i:]) {
+				return true
+			}
+		}
+		return false
+	case '?':
+		return s != "" && wildcardMatch(pattern[1:], s[1:])
+	default:
+		return s != "" && s[0] == pattern[0] && wildcardMatch(pattern[1:], s[1:])
+	}
+}
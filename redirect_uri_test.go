@@ -0,0 +1,55 @@
+package uaa_test
+
+import (
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestValidateRedirectURI(t *testing.T) {
+	spec.Run(t, "ValidateRedirectURI", testValidateRedirectURI, spec.Report(report.Terminal{}))
+}
+
+func testValidateRedirectURI(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("accepts matching URIs", func() {
+		for _, c := range [][2]string{
+			{"https://app.example.com/callback", "https://app.example.com/callback"},
+			{"https://app.example.com/callback", "HTTPS://App.Example.com:443/callback"},
+			{"http*://app.example.com/callback", "http://app.example.com/callback"},
+			{"https://*.example.com/callback", "https://dashboard.example.com/callback"},
+			{"http://localhost:*/callback", "http://localhost:8080/callback"},
+			{"http://ant.path.wildcard/**/passback/*", "http://ant.path.wildcard/a/b/passback/done"},
+			{"https://app.example.com/**", "https://app.example.com/any/path?with=query"},
+			{"https://app.example.com/cb?mode=login", "https://app.example.com/cb?mode=login"},
+		} {
+			Expect(uaa.ValidateRedirectURI(c[0], c[1])).To(Succeed(), c[1])
+		}
+	})
+
+	it("rejects URIs that do not match", func() {
+		for _, c := range [][2]string{
+			{"https://app.example.com/callback", "http://app.example.com/callback"},
+			{"https://app.example.com/callback", "https://app.example.com:8443/callback"},
+			{"https://*.example.com/callback", "https://evil.com/.example.com/callback"},
+			{"https://*.example.com/callback", "https://a.b.example.com/callback"},
+			{"https://app.example.com/callback", "https://app.example.com/callback/extra"},
+			{"https://app.example.com/callback", "https://app.example.com/callback?next=https://evil.com"},
+			{"https://app.example.com/**", "https://app.example.com/a/../../b"},
+		} {
+			Expect(uaa.ValidateRedirectURI(c[0], c[1])).NotTo(Succeed(), c[1])
+		}
+	})
+
+	it("rejects open redirect tricks", func() {
+		Expect(uaa.ValidateRedirectURI("https://**", "https://app.example.com@evil.com/")).To(MatchError(ContainSubstring("user information")))
+		Expect(uaa.ValidateRedirectURI("https://app.example.com/**", "https://app.example.com/cb#https://evil.com")).To(MatchError(ContainSubstring("fragment")))
+		Expect(uaa.ValidateRedirectURI("https://app.example.com/**", "/cb")).To(MatchError(ContainSubstring("absolute")))
+	})
+}
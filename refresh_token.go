@@ -0,0 +1,76 @@
+package uaa
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrOpaqueRefreshToken is returned when a refresh token is opaque rather
+// than a JSON Web Token, so its expiry and scopes cannot be read without the
+// UAA. UAA issues opaque refresh tokens when the zone's token policy sets the
+// refresh token format to opaque.
+var ErrOpaqueRefreshToken = errors.New("the refresh token is opaque")
+
+// RefreshTokenInfo describes a refresh token.
+type RefreshTokenInfo struct {
+	// ExpiresAt is the time after which the token can no longer be used to
+	// obtain access tokens, at which point the user must authenticate again.
+	ExpiresAt time.Time
+	// Scopes are the scopes that access tokens obtained with the refresh
+	// token can be granted.
+	Scopes []string
+	Claims *Claims
+}
+
+// ExpiresWithin returns true if the refresh token expires within d of now.
+func (i *RefreshTokenInfo) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return !i.ExpiresAt.After(now.Add(d))
+}
+
+// DecodeRefreshToken decodes a refresh token that is a JSON Web Token. The
+// signature is not verified, so the result must only be used to decide when
+// to authenticate again, not to make access decisions. It returns
+// ErrOpaqueRefreshToken if the token is opaque.
+func DecodeRefreshToken(refreshToken string) (*RefreshTokenInfo, error) {
+	if refreshToken == "" {
+		return nil, errors.New("there is no refresh token")
+	}
+	if strings.Count(refreshToken, ".") != 2 {
+		return nil, ErrOpaqueRefreshToken
+	}
+	claims, err := DecodeClaims(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Expiry == 0 {
+		return nil, errors.New("the refresh token has no exp claim")
+	}
+	return &RefreshTokenInfo{
+		ExpiresAt: claims.ExpiresAt(),
+		Scopes:    claims.Scope,
+		Claims:    claims,
+	}, nil
+}
+
+// RefreshToken decodes the refresh token of the API's current token; see
+// DecodeRefreshToken.
+func (a *API) RefreshToken() (*RefreshTokenInfo, error) {
+	token, err := a.Token()
+	if err != nil {
+		return nil, err
+	}
+	return DecodeRefreshToken(token.RefreshToken)
+}
+
+// RefreshTokenExpiresWithin returns true if the refresh token of the API's
+// current token expires within d according to the API's clock, so that a
+// long-running program can have the user authenticate again before the API
+// stops being able to obtain tokens.
+func (a *API) RefreshTokenExpiresWithin(d time.Duration) (bool, error) {
+	info, err := a.RefreshToken()
+	if err != nil {
+		return false, err
+	}
+	return info.ExpiresWithin(a.getClock().Now(), d), nil
+}
@@ -0,0 +1,76 @@
+package uaa_test
+
+import (
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/oauth2"
+)
+
+func TestRefreshToken(t *testing.T) {
+	spec.Run(t, "RefreshToken", testRefreshToken, spec.Report(report.Terminal{}))
+}
+
+func testRefreshToken(t *testing.T, when spec.G, it spec.S) {
+	var (
+		expiry       time.Time
+		refreshToken string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		expiry = time.Now().Add(24 * time.Hour).Truncate(time.Second)
+		refreshToken = unsignedJWT(map[string]interface{}{
+			"jti":   "abc-r",
+			"scope": []string{"openid", "cloud_controller.read"},
+			"exp":   expiry.Unix(),
+		})
+	})
+
+	when("DecodeRefreshToken()", func() {
+		it("reports the expiry and scopes", func() {
+			info, err := uaa.DecodeRefreshToken(refreshToken)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.ExpiresAt).To(Equal(expiry))
+			Expect(info.Scopes).To(ConsistOf("openid", "cloud_controller.read"))
+			Expect(info.Claims.JTI).To(Equal("abc-r"))
+			Expect(info.ExpiresWithin(time.Now(), time.Hour)).To(BeFalse())
+			Expect(info.ExpiresWithin(time.Now(), 25*time.Hour)).To(BeTrue())
+		})
+
+		it("returns ErrOpaqueRefreshToken for opaque tokens", func() {
+			_, err := uaa.DecodeRefreshToken("1f3e2a0c41b24c2e9e0d-r")
+			Expect(err).To(Equal(uaa.ErrOpaqueRefreshToken))
+		})
+
+		it("fails when there is no refresh token", func() {
+			_, err := uaa.DecodeRefreshToken("")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	when("RefreshTokenExpiresWithin()", func() {
+		it("uses the API's clock", func() {
+			clock := uaa.NewFakeClock()
+			a, err := uaa.NewWithToken("https://uaa.example.com", "", oauth2.Token{
+				AccessToken:  "access-token",
+				RefreshToken: refreshToken,
+				Expiry:       clock.Now().Add(time.Hour),
+			}, uaa.WithClock(clock))
+			Expect(err).NotTo(HaveOccurred())
+
+			expires, err := a.RefreshTokenExpiresWithin(time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expires).To(BeFalse())
+
+			clock.Advance(23*time.Hour + time.Minute)
+			expires, err = a.RefreshTokenExpiresWithin(time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(expires).To(BeTrue())
+		})
+	})
+}
@@ -0,0 +1,56 @@
+package uaa
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUsernameConflict is returned by RenameUser when another user in the same
+// origin already has the new username.
+type ErrUsernameConflict struct {
+	Username string
+	Origin   string
+	// UserID is the ID of the user who has the username.
+	UserID string
+}
+
+func (e ErrUsernameConflict) Error() string {
+	return fmt.Sprintf("username %v is already taken in origin %v by user %v", e.Username, e.Origin, e.UserID)
+}
+
+// RenameUser changes the username of the user with the given ID. Usernames
+// are unique within an origin, so RenameUser first checks that no other user
+// in the user's origin has the new username, and returns an
+// ErrUsernameConflict naming the user who does. Only users of the uaa origin
+// can be renamed: the usernames of users from other identity providers are
+// set by the provider, and would be reset, or a duplicate user created, the
+// next time the user logs in.
+func (a *API) RenameUser(userID, newUsername string) (*User, error) {
+	if newUsername == "" {
+		return nil, errors.New("username cannot be blank")
+	}
+	return a.modifyUser(userID, func(user *User) error {
+		origin := user.Origin
+		if origin == "" {
+			origin = string(UAAOrigin)
+		}
+		if origin != string(UAAOrigin) {
+			return fmt.Errorf("cannot rename user %v: the usernames of %v users are set by their identity provider", userID, origin)
+		}
+		if user.Username == newUsername {
+			return nil
+		}
+		filter := fmt.Sprintf(`userName eq "%v" and origin eq "%v"`, newUsername, origin)
+		existing, err := a.ListAllUsers(filter, "", "id", "")
+		if err != nil {
+			return err
+		}
+		for _, other := range existing {
+			if other.ID != userID {
+				return ErrUsernameConflict{Username: newUsername, Origin: origin, UserID: other.ID}
+			}
+		}
+		user.Username = newUsername
+		return nil
+	})
+}
@@ -0,0 +1,79 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestRenameUser(t *testing.T) {
+	spec.Run(t, "RenameUser", testRenameUser, spec.Report(report.Terminal{}))
+}
+
+func testRenameUser(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		a        *uaa.API
+		current  uaa.User
+		existing []interface{}
+		updated  *uaa.User
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		current = uaa.User{ID: "marcus-id", Username: "marcus", Origin: "uaa"}
+		existing = nil
+		updated = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.Method == http.MethodGet && req.URL.Path == uaa.UsersEndpoint:
+				Expect(req.URL.Query().Get("filter")).To(Equal(`userName eq "aurelius" and origin eq "uaa"`))
+				w.Write([]byte(PaginatedResponse(existing...)))
+			case req.Method == http.MethodGet:
+				json.NewEncoder(w).Encode(current)
+			case req.Method == http.MethodPut:
+				body, _ := ioutil.ReadAll(req.Body)
+				updated = &uaa.User{}
+				Expect(json.Unmarshal(body, updated)).To(Succeed())
+				w.Write(body)
+			}
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("renames the user", func() {
+		user, err := a.RenameUser("marcus-id", "aurelius")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("aurelius"))
+		Expect(updated.ID).To(Equal("marcus-id"))
+	})
+
+	it("reports the user who has the username", func() {
+		existing = []interface{}{uaa.User{ID: "aurelius-id"}}
+		_, err := a.RenameUser("marcus-id", "aurelius")
+		Expect(err).To(Equal(uaa.ErrUsernameConflict{Username: "aurelius", Origin: "uaa", UserID: "aurelius-id"}))
+		Expect(updated).To(BeNil())
+	})
+
+	it("refuses to rename users from other identity providers", func() {
+		current.Origin = "ldap"
+		_, err := a.RenameUser("marcus-id", "aurelius")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("set by their identity provider"))
+		Expect(updated).To(BeNil())
+	})
+}
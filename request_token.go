@@ -0,0 +1,53 @@
+package uaa
+
+import (
+	"context"
+	"errors"
+	"net/url"
+
+	"golang.org/x/oauth2"
+)
+
+// RequestToken requests a new token in the given format using the API's
+// grant, for example to obtain an opaque token for one downstream call while
+// the API itself uses JSON Web Tokens. The new token is not cached and does
+// not replace the token the API uses. APIs built with an authorization code
+// request the new token with their refresh token. RequestToken returns an
+// error for APIs built with a token, which cannot request another.
+func (a *API) RequestToken(format TokenFormat) (*oauth2.Token, error) {
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+	if a.requestToken == nil {
+		return nil, errors.New("the API cannot request tokens")
+	}
+	params := url.Values{}
+	for key, values := range a.tokenParams {
+		params[key] = values
+	}
+	params.Set("token_format", format.String())
+	return a.requestToken(params)
+}
+
+// refreshTokenRequester returns a function that requests tokens for an API
+// built with an authorization code, using the refresh token of the API's
+// current token.
+func refreshTokenRequester(ctx context.Context, a *API, c *oauth2.Config) func(url.Values) (*oauth2.Token, error) {
+	return func(params url.Values) (*oauth2.Token, error) {
+		current, err := a.Token()
+		if err != nil {
+			return nil, err
+		}
+		if current.RefreshToken == "" {
+			return nil, errors.New("the API's token cannot be refreshed")
+		}
+		tokenURL, err := url.Parse(c.Endpoint.TokenURL)
+		if err != nil {
+			return nil, err
+		}
+		tokenURL.RawQuery = params.Encode()
+		config := *c
+		config.Endpoint.TokenURL = tokenURL.String()
+		return config.TokenSource(ctx, &oauth2.Token{RefreshToken: current.RefreshToken}).Token()
+	}
+}
@@ -0,0 +1,93 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/oauth2"
+)
+
+func TestRequestToken(t *testing.T) {
+	spec.Run(t, "RequestToken", testRequestToken, spec.Report(report.Terminal{}))
+}
+
+func testRequestToken(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		requests []url.Values
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		requests = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal("/oauth/token"))
+			Expect(req.ParseForm()).To(Succeed())
+			requests = append(requests, req.Form)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"` + req.Form.Get("token_format") + `-token","refresh_token":"refresh","token_type":"bearer","expires_in":3600}`))
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("requests a token in another format without replacing the API's token", func() {
+		api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken, uaa.WithScopes("uaa.resource"))
+		Expect(err).NotTo(HaveOccurred())
+		token, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("jwt-token"))
+
+		opaque, err := api.RequestToken(uaa.OpaqueToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opaque.AccessToken).To(Equal("opaque-token"))
+		Expect(requests[1].Get("scope")).To(Equal("uaa.resource"))
+
+		token, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("jwt-token"))
+		Expect(requests).To(HaveLen(2))
+	})
+
+	it("requests a token with the password grant", func() {
+		api, err := uaa.NewWithPasswordCredentials(s.URL, "", "client", "secret", "user", "pass", uaa.JSONWebToken)
+		Expect(err).NotTo(HaveOccurred())
+		opaque, err := api.RequestToken(uaa.OpaqueToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opaque.AccessToken).To(Equal("opaque-token"))
+		Expect(requests[0].Get("grant_type")).To(Equal("password"))
+	})
+
+	it("refreshes the token of an API built with an authorization code", func() {
+		api, err := uaa.NewWithAuthorizationCode(s.URL, "", "client", "secret", "code", false, uaa.JSONWebToken)
+		Expect(err).NotTo(HaveOccurred())
+		opaque, err := api.RequestToken(uaa.OpaqueToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(opaque.AccessToken).To(Equal("opaque-token"))
+		Expect(requests[1].Get("grant_type")).To(Equal("refresh_token"))
+		Expect(requests[1].Get("refresh_token")).To(Equal("refresh"))
+	})
+
+	it("returns an error for an API built with a token", func() {
+		api, err := uaa.NewWithToken(s.URL, "", oauth2.Token{AccessToken: "token", Expiry: time.Now().Add(time.Hour)})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.RequestToken(uaa.OpaqueToken)
+		Expect(err).To(HaveOccurred())
+	})
+
+	it("rejects unknown formats", func() {
+		api, _ := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken)
+		_, err := api.RequestToken(uaa.TokenFormat(7))
+		Expect(err).To(HaveOccurred())
+		Expect(requests).To(BeEmpty())
+	})
+}
@@ -0,0 +1,67 @@
+package uaa
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrResponseTooLarge is returned when the body of a response from the UAA is
+// larger than the maximum set with WithMaxResponseSize. Use errors.Cause from
+// github.com/pkg/errors to compare against it.
+type ErrResponseTooLarge struct {
+	Limit int64
+}
+
+func (e ErrResponseTooLarge) Error() string {
+	return fmt.Sprintf("the response is larger than the limit of %d bytes", e.Limit)
+}
+
+// WithMaxResponseSize returns an Option that fails requests whose responses
+// have bodies of more than n bytes with an ErrResponseTooLarge, so that a
+// misbehaving endpoint cannot make the client run out of memory. Responses
+// that announce a larger Content-Length are rejected before they are read.
+// The Each* methods and ExportUsersCSV decode list responses as they are
+// read rather than holding them in memory, so the limit applies to each page.
+// An n of 0 or less removes the limit, which is the default.
+func WithMaxResponseSize(n int64) Option {
+	return func(a *API) {
+		a.maxResponseSize = n
+	}
+}
+
+// limitBody returns the response's body, limited to the API's maximum
+// response size.
+func (a *API) limitBody(resp *http.Response) (io.Reader, error) {
+	if a.maxResponseSize <= 0 {
+		return resp.Body, nil
+	}
+	if resp.ContentLength > a.maxResponseSize {
+		return nil, ErrResponseTooLarge{Limit: a.maxResponseSize}
+	}
+	return &maxBytesReader{r: resp.Body, remaining: a.maxResponseSize, limit: a.maxResponseSize}, nil
+}
+
+// maxBytesReader reads from r until more than limit bytes have been read, and
+// then fails with ErrResponseTooLarge.
+type maxBytesReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.remaining < 0 {
+		return 0, ErrResponseTooLarge{Limit: m.limit}
+	}
+	// Read one byte more than remains, to find out whether the body goes on.
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n + int(m.remaining), ErrResponseTooLarge{Limit: m.limit}
+	}
+	return n, err
+}
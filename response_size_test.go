@@ -0,0 +1,91 @@
+package uaa_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestResponseSize(t *testing.T) {
+	spec.Run(t, "ResponseSize", testResponseSize, spec.Report(report.Terminal{}))
+}
+
+func testResponseSize(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		a       *uaa.API
+		chunked bool
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		chunked = false
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			var body string
+			switch req.URL.Path {
+			case "/info":
+				body = fmt.Sprintf(`{"zone_name":"%s"}`, strings.Repeat("a", 1000))
+			case "/Users":
+				users := make([]string, 50)
+				for i := range users {
+					users[i] = fmt.Sprintf(`{"id":"%d","userName":"user-%d"}`, i, i)
+				}
+				body = fmt.Sprintf(`{"resources":[%s],"startIndex":1,"itemsPerPage":50,"totalResults":50}`, strings.Join(users, ","))
+			}
+			if chunked {
+				w.(http.Flusher).Flush()
+			}
+			w.Write([]byte(body))
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("reads responses within the limit", func() {
+		uaa.WithMaxResponseSize(2000)(a)
+		info, err := a.GetInfo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.ZoneName).To(HaveLen(1000))
+	})
+
+	it("rejects a response whose Content-Length is over the limit", func() {
+		uaa.WithMaxResponseSize(500)(a)
+		_, err := a.GetInfo()
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrResponseTooLarge{Limit: 500}))
+	})
+
+	it("rejects a response without a Content-Length once it passes the limit", func() {
+		chunked = true
+		uaa.WithMaxResponseSize(500)(a)
+		_, err := a.GetInfo()
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrResponseTooLarge{Limit: 500}))
+	})
+
+	it("limits each page that is streamed", func() {
+		chunked = true
+		uaa.WithMaxResponseSize(500)(a)
+		err := a.EachUser("", "", "", "", func(uaa.User) error { return nil })
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrResponseTooLarge{Limit: 500}))
+
+		uaa.WithMaxResponseSize(5000)(a)
+		count := 0
+		err = a.EachUser("", "", "", "", func(uaa.User) error { count++; return nil })
+		Expect(err).NotTo(HaveOccurred())
+		Expect(count).To(Equal(50))
+	})
+}
@@ -0,0 +1,144 @@
+package uaa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// RestrictedClientsEndpoint is the path to the clients resource that refuses
+// restricted scopes.
+const RestrictedClientsEndpoint string = "/oauth/clients/restricted"
+
+// ErrRestrictedScopes is returned, when the API was created with
+// WithRestrictedScopeCheck or by CheckRestrictedScopes, for a client that
+// would be given scopes or authorities that the UAA restricts to its own
+// administration, such as uaa.admin. Use errors.Cause from
+// github.com/pkg/errors to get it from the returned error.
+type ErrRestrictedScopes struct {
+	ClientID string
+	// Scopes are the client's restricted scopes and authorities.
+	Scopes []string
+}
+
+func (e ErrRestrictedScopes) Error() string {
+	return fmt.Sprintf("client %s would be given the restricted scopes %s", e.ClientID, strings.Join(e.Scopes, ", "))
+}
+
+// RestrictedScopes returns the scopes that the UAA restricts to its own
+// administration, which clients created with CreateRestrictedClient may not
+// be given.
+func (a *API) RestrictedScopes() ([]string, error) {
+	u := urlWithPath(*a.TargetURL, RestrictedClientsEndpoint)
+	var scopes []string
+	err := a.doJSON(http.MethodGet, &u, nil, &scopes, true)
+	if err != nil {
+		return nil, err
+	}
+	return scopes, nil
+}
+
+// RestrictedScopes returns the client's scopes and authorities that are among
+// the restricted scopes, without duplicates.
+func (c *Client) RestrictedScopes(restricted []string) []string {
+	var found []string
+	for _, scope := range append(append([]string(nil), c.Scope...), c.Authorities...) {
+		if contains(restricted, scope) && !contains(found, scope) {
+			found = append(found, scope)
+		}
+	}
+	return found
+}
+
+// CheckRestrictedScopes returns an ErrRestrictedScopes if the client has any
+// of the UAA's restricted scopes among its scopes or authorities.
+func (a *API) CheckRestrictedScopes(client Client) error {
+	restricted, err := a.RestrictedScopes()
+	if err != nil {
+		return err
+	}
+	if found := client.RestrictedScopes(restricted); len(found) > 0 {
+		return ErrRestrictedScopes{ClientID: client.ClientID, Scopes: found}
+	}
+	return nil
+}
+
+// CreateRestrictedClient creates the client through the UAA's restricted
+// clients endpoint, which refuses to create a client with any of the
+// restricted scopes.
+func (a *API) CreateRestrictedClient(client Client) (*Client, error) {
+	return a.writeRestrictedClient(http.MethodPost, RestrictedClientsEndpoint, client)
+}
+
+// UpdateRestrictedClient updates the client through the UAA's restricted
+// clients endpoint, which refuses to give the client any of the restricted
+// scopes.
+func (a *API) UpdateRestrictedClient(client Client) (*Client, error) {
+	if client.ClientID == "" {
+		return nil, fmt.Errorf("client ID cannot be blank")
+	}
+	return a.writeRestrictedClient(http.MethodPut, fmt.Sprintf("%s/%s", RestrictedClientsEndpoint, client.ClientID), client)
+}
+
+func (a *API) writeRestrictedClient(method string, path string, client Client) (*Client, error) {
+	u := urlWithPath(*a.TargetURL, path)
+	j, err := json.Marshal(client)
+	if err != nil {
+		return nil, err
+	}
+	written := &Client{}
+	err = a.doJSON(method, &u, bytes.NewBuffer(j), written, true)
+	if err != nil {
+		return nil, err
+	}
+	return written, nil
+}
+
+// WithRestrictedScopeCheck returns an Option under which requests that would
+// create or update a client with any of the UAA's restricted scopes among
+// its scopes or authorities fail with ErrRestrictedScopes without being
+// sent, so that automation does not grant administrative privileges by
+// accident. The restricted scopes are read from the UAA before each such
+// request. Clients written through the restricted clients endpoint, and with
+// Curl, are not checked.
+func WithRestrictedScopeCheck() Option {
+	return func(a *API) {
+		a.restrictedScopeCheck = true
+	}
+}
+
+// checkRestrictedScopes returns an ErrRestrictedScopes if the request would
+// create or update a client with restricted scopes.
+func (a *API) checkRestrictedScopes(req *http.Request) error {
+	if !a.restrictedScopeCheck || req.GetBody == nil {
+		return nil
+	}
+	if req.Method != http.MethodPost && req.Method != http.MethodPut {
+		return nil
+	}
+	segments := strings.Split(strings.TrimPrefix(req.URL.Path, ClientsEndpoint), "/")
+	if !strings.HasPrefix(req.URL.Path, ClientsEndpoint) || len(segments) > 2 || segments[0] != "" {
+		return nil
+	}
+	if len(segments) == 2 && (segments[1] == "restricted" || segments[1] == "tx") {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil
+	}
+	var client Client
+	if err := json.Unmarshal(data, &client); err != nil {
+		return nil
+	}
+	return a.CheckRestrictedScopes(client)
+}
@@ -0,0 +1,101 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestRestrictedScopes(t *testing.T) {
+	spec.Run(t, "RestrictedScopes", testRestrictedScopes, spec.Report(report.Terminal{}))
+}
+
+func testRestrictedScopes(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s     *httptest.Server
+		a     *uaa.API
+		calls []string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		calls = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, req.Method+" "+req.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			if req.Method == http.MethodGet && req.URL.Path == uaa.RestrictedClientsEndpoint {
+				w.Write([]byte(`["uaa.admin","scim.write","clients.admin"]`))
+				return
+			}
+			var client uaa.Client
+			Expect(json.NewDecoder(req.Body).Decode(&client)).To(Succeed())
+			json.NewEncoder(w).Encode(client)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("lists the restricted scopes", func() {
+		scopes, err := a.RestrictedScopes()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(scopes).To(Equal([]string{"uaa.admin", "scim.write", "clients.admin"}))
+	})
+
+	it("finds a client's restricted scopes and authorities", func() {
+		client := uaa.Client{Scope: []string{"openid", "scim.write"}, Authorities: []string{"uaa.admin", "scim.write"}}
+		Expect(client.RestrictedScopes([]string{"uaa.admin", "scim.write"})).To(Equal([]string{"scim.write", "uaa.admin"}))
+	})
+
+	it("writes clients through the restricted endpoint", func() {
+		_, err := a.CreateRestrictedClient(uaa.Client{ClientID: "app"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = a.UpdateRestrictedClient(uaa.Client{ClientID: "app"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal([]string{"POST /oauth/clients/restricted", "PUT /oauth/clients/restricted/app"}))
+	})
+
+	when("the restricted scope check is enabled", func() {
+		it.Before(func() {
+			uaa.WithRestrictedScopeCheck()(a)
+		})
+
+		it("refuses to create a client with restricted scopes", func() {
+			_, err := a.CreateClient(uaa.Client{ClientID: "app", Authorities: []string{"clients.admin"}})
+			Expect(errors.Cause(err)).To(Equal(uaa.ErrRestrictedScopes{ClientID: "app", Scopes: []string{"clients.admin"}}))
+			Expect(calls).To(Equal([]string{"GET /oauth/clients/restricted"}))
+		})
+
+		it("refuses to update a client with restricted scopes", func() {
+			_, err := a.UpdateClient(uaa.Client{ClientID: "app", Scope: []string{"uaa.admin"}})
+			Expect(errors.Cause(err)).To(BeAssignableToTypeOf(uaa.ErrRestrictedScopes{}))
+		})
+
+		it("creates a client without restricted scopes", func() {
+			_, err := a.CreateClient(uaa.Client{ClientID: "app", Scope: []string{"openid"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(calls).To(Equal([]string{"GET /oauth/clients/restricted", "POST /oauth/clients"}))
+		})
+
+		it("does not check other client requests", func() {
+			Expect(a.ChangeClientSecret("app", "secret")).To(Succeed())
+			Expect(calls).To(Equal([]string{"PUT /oauth/clients/app/secret"}))
+		})
+	})
+}
@@ -1,16 +1,17 @@
 package uaa
 
 import (
-	"crypto/tls"
+	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
 )
 
@@ -42,95 +43,210 @@ func (a *API) doJSONWithHeaders(method string, url *url.URL, headers map[string]
 }
 
 func (a *API) doAndRead(req *http.Request, needsAuthentication bool) ([]byte, error) {
+	_, bytes, err := a.doAndReadWithHeader(req, needsAuthentication)
+	return bytes, err
+}
+
+// doAndReadWithHeader is doAndRead for callers that need the response's
+// header too. The header is nil when the request is recorded in dry-run mode.
+func (a *API) doAndReadWithHeader(req *http.Request, needsAuthentication bool) (http.Header, []byte, error) {
+	resp, planned, err := a.doRequest(req, needsAuthentication)
+	if err != nil || planned != nil {
+		return nil, planned, err
+	}
+	defer resp.Body.Close()
+
+	if !is2XX(resp.StatusCode) {
+		err := statusError(req, resp)
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil, nil, err
+	}
+
+	bytes, err := a.readBody(resp)
+	if _, ok := err.(ErrResponseTooLarge); ok {
+		return nil, nil, errors.Wrapf(err, "An error occurred while calling %s", req.URL.String())
+	}
+	if err != nil {
+		if a.verbose() {
+			fmt.Printf("%v\n\n", err)
+		}
+		return nil, nil, unknownError()
+	}
+	return resp.Header, bytes, nil
+}
+
+// readBody reads the response's body into a buffer sized from its
+// Content-Length, so that large responses are not copied as the buffer grows.
+func (a *API) readBody(resp *http.Response) ([]byte, error) {
+	body, err := a.limitBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ContentLength <= 0 {
+		return ioutil.ReadAll(body)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, resp.ContentLength+bytes.MinRead))
+	_, err = buf.ReadFrom(body)
+	return buf.Bytes(), err
+}
+
+// doStream sends the request and passes the body of a successful response to
+// read, so that large responses can be decoded without holding them in
+// memory.
+func (a *API) doStream(req *http.Request, needsAuthentication bool, read func(io.Reader) error) error {
+	resp, planned, err := a.doRequest(req, needsAuthentication)
+	if err != nil {
+		return err
+	}
+	if planned != nil {
+		return read(bytes.NewReader(planned))
+	}
+	defer resp.Body.Close()
+
+	if !is2XX(resp.StatusCode) {
+		err := statusError(req, resp)
+		io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+	body, err := a.limitBody(resp)
+	if err != nil {
+		return errors.Wrapf(err, "An error occurred while calling %s", req.URL.String())
+	}
+	return read(body)
+}
+
+// doRequest sends the request. If the request is recorded in dry-run mode
+// instead of being sent, the planned response body is returned in place of
+// the response.
+func (a *API) doRequest(req *http.Request, needsAuthentication bool) (*http.Response, []byte, error) {
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("X-Identity-Zone-Id", a.ZoneID)
 	switch req.Method {
 	case http.MethodPut, http.MethodPost, http.MethodPatch:
-		req.Header.Add("Content-Type", "application/json")
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Add("Content-Type", "application/json")
+		}
 	}
-	if a.Verbose {
+	if a.verbose() {
 		logRequest(req)
 	}
+	if err := a.checkReadOnly(req); err != nil {
+		return nil, nil, err
+	}
+	if body, planned, err := a.plan(req); planned || err != nil {
+		return nil, body, err
+	}
+	if needsAuthentication {
+		if err := a.checkScopes(req); err != nil {
+			return nil, nil, err
+		}
+		if err := a.checkRestrictedScopes(req); err != nil {
+			return nil, nil, err
+		}
+	}
 	if a.AuthenticatedClient == nil {
-		return nil, errors.New("doAndRead: the HTTPClient cannot be nil")
+		return nil, nil, errors.New("doAndRead: the HTTPClient cannot be nil")
 	}
 	a.ensureTimeout()
 	var (
 		resp *http.Response
 		err  error
 	)
+	// The unauthenticated client is also used to fetch tokens.
+	a.ensureTransport(a.UnauthenticatedClient)
 	if needsAuthentication {
 		a.ensureTransport(a.AuthenticatedClient)
-		resp, err = a.AuthenticatedClient.Do(req)
+		resp, err = a.send(a.AuthenticatedClient, req)
 	} else {
-		a.ensureTransport(a.UnauthenticatedClient)
-		resp, err = a.UnauthenticatedClient.Do(req)
+		resp, err = a.send(a.UnauthenticatedClient, req)
 	}
 
 	if err != nil {
-		if a.Verbose {
+		if a.verbose() {
 			fmt.Printf("%v\n\n", err)
 		}
+		if errors.Cause(err) == ErrCircuitOpen {
+			return nil, nil, err
+		}
+		if credentialsErr := badCredentials(err); credentialsErr != nil {
+			return nil, nil, credentialsErr
+		}
 
-		return nil, requestError(req.URL.String())
+		return nil, nil, requestError(req.URL.String())
 	}
 
-	if a.Verbose {
+	if a.verbose() {
 		logResponse(resp)
 	}
-
-	bytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		if a.Verbose {
-			fmt.Printf("%v\n\n", err)
-		}
-		return nil, unknownError()
-	}
-
-	if !is2XX(resp.StatusCode) {
-		return nil, requestError(req.URL.String())
-	}
-	return bytes, nil
+	return resp, nil, nil
 }
 
+// transportMu guards the clients' timeouts and transports, which
+// ensureTimeout and ensureTransport set before requests. It is not per API
+// because clients may be shared between APIs.
+var transportMu sync.Mutex
+
 func (a *API) ensureTimeout() {
+	transportMu.Lock()
+	defer transportMu.Unlock()
 	if a.AuthenticatedClient != nil && a.AuthenticatedClient.Timeout == 0 {
 		a.AuthenticatedClient.Timeout = time.Second * 120
 	}
 }
 
+// ensureTransport makes the client's transport verify certificates or not
+// according to SkipSSLValidation, so that setting the field after the API is
+// built takes effect. The transport is replaced with a modified copy, so
+// transports such as http.DefaultTransport are not affected. Each client is
+// only checked again when the setting changes, so that transports are not
+// replaced while requests are in flight.
 func (a *API) ensureTransport(c *http.Client) {
 	if c == nil {
 		return
 	}
-	switch t := c.Transport.(type) {
-	case *oauth2.Transport:
-		b, ok := t.Base.(*http.Transport)
-		if !ok {
-			return
-		}
-		if b.TLSClientConfig == nil && !a.SkipSSLValidation {
-			return
-		}
-		if b.TLSClientConfig == nil {
-			b.TLSClientConfig = &tls.Config{}
-		}
-		b.TLSClientConfig.InsecureSkipVerify = a.SkipSSLValidation
-	case *tokenTransport:
-		if t.underlyingTransport.TLSClientConfig == nil && !a.SkipSSLValidation {
-			return
-		}
-		if t.underlyingTransport.TLSClientConfig == nil {
-			t.underlyingTransport.TLSClientConfig = &tls.Config{}
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	skipped, ok := a.configuredClients[c]
+	if ok && skipped == a.SkipSSLValidation {
+		return
+	}
+	if a.configuredClients == nil {
+		a.configuredClients = map[*http.Client]bool{}
+	}
+	a.configuredClients[c] = a.SkipSSLValidation
+	if !a.SkipSSLValidation && !skipped {
+		return
+	}
+	if skipsVerification(c.Transport) == a.SkipSSLValidation {
+		return
+	}
+	c.Transport = modifyTransport(c.Transport, insecureSkipVerify(a.SkipSSLValidation))
+}
+
+// unauthenticatedClient returns the unauthenticated client, configured by
+// ensureTransport, or a client using http.DefaultTransport if there is none.
+func (a *API) unauthenticatedClient() *http.Client {
+	if a.UnauthenticatedClient == nil {
+		c := &http.Client{Transport: http.DefaultTransport}
+		if a.SkipSSLValidation {
+			c.Transport = modifyTransport(c.Transport, insecureSkipVerify(true))
 		}
-		t.underlyingTransport.TLSClientConfig.InsecureSkipVerify = a.SkipSSLValidation
+		return c
+	}
+	a.ensureTransport(a.UnauthenticatedClient)
+	return a.UnauthenticatedClient
+}
+
+// skipsVerification reports whether the transport underneath rt skips
+// certificate verification.
+func skipsVerification(rt http.RoundTripper) bool {
+	switch t := unwrapTransport(rt).(type) {
 	case *http.Transport:
-		if t.TLSClientConfig == nil && !a.SkipSSLValidation {
-			return
-		}
-		if t.TLSClientConfig == nil {
-			t.TLSClientConfig = &tls.Config{}
-		}
-		t.TLSClientConfig.InsecureSkipVerify = a.SkipSSLValidation
+		return t.TLSClientConfig != nil && t.TLSClientConfig.InsecureSkipVerify
+	case *oauth2.Transport:
+		return skipsVerification(t.Base)
+	case *tokenTransport:
+		return t.underlyingTransport != nil && skipsVerification(t.underlyingTransport)
 	}
+	return false
 }
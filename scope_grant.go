@@ -0,0 +1,87 @@
+package uaa
+
+import (
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// ScopeGrant compares the scopes requested for a token with those the UAA
+// granted. The UAA silently grants fewer scopes than were requested when the
+// client or user is not allowed some of them, which otherwise only shows up
+// as 403 responses from the APIs that need them.
+type ScopeGrant struct {
+	// Requested are the scopes requested with WithScopes, or nil if every
+	// scope the client is allowed was requested.
+	Requested []string
+	// Granted are the scopes the token carries.
+	Granted []string
+}
+
+// NewScopeGrant compares the requested scopes with the scopes granted with the
+// token, read from the token response or else from the token's claims.
+func NewScopeGrant(requested []string, token *oauth2.Token) ScopeGrant {
+	grant := ScopeGrant{Requested: requested, Granted: GrantedScopes(token)}
+	if len(grant.Granted) == 0 {
+		if claims, err := DecodeClaims(token.AccessToken); err == nil {
+			grant.Granted = claims.Scope
+		}
+	}
+	return grant
+}
+
+// Missing returns the requested scopes that were not granted. A requested
+// scope with a wildcard is granted if any granted scope matches it.
+func (g ScopeGrant) Missing() []string {
+	var missing []string
+	for _, scope := range g.Requested {
+		granted := false
+		for _, s := range g.Granted {
+			if ScopeMatches(scope, s) {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
+// Narrowed returns true if any requested scope was not granted.
+func (g ScopeGrant) Narrowed() bool {
+	return len(g.Missing()) > 0
+}
+
+// WithNarrowedScopesHook returns an Option that calls hook whenever the API
+// obtains a token without some of the scopes requested with WithScopes, so
+// that the shortfall can be logged when the token is issued rather than
+// discovered from a 403. hook is called synchronously while the token is
+// obtained, so it must not block or use the API. It applies to APIs that
+// obtain their own tokens and must be passed to the constructor.
+func WithNarrowedScopesHook(hook func(ScopeGrant)) Option {
+	return func(a *API) {
+		a.narrowedScopes = hook
+	}
+}
+
+// ScopeGrant compares the scopes requested with WithScopes with those granted
+// with the API's current token.
+func (a *API) ScopeGrant() (ScopeGrant, error) {
+	token, err := a.Token()
+	if err != nil {
+		return ScopeGrant{}, err
+	}
+	return NewScopeGrant(a.requestedScopes(), token), nil
+}
+
+// requestedScopes returns the scopes requested with token requests, or nil
+// if none were.
+func (a *API) requestedScopes() []string {
+	scopes := strings.Fields(strings.Join(a.tokenParams["scope"], " "))
+	if len(scopes) == 0 {
+		return nil
+	}
+	return scopes
+}
@@ -0,0 +1,92 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestScopeGrant(t *testing.T) {
+	spec.Run(t, "ScopeGrant", testScopeGrant, spec.Report(report.Terminal{}))
+}
+
+func testScopeGrant(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		granted  string
+		narrowed []uaa.ScopeGrant
+		events   []uaa.TokenEvent
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		granted = "scim.read"
+		narrowed = nil
+		events = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": unsignedJWT(map[string]interface{}{"jti": "1"}),
+				"token_type":   "bearer",
+				"expires_in":   3600,
+				"scope":        granted,
+			})
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	newAPI := func(opts ...uaa.Option) *uaa.API {
+		opts = append(opts,
+			uaa.WithNarrowedScopesHook(func(g uaa.ScopeGrant) { narrowed = append(narrowed, g) }),
+			uaa.WithTokenEvents(func(e uaa.TokenEvent) { events = append(events, e) }))
+		api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken, opts...)
+		Expect(err).NotTo(HaveOccurred())
+		return api
+	}
+
+	it("warns when fewer scopes are granted than requested", func() {
+		api := newAPI(uaa.WithScopes("scim.read", "scim.write"))
+		grant, err := api.ScopeGrant()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grant).To(Equal(uaa.ScopeGrant{Requested: []string{"scim.read", "scim.write"}, Granted: []string{"scim.read"}}))
+		Expect(grant.Missing()).To(Equal([]string{"scim.write"}))
+		Expect(narrowed).To(Equal([]uaa.ScopeGrant{grant}))
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].ScopeGrant()).To(Equal(grant))
+	})
+
+	it("does not warn when every requested scope is granted", func() {
+		granted = "scim.read scim.write"
+		api := newAPI(uaa.WithScopes("scim.write"))
+		grant, err := api.ScopeGrant()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grant.Narrowed()).To(BeFalse())
+		Expect(narrowed).To(BeEmpty())
+	})
+
+	it("does not warn when no scopes were requested", func() {
+		api := newAPI()
+		grant, err := api.ScopeGrant()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grant.Requested).To(BeNil())
+		Expect(grant.Narrowed()).To(BeFalse())
+		Expect(narrowed).To(BeEmpty())
+	})
+
+	it("matches requested wildcard scopes", func() {
+		grant := uaa.ScopeGrant{
+			Requested: []string{"spaces.*.developer", "orgs.*.manager"},
+			Granted:   []string{"spaces.a1b2.developer"},
+		}
+		Expect(grant.Missing()).To(Equal([]string{"orgs.*.manager"}))
+	})
+}
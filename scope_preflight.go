@@ -0,0 +1,127 @@
+package uaa
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrInsufficientScope is returned, when the API was created with
+// WithScopePreflight, for a request that the API's token does not have the
+// scopes to make. Use errors.Cause from github.com/pkg/errors to get it from
+// the returned error.
+type ErrInsufficientScope struct {
+	// Operation is the kind of request that was refused.
+	Operation Operation
+	// Needed are the scopes that allow the operation, any one of which
+	// would do.
+	Needed []string
+	// Have are the token's scopes and authorities.
+	Have []string
+}
+
+func (e ErrInsufficientScope) Error() string {
+	return fmt.Sprintf("the token cannot %s: it needs one of the scopes %v, but has %v", e.Operation, e.Needed, e.Have)
+}
+
+// WithScopePreflight returns an Option under which requests that the API's
+// token does not have the scopes to make fail with ErrInsufficientScope
+// without being sent, rather than being refused by the UAA with a less
+// helpful 403. Only JSON Web Tokens can be checked; requests made with an
+// opaque token, or that are not recognized, are sent as usual. When the API
+// targets a zone, tokens with zone-specific scopes for it are not checked.
+func WithScopePreflight() Option {
+	return func(a *API) {
+		a.scopePreflight = true
+	}
+}
+
+// checkScopes returns an ErrInsufficientScope if the request is known to
+// require scopes that the API's token does not have.
+func (a *API) checkScopes(req *http.Request) error {
+	if !a.scopePreflight {
+		return nil
+	}
+	operation := operationFor(req)
+	if operation == "" {
+		return nil
+	}
+	token, err := a.Token()
+	if err != nil {
+		return nil
+	}
+	claims, err := DecodeClaims(token.AccessToken)
+	if err != nil {
+		return nil
+	}
+	if a.ZoneID != "" {
+		for _, scope := range append(claims.Scope, claims.Authorities...) {
+			if strings.HasPrefix(scope, "zones."+a.ZoneID+".") {
+				return nil
+			}
+		}
+	}
+	if claims.CanPerform(operation) {
+		return nil
+	}
+	return ErrInsufficientScope{
+		Operation: operation,
+		Needed:    RequiredScopesFor(operation),
+		Have:      append(append([]string(nil), claims.Scope...), claims.Authorities...),
+	}
+}
+
+// operationFor returns the operation the request performs, or "" if it is
+// not known.
+func operationFor(req *http.Request) Operation {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	read := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	switch "/" + segments[0] {
+	case UsersEndpoint:
+		switch {
+		case len(segments) > 2:
+			return ""
+		case read:
+			return ReadUsers
+		case req.Method == http.MethodPost:
+			return CreateUsers
+		}
+		return WriteUsers
+	case GroupsEndpoint:
+		switch {
+		case read:
+			return ReadGroups
+		case len(segments) > 2 && segments[2] == "members":
+			return WriteGroupMembers
+		case len(segments) > 2:
+			return ""
+		}
+		return WriteGroups
+	case IdentityZonesEndpoint:
+		if read {
+			return ReadIdentityZones
+		}
+		return WriteIdentityZones
+	case IdentityProvidersEndpoint:
+		if read {
+			return ReadIdentityProviders
+		}
+		return WriteIdentityProviders
+	case "/userinfo":
+		return ReadUserInfo
+	}
+
+	if len(segments) >= 2 && "/"+segments[0]+"/"+segments[1] == ClientsEndpoint {
+		switch {
+		case read:
+			return ReadClients
+		case len(segments) == 4 && segments[3] == "secret":
+			return ChangeClientSecrets
+		case len(segments) > 3:
+			return ""
+		}
+		return WriteClients
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/oauth2"
+)
+
+func TestScopePreflight(t *testing.T) {
+	spec.Run(t, "ScopePreflight", testScopePreflight, spec.Report(report.Terminal{}))
+}
+
+func testScopePreflight(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s      *httptest.Server
+		called int
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		called = 0
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			called++
+			w.Write([]byte(PaginatedResponse(uaa.User{ID: "user-id"})))
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	newAPI := func(accessToken string, zoneID string, opts ...uaa.Option) *uaa.API {
+		api, err := uaa.NewWithToken(s.URL, zoneID, oauth2.Token{AccessToken: accessToken, Expiry: time.Now().Add(time.Hour)}, opts...)
+		Expect(err).NotTo(HaveOccurred())
+		return api
+	}
+
+	it("refuses requests the token cannot make without sending them", func() {
+		api := newAPI(unsignedJWT(map[string]interface{}{"scope": []string{"scim.read"}}), "", uaa.WithScopePreflight())
+		_, err := api.CreateUser(uaa.User{Username: "marcus"})
+		Expect(called).To(Equal(0))
+		insufficient, ok := errors.Cause(err).(uaa.ErrInsufficientScope)
+		Expect(ok).To(BeTrue())
+		Expect(insufficient.Operation).To(Equal(uaa.CreateUsers))
+		Expect(insufficient.Needed).To(ContainElement(uaa.ScopeSCIMWrite))
+		Expect(insufficient.Have).To(Equal([]string{"scim.read"}))
+		Expect(err.Error()).To(ContainSubstring("the token cannot create users"))
+	})
+
+	it("sends requests the token can make", func() {
+		api := newAPI(unsignedJWT(map[string]interface{}{"scope": []string{"scim.read"}}), "", uaa.WithScopePreflight())
+		_, err := api.ListAllUsers("", "", "", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(called).To(Equal(1))
+	})
+
+	it("checks client authorities", func() {
+		api := newAPI(unsignedJWT(map[string]interface{}{"authorities": []string{"clients.read"}}), "", uaa.WithScopePreflight())
+		_, err := api.DeleteClient("app")
+		_, ok := errors.Cause(err).(uaa.ErrInsufficientScope)
+		Expect(ok).To(BeTrue())
+		Expect(called).To(Equal(0))
+	})
+
+	it("sends requests when the token is opaque", func() {
+		api := newAPI("opaque-token", "", uaa.WithScopePreflight())
+		_, err := api.CreateUser(uaa.User{Username: "marcus"})
+		Expect(errors.Cause(err)).NotTo(BeAssignableToTypeOf(uaa.ErrInsufficientScope{}))
+		Expect(called).To(Equal(1))
+	})
+
+	it("leaves zone-specific scopes to the UAA", func() {
+		api := newAPI(unsignedJWT(map[string]interface{}{"scope": []string{"zones.zone1.admin"}}), "zone1", uaa.WithScopePreflight())
+		api.CreateUser(uaa.User{Username: "marcus"})
+		Expect(called).To(Equal(1))
+	})
+
+	it("does not check scopes by default", func() {
+		api := newAPI(unsignedJWT(map[string]interface{}{"scope": []string{"scim.read"}}), "")
+		api.CreateUser(uaa.User{Username: "marcus"})
+		Expect(called).To(Equal(1))
+	})
+}
@@ -0,0 +1,79 @@
+package uaa
+
+import "sort"
+
+// UndefinedScope is a scope or authority that clients are granted but that no
+// group defines, so that no user can hold it.
+type UndefinedScope struct {
+	Scope   string   `json:"scope"`
+	Clients []string `json:"clients"`
+}
+
+// ScopeReport cross-references the scopes and authorities of clients with the
+// groups that define them.
+type ScopeReport struct {
+	// Undefined are the scopes and authorities granted to clients that no
+	// group defines. Tokens will never contain them, which shows up as
+	// insufficient_scope errors.
+	Undefined []UndefinedScope `json:"undefined"`
+	// Unused are the display names of groups that no client is granted as a
+	// scope or authority.
+	Unused []string `json:"unused"`
+}
+
+// ScopeReport lists every client and group and reports the scopes that
+// clients are granted but no group defines, and the groups that no client is
+// granted. A client scope ending in a wildcard, such as "cloud_controller.*",
+// covers every group with that prefix. The special scope "uaa.none" is
+// ignored.
+func (a *API) ScopeReport() (*ScopeReport, error) {
+	clients, err := a.ListAllClients("", "", "")
+	if err != nil {
+		return nil, err
+	}
+	groups, err := a.ListAllGroups("", "", "displayName", "")
+	if err != nil {
+		return nil, err
+	}
+
+	defined := map[string]bool{}
+	for _, group := range groups {
+		defined[group.DisplayName] = true
+	}
+
+	used := map[string]bool{}
+	undefined := map[string][]string{}
+	for _, client := range clients {
+		for _, scope := range append(append([]string{}, client.Scope...), client.Authorities...) {
+			if scope == "" || scope == "uaa.none" {
+				continue
+			}
+			matched := false
+			for name := range defined {
+				if ScopeMatches(scope, name) {
+					used[name] = true
+					matched = true
+				}
+			}
+			if !matched && !contains(undefined[scope], client.ClientID) {
+				undefined[scope] = append(undefined[scope], client.ClientID)
+			}
+		}
+	}
+
+	report := &ScopeReport{}
+	for scope, clientIDs := range undefined {
+		sort.Strings(clientIDs)
+		report.Undefined = append(report.Undefined, UndefinedScope{Scope: scope, Clients: clientIDs})
+	}
+	sort.Slice(report.Undefined, func(i, j int) bool {
+		return report.Undefined[i].Scope < report.Undefined[j].Scope
+	})
+	for name := range defined {
+		if !used[name] {
+			report.Unused = append(report.Unused, name)
+		}
+	}
+	sort.Strings(report.Unused)
+	return report, nil
+}
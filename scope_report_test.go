@@ -0,0 +1,63 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestScopeReport(t *testing.T) {
+	spec.Run(t, "ScopeReport", testScopeReport, spec.Report(report.Terminal{}))
+}
+
+func testScopeReport(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s *httptest.Server
+		a *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case uaa.ClientsEndpoint:
+				w.Write([]byte(PaginatedResponse(
+					uaa.Client{ClientID: "cf", Scope: []string{"openid", "cloud_controller.*", "uaa.none"}},
+					uaa.Client{ClientID: "dashboard", Scope: []string{"openid", "dashboard.read"}, Authorities: []string{"dashboard.read"}},
+					uaa.Client{ClientID: "admin", Authorities: []string{"scim.write", "dashboard.read"}},
+				)))
+			case uaa.GroupsEndpoint:
+				w.Write([]byte(PaginatedResponse(
+					uaa.Group{DisplayName: "openid"},
+					uaa.Group{DisplayName: "cloud_controller.read"},
+					uaa.Group{DisplayName: "cloud_controller.write"},
+					uaa.Group{DisplayName: "cloud_controller.admin.read"},
+					uaa.Group{DisplayName: "scim.write"},
+					uaa.Group{DisplayName: "password.write"},
+				)))
+			}
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("flags undefined scopes and unused groups", func() {
+		r, err := a.ScopeReport()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Undefined).To(Equal([]uaa.UndefinedScope{
+			{Scope: "dashboard.read", Clients: []string{"admin", "dashboard"}},
+		}))
+		Expect(r.Unused).To(Equal([]string{"cloud_controller.admin.read", "password.write"}))
+	})
+}
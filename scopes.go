@@ -0,0 +1,108 @@
+package uaa
+
+// Well-known UAA scopes and authorities.
+const (
+	ScopeOpenID         = "openid"
+	ScopeProfile        = "profile"
+	ScopeEmail          = "email"
+	ScopeRoles          = "roles"
+	ScopeUserAttributes = "user_attributes"
+	ScopeUAAAdmin       = "uaa.admin"
+	ScopeUAAResource    = "uaa.resource"
+	ScopeUAAUser        = "uaa.user"
+	ScopeSCIMRead       = "scim.read"
+	ScopeSCIMWrite      = "scim.write"
+	ScopeSCIMCreate     = "scim.create"
+	ScopeSCIMInvite     = "scim.invite"
+	ScopeSCIMUserIDs    = "scim.userids"
+	ScopeGroupsUpdate   = "groups.update"
+	ScopePasswordWrite  = "password.write"
+	ScopeClientsRead    = "clients.read"
+	ScopeClientsWrite   = "clients.write"
+	ScopeClientsSecret  = "clients.secret"
+	ScopeClientsAdmin   = "clients.admin"
+	ScopeZonesRead      = "zones.read"
+	ScopeZonesWrite     = "zones.write"
+	ScopeIDPsRead       = "idps.read"
+	ScopeIDPsWrite      = "idps.write"
+	ScopeTokensRevoke   = "tokens.revoke"
+	ScopeTokensList     = "tokens.list"
+	ScopeOAuthLogin     = "oauth.login"
+	ScopeOAuthApprovals = "oauth.approvals"
+	ScopeUAANone        = "uaa.none"
+	ScopeSCIMZones      = "scim.zones"
+)
+
+// Operation is a kind of call to the UAA API, used to look up the scopes it
+// requires.
+type Operation string
+
+// Valid Operation values.
+const (
+	ReadUsers              = Operation("read users")
+	CreateUsers            = Operation("create users")
+	WriteUsers             = Operation("write users")
+	ReadGroups             = Operation("read groups")
+	WriteGroups            = Operation("write groups")
+	WriteGroupMembers      = Operation("write group members")
+	ReadClients            = Operation("read clients")
+	WriteClients           = Operation("write clients")
+	ChangeClientSecrets    = Operation("change client secrets")
+	ReadIdentityZones      = Operation("read identity zones")
+	WriteIdentityZones     = Operation("write identity zones")
+	ReadIdentityProviders  = Operation("read identity providers")
+	WriteIdentityProviders = Operation("write identity providers")
+	ReadUserInfo           = Operation("read user info")
+	IntrospectTokens       = Operation("introspect tokens")
+	RevokeTokens           = Operation("revoke tokens")
+)
+
+// requiredScopes are the scopes for each operation, any one of which allows
+// it, as documented at http://docs.cloudfoundry.org/api/uaa/version/4.14.0/.
+var requiredScopes = map[Operation][]string{
+	ReadUsers:              {ScopeSCIMRead, ScopeUAAAdmin},
+	CreateUsers:            {ScopeSCIMWrite, ScopeSCIMCreate, ScopeUAAAdmin},
+	WriteUsers:             {ScopeSCIMWrite, ScopeUAAAdmin},
+	ReadGroups:             {ScopeSCIMRead, ScopeUAAAdmin},
+	WriteGroups:            {ScopeSCIMWrite, ScopeUAAAdmin},
+	WriteGroupMembers:      {ScopeSCIMWrite, ScopeGroupsUpdate, ScopeUAAAdmin},
+	ReadClients:            {ScopeClientsRead, ScopeClientsAdmin, ScopeUAAAdmin},
+	WriteClients:           {ScopeClientsWrite, ScopeClientsAdmin, ScopeUAAAdmin},
+	ChangeClientSecrets:    {ScopeClientsSecret, ScopeClientsAdmin, ScopeUAAAdmin},
+	ReadIdentityZones:      {ScopeZonesRead, ScopeUAAAdmin},
+	WriteIdentityZones:     {ScopeZonesWrite, ScopeUAAAdmin},
+	ReadIdentityProviders:  {ScopeIDPsRead, ScopeUAAAdmin},
+	WriteIdentityProviders: {ScopeIDPsWrite, ScopeUAAAdmin},
+	ReadUserInfo:           {ScopeOpenID},
+	IntrospectTokens:       {ScopeUAAResource},
+	RevokeTokens:           {ScopeTokensRevoke, ScopeUAAAdmin},
+}
+
+// RequiredScopesFor returns the scopes that allow the operation; a token
+// needs any one of them. It returns nil for unknown operations.
+func RequiredScopesFor(operation Operation) []string {
+	scopes := requiredScopes[operation]
+	if scopes == nil {
+		return nil
+	}
+	return append([]string(nil), scopes...)
+}
+
+// CanPerform returns true if the token has one of the scopes required for the
+// operation, either as a scope or, for client credentials tokens, as an
+// authority. It returns true for unknown operations.
+func (c *Claims) CanPerform(operation Operation) bool {
+	scopes := requiredScopes[operation]
+	if scopes == nil {
+		return true
+	}
+	if c.HasAnyScope(scopes...) {
+		return true
+	}
+	for _, scope := range scopes {
+		if contains(c.Authorities, scope) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package uaa_test
+
+import (
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestScopes(t *testing.T) {
+	spec.Run(t, "Scopes", testScopes, spec.Report(report.Terminal{}))
+}
+
+func testScopes(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	when("RequiredScopesFor()", func() {
+		it("returns the scopes that allow an operation", func() {
+			Expect(uaa.RequiredScopesFor(uaa.ReadUsers)).To(ConsistOf(uaa.ScopeSCIMRead, uaa.ScopeUAAAdmin))
+			Expect(uaa.RequiredScopesFor(uaa.WriteClients)).To(ContainElement(uaa.ScopeClientsAdmin))
+		})
+
+		it("returns nil for unknown operations", func() {
+			Expect(uaa.RequiredScopesFor(uaa.Operation("fly"))).To(BeNil())
+		})
+
+		it("returns a copy", func() {
+			uaa.RequiredScopesFor(uaa.ReadUsers)[0] = "tampered"
+			Expect(uaa.RequiredScopesFor(uaa.ReadUsers)).NotTo(ContainElement("tampered"))
+		})
+	})
+
+	when("Claims.CanPerform()", func() {
+		it("checks the token's scopes", func() {
+			claims := &uaa.Claims{Scope: []string{uaa.ScopeSCIMRead}}
+			Expect(claims.CanPerform(uaa.ReadUsers)).To(BeTrue())
+			Expect(claims.CanPerform(uaa.WriteUsers)).To(BeFalse())
+		})
+
+		it("checks a client's authorities", func() {
+			claims := &uaa.Claims{Authorities: []string{uaa.ScopeClientsAdmin}}
+			Expect(claims.CanPerform(uaa.ChangeClientSecrets)).To(BeTrue())
+			Expect(claims.CanPerform(uaa.ReadUsers)).To(BeFalse())
+		})
+
+		it("allows unknown operations", func() {
+			Expect((&uaa.Claims{}).CanPerform(uaa.Operation("fly"))).To(BeTrue())
+		})
+	})
+}
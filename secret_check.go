@@ -0,0 +1,93 @@
+package uaa
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultSecretCheckInterval is the time CheckClientSecrets waits between
+// attempts if no interval is given.
+const DefaultSecretCheckInterval = time.Second
+
+// SecretCheckResult is the outcome of authenticating as a client with one of
+// the candidate secrets given to CheckClientSecrets.
+type SecretCheckResult struct {
+	// Index is the position of the secret in the candidates.
+	Index int
+	// Valid is true if the UAA accepted the secret.
+	Valid bool
+	// Err is set if the check could not be completed, in which case Valid
+	// is false but the secret may still be accepted.
+	Err error
+}
+
+// CheckClientSecrets attempts to authenticate as the client with the given
+// ID using each of the candidate secrets in turn, waiting interval between
+// attempts so as not to load the UAA, and reports which are accepted. It is
+// intended for verifying that rotated secrets no longer work. A secret is
+// accepted if the UAA authenticates the client, even if it then refuses to
+// issue a token because the client is not allowed the client credentials
+// grant. The secrets are not included in the results.
+func (a *API) CheckClientSecrets(clientID string, candidates []string, interval time.Duration) ([]SecretCheckResult, error) {
+	if clientID == "" {
+		return nil, fmt.Errorf("clientID cannot be blank")
+	}
+	if interval <= 0 {
+		interval = DefaultSecretCheckInterval
+	}
+	client := a.unauthenticatedClient()
+
+	clock := a.getClock()
+	results := make([]SecretCheckResult, len(candidates))
+	for i, secret := range candidates {
+		if i > 0 {
+			clock.Sleep(interval)
+		}
+		valid, err := a.checkClientSecret(client, clientID, secret)
+		results[i] = SecretCheckResult{Index: i, Valid: valid, Err: err}
+	}
+	return results, nil
+}
+
+func (a *API) checkClientSecret(client *http.Client, clientID string, secret string) (bool, error) {
+	u := urlWithPath(*a.TargetURL, "/oauth/token")
+	form := url.Values{"grant_type": {"client_credentials"}, "token_format": {OpaqueToken.String()}}
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Identity-Zone-Id", a.ZoneID)
+
+	resp, err := a.send(client, req)
+	if err != nil {
+		return false, requestError(u.String())
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+
+	switch {
+	case is2XX(resp.StatusCode):
+		return true, nil
+	case resp.StatusCode == http.StatusUnauthorized:
+		return false, nil
+	case resp.StatusCode == http.StatusBadRequest:
+		// The client authenticated but may not use the grant.
+		var oauthErr struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(body, &oauthErr)
+		if oauthErr.Error == "invalid_client" {
+			return false, nil
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("unexpected response status %v", resp.Status)
+}
@@ -0,0 +1,92 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestCheckClientSecrets(t *testing.T) {
+	spec.Run(t, "CheckClientSecrets", testCheckClientSecrets, spec.Report(report.Terminal{}))
+}
+
+func testCheckClientSecrets(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		a        *uaa.API
+		attempts []time.Time
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		attempts = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			attempts = append(attempts, time.Now())
+			Expect(req.URL.Path).To(Equal("/oauth/token"))
+			id, secret, ok := req.BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(id).To(Equal("app"))
+			w.Header().Set("Content-Type", "application/json")
+			switch secret {
+			case "current":
+				w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":3600}`))
+			case "no-grant":
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"unauthorized_client","error_description":"Unauthorized grant type: client_credentials"}`))
+			case "broken":
+				w.WriteHeader(http.StatusInternalServerError)
+			default:
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"unauthorized","error_description":"Bad credentials"}`))
+			}
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("reports which secrets are accepted", func() {
+		results, err := a.CheckClientSecrets("app", []string{"rotated", "current", "no-grant", "broken"}, time.Millisecond)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(4))
+		Expect(results[0]).To(Equal(uaa.SecretCheckResult{Index: 0, Valid: false}))
+		Expect(results[1]).To(Equal(uaa.SecretCheckResult{Index: 1, Valid: true}))
+		Expect(results[2]).To(Equal(uaa.SecretCheckResult{Index: 2, Valid: true}))
+		Expect(results[3].Valid).To(BeFalse())
+		Expect(results[3].Err).To(HaveOccurred())
+	})
+
+	it("waits between attempts", func() {
+		_, err := a.CheckClientSecrets("app", []string{"a", "b", "c"}, 20*time.Millisecond)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attempts).To(HaveLen(3))
+		Expect(attempts[2].Sub(attempts[0])).To(BeNumerically(">=", 40*time.Millisecond))
+	})
+
+	it("waits on the API's clock", func() {
+		clock := uaa.NewFakeClock()
+		uaa.WithClock(clock)(a)
+		start := clock.Now()
+		_, err := a.CheckClientSecrets("app", []string{"a", "b", "c"}, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attempts).To(HaveLen(3))
+		Expect(clock.Now().Sub(start)).To(Equal(2 * time.Hour))
+	})
+
+	it("requires a client ID", func() {
+		_, err := a.CheckClientSecrets("", []string{"a"}, time.Millisecond)
+		Expect(err).To(HaveOccurred())
+		Expect(attempts).To(BeEmpty())
+	})
+}
@@ -0,0 +1,78 @@
+package uaa
+
+import (
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// ClientSecretProvider returns the current secret of the API's client, for
+// example by reading it from a file or a secret store that is updated when
+// the secret is rotated.
+type ClientSecretProvider func() (string, error)
+
+// WithClientSecretProvider returns an Option that makes the API ask provider
+// for the client secret when the UAA rejects the client's credentials, and,
+// if the secret has changed, repeat the token request once with the new
+// secret. The new secret is used from then on, so that rotating a client
+// secret does not require restarting every program that uses it. The secret
+// passed to the constructor is used until then. It applies to
+// NewWithClientCredentials and NewWithPasswordCredentials.
+func WithClientSecretProvider(provider ClientSecretProvider) Option {
+	return func(a *API) {
+		a.secretRotation = &secretRotation{provider: provider}
+	}
+}
+
+// secretRotation holds the client secret most recently obtained from a
+// ClientSecretProvider.
+type secretRotation struct {
+	provider ClientSecretProvider
+
+	mu     sync.Mutex
+	secret string
+}
+
+// clientSecret returns the secret to use in place of the one given to the
+// constructor.
+func (a *API) clientSecret(configured string) string {
+	if a.secretRotation == nil {
+		return configured
+	}
+	a.secretRotation.mu.Lock()
+	defer a.secretRotation.mu.Unlock()
+	if a.secretRotation.secret == "" {
+		return configured
+	}
+	return a.secretRotation.secret
+}
+
+// rotateClientSecret fetches the secret from the provider and returns true if
+// it differs from the one in use.
+func (a *API) rotateClientSecret(configured string) bool {
+	if a.secretRotation == nil {
+		return false
+	}
+	secret, err := a.secretRotation.provider()
+	if err != nil || secret == "" {
+		return false
+	}
+	current := a.clientSecret(configured)
+	a.secretRotation.mu.Lock()
+	defer a.secretRotation.mu.Unlock()
+	a.secretRotation.secret = secret
+	return secret != current
+}
+
+// withRotatedSecret returns a token request that is repeated once with a new
+// client secret if the UAA rejects the credentials and the secret has been
+// rotated.
+func (a *API) withRotatedSecret(configured string, request func(secret string) (*oauth2.Token, error)) func(*oauth2.Token) (*oauth2.Token, error) {
+	return func(*oauth2.Token) (*oauth2.Token, error) {
+		token, err := request(a.clientSecret(configured))
+		if err != nil && badCredentials(err) != nil && a.rotateClientSecret(configured) {
+			return request(a.clientSecret(configured))
+		}
+		return token, err
+	}
+}
@@ -0,0 +1,119 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestSecretRotation(t *testing.T) {
+	spec.Run(t, "SecretRotation", testSecretRotation, spec.Report(report.Terminal{}))
+}
+
+func testSecretRotation(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		valid    string
+		secrets  []string
+		provided string
+		calls    int
+		clock    *uaa.FakeClock
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		valid = "old-secret"
+		secrets = nil
+		provided = "new-secret"
+		calls = 0
+		clock = uaa.NewFakeClock()
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// oauth2 repeats a rejected request with the credentials in the
+			// form, which is only counted once.
+			_, secret, ok := req.BasicAuth()
+			if ok {
+				secrets = append(secrets, secret)
+			} else {
+				secret = req.FormValue("client_secret")
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if secret != valid {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"unauthorized","error_description":"Bad credentials"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "token_type": "bearer", "expires_in": 3600})
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	newAPI := func() *uaa.API {
+		api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "old-secret", uaa.OpaqueToken,
+			uaa.WithClock(clock),
+			uaa.WithClientSecretProvider(func() (string, error) {
+				calls++
+				return provided, nil
+			}))
+		Expect(err).NotTo(HaveOccurred())
+		return api
+	}
+
+	it("uses the configured secret while it is accepted", func() {
+		_, err := newAPI().Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secrets).To(Equal([]string{"old-secret"}))
+		Expect(calls).To(Equal(0))
+	})
+
+	it("retries the first token request with the rotated secret", func() {
+		valid = "new-secret"
+		_, err := newAPI().Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secrets).To(Equal([]string{"old-secret", "old-secret", "new-secret"}))
+	})
+
+	it("uses the rotated secret for later refreshes", func() {
+		api := newAPI()
+		_, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+
+		valid = "new-secret"
+		clock.Advance(2 * time.Hour)
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		clock.Advance(2 * time.Hour)
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(secrets).To(Equal([]string{"old-secret", "old-secret", "new-secret", "new-secret"}))
+		Expect(calls).To(Equal(1))
+	})
+
+	it("does not retry when the secret has not changed", func() {
+		valid = "other-secret"
+		provided = "old-secret"
+		_, err := newAPI().Token()
+		Expect(err).To(HaveOccurred())
+		Expect(secrets).To(Equal([]string{"old-secret", "old-secret"}))
+	})
+
+	it("does not retry when the provider fails", func() {
+		valid = "new-secret"
+		api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "old-secret", uaa.OpaqueToken,
+			uaa.WithClientSecretProvider(func() (string, error) { return "", errors.New("vault is sealed") }))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).To(HaveOccurred())
+		Expect(secrets).To(Equal([]string{"old-secret", "old-secret"}))
+	})
+}
@@ -0,0 +1,71 @@
+package uaa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ShadowUserAttributes are the optional attributes of a user created with
+// CreateShadowUser.
+type ShadowUserAttributes struct {
+	// Email is the user's email address. The UAA requires one, so if it is
+	// blank the username is used when it is an email address.
+	Email string
+	// ExternalID is the user's ID in the identity provider: the SAML NameID,
+	// the OIDC sub claim, or the LDAP DN. If it is blank the UAA sets it when
+	// the user first logs in.
+	ExternalID  string
+	GivenName   string
+	FamilyName  string
+	PhoneNumber string
+}
+
+// CreateShadowUser creates a user of an external identity provider, such as
+// a SAML or OIDC provider, before they first log in, so that they can be
+// added to groups in advance. When the user logs in through the provider
+// with the given origin key, the UAA matches them to the user by username
+// rather than creating another. The user has no password, because the
+// provider authenticates them, and is created verified, because the UAA
+// does not send verification emails to external users. If a user with the
+// username already exists in the origin, it is returned unchanged.
+func (a *API) CreateShadowUser(username string, origin string, attrs ShadowUserAttributes) (*User, error) {
+	if username == "" {
+		return nil, errors.New("username cannot be blank")
+	}
+	if origin == "" || origin == string(UAAOrigin) {
+		return nil, fmt.Errorf("a shadow user must have an external origin, not %q", origin)
+	}
+	email := attrs.Email
+	if email == "" {
+		if !strings.Contains(username, "@") {
+			return nil, fmt.Errorf("an email address is required for user %s", username)
+		}
+		email = username
+	}
+
+	existing, err := a.ListAllUsers(fmt.Sprintf(`userName eq "%v" and origin eq "%v"`, username, origin), "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return &existing[0], nil
+	}
+
+	active, verified, primary := true, true, true
+	user := User{
+		Username:   username,
+		Origin:     origin,
+		ExternalID: attrs.ExternalID,
+		Emails:     []Email{{Value: email, Primary: &primary}},
+		Active:     &active,
+		Verified:   &verified,
+	}
+	if attrs.GivenName != "" || attrs.FamilyName != "" {
+		user.Name = &UserName{GivenName: attrs.GivenName, FamilyName: attrs.FamilyName}
+	}
+	if attrs.PhoneNumber != "" {
+		user.PhoneNumbers = []PhoneNumber{{Value: attrs.PhoneNumber}}
+	}
+	return a.CreateUser(user)
+}
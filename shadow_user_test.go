@@ -0,0 +1,91 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestShadowUser(t *testing.T) {
+	spec.Run(t, "ShadowUser", testShadowUser, spec.Report(report.Terminal{}))
+}
+
+func testShadowUser(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		a        *uaa.API
+		existing []uaa.User
+		created  map[string]interface{}
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		existing = nil
+		created = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal(uaa.UsersEndpoint))
+			w.Header().Set("Content-Type", "application/json")
+			switch req.Method {
+			case http.MethodGet:
+				Expect(req.URL.Query().Get("filter")).To(Equal(`userName eq "marcus@stoicism.com" and origin eq "okta"`))
+				json.NewEncoder(w).Encode(map[string]interface{}{"resources": existing, "startIndex": 1, "itemsPerPage": len(existing), "totalResults": len(existing)})
+			case http.MethodPost:
+				body, _ := ioutil.ReadAll(req.Body)
+				Expect(json.Unmarshal(body, &created)).To(Succeed())
+				w.Write(body)
+			}
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("creates a verified user without a password", func() {
+		user, err := a.CreateShadowUser("marcus@stoicism.com", "okta", uaa.ShadowUserAttributes{ExternalID: "00u1abc", GivenName: "Marcus"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Origin).To(Equal("okta"))
+		Expect(created).To(Equal(map[string]interface{}{
+			"userName":   "marcus@stoicism.com",
+			"origin":     "okta",
+			"externalId": "00u1abc",
+			"emails":     []interface{}{map[string]interface{}{"value": "marcus@stoicism.com", "primary": true}},
+			"name":       map[string]interface{}{"givenName": "Marcus"},
+			"active":     true,
+			"verified":   true,
+		}))
+	})
+
+	it("returns an existing user unchanged", func() {
+		existing = []uaa.User{{ID: "user-1", Username: "marcus@stoicism.com", Origin: "okta"}}
+		user, err := a.CreateShadowUser("marcus@stoicism.com", "okta", uaa.ShadowUserAttributes{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.ID).To(Equal("user-1"))
+		Expect(created).To(BeNil())
+	})
+
+	it("refuses users of the uaa origin", func() {
+		_, err := a.CreateShadowUser("marcus@stoicism.com", "uaa", uaa.ShadowUserAttributes{})
+		Expect(err).To(MatchError(`a shadow user must have an external origin, not "uaa"`))
+	})
+
+	it("requires an email address", func() {
+		_, err := a.CreateShadowUser("marcus", "okta", uaa.ShadowUserAttributes{})
+		Expect(err).To(MatchError("an email address is required for user marcus"))
+	})
+}
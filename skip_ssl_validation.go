@@ -0,0 +1,48 @@
+package uaa
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// WithSkipSSLValidation returns an Option that sets SkipSSLValidation. Unlike
+// setting the field, which takes effect at the next request, it configures
+// the API's clients before the API makes any requests, including the token
+// requests of WithKeepAlive, and so is safe to use with APIs that are used
+// concurrently.
+//
+// The option must be applied after the API's clients are set; the New*
+// constructors do this. The clients' transports are copied rather than
+// modified, so http.DefaultTransport is not affected.
+func WithSkipSSLValidation(skip bool) Option {
+	return func(a *API) {
+		if !skip && !a.SkipSSLValidation {
+			return
+		}
+		a.SkipSSLValidation = skip
+		a.modifyTransports(insecureSkipVerify(skip))
+
+		transportMu.Lock()
+		defer transportMu.Unlock()
+		a.configuredClients = map[*http.Client]bool{}
+		for _, c := range []*http.Client{a.AuthenticatedClient, a.UnauthenticatedClient} {
+			if c != nil {
+				a.configuredClients[c] = skip
+			}
+		}
+	}
+}
+
+// insecureSkipVerify returns a function that sets whether a transport
+// verifies certificates.
+func insecureSkipVerify(skip bool) func(*http.Transport) {
+	return func(t *http.Transport) {
+		if t.TLSClientConfig == nil {
+			if !skip {
+				return
+			}
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.InsecureSkipVerify = skip
+	}
+}
@@ -0,0 +1,111 @@
+package uaa_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestSkipSSLValidation(t *testing.T) {
+	spec.Run(t, "SkipSSLValidation", testSkipSSLValidation, spec.Report(report.Terminal{}))
+}
+
+func expectDefaultTransportVerifies() {
+	t := http.DefaultTransport.(*http.Transport)
+	if t.TLSClientConfig != nil {
+		ExpectWithOffset(1, t.TLSClientConfig.InsecureSkipVerify).To(BeFalse())
+	}
+}
+
+func testSkipSSLValidation(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		issued  int
+		revoked string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		issued = 0
+		revoked = ""
+		s = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch req.URL.Path {
+			case "/info":
+				w.Write([]byte(`{"app":{"version":"74.4.0"}}`))
+			case "/Users/user-id":
+				if req.Header.Get("Authorization") == "Bearer "+revoked {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error":"invalid_token"}`))
+					return
+				}
+				w.Write([]byte(`{"id":"user-id","userName":"marcus"}`))
+			default:
+				issued++
+				fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":3600}`, issued)
+			}
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("requests tokens from a server with a self-signed certificate", func() {
+		a, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken, uaa.WithSkipSSLValidation(true))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a.SkipSSLValidation).To(BeTrue())
+		token, err := a.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("token-1"))
+		expectDefaultTransportVerifies()
+	})
+
+	it("takes effect when the field is set after the API is built", func() {
+		a, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken)
+		Expect(err).NotTo(HaveOccurred())
+		a.SkipSSLValidation = true
+		user, err := a.GetUser("user-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("marcus"))
+		Expect(issued).To(Equal(1))
+		expectDefaultTransportVerifies()
+
+		revoked = "token-1"
+		user, err = a.GetUser("user-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("marcus"))
+		Expect(issued).To(Equal(2))
+	})
+
+	it("is applied by NewWithAuthorizationCode", func() {
+		a, err := uaa.NewWithAuthorizationCode(s.URL, "", "client", "secret", "code", true, uaa.JSONWebToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a.SkipSSLValidation).To(BeTrue())
+		expectDefaultTransportVerifies()
+	})
+
+	it("replaces rather than modifies the transports of an API built without a constructor", func() {
+		u, _ := url.Parse(s.URL)
+		c := &http.Client{Transport: http.DefaultTransport}
+		a := &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c, SkipSSLValidation: true}
+		_, err := a.GetInfo()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Transport).NotTo(BeIdenticalTo(http.DefaultTransport))
+		expectDefaultTransportVerifies()
+	})
+
+	it("verifies certificates unless it is set", func() {
+		a, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = a.Token()
+		Expect(err).To(HaveOccurred())
+	})
+}
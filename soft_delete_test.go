@@ -0,0 +1,85 @@
+package uaa_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestSoftDelete(t *testing.T) {
+	spec.Run(t, "SoftDelete", testSoftDelete, spec.Report(report.Terminal{}))
+}
+
+func testSoftDelete(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		handler  http.Handler
+		a        *uaa.API
+		requests []string
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		requests = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			handler.ServeHTTP(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("deactivates the user at its current version", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodGet {
+				w.Write([]byte(`{"id":"marcus-id","active":true,"meta":{"version":4}}`))
+				return
+			}
+			Expect(req.Method).To(Equal(http.MethodPatch))
+			Expect(req.Header.Get("If-Match")).To(Equal("4"))
+			body, _ := ioutil.ReadAll(req.Body)
+			Expect(body).To(MatchJSON(`{"active":false}`))
+			w.Write([]byte(`{}`))
+		})
+		Expect(a.SoftDeleteUser("marcus-id")).To(Succeed())
+		Expect(requests).To(Equal([]string{"GET /Users/marcus-id", "PATCH /Users/marcus-id"}))
+	})
+
+	it("does not restore an active user", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"id":"marcus-id","active":true,"meta":{"version":4}}`))
+		})
+		Expect(a.RestoreUser("marcus-id")).To(Succeed())
+		Expect(requests).To(Equal([]string{"GET /Users/marcus-id"}))
+	})
+
+	it("lists users whose grace period has passed", func() {
+		inactive := false
+		old := time.Now().Add(-48 * time.Hour).Format(time.RFC3339)
+		recent := time.Now().Format(time.RFC3339)
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Query().Get("filter")).To(Equal(`(origin eq "uaa") and active eq false`))
+			w.Write([]byte(PaginatedResponse(
+				uaa.User{Username: "marcus", Active: &inactive, Meta: &uaa.Meta{LastModified: old}},
+				uaa.User{Username: "seneca", Active: &inactive, Meta: &uaa.Meta{LastModified: recent}},
+			)))
+		})
+		users, err := a.ListDeactivatedUsers(`origin eq "uaa"`, 24*time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(1))
+		Expect(users[0].Username).To(Equal("marcus"))
+	})
+}
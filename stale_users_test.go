@@ -0,0 +1,108 @@
+package uaa_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestStaleUsers(t *testing.T) {
+	spec.Run(t, "StaleUsers", testStaleUsers, spec.Report(report.Terminal{}))
+}
+
+func testStaleUsers(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		handler http.Handler
+		a       *uaa.API
+	)
+
+	millis := func(t time.Time) int {
+		return int(t.UnixNano() / int64(time.Millisecond))
+	}
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			handler.ServeHTTP(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		}
+	})
+
+	it.After(func() {
+		if s != nil {
+			s.Close()
+		}
+	})
+
+	it("returns users who have not logged on within the duration", func() {
+		now := time.Now()
+		recent := uaa.User{ID: "recent", Username: "recent", LastLogonTime: millis(now.Add(-time.Hour))}
+		stale := uaa.User{ID: "stale", Username: "stale", Origin: "uaa", LastLogonTime: millis(now.Add(-100 * 24 * time.Hour))}
+		never := uaa.User{ID: "never", Username: "never", Meta: &uaa.Meta{Created: "2017-01-15T16:54:15.677Z"}}
+		fresh := uaa.User{ID: "fresh", Username: "fresh", Meta: &uaa.Meta{Created: now.UTC().Format(time.RFC3339Nano)}}
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal(uaa.UsersEndpoint))
+			Expect(req.URL.Query().Get("filter")).To(Equal(`origin eq "uaa"`))
+			w.Write([]byte(PaginatedResponse(recent, stale, never, fresh)))
+		})
+
+		report, err := a.StaleUsers(`origin eq "uaa"`, 90*24*time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report).To(HaveLen(2))
+		Expect(report[0].ID).To(Equal("stale"))
+		Expect(report[0].LastLogon).NotTo(BeNil())
+		Expect(report[1].ID).To(Equal("never"))
+		Expect(report[1].LastLogon).To(BeNil())
+	})
+
+	it("returns an error when the users cannot be listed", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		report, err := a.StaleUsers("", time.Hour)
+		Expect(err).To(HaveOccurred())
+		Expect(report).To(BeNil())
+	})
+
+	when("writing the report", func() {
+		var r uaa.StaleUserReport
+
+		it.Before(func() {
+			lastLogon := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+			r = uaa.StaleUserReport{
+				{ID: "1", Username: "marcus", Origin: "uaa", LastLogon: &lastLogon},
+				{ID: "2", Username: "seneca", Origin: "ldap"},
+			}
+		})
+
+		it("writes CSV", func() {
+			buf := &bytes.Buffer{}
+			Expect(r.WriteCSV(buf)).To(Succeed())
+			Expect(buf.String()).To(Equal("id,userName,origin,created,lastLogon\n1,marcus,uaa,,2018-01-02T03:04:05Z\n2,seneca,ldap,,\n"))
+		})
+
+		it("writes JSON", func() {
+			buf := &bytes.Buffer{}
+			Expect(r.WriteJSON(buf)).To(Succeed())
+			Expect(buf.String()).To(MatchJSON(`[
+				{"id":"1","userName":"marcus","origin":"uaa","lastLogon":"2018-01-02T03:04:05Z"},
+				{"id":"2","userName":"seneca","origin":"ldap"}
+			]`))
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package uaa
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// callbackError carries an error returned by a caller's callback through the
+// decoder, so that it is returned to the caller unchanged.
+type callbackError struct {
+	err error
+}
+
+func (e callbackError) Error() string {
+	return e.err.Error()
+}
+
+// eachResource pages through the SCIM list at the endpoint, decoding each
+// resource with decode as it is read from the response.
+func (a *API) eachResource(endpoint string, query url.Values, decode func(*json.Decoder) error) error {
+	page := Page{StartIndex: 1, ItemsPerPage: 100}
+	for {
+		query.Set("startIndex", strconv.Itoa(page.StartIndex))
+		query.Set("count", strconv.Itoa(page.ItemsPerPage))
+		u := urlWithPath(*a.TargetURL, endpoint)
+		u.RawQuery = query.Encode()
+		req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+
+		var (
+			current Page
+			count   int
+		)
+		err = a.doStream(req, true, func(r io.Reader) error {
+			var err error
+			current, count, err = decodeList(r, decode)
+			if e, ok := err.(callbackError); ok {
+				return e.err
+			}
+			if err != nil {
+				return errors.Wrapf(err, "An unknown error occurred while parsing response from %s", u.String())
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if count == 0 || current.StartIndex+current.ItemsPerPage > current.TotalResults {
+			return nil
+		}
+		page.StartIndex = current.StartIndex + current.ItemsPerPage
+	}
+}
+
+// decodeList reads a SCIM list response, calling decode for each element of
+// its resources array, and returns the paging information and the number of
+// resources.
+func decodeList(r io.Reader, decode func(*json.Decoder) error) (Page, int, error) {
+	var (
+		page  Page
+		count int
+	)
+	d := json.NewDecoder(r)
+	if err := expectDelim(d, '{'); err != nil {
+		return page, count, err
+	}
+	for d.More() {
+		key, err := d.Token()
+		if err != nil {
+			return page, count, err
+		}
+		switch key {
+		case "resources":
+			var t json.Token
+			if t, err = d.Token(); err != nil {
+				return page, count, err
+			}
+			if t == nil {
+				continue
+			}
+			if t != json.Delim('[') {
+				return page, count, fmt.Errorf("expected [ but found %v", t)
+			}
+			for d.More() {
+				if err := decode(d); err != nil {
+					return page, count, err
+				}
+				count++
+			}
+			if err := expectDelim(d, ']'); err != nil {
+				return page, count, err
+			}
+		case "startIndex":
+			err = d.Decode(&page.StartIndex)
+		case "itemsPerPage":
+			err = d.Decode(&page.ItemsPerPage)
+		case "totalResults":
+			err = d.Decode(&page.TotalResults)
+		default:
+			var skip json.RawMessage
+			err = d.Decode(&skip)
+		}
+		if err != nil {
+			return page, count, err
+		}
+	}
+	return page, count, expectDelim(d, '}')
+}
+
+func expectDelim(d *json.Decoder, delim json.Delim) error {
+	t, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("expected %v but found %v", delim, t)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package uaa_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestStream(t *testing.T) {
+	spec.Run(t, "Stream", testStream, spec.Report(report.Terminal{}))
+}
+
+func testStream(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		handler http.Handler
+		a       *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			handler.ServeHTTP(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	pages := func(w http.ResponseWriter, req *http.Request) {
+		Expect(req.URL.Query().Get("filter")).To(Equal(`origin eq "uaa"`))
+		switch req.URL.Query().Get("startIndex") {
+		case "1":
+			w.Write([]byte(MultiPaginatedResponse(1, 2, 3, uaa.User{Username: "marcus"}, uaa.User{Username: "seneca"})))
+		case "3":
+			w.Write([]byte(MultiPaginatedResponse(3, 2, 3, uaa.User{Username: "epictetus"})))
+		default:
+			t.Errorf("unexpected request %v", req.URL)
+		}
+	}
+
+	it("calls the callback with each resource on every page", func() {
+		handler = http.HandlerFunc(pages)
+		var usernames []string
+		err := a.EachUser(`origin eq "uaa"`, "", "", "", func(user uaa.User) error {
+			usernames = append(usernames, user.Username)
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(usernames).To(Equal([]string{"marcus", "seneca", "epictetus"}))
+	})
+
+	it("stops when the callback returns an error", func() {
+		handler = http.HandlerFunc(pages)
+		stop := errors.New("stop")
+		var usernames []string
+		err := a.EachUser(`origin eq "uaa"`, "", "", "", func(user uaa.User) error {
+			usernames = append(usernames, user.Username)
+			return stop
+		})
+		Expect(err).To(Equal(stop))
+		Expect(usernames).To(Equal([]string{"marcus"}))
+	})
+
+	it("handles resources before the paging fields and null resources", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"totalResults":0,"resources":null,"startIndex":1,"itemsPerPage":100}`))
+		})
+		called := false
+		err := a.EachGroup("", "", "", "", func(uaa.Group) error {
+			called = true
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(called).To(BeFalse())
+	})
+
+	it("returns an error when the response cannot be parsed", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(`{"resources":[{"client_id":"a"},{unparsable}]}`))
+		})
+		err := a.EachClient("", "", "", func(uaa.Client) error { return nil })
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("An unknown error occurred while parsing response from"))
+	})
+
+	it("returns an error when the request fails", func() {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+		err := a.EachUser("", "", "", "", func(uaa.User) error { return nil })
+		Expect(err).To(MatchError("An unknown error occurred while calling " + s.URL + "/Users?count=100&startIndex=1: the UAA responded 403 Forbidden"))
+	})
+}
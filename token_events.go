@@ -0,0 +1,81 @@
+package uaa
+
+import (
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// TokenEventType is the kind of a TokenEvent.
+type TokenEventType string
+
+// Token event types.
+const (
+	// TokenIssued reports that the API obtained its first token.
+	TokenIssued TokenEventType = "issued"
+	// TokenIssueFailed reports that the API failed to obtain its first
+	// token; Err is set.
+	TokenIssueFailed TokenEventType = "issue-failed"
+	// TokenRefreshed reports that the API replaced a token that had expired
+	// or was about to.
+	TokenRefreshed TokenEventType = "refreshed"
+	// TokenRefreshFailed reports that the API failed to replace its token;
+	// Err is set.
+	TokenRefreshFailed TokenEventType = "refresh-failed"
+)
+
+var failedTokenEvent = map[TokenEventType]TokenEventType{
+	TokenIssued:    TokenIssueFailed,
+	TokenRefreshed: TokenRefreshFailed,
+}
+
+// TokenEvent describes a token request made by the API. It carries metadata
+// about the token but never the token itself, so that it can be logged and
+// turned into metrics safely.
+type TokenEvent struct {
+	Type TokenEventType
+	// Elapsed is how long the token request took.
+	Elapsed time.Duration
+	// Expiry is when the new token expires.
+	Expiry time.Time
+	// Scopes are the scopes that were granted with the new token.
+	Scopes []string
+	// RequestedScopes are the scopes requested with WithScopes, or nil if
+	// every scope the client is allowed was requested.
+	RequestedScopes []string
+	// JTI is the ID of the new token, if it is a JSON Web Token.
+	JTI string
+	// Err is the error of a failed request.
+	Err error
+}
+
+// WithTokenEvents returns an Option that calls fn for each token the API
+// requests and each request that fails, so that applications can emit
+// metrics and raise alerts when refreshes start failing, before requests do.
+// fn is called synchronously while the token is obtained, so it must not
+// block or use the API. It applies to APIs that obtain their own tokens and
+// must be passed to the constructor.
+func WithTokenEvents(fn func(TokenEvent)) Option {
+	return func(a *API) {
+		a.tokenEvents = fn
+	}
+}
+
+// ScopeGrant compares the requested scopes with those granted with the new
+// token.
+func (e TokenEvent) ScopeGrant() ScopeGrant {
+	return ScopeGrant{Requested: e.RequestedScopes, Granted: e.Scopes}
+}
+
+// newTokenEvent adds the token's metadata to the event.
+func newTokenEvent(event TokenEvent, token *oauth2.Token) TokenEvent {
+	event.Expiry = token.Expiry
+	event.Scopes = GrantedScopes(token)
+	if claims, err := DecodeClaims(token.AccessToken); err == nil {
+		event.JTI = claims.JTI
+		if len(event.Scopes) == 0 {
+			event.Scopes = claims.Scope
+		}
+	}
+	return event
+}
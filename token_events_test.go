@@ -0,0 +1,100 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestTokenEvents(t *testing.T) {
+	spec.Run(t, "TokenEvents", testTokenEvents, spec.Report(report.Terminal{}))
+}
+
+func testTokenEvents(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s      *httptest.Server
+		issued int
+		fail   bool
+		clock  *uaa.FakeClock
+		events []uaa.TokenEvent
+		api    *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		issued = 0
+		fail = false
+		events = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if fail {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"unauthorized"}`))
+				return
+			}
+			issued++
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": unsignedJWT(map[string]interface{}{"jti": strconv.Itoa(issued)}),
+				"token_type":   "bearer",
+				"expires_in":   3600,
+				"scope":        "uaa.admin scim.read",
+			})
+		}))
+		clock = uaa.NewFakeClock()
+		var err error
+		api, err = uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken,
+			uaa.WithClock(clock),
+			uaa.WithTokenEvents(func(e uaa.TokenEvent) { events = append(events, e) }))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("reports issued and refreshed tokens", func() {
+		_, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		clock.Advance(2 * time.Hour)
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Type).To(Equal(uaa.TokenIssued))
+		Expect(events[0].JTI).To(Equal("1"))
+		Expect(events[0].Scopes).To(ConsistOf("uaa.admin", "scim.read"))
+		Expect(events[0].Expiry).To(BeTemporally("~", clock.Now().Add(-time.Hour), time.Minute))
+		Expect(events[1].Type).To(Equal(uaa.TokenRefreshed))
+		Expect(events[1].JTI).To(Equal("2"))
+		Expect(events[1].Err).NotTo(HaveOccurred())
+	})
+
+	it("reports failed requests", func() {
+		fail = true
+		_, err := api.Token()
+		Expect(err).To(HaveOccurred())
+		fail = false
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		fail = true
+		clock.Advance(2 * time.Hour)
+		_, err = api.Token()
+		Expect(err).To(HaveOccurred())
+
+		var types []uaa.TokenEventType
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		Expect(types).To(Equal([]uaa.TokenEventType{uaa.TokenIssueFailed, uaa.TokenIssued, uaa.TokenRefreshFailed}))
+		Expect(events[0].Err).To(HaveOccurred())
+		Expect(events[2].Err).To(HaveOccurred())
+	})
+}
@@ -13,6 +13,9 @@ type JWK struct {
 	Alg   string `json:"alg"`
 	Value string `json:"value"`
 	N     string `json:"n,omitempty"`
+	Crv   string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
 }
 
 // TokenKey retrieves a JWK from the token_key endpoint
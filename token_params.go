@@ -0,0 +1,70 @@
+package uaa
+
+import (
+	"net/url"
+	"strings"
+)
+
+// WithScopes requests tokens limited to the given scopes, rather than every
+// scope the client is allowed. With the client credentials grant the scopes
+// are chosen from the client's authorities; with the password grant they are
+// chosen from the client's scopes that the user has been granted. UAA rejects
+// the token request if any of the scopes is not allowed. WithScopes has no
+// effect on APIs that do not request their own tokens.
+func WithScopes(scopes ...string) Option {
+	return func(a *API) {
+		a.setTokenParam("scope", strings.Join(scopes, " "))
+	}
+}
+
+// WithAudience sends an aud parameter for each of the audiences with token
+// requests, asking for a token that is restricted to those audiences. UAA
+// otherwise sets a token's audience from the resource prefixes of its scopes,
+// so WithAudience only takes effect on UAAs that honor the parameter.
+func WithAudience(audiences ...string) Option {
+	return func(a *API) {
+		a.setTokenParam("aud", audiences...)
+	}
+}
+
+// WithResource sends a resource parameter (RFC 8707) for each of the
+// resource URIs with token requests, asking for a token that is restricted to
+// the downstream APIs at those URIs. As with WithAudience, it only takes
+// effect on UAAs that honor the parameter.
+func WithResource(resources ...string) Option {
+	return func(a *API) {
+		a.setTokenParam("resource", resources...)
+	}
+}
+
+// WithLoginHint sends a login_hint parameter with password grant token
+// requests, so that the user's credentials are checked against the identity
+// provider with the given origin (such as LDAPOrigin) rather than against
+// each provider in turn.
+func WithLoginHint(origin Origin) Option {
+	return func(a *API) {
+		a.setTokenParam("login_hint", LoginHint(origin))
+	}
+}
+
+// WithTokenParams sends the given parameters with token requests, in
+// addition to those the grant requires, so that UAA extensions and
+// experimental features can be used before this package supports them. The
+// parameters replace any earlier values with the same names, including those
+// set by WithScopes, WithAudience and WithResource. They must not include the
+// grant's own parameters, such as grant_type or password.
+func WithTokenParams(params url.Values) Option {
+	return func(a *API) {
+		for key, values := range params {
+			a.setTokenParam(key, values...)
+		}
+	}
+}
+
+// setTokenParam sets a parameter that is sent with each token request.
+func (a *API) setTokenParam(key string, values ...string) {
+	if a.tokenParams == nil {
+		a.tokenParams = url.Values{}
+	}
+	a.tokenParams[key] = values
+}
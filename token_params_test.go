@@ -0,0 +1,120 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestTokenParams(t *testing.T) {
+	spec.Run(t, "TokenParams", testTokenParams, spec.Report(report.Terminal{}))
+}
+
+func testTokenParams(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s    *httptest.Server
+		form url.Values
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		form = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.URL.Path).To(Equal("/oauth/token"))
+			Expect(req.ParseForm()).To(Succeed())
+			form = req.PostForm
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":3600}`))
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	when("WithScopes()", func() {
+		it("requests the scopes with the client credentials grant", func() {
+			api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken, uaa.WithScopes("uaa.resource", "scim.read"))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = api.Token()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(form.Get("grant_type")).To(Equal("client_credentials"))
+			Expect(form.Get("scope")).To(Equal("uaa.resource scim.read"))
+			Expect(form.Get("token_format")).To(Equal("jwt"))
+		})
+
+		it("requests the scopes with the password grant", func() {
+			api, err := uaa.NewWithPasswordCredentials(s.URL, "", "client", "secret", "user", "pass", uaa.OpaqueToken, uaa.WithScopes("openid"))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = api.Token()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(form.Get("grant_type")).To(Equal("password"))
+			Expect(form.Get("scope")).To(Equal("openid"))
+		})
+
+		it("does not request scopes by default", func() {
+			api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.OpaqueToken)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = api.Token()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(form).NotTo(HaveKey("scope"))
+		})
+	})
+
+	when("WithAudience() and WithResource()", func() {
+		it("sends each audience and resource with the token request", func() {
+			api, err := uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.JSONWebToken,
+				uaa.WithAudience("billing", "reports"),
+				uaa.WithResource("https://billing.example.com/"))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = api.Token()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(form["aud"]).To(Equal([]string{"billing", "reports"}))
+			Expect(form["resource"]).To(Equal([]string{"https://billing.example.com/"}))
+		})
+	})
+
+	when("WithTokenParams()", func() {
+		it("sends the extra parameters with the token request", func() {
+			api, err := uaa.NewWithPasswordCredentials(s.URL, "", "client", "secret", "user", "pass", uaa.OpaqueToken,
+				uaa.WithScopes("openid"),
+				uaa.WithTokenParams(url.Values{"scope": {"openid profile"}, "x-experiment": {"on"}}))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = api.Token()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(form.Get("scope")).To(Equal("openid profile"))
+			Expect(form.Get("x-experiment")).To(Equal("on"))
+		})
+
+		it("sends the extra parameters when exchanging an authorization code", func() {
+			var query url.Values
+			code := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				query = req.URL.Query()
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"access_token":"token","token_type":"bearer","expires_in":3600}`))
+			}))
+			defer code.Close()
+			_, err := uaa.NewWithAuthorizationCode(code.URL, "", "client", "secret", "code", false, uaa.OpaqueToken,
+				uaa.WithTokenParams(url.Values{"x-experiment": {"on"}}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(query.Get("x-experiment")).To(Equal("on"))
+			Expect(query.Get("token_format")).To(Equal("opaque"))
+		})
+	})
+
+	when("WithLoginHint()", func() {
+		it("sends the login hint with the password grant", func() {
+			api, err := uaa.NewWithPasswordCredentials(s.URL, "", "client", "secret", "user", "pass", uaa.OpaqueToken, uaa.WithLoginHint(uaa.LDAPOrigin))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = api.Token()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(form.Get("login_hint")).To(Equal(`{"origin":"ldap"}`))
+		})
+	})
+}
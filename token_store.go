@@ -0,0 +1,35 @@
+package uaa
+
+import (
+	"errors"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrTokenNotFound is returned by a TokenStore's Load method when it holds no
+// token.
+var ErrTokenNotFound = errors.New("no token is stored")
+
+// TokenStore keeps an API's token between runs of a program, so that a CLI
+// can refresh the token it obtained last time rather than asking the user to
+// log in again. Implementations for operating system keychains are in the
+// uaakeychain package; EncryptedFileTokenStore suits systems without one.
+type TokenStore interface {
+	// Load returns the stored token, or ErrTokenNotFound.
+	Load() (*oauth2.Token, error)
+	// Save stores the token, replacing any stored token.
+	Save(token *oauth2.Token) error
+	// Delete removes the stored token, if there is one.
+	Delete() error
+}
+
+// WithTokenStore returns an Option that saves each token the API obtains to
+// store, and that starts the API with the stored token, if there is one,
+// instead of requesting a new token. A stored token that has expired is
+// refreshed with its refresh token. It applies to APIs that obtain their own
+// tokens and must be passed to the constructor.
+func WithTokenStore(store TokenStore) Option {
+	return func(a *API) {
+		a.tokenStore = store
+	}
+}
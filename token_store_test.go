@@ -0,0 +1,98 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/oauth2"
+)
+
+type memoryTokenStore struct {
+	token *oauth2.Token
+	saves int
+}
+
+func (s *memoryTokenStore) Load() (*oauth2.Token, error) {
+	if s.token == nil {
+		return nil, uaa.ErrTokenNotFound
+	}
+	return s.token, nil
+}
+
+func (s *memoryTokenStore) Save(token *oauth2.Token) error {
+	s.token = token
+	s.saves++
+	return nil
+}
+
+func (s *memoryTokenStore) Delete() error {
+	s.token = nil
+	return nil
+}
+
+func TestTokenStore(t *testing.T) {
+	spec.Run(t, "TokenStore", testTokenStore, spec.Report(report.Terminal{}))
+}
+
+func testTokenStore(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s      *httptest.Server
+		grants []string
+		store  *memoryTokenStore
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		grants = nil
+		store = &memoryTokenStore{}
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.ParseForm()).To(Succeed())
+			grants = append(grants, req.Form.Get("grant_type"))
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "issued", "refresh_token": "refresh", "token_type": "bearer", "expires_in": 3600})
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("saves the tokens it obtains", func() {
+		api, err := uaa.NewWithPasswordCredentials(s.URL, "", "cf", "", "marcus", "meditations", uaa.OpaqueToken, uaa.WithTokenStore(store))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store.saves).To(Equal(1))
+		Expect(store.token.AccessToken).To(Equal("issued"))
+	})
+
+	it("starts with the stored token", func() {
+		store.token = &oauth2.Token{AccessToken: "stored", RefreshToken: "refresh", TokenType: "bearer", Expiry: time.Now().Add(time.Hour)}
+		api, err := uaa.NewWithPasswordCredentials(s.URL, "", "cf", "", "marcus", "meditations", uaa.OpaqueToken, uaa.WithTokenStore(store))
+		Expect(err).NotTo(HaveOccurred())
+		token, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("stored"))
+		Expect(grants).To(BeEmpty())
+	})
+
+	it("refreshes a stored token that has expired", func() {
+		store.token = &oauth2.Token{AccessToken: "stored", RefreshToken: "refresh", TokenType: "bearer", Expiry: time.Now().Add(-time.Hour)}
+		api, err := uaa.NewWithCredentialsPrompter(s.URL, "", "cf", "", uaa.CredentialsPrompterFunc(func() (uaa.PromptedCredentials, error) {
+			panic("prompted for credentials")
+		}), uaa.OpaqueToken, uaa.WithTokenStore(store))
+		Expect(err).NotTo(HaveOccurred())
+		token, err := api.Token()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("issued"))
+		Expect(grants).To(Equal([]string{"refresh_token"}))
+		Expect(store.token.AccessToken).To(Equal("issued"))
+	})
+}
@@ -0,0 +1,344 @@
+package uaa
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// InvalidTokenError is returned when a token is malformed, has an invalid
+// signature, has expired, or is no longer active.
+type InvalidTokenError struct {
+	Reason string
+}
+
+func (e *InvalidTokenError) Error() string {
+	return "invalid token: " + e.Reason
+}
+
+func invalidToken(format string, args ...interface{}) error {
+	return &InvalidTokenError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// KeySource supplies the keys used to verify JSON Web Tokens.
+type KeySource interface {
+	// Key returns the key with the given key ID. If kid is blank, the default
+	// key is returned.
+	Key(kid string) (*JWK, error)
+}
+
+// apiKeySource fetches keys from the UAA every time a key is requested.
+type apiKeySource struct {
+	api *API
+}
+
+func (s *apiKeySource) Key(kid string) (*JWK, error) {
+	keys, err := s.api.TokenKeys()
+	if err != nil {
+		return nil, err
+	}
+	return findKey(keys, kid)
+}
+
+func findKey(keys []JWK, kid string) (*JWK, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no token keys are available")
+	}
+	if kid == "" {
+		return &keys[0], nil
+	}
+	for i := range keys {
+		if keys[i].Kid == kid {
+			return &keys[i], nil
+		}
+	}
+	return nil, invalidToken("token key %v not found", kid)
+}
+
+// PublicKey returns the RSA public key described by the JWK.
+func (k JWK) PublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "" && k.Kty != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %v", k.Kty)
+	}
+	if k.N != "" && k.E != "" {
+		n, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.N, "="))
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.E, "="))
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	}
+	block, _ := pem.Decode([]byte(k.Value))
+	if block == nil {
+		return nil, errors.New("key has neither a modulus and exponent nor a PEM value")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("key is not an RSA public key")
+	}
+	return rsaPub, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+var signingHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+}
+
+func decodeSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+}
+
+// jwtSegments are the three segments of a JSON Web Token.
+type jwtSegments struct {
+	header    string
+	payload   string
+	signature string
+}
+
+// signingInput returns the part of the token that the signature covers.
+func (s jwtSegments) signingInput(token string) string {
+	return token[:len(s.header)+1+len(s.payload)]
+}
+
+// splitJWT splits the token into its segments without allocating.
+func splitJWT(token string) (jwtSegments, bool) {
+	first := strings.IndexByte(token, '.')
+	if first < 0 {
+		return jwtSegments{}, false
+	}
+	second := strings.IndexByte(token[first+1:], '.')
+	if second < 0 {
+		return jwtSegments{}, false
+	}
+	second += first + 1
+	if strings.IndexByte(token[second+1:], '.') >= 0 {
+		return jwtSegments{}, false
+	}
+	return jwtSegments{header: token[:first], payload: token[first+1 : second], signature: token[second+1:]}, true
+}
+
+func parseJWTHeader(token string) (jwtSegments, *jwtHeader, error) {
+	segments, ok := splitJWT(token)
+	if !ok {
+		return jwtSegments{}, nil, invalidToken("token is not a JSON Web Token")
+	}
+	h, err := decodeSegment(segments.header)
+	if err != nil {
+		return jwtSegments{}, nil, invalidToken("malformed header")
+	}
+	header := &jwtHeader{}
+	if err := json.Unmarshal(h, header); err != nil {
+		return jwtSegments{}, nil, invalidToken("malformed header")
+	}
+	return segments, header, nil
+}
+
+// VerifyToken verifies the signature of the given JSON Web Token with the
+// given key and returns its claims. VerifyToken does not check whether the
+// token has expired.
+func VerifyToken(token string, key *JWK) (*Claims, error) {
+	segments, header, err := parseJWTHeader(token)
+	if err != nil {
+		return nil, err
+	}
+	return verifyToken(token, segments, header, key)
+}
+
+func verifyToken(token string, segments jwtSegments, header *jwtHeader, key *JWK) (*Claims, error) {
+	hash, ok := signingHashes[header.Alg]
+	if !ok {
+		return nil, invalidToken("unsupported signing algorithm %v", header.Alg)
+	}
+	if key.Alg != "" && key.Alg != header.Alg {
+		return nil, invalidToken("token algorithm %v does not match key algorithm %v", header.Alg, key.Alg)
+	}
+	pub, err := key.cachedPublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := decodeSegment(segments.signature)
+	if err != nil {
+		return nil, invalidToken("malformed signature")
+	}
+	h := hash.New()
+	io.WriteString(h, segments.signingInput(token))
+	if err := rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), signature); err != nil {
+		return nil, invalidToken("signature verification failed")
+	}
+
+	claims, err := decodeClaims(segments.payload)
+	if err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+	return claims, nil
+}
+
+// maxParsedKeys bounds the cache of parsed public keys. The UAA publishes
+// only a few keys at a time, so the cache is simply emptied if it fills up.
+const maxParsedKeys = 64
+
+// jwkMaterial identifies a JWK's public key.
+type jwkMaterial struct {
+	kty, n, e, value string
+}
+
+// parsedKeys caches the public keys parsed from JWKs, because decoding the
+// modulus is a large part of the cost of verifying a token.
+var parsedKeys = struct {
+	sync.RWMutex
+	keys map[jwkMaterial]*rsa.PublicKey
+}{keys: map[jwkMaterial]*rsa.PublicKey{}}
+
+// cachedPublicKey returns the key's RSA public key, parsing it only the first
+// time. The key is shared, so it must not be modified.
+func (k *JWK) cachedPublicKey() (*rsa.PublicKey, error) {
+	material := jwkMaterial{kty: k.Kty, n: k.N, e: k.E, value: k.Value}
+	parsedKeys.RLock()
+	pub, ok := parsedKeys.keys[material]
+	parsedKeys.RUnlock()
+	if ok {
+		return pub, nil
+	}
+	pub, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	parsedKeys.Lock()
+	if len(parsedKeys.keys) >= maxParsedKeys {
+		parsedKeys.keys = map[jwkMaterial]*rsa.PublicKey{}
+	}
+	parsedKeys.keys[material] = pub
+	parsedKeys.Unlock()
+	return pub, nil
+}
+
+// TokenValidator validates the bearer tokens presented to a resource server.
+// JSON Web Tokens are verified locally against the UAA's token keys, and
+// opaque tokens are checked with the UAA's introspection endpoint.
+type TokenValidator struct {
+	// API is used to fetch token keys and to introspect opaque tokens.
+	API *API
+	// Keys supplies the keys used to verify JSON Web Tokens. If Keys is nil,
+	// the keys are fetched from the API for every validation.
+	Keys KeySource
+	// Issuer, if set, must match the iss claim of the token.
+	Issuer string
+	// Audience, if set, must be contained in the aud claim of the token.
+	Audience string
+	// IntrospectionCache, if set, caches the results of introspecting opaque
+	// tokens.
+	IntrospectionCache *IntrospectionCache
+	// Clock is used to decide whether a token has expired. If Clock is nil,
+	// the API's clock is used, or the system clock if there is no API.
+	Clock Clock
+}
+
+// Validate returns the claims of the token if it is valid, an
+// *InvalidTokenError if it is not, or another error if the token could not be
+// validated.
+func (v *TokenValidator) Validate(token string) (*Claims, error) {
+	if token == "" {
+		return nil, invalidToken("token is blank")
+	}
+
+	var claims *Claims
+	if strings.Count(token, ".") == 2 {
+		c, err := v.verifyJWT(token)
+		if err != nil {
+			return nil, err
+		}
+		claims = c
+	} else {
+		c, err := v.introspect(token)
+		if err != nil {
+			return nil, err
+		}
+		claims = c
+	}
+
+	if claims.Expiry == 0 || !v.getClock().Now().Before(claims.ExpiresAt()) {
+		return nil, invalidToken("token has expired")
+	}
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return nil, invalidToken("unexpected issuer %v", claims.Issuer)
+	}
+	if v.Audience != "" && !contains(claims.Audience, v.Audience) {
+		return nil, invalidToken("token is not intended for audience %v", v.Audience)
+	}
+	return claims, nil
+}
+
+func (v *TokenValidator) verifyJWT(token string) (*Claims, error) {
+	segments, header, err := parseJWTHeader(token)
+	if err != nil {
+		return nil, err
+	}
+	keys := v.Keys
+	if keys == nil {
+		if v.API == nil {
+			return nil, errors.New("an API or Keys is required to verify JSON Web Tokens")
+		}
+		keys = &apiKeySource{api: v.API}
+	}
+	key, err := keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	return verifyToken(token, segments, header, key)
+}
+
+// getClock returns the validator's clock, falling back to the API's.
+func (v *TokenValidator) getClock() Clock {
+	if v.Clock != nil {
+		return v.Clock
+	}
+	if v.API != nil {
+		return v.API.getClock()
+	}
+	return systemClock{}
+}
+
+func (v *TokenValidator) introspect(token string) (*Claims, error) {
+	if v.API == nil {
+		return nil, errors.New("an API is required to introspect opaque tokens")
+	}
+	introspection, ok := v.IntrospectionCache.lookup(token, v.getClock().Now())
+	if !ok {
+		var err error
+		introspection, err = v.API.IntrospectToken(token)
+		if err != nil {
+			return nil, err
+		}
+		v.IntrospectionCache.store(token, introspection, v.getClock().Now())
+	}
+	if !introspection.Active {
+		return nil, invalidToken("token is not active")
+	}
+	return &introspection.Claims, nil
+}
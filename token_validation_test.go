@@ -0,0 +1,223 @@
+package uaa_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestTokenValidation(t *testing.T) {
+	spec.Run(t, "TokenValidation", testTokenValidation, spec.Report(report.Terminal{}))
+}
+
+func signedJWT(key *rsa.PrivateKey, kid string, claims interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func publicJWK(key *rsa.PrivateKey, kid string) uaa.JWK {
+	return uaa.JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+	}
+}
+
+func testTokenValidation(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s            *httptest.Server
+		a            *uaa.API
+		key          *rsa.PrivateKey
+		keysRequests int
+		introspected string
+		active       bool
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		keysRequests = 0
+		introspected = ""
+		active = true
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/token_keys":
+				keysRequests++
+				json.NewEncoder(w).Encode(uaa.Keys{Keys: []uaa.JWK{publicJWK(key, "key-1")}})
+			case "/introspect":
+				Expect(req.Method).To(Equal(http.MethodPost))
+				Expect(req.Header.Get("Content-Type")).To(Equal("application/x-www-form-urlencoded"))
+				Expect(req.ParseForm()).To(Succeed())
+				introspected = req.PostForm.Get("token")
+				fmt.Fprintf(w, `{"active":%t,"scope":["scim.read"],"exp":%d,"client_id":"app"}`, active, time.Now().Add(time.Hour).Unix())
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		}
+	})
+
+	it.After(func() {
+		if s != nil {
+			s.Close()
+		}
+	})
+
+	when("JWK.PublicKey()", func() {
+		it("builds a key from the modulus and exponent", func() {
+			pub, err := publicJWK(key, "key-1").PublicKey()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pub.N.Cmp(key.PublicKey.N)).To(Equal(0))
+			Expect(pub.E).To(Equal(key.PublicKey.E))
+		})
+
+		it("rejects keys that are not RSA keys", func() {
+			_, err := uaa.JWK{Kty: "oct", Value: "secret"}.PublicKey()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	when("VerifyToken()", func() {
+		it("returns the claims of a correctly signed token", func() {
+			jwk := publicJWK(key, "key-1")
+			claims, err := uaa.VerifyToken(signedJWT(key, "key-1", map[string]interface{}{"sub": "abc"}), &jwk)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims.Subject).To(Equal("abc"))
+		})
+
+		it("rejects a token signed by another key", func() {
+			other, _ := rsa.GenerateKey(rand.Reader, 2048)
+			jwk := publicJWK(key, "key-1")
+			_, err := uaa.VerifyToken(signedJWT(other, "key-1", map[string]interface{}{"sub": "abc"}), &jwk)
+			Expect(err).To(BeAssignableToTypeOf(&uaa.InvalidTokenError{}))
+		})
+
+		it("rejects unsigned tokens", func() {
+			jwk := publicJWK(key, "key-1")
+			_, err := uaa.VerifyToken(unsignedJWT(map[string]interface{}{"sub": "abc"}), &jwk)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported signing algorithm none"))
+		})
+	})
+
+	when("TokenValidator.Validate()", func() {
+		var v *uaa.TokenValidator
+
+		it.Before(func() {
+			v = &uaa.TokenValidator{API: a}
+		})
+
+		it("validates a JSON Web Token locally", func() {
+			token := signedJWT(key, "key-1", map[string]interface{}{
+				"scope": []string{"openid"},
+				"exp":   time.Now().Add(time.Hour).Unix(),
+			})
+			claims, err := v.Validate(token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(claims.Scope).To(ConsistOf("openid"))
+			Expect(keysRequests).To(Equal(1))
+			Expect(introspected).To(BeEmpty())
+		})
+
+		it("rejects an expired token", func() {
+			token := signedJWT(key, "key-1", map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()})
+			_, err := v.Validate(token)
+			Expect(err).To(BeAssignableToTypeOf(&uaa.InvalidTokenError{}))
+			Expect(err.Error()).To(ContainSubstring("expired"))
+		})
+
+		it("rejects a token signed with an unknown key", func() {
+			token := signedJWT(key, "key-2", map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()})
+			_, err := v.Validate(token)
+			Expect(err).To(BeAssignableToTypeOf(&uaa.InvalidTokenError{}))
+		})
+
+		it("checks the issuer and audience when configured", func() {
+			v.Issuer = "https://uaa.example.com/oauth/token"
+			v.Audience = "cloud_controller"
+			token := signedJWT(key, "key-1", map[string]interface{}{
+				"iss": "https://uaa.example.com/oauth/token",
+				"aud": []string{"cloud_controller"},
+				"exp": time.Now().Add(time.Hour).Unix(),
+			})
+			_, err := v.Validate(token)
+			Expect(err).NotTo(HaveOccurred())
+
+			v.Audience = "other"
+			_, err = v.Validate(token)
+			Expect(err).To(BeAssignableToTypeOf(&uaa.InvalidTokenError{}))
+		})
+
+		it("introspects opaque tokens", func() {
+			claims, err := v.Validate("opaque-token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(introspected).To(Equal("opaque-token"))
+			Expect(claims.ClientID).To(Equal("app"))
+			Expect(claims.Scope).To(ConsistOf("scim.read"))
+		})
+
+		it("rejects inactive opaque tokens", func() {
+			active = false
+			_, err := v.Validate("opaque-token")
+			Expect(err).To(BeAssignableToTypeOf(&uaa.InvalidTokenError{}))
+		})
+
+		it("returns a non-validation error when the keys cannot be fetched", func() {
+			s.Close()
+			token := signedJWT(key, "key-1", map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()})
+			_, err := v.Validate(token)
+			Expect(err).To(HaveOccurred())
+			Expect(err).NotTo(BeAssignableToTypeOf(&uaa.InvalidTokenError{}))
+		})
+
+		it("requires an API or keys to verify a JSON Web Token", func() {
+			v.API = nil
+			token := signedJWT(key, "key-1", map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()})
+			_, err := v.Validate(token)
+			Expect(err).To(MatchError("an API or Keys is required to verify JSON Web Tokens"))
+		})
+
+		it("checks expiry against the clock", func() {
+			clock := uaa.NewFakeClock()
+			v.Clock = clock
+			token := signedJWT(key, "key-1", map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()})
+			_, err := v.Validate(token)
+			Expect(err).NotTo(HaveOccurred())
+
+			clock.Advance(2 * time.Hour)
+			_, err = v.Validate(token)
+			Expect(err).To(BeAssignableToTypeOf(&uaa.InvalidTokenError{}))
+			Expect(err.Error()).To(ContainSubstring("expired"))
+		})
+	})
+}
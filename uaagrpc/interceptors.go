@@ -0,0 +1,128 @@
+// Package uaagrpc provides gRPC interceptors that authenticate calls with
+// tokens issued by the UAA.
+package uaagrpc
+
+import (
+	"context"
+	"strings"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	"github.com/cloudfoundry-community/go-uaa/uaamiddleware"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator authenticates incoming gRPC calls using the bearer token in
+// their "authorization" metadata. The token's claims are stored in the call's
+// context and can be retrieved with uaamiddleware.ClaimsFromContext.
+type Authenticator struct {
+	// Validator validates the bearer token. A *uaa.TokenValidator is
+	// typically used.
+	Validator uaamiddleware.Validator
+	// RequiredScopes must all be granted to the token. Scopes may contain
+	// wildcards (see uaa.ScopeMatches).
+	RequiredScopes []string
+}
+
+// UnaryServerInterceptor returns an interceptor that authenticates unary
+// calls.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that authenticates streaming
+// calls.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (a *Authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	token, ok := bearerToken(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "a bearer token is required")
+	}
+
+	claims, err := a.Validator.Validate(token)
+	if err != nil {
+		if _, ok := err.(*uaa.InvalidTokenError); ok {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, status.Error(codes.Unavailable, "the token could not be validated")
+	}
+
+	if !claims.HasAllScopes(a.RequiredScopes...) {
+		return nil, status.Errorf(codes.PermissionDenied, "requires scopes %v", strings.Join(a.RequiredScopes, " "))
+	}
+	return uaamiddleware.NewContext(ctx, claims), nil
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	for _, header := range md.Get("authorization") {
+		if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+			token := strings.TrimSpace(header[7:])
+			return token, token != ""
+		}
+	}
+	return "", false
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// UnaryClientInterceptor returns an interceptor that attaches a token from the
+// given source to outgoing unary calls. Use api.TokenSource() to attach the
+// token of a *uaa.API.
+func UnaryClientInterceptor(source oauth2.TokenSource) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := withToken(ctx, source)
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns an interceptor that attaches a token from
+// the given source to outgoing streaming calls.
+func StreamClientInterceptor(source oauth2.TokenSource) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := withToken(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func withToken(ctx context.Context, source oauth2.TokenSource) (context.Context, error) {
+	token, err := source.Token()
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "could not obtain a token: %v", err)
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", token.Type()+" "+token.AccessToken), nil
+}
@@ -0,0 +1,134 @@
+package uaagrpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	"github.com/cloudfoundry-community/go-uaa/uaagrpc"
+	"github.com/cloudfoundry-community/go-uaa/uaamiddleware"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeValidator struct {
+	claims *uaa.Claims
+	err    error
+}
+
+func (v *fakeValidator) Validate(token string) (*uaa.Claims, error) {
+	if token != "the-token" {
+		return nil, &uaa.InvalidTokenError{Reason: "unknown token"}
+	}
+	return v.claims, v.err
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInterceptors(t *testing.T) {
+	spec.Run(t, "Interceptors", testInterceptors, spec.Report(report.Terminal{}))
+}
+
+func testInterceptors(t *testing.T, when spec.G, it spec.S) {
+	var (
+		validator *fakeValidator
+		a         *uaagrpc.Authenticator
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		validator = &fakeValidator{claims: &uaa.Claims{Subject: "user-id", Scope: []string{"scim.read"}}}
+		a = &uaagrpc.Authenticator{Validator: validator}
+	})
+
+	incoming := func(authorization string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", authorization))
+	}
+
+	when("UnaryServerInterceptor()", func() {
+		var (
+			seen    *uaa.Claims
+			handler grpc.UnaryHandler
+		)
+
+		it.Before(func() {
+			seen = nil
+			handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+				seen, _ = uaamiddleware.ClaimsFromContext(ctx)
+				return "ok", nil
+			}
+		})
+
+		it("passes the claims of a valid token to the handler", func() {
+			resp, err := a.UnaryServerInterceptor()(incoming("Bearer the-token"), nil, &grpc.UnaryServerInfo{}, handler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp).To(Equal("ok"))
+			Expect(seen.Subject).To(Equal("user-id"))
+		})
+
+		it("rejects calls without a token", func() {
+			_, err := a.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+			Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+			Expect(seen).To(BeNil())
+		})
+
+		it("rejects invalid tokens", func() {
+			_, err := a.UnaryServerInterceptor()(incoming("Bearer other-token"), nil, &grpc.UnaryServerInfo{}, handler)
+			Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+		})
+
+		it("returns unavailable when the token cannot be validated", func() {
+			validator.err = errors.New("connection refused")
+			_, err := a.UnaryServerInterceptor()(incoming("Bearer the-token"), nil, &grpc.UnaryServerInfo{}, handler)
+			Expect(status.Code(err)).To(Equal(codes.Unavailable))
+		})
+
+		it("enforces the required scopes", func() {
+			a.RequiredScopes = []string{"scim.write"}
+			_, err := a.UnaryServerInterceptor()(incoming("Bearer the-token"), nil, &grpc.UnaryServerInfo{}, handler)
+			Expect(status.Code(err)).To(Equal(codes.PermissionDenied))
+		})
+	})
+
+	when("StreamServerInterceptor()", func() {
+		it("replaces the stream context with one carrying the claims", func() {
+			var seen *uaa.Claims
+			handler := func(srv interface{}, ss grpc.ServerStream) error {
+				seen, _ = uaamiddleware.ClaimsFromContext(ss.Context())
+				return nil
+			}
+			err := a.StreamServerInterceptor()(nil, &fakeServerStream{ctx: incoming("Bearer the-token")}, &grpc.StreamServerInfo{}, handler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(seen.Subject).To(Equal("user-id"))
+		})
+	})
+
+	when("UnaryClientInterceptor()", func() {
+		it("attaches the token to outgoing calls", func() {
+			source := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "the-token", TokenType: "bearer"})
+			var authorization []string
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				authorization = md.Get("authorization")
+				return nil
+			}
+			err := uaagrpc.UnaryClientInterceptor(source)(context.Background(), "/svc/Method", nil, nil, nil, invoker)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(authorization).To(ConsistOf("Bearer the-token"))
+		})
+	})
+}
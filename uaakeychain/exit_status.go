@@ -0,0 +1,17 @@
+//go:build darwin || linux
+// +build darwin linux
+
+package uaakeychain
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// exitStatus returns the exit status of the command that failed with err.
+func exitStatus(err *exec.ExitError) int {
+	if status, ok := err.Sys().(syscall.WaitStatus); ok {
+		return status.ExitStatus()
+	}
+	return -1
+}
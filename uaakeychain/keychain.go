@@ -0,0 +1,74 @@
+// Package uaakeychain stores go-uaa tokens in the operating system's
+// keychain: the login keychain on macOS, the Secret Service on Linux, and the
+// Credential Manager on Windows. Use a Store with uaa.WithTokenStore so that
+// refresh tokens are not kept in plaintext files.
+package uaakeychain
+
+import (
+	"encoding/json"
+	"errors"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	"golang.org/x/oauth2"
+)
+
+// ErrUnsupported is returned on operating systems that have no supported
+// keychain.
+var ErrUnsupported = errors.New("there is no supported keychain on this operating system")
+
+// errNotFound is returned by the keychain functions when the keychain holds
+// no item for the service and account.
+var errNotFound = errors.New("the keychain item does not exist")
+
+// Store is a uaa.TokenStore that keeps a token in the operating system's
+// keychain, in an item identified by a service name, such as the name of the
+// CLI, and an account name, such as the UAA's URL and the user's name.
+//
+// On macOS the security command is used, and on Linux the secret-tool
+// command from libsecret, so they must be installed. On Windows, the
+// Credential Manager limits items to 2560 bytes, which may be too small for
+// JSON Web Tokens with a refresh token; use opaque tokens there.
+type Store struct {
+	Service string
+	Account string
+}
+
+var _ uaa.TokenStore = &Store{}
+
+// New returns a Store for the given service and account.
+func New(service string, account string) *Store {
+	return &Store{Service: service, Account: account}
+}
+
+// Load returns the token in the keychain, or uaa.ErrTokenNotFound.
+func (s *Store) Load() (*oauth2.Token, error) {
+	data, err := get(s.Service, s.Account)
+	if err == errNotFound {
+		return nil, uaa.ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	token := &oauth2.Token{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Save stores the token in the keychain, replacing any stored token.
+func (s *Store) Save(token *oauth2.Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return set(s.Service, s.Account, data)
+}
+
+// Delete removes the token from the keychain, if it is there.
+func (s *Store) Delete() error {
+	if err := remove(s.Service, s.Account); err != nil && err != errNotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package uaakeychain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// itemNotFound is the exit status of the security command when there is no
+// matching keychain item.
+const itemNotFound = 44
+
+func get(service string, account string) ([]byte, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		return nil, securityError(err)
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+// set adds or updates the item through the security command's interactive
+// mode, so that the token is not visible in the process list.
+func set(service string, account string, data []byte) error {
+	password := hex.EncodeToString([]byte(base64.StdEncoding.EncodeToString(data)))
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", quote(service), quote(account), password))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// The interactive mode exits successfully even if the command fails, so
+	// failures are recognized by their output.
+	if err := cmd.Run(); err != nil || stderr.Len() > 0 {
+		return fmt.Errorf("security add-generic-password: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func remove(service string, account string) error {
+	if err := exec.Command("security", "delete-generic-password", "-s", service, "-a", account).Run(); err != nil {
+		return securityError(err)
+	}
+	return nil
+}
+
+func securityError(err error) error {
+	if exitErr, ok := err.(*exec.ExitError); ok && exitStatus(exitErr) == itemNotFound {
+		return errNotFound
+	}
+	return err
+}
+
+// quote quotes s for the security command's interactive mode, which splits
+// its input like a shell.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,55 @@
+package uaakeychain
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+func get(service string, account string) ([]byte, error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// secret-tool exits with status 1 and no output when there is no
+		// matching item.
+		if exitErr, ok := err.(*exec.ExitError); ok && exitStatus(exitErr) == 1 && stdout.Len() == 0 && stderr.Len() == 0 {
+			return nil, errNotFound
+		}
+		return nil, secretToolError("lookup", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// set stores the item, passing the token on standard input so that it is not
+// visible in the process list.
+func set(service string, account string, data []byte) error {
+	label := fmt.Sprintf("%s (%s)", service, account)
+	cmd := exec.Command("secret-tool", "store", "--label", label, "service", service, "account", account)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return secretToolError("store", err, stderr.String())
+	}
+	return nil
+}
+
+func remove(service string, account string) error {
+	cmd := exec.Command("secret-tool", "clear", "service", service, "account", account)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitStatus(exitErr) == 1 && stderr.Len() == 0 {
+			return errNotFound
+		}
+		return secretToolError("clear", err, stderr.String())
+	}
+	return nil
+}
+
+func secretToolError(command string, err error, stderr string) error {
+	return fmt.Errorf("secret-tool %s: %v: %s", command, err, strings.TrimSpace(stderr))
+}
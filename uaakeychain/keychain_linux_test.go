@@ -0,0 +1,90 @@
+package uaakeychain_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	"github.com/cloudfoundry-community/go-uaa/uaakeychain"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/oauth2"
+)
+
+// fakeSecretTool is a secret-tool that keeps each item in a file named after
+// its attributes.
+const fakeSecretTool = `#!/bin/sh
+command=$1
+shift
+if [ "$command" = store ]; then
+	shift 2
+fi
+item="$SECRETS/$(echo "$@" | tr ' /' '__')"
+case $command in
+store) cat > "$item" ;;
+lookup) [ -f "$item" ] || exit 1; cat "$item" ;;
+clear) [ -f "$item" ] || exit 1; rm "$item" ;;
+esac
+`
+
+func TestKeychain(t *testing.T) {
+	spec.Run(t, "Keychain", testKeychain, spec.Report(report.Terminal{}))
+}
+
+func testKeychain(t *testing.T, when spec.G, it spec.S) {
+	var (
+		dir   string
+		path  string
+		store *uaakeychain.Store
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		var err error
+		dir, err = ioutil.TempDir("", "uaakeychain")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Mkdir(filepath.Join(dir, "secrets"), 0700)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "secret-tool"), []byte(fakeSecretTool), 0700)).To(Succeed())
+		path = os.Getenv("PATH")
+		os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+		os.Setenv("SECRETS", filepath.Join(dir, "secrets"))
+		store = uaakeychain.New("uaa-cli", "https://uaa.example.com marcus")
+	})
+
+	it.After(func() {
+		os.Setenv("PATH", path)
+		os.Unsetenv("SECRETS")
+		os.RemoveAll(dir)
+	})
+
+	it("saves, loads and deletes the token", func() {
+		expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		token := &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", TokenType: "bearer", Expiry: expiry}
+		Expect(store.Save(token)).To(Succeed())
+
+		loaded, err := store.Load()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.AccessToken).To(Equal("access"))
+		Expect(loaded.RefreshToken).To(Equal("refresh"))
+		Expect(loaded.Expiry.Equal(expiry)).To(BeTrue())
+
+		Expect(store.Delete()).To(Succeed())
+		_, err = store.Load()
+		Expect(err).To(Equal(uaa.ErrTokenNotFound))
+	})
+
+	it("keeps tokens for different accounts apart", func() {
+		Expect(store.Save(&oauth2.Token{AccessToken: "marcus"})).To(Succeed())
+		other := uaakeychain.New("uaa-cli", "https://uaa.example.com seneca")
+		_, err := other.Load()
+		Expect(err).To(Equal(uaa.ErrTokenNotFound))
+	})
+
+	it("deletes a token that is not there", func() {
+		Expect(store.Delete()).To(Succeed())
+	})
+}
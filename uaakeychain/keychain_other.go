@@ -0,0 +1,16 @@
+//go:build !darwin && !linux && !windows
+// +build !darwin,!linux,!windows
+
+package uaakeychain
+
+func get(service string, account string) ([]byte, error) {
+	return nil, ErrUnsupported
+}
+
+func set(service string, account string, data []byte) error {
+	return ErrUnsupported
+}
+
+func remove(service string, account string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,112 @@
+package uaakeychain
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32        = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	// credMaxBlobSize is CRED_MAX_CREDENTIAL_BLOB_SIZE.
+	credMaxBlobSize = 5 * 512
+	// errorNotFound is ERROR_NOT_FOUND.
+	errorNotFound = syscall.Errno(1168)
+)
+
+// credential is the Windows CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// targetName returns the name of the Credential Manager item for the service
+// and account.
+func targetName(service string, account string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(service + ":" + account)
+}
+
+func get(service string, account string) ([]byte, error) {
+	target, err := targetName(service, account)
+	if err != nil {
+		return nil, err
+	}
+	var cred *credential
+	r, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if r == 0 {
+		return nil, credError(err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	data := make([]byte, cred.CredentialBlobSize)
+	if len(data) > 0 {
+		copy(data, (*[credMaxBlobSize]byte)(unsafe.Pointer(cred.CredentialBlob))[:len(data):len(data)])
+	}
+	return data, nil
+}
+
+func set(service string, account string, data []byte) error {
+	if len(data) > credMaxBlobSize {
+		return fmt.Errorf("the token is %d bytes, more than the %d bytes the Credential Manager can store", len(data), credMaxBlobSize)
+	}
+	target, err := targetName(service, account)
+	if err != nil {
+		return err
+	}
+	userName, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(data)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(data) > 0 {
+		cred.CredentialBlob = &data[0]
+	}
+	r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if r == 0 {
+		return credError(err)
+	}
+	return nil
+}
+
+func remove(service string, account string) error {
+	target, err := targetName(service, account)
+	if err != nil {
+		return err
+	}
+	r, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if r == 0 {
+		return credError(err)
+	}
+	return nil
+}
+
+func credError(err error) error {
+	if err == errorNotFound {
+		return errNotFound
+	}
+	return err
+}
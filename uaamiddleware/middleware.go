@@ -0,0 +1,108 @@
+// Package uaamiddleware provides net/http middleware for resource servers that
+// accept tokens issued by the UAA.
+package uaamiddleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries the given claims.
+func NewContext(ctx context.Context, claims *uaa.Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored in ctx by the middleware, if
+// any.
+func ClaimsFromContext(ctx context.Context) (*uaa.Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*uaa.Claims)
+	return claims, ok
+}
+
+// Validator validates a bearer token and returns its claims.
+type Validator interface {
+	Validate(token string) (*uaa.Claims, error)
+}
+
+// Middleware authenticates requests using the bearer token in their
+// Authorization header.
+type Middleware struct {
+	// Validator validates the bearer token. A *uaa.TokenValidator is
+	// typically used.
+	Validator Validator
+	// RequiredScopes must all be granted to the token. Scopes may contain
+	// wildcards (see uaa.ScopeMatches).
+	RequiredScopes []string
+	// Realm is included in the WWW-Authenticate header of error responses.
+	Realm string
+}
+
+// Handler returns an http.Handler that validates the request's bearer token,
+// enforces the required scopes, and calls next with the token's claims stored
+// in the request context.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, ok := BearerToken(req)
+		if !ok {
+			m.challenge(w, http.StatusUnauthorized, "", "")
+			return
+		}
+
+		claims, err := m.Validator.Validate(token)
+		if err != nil {
+			if _, ok := err.(*uaa.InvalidTokenError); ok {
+				m.challenge(w, http.StatusUnauthorized, "invalid_token", err.Error())
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		if !claims.HasAllScopes(m.RequiredScopes...) {
+			description := fmt.Sprintf("requires scopes %v", strings.Join(m.RequiredScopes, " "))
+			m.challenge(w, http.StatusForbidden, "insufficient_scope", description)
+			return
+		}
+
+		next.ServeHTTP(w, req.WithContext(NewContext(req.Context(), claims)))
+	})
+}
+
+func (m *Middleware) challenge(w http.ResponseWriter, status int, code string, description string) {
+	params := []string{}
+	if m.Realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", m.Realm))
+	}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code))
+	}
+	if description != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", description))
+	}
+	if len(m.RequiredScopes) > 0 {
+		params = append(params, fmt.Sprintf("scope=%q", strings.Join(m.RequiredScopes, " ")))
+	}
+
+	challenge := "Bearer"
+	if len(params) > 0 {
+		challenge += " " + strings.Join(params, ", ")
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// BearerToken returns the bearer token in the request's Authorization header.
+func BearerToken(req *http.Request) (string, bool) {
+	header := req.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
@@ -0,0 +1,106 @@
+package uaamiddleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	"github.com/cloudfoundry-community/go-uaa/uaamiddleware"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+type fakeValidator struct {
+	claims *uaa.Claims
+	err    error
+	token  string
+}
+
+func (v *fakeValidator) Validate(token string) (*uaa.Claims, error) {
+	v.token = token
+	return v.claims, v.err
+}
+
+func TestMiddleware(t *testing.T) {
+	spec.Run(t, "Middleware", testMiddleware, spec.Report(report.Terminal{}))
+}
+
+func testMiddleware(t *testing.T, when spec.G, it spec.S) {
+	var (
+		validator *fakeValidator
+		m         *uaamiddleware.Middleware
+		handler   http.Handler
+		seen      *uaa.Claims
+		called    bool
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		called = false
+		seen = nil
+		validator = &fakeValidator{claims: &uaa.Claims{Subject: "user-id", Scope: []string{"scim.read"}}}
+		m = &uaamiddleware.Middleware{Validator: validator}
+		handler = m.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			called = true
+			seen, _ = uaamiddleware.ClaimsFromContext(req.Context())
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/resource", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	it("passes the claims of a valid token to the next handler", func() {
+		w := serve("Bearer the-token")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(called).To(BeTrue())
+		Expect(validator.token).To(Equal("the-token"))
+		Expect(seen.Subject).To(Equal("user-id"))
+	})
+
+	it("challenges requests without a bearer token", func() {
+		w := serve("Basic dXNlcjpwYXNz")
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		Expect(w.Header().Get("WWW-Authenticate")).To(Equal("Bearer"))
+		Expect(called).To(BeFalse())
+	})
+
+	it("rejects invalid tokens", func() {
+		m.Realm = "api"
+		validator.err = &uaa.InvalidTokenError{Reason: "token has expired"}
+		w := serve("bearer the-token")
+		Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		Expect(w.Header().Get("WWW-Authenticate")).To(Equal(`Bearer realm="api", error="invalid_token", error_description="invalid token: token has expired"`))
+		Expect(called).To(BeFalse())
+	})
+
+	it("returns service unavailable when the token cannot be validated", func() {
+		validator.err = errors.New("connection refused")
+		w := serve("Bearer the-token")
+		Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(called).To(BeFalse())
+	})
+
+	it("enforces the required scopes", func() {
+		m.RequiredScopes = []string{"scim.write"}
+		w := serve("Bearer the-token")
+		Expect(w.Code).To(Equal(http.StatusForbidden))
+		Expect(w.Header().Get("WWW-Authenticate")).To(ContainSubstring(`error="insufficient_scope"`))
+		Expect(w.Header().Get("WWW-Authenticate")).To(ContainSubstring(`scope="scim.write"`))
+		Expect(called).To(BeFalse())
+
+		m.RequiredScopes = []string{"scim.*"}
+		w = serve("Bearer the-token")
+		Expect(w.Code).To(Equal(http.StatusOK))
+	})
+}
@@ -0,0 +1,129 @@
+package uaasync
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// Checkpoint records the operations that have been applied, so that a plan
+// can be applied again after an interruption without repeating them.
+type Checkpoint interface {
+	// Load returns the keys of the completed operations, mapped to the ID of
+	// the user or group each one changed.
+	Load() (map[string]string, error)
+	// Save records that the operation with the given key completed.
+	Save(key string, id string) error
+	// Clear removes the recorded operations once a plan has been applied.
+	Clear() error
+}
+
+// MemoryCheckpoint is a Checkpoint held in memory. It allows a plan to be
+// retried within a process.
+type MemoryCheckpoint struct {
+	mu   sync.Mutex
+	done map[string]string
+}
+
+// NewMemoryCheckpoint returns an empty MemoryCheckpoint.
+func NewMemoryCheckpoint() *MemoryCheckpoint {
+	return &MemoryCheckpoint{done: map[string]string{}}
+}
+
+// Load returns the completed operations.
+func (c *MemoryCheckpoint) Load() (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	done := map[string]string{}
+	for key, id := range c.done {
+		done[key] = id
+	}
+	return done, nil
+}
+
+// Save records a completed operation.
+func (c *MemoryCheckpoint) Save(key string, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.done[key] = id
+	return nil
+}
+
+// Clear removes the completed operations.
+func (c *MemoryCheckpoint) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.done = map[string]string{}
+	return nil
+}
+
+// FileCheckpoint is a Checkpoint stored in a file, one JSON object per
+// completed operation. It allows a plan to be resumed by a later process.
+// The file is removed once the plan has been applied.
+type FileCheckpoint struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+type checkpointEntry struct {
+	Key string `json:"key"`
+	ID  string `json:"id,omitempty"`
+}
+
+// Load reads the completed operations from the file. A missing file has no
+// completed operations.
+func (c *FileCheckpoint) Load() (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	done := map[string]string{}
+	f, err := os.Open(c.Path)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry := checkpointEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A partially written final line is ignored.
+			continue
+		}
+		done[entry.Key] = entry.ID
+	}
+	return done, scanner.Err()
+}
+
+// Save appends a completed operation to the file.
+func (c *FileCheckpoint) Save(key string, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	j, err := json.Marshal(checkpointEntry{Key: key, ID: id})
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(c.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(j, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Clear removes the file.
+func (c *FileCheckpoint) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Remove(c.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
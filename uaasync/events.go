@@ -0,0 +1,42 @@
+package uaasync
+
+// EventType is the outcome of an operation reported in an Event.
+type EventType string
+
+// Event types.
+const (
+	// ResourceCreated reports that a user, group, or membership was created.
+	ResourceCreated EventType = "created"
+	// ResourceUpdated reports that a user or group was updated.
+	ResourceUpdated EventType = "updated"
+	// ResourceDeleted reports that a user, group, or membership was deleted.
+	ResourceDeleted EventType = "deleted"
+	// ResourceSkipped reports that the checkpoint recorded the operation as
+	// completed by an earlier run.
+	ResourceSkipped EventType = "skipped"
+	// ResourceFailed reports that the operation failed; Err is set.
+	ResourceFailed EventType = "failed"
+)
+
+// Event describes the outcome of a single operation during Apply.
+type Event struct {
+	Type      EventType
+	Operation Operation
+	// ID is the ID of the user or group the operation created or changed, if
+	// known.
+	ID string
+	// Err is the error of a failed operation.
+	Err error
+}
+
+// appliedEvent returns the event for an operation that succeeded.
+func appliedEvent(op Operation, id string) Event {
+	event := Event{Type: ResourceUpdated, Operation: op, ID: id}
+	switch op.Action {
+	case ActionCreate:
+		event.Type = ResourceCreated
+	case ActionDelete:
+		event.Type = ResourceDeleted
+	}
+	return event
+}
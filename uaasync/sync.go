@@ -0,0 +1,655 @@
+// Package uaasync synchronizes UAA users and groups with an external source of
+// truth, such as an HR system or a directory. A Syncer takes a snapshot of the
+// desired users and groups from a Source, computes the changes needed to make
+// the UAA match it, and applies them.
+package uaasync
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+)
+
+// DefaultConcurrency is the number of operations a Syncer applies at once
+// when Concurrency is not set.
+const DefaultConcurrency = 4
+
+// Source supplies the desired state of the UAA.
+type Source interface {
+	Snapshot() (*Snapshot, error)
+}
+
+// SourceFunc adapts a function to the Source interface.
+type SourceFunc func() (*Snapshot, error)
+
+// Snapshot returns f().
+func (f SourceFunc) Snapshot() (*Snapshot, error) {
+	return f()
+}
+
+// Snapshot is the desired set of users and groups. Users are matched to UAA
+// users by the Syncer's UserKey and groups are matched by display name.
+// Group members are given by username.
+type Snapshot struct {
+	Users  []uaa.User
+	Groups []Group
+}
+
+// Group is a desired group and the usernames of its members.
+type Group struct {
+	DisplayName string
+	Description string
+	Members     []string
+}
+
+// UserKey is the attribute by which desired users are matched to UAA users.
+type UserKey string
+
+// User keys.
+const (
+	// KeyUsername matches users by username.
+	KeyUsername UserKey = "userName"
+	// KeyExternalID matches users by external ID, their ID in the identity
+	// provider, so that a user whose username changes upstream is renamed
+	// rather than replaced. Every desired user must have an external ID, and
+	// managed UAA users without one are treated as not desired.
+	KeyExternalID UserKey = "externalId"
+)
+
+// of returns the user's key.
+func (k UserKey) of(user uaa.User) string {
+	if k == KeyExternalID {
+		return user.ExternalID
+	}
+	return user.Username
+}
+
+// Kind is the kind of resource an operation changes.
+type Kind string
+
+// Kinds of resources.
+const (
+	KindUser       Kind = "user"
+	KindGroup      Kind = "group"
+	KindMembership Kind = "membership"
+)
+
+// Action is the change an operation makes.
+type Action string
+
+// Actions.
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Operation is a single change to the UAA.
+type Operation struct {
+	Kind   Kind   `json:"kind"`
+	Action Action `json:"action"`
+	// Name is the username of a user, or the display name of a group or of
+	// the group a membership belongs to.
+	Name string `json:"name"`
+	// Member is the username of the member of a membership.
+	Member string `json:"member,omitempty"`
+	// User is the user to create, update, or delete, or the member of a
+	// membership.
+	User *uaa.User `json:"user,omitempty"`
+	// Group is the group to create, update, or delete.
+	Group *uaa.Group `json:"group,omitempty"`
+}
+
+// String describes the operation, e.g. "create membership admins/marcus". It
+// is unique within a plan.
+func (o Operation) String() string {
+	if o.Kind == KindMembership {
+		return fmt.Sprintf("%s %s %s/%s", o.Action, o.Kind, o.Name, o.Member)
+	}
+	return fmt.Sprintf("%s %s %s", o.Action, o.Kind, o.Name)
+}
+
+// Plan is the list of operations needed to bring the UAA in line with a
+// snapshot. A Plan can be marshalled to JSON, reviewed, and applied later.
+type Plan struct {
+	// ID identifies the plan in a checkpoint, so that operations recorded
+	// while applying another plan are not skipped.
+	ID         string      `json:"id"`
+	Operations []Operation `json:"operations"`
+	// UserIDs and GroupIDs map the usernames and group display names known
+	// when the plan was made to their IDs.
+	UserIDs  map[string]string `json:"userIds"`
+	GroupIDs map[string]string `json:"groupIds"`
+}
+
+// Empty returns true if the UAA already matches the snapshot.
+func (p *Plan) Empty() bool {
+	return len(p.Operations) == 0
+}
+
+// OperationError describes an operation that failed.
+type OperationError struct {
+	Operation Operation
+	Err       error
+}
+
+func (e OperationError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Operation, e.Err)
+}
+
+// Result summarizes the application of a plan.
+type Result struct {
+	// Applied are the operations that succeeded.
+	Applied []Operation
+	// Skipped are the operations that the checkpoint recorded as completed
+	// by an earlier run.
+	Skipped []Operation
+	// Errors are the operations that failed.
+	Errors []OperationError
+}
+
+// Syncer synchronizes the UAA with a Source.
+type Syncer struct {
+	API    *uaa.API
+	Source Source
+
+	// UserFilter and GroupFilter are SCIM filters selecting the users and
+	// groups managed by the Syncer, e.g. `origin eq "ldap"`. Resources
+	// outside of them are never changed. If blank, all users or groups are
+	// managed.
+	UserFilter  string
+	GroupFilter string
+	// DeleteUsers and DeleteGroups delete managed users and groups that are
+	// not in the snapshot.
+	DeleteUsers  bool
+	DeleteGroups bool
+	// UserKey is the attribute by which users are matched. Defaults to
+	// KeyUsername.
+	UserKey UserKey
+
+	// Concurrency is the number of operations applied at once. Defaults to
+	// DefaultConcurrency.
+	Concurrency int
+	// Checkpoint records completed operations so that an interrupted Apply
+	// can be resumed. Optional.
+	Checkpoint Checkpoint
+	// Progress is called after each operation is applied or skipped.
+	// Optional.
+	Progress uaa.ProgressFunc
+	// Events receives an Event for each operation as it is applied, skipped,
+	// or fails. Sends block, so the channel must be drained or buffered
+	// while Apply runs; it is not closed when Apply returns. Events for
+	// operations of the same stage may arrive in any order. Optional.
+	Events chan<- Event
+}
+
+// Run takes a snapshot from the source and applies the resulting plan.
+func (s *Syncer) Run() (*Result, error) {
+	plan, err := s.Plan()
+	if err != nil {
+		return nil, err
+	}
+	return s.Apply(plan)
+}
+
+// Plan takes a snapshot from the source and compares it with the UAA. It does
+// not change anything.
+func (s *Syncer) Plan() (*Plan, error) {
+	snapshot, err := s.Source.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	key := s.UserKey
+	if key == "" {
+		key = KeyUsername
+	}
+	for _, user := range snapshot.Users {
+		if key.of(user) == "" {
+			return nil, fmt.Errorf("user %v has no %v", user.Username, key)
+		}
+	}
+	users, err := s.API.ListAllUsers(s.UserFilter, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.API.ListAllGroups(s.GroupFilter, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	plan := diff(snapshot, users, groups, key, s.DeleteUsers, s.DeleteGroups)
+	if plan.ID, err = newPlanID(); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func newPlanID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// checkpointKey identifies the operation in a checkpoint by the plan's ID and
+// a hash of the operation, so that an entry for a different change to the
+// same user or group is not mistaken for it.
+func checkpointKey(planID string, op Operation) (string, error) {
+	j, err := json.Marshal(op)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(j)
+	return planID + "/" + hex.EncodeToString(sum[:]), nil
+}
+
+// Apply applies the plan. Users are created and updated first, then groups,
+// then memberships, and finally users and groups are deleted; within each
+// stage up to Concurrency operations run at once. A failed operation is
+// reported in the result and does not stop the others. If every operation
+// succeeds, the checkpoint is cleared. An error is returned only if the
+// checkpoint cannot be read, written or cleared.
+func (s *Syncer) Apply(plan *Plan) (*Result, error) {
+	state := &applyState{
+		userIDs:  map[string]string{},
+		groupIDs: map[string]string{},
+		result:   &Result{},
+	}
+	for name, id := range plan.UserIDs {
+		state.userIDs[name] = id
+	}
+	for name, id := range plan.GroupIDs {
+		state.groupIDs[name] = id
+	}
+
+	done := map[string]string{}
+	if s.Checkpoint != nil {
+		var err error
+		if done, err = s.Checkpoint.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	state.progress = uaa.NewProgressTracker(len(plan.Operations), s.Progress)
+	state.events = s.Events
+	for _, stage := range stages(plan.Operations) {
+		var pending []Operation
+		for _, op := range stage {
+			key, err := checkpointKey(plan.ID, op)
+			if err != nil {
+				return state.result, err
+			}
+			id, ok := done[key]
+			if !ok {
+				pending = append(pending, op)
+				continue
+			}
+			state.record(op, id)
+			state.result.Skipped = append(state.result.Skipped, op)
+			state.progress.Done(nil)
+			state.emit(Event{Type: ResourceSkipped, Operation: op, ID: id})
+		}
+		if err := s.applyStage(plan.ID, pending, state); err != nil {
+			return state.result, err
+		}
+	}
+	if s.Checkpoint != nil && len(state.result.Errors) == 0 {
+		if err := s.Checkpoint.Clear(); err != nil {
+			return state.result, err
+		}
+	}
+	return state.result, nil
+}
+
+func (s *Syncer) applyStage(planID string, ops []Operation, state *applyState) error {
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	var (
+		wg            sync.WaitGroup
+		sem           = make(chan struct{}, concurrency)
+		checkpointMu  sync.Mutex
+		checkpointErr error
+	)
+	for _, op := range ops {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(op Operation) {
+			defer func() { <-sem; wg.Done() }()
+			id, err := s.apply(op, state)
+			if err != nil {
+				state.fail(op, err)
+				return
+			}
+			state.succeed(op, id)
+			if s.Checkpoint == nil {
+				return
+			}
+			key, err := checkpointKey(planID, op)
+			if err == nil {
+				err = s.Checkpoint.Save(key, id)
+			}
+			if err != nil {
+				checkpointMu.Lock()
+				checkpointErr = err
+				checkpointMu.Unlock()
+			}
+		}(op)
+	}
+	wg.Wait()
+	return checkpointErr
+}
+
+// apply applies the operation and returns the ID of the user or group it
+// created or changed.
+func (s *Syncer) apply(op Operation, state *applyState) (string, error) {
+	switch op.Kind {
+	case KindUser:
+		switch op.Action {
+		case ActionCreate:
+			user, err := s.API.CreateUser(*op.User)
+			if err != nil {
+				return "", err
+			}
+			return user.ID, nil
+		case ActionUpdate:
+			user, err := s.API.UpdateUser(*op.User)
+			if err != nil {
+				return "", err
+			}
+			return user.ID, nil
+		case ActionDelete:
+			_, err := s.API.DeleteUser(op.User.ID)
+			return op.User.ID, err
+		}
+	case KindGroup:
+		switch op.Action {
+		case ActionCreate:
+			group, err := s.API.CreateGroup(*op.Group)
+			if err != nil {
+				return "", err
+			}
+			return group.ID, nil
+		case ActionUpdate:
+			group, err := s.API.UpdateGroup(*op.Group)
+			if err != nil {
+				return "", err
+			}
+			return group.ID, nil
+		case ActionDelete:
+			_, err := s.API.DeleteGroup(op.Group.ID)
+			return op.Group.ID, err
+		}
+	case KindMembership:
+		groupID, userID, err := state.membershipIDs(op)
+		if err != nil {
+			return "", err
+		}
+		switch op.Action {
+		case ActionCreate:
+			return userID, s.API.AddGroupMember(groupID, userID, "USER", op.User.Origin)
+		case ActionDelete:
+			return userID, s.API.RemoveGroupMember(groupID, userID)
+		}
+	}
+	return "", fmt.Errorf("unsupported operation %v", op)
+}
+
+type applyState struct {
+	mu       sync.Mutex
+	userIDs  map[string]string
+	groupIDs map[string]string
+	result   *Result
+	progress *uaa.ProgressTracker
+	events   chan<- Event
+}
+
+// emit sends the event, if events are wanted. It is called without mu held,
+// so that a slow receiver does not stop other operations from being recorded.
+func (st *applyState) emit(event Event) {
+	if st.events != nil {
+		st.events <- event
+	}
+}
+
+func (st *applyState) record(op Operation, id string) {
+	if id == "" || op.Action == ActionDelete {
+		return
+	}
+	switch op.Kind {
+	case KindUser:
+		st.userIDs[op.Name] = id
+	case KindGroup:
+		st.groupIDs[op.Name] = id
+	}
+}
+
+func (st *applyState) succeed(op Operation, id string) {
+	st.mu.Lock()
+	st.record(op, id)
+	st.result.Applied = append(st.result.Applied, op)
+	st.progress.Done(nil)
+	st.mu.Unlock()
+	st.emit(appliedEvent(op, id))
+}
+
+func (st *applyState) fail(op Operation, err error) {
+	st.mu.Lock()
+	st.result.Errors = append(st.result.Errors, OperationError{Operation: op, Err: err})
+	st.progress.Done(err)
+	st.mu.Unlock()
+	st.emit(Event{Type: ResourceFailed, Operation: op, Err: err})
+}
+
+func (st *applyState) membershipIDs(op Operation) (string, string, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	groupID, ok := st.groupIDs[op.Name]
+	if !ok {
+		return "", "", fmt.Errorf("group %v does not exist", op.Name)
+	}
+	userID, ok := st.userIDs[op.Member]
+	if !ok {
+		return "", "", fmt.Errorf("user %v does not exist", op.Member)
+	}
+	return groupID, userID, nil
+}
+
+// stages splits the operations into the groups that must be applied in order.
+func stages(ops []Operation) [][]Operation {
+	stage := func(op Operation) int {
+		switch {
+		case op.Kind == KindUser && op.Action != ActionDelete:
+			return 0
+		case op.Kind == KindGroup && op.Action != ActionDelete:
+			return 1
+		case op.Kind == KindMembership:
+			return 2
+		case op.Kind == KindUser:
+			return 3
+		default:
+			return 4
+		}
+	}
+	result := make([][]Operation, 5)
+	for _, op := range ops {
+		i := stage(op)
+		result[i] = append(result[i], op)
+	}
+	return result
+}
+
+func diff(snapshot *Snapshot, users []uaa.User, groups []uaa.Group, key UserKey, deleteUsers, deleteGroups bool) *Plan {
+	plan := &Plan{UserIDs: map[string]string{}, GroupIDs: map[string]string{}}
+
+	current := map[string]uaa.User{}
+	currentByName := map[string]uaa.User{}
+	usernames := map[string]string{}
+	for _, user := range users {
+		if k := key.of(user); k != "" {
+			current[k] = user
+		}
+		currentByName[user.Username] = user
+		usernames[user.ID] = user.Username
+		plan.UserIDs[user.Username] = user.ID
+	}
+	desired := map[string]uaa.User{}
+	desiredByName := map[string]uaa.User{}
+	for _, user := range snapshot.Users {
+		desired[key.of(user)] = user
+		desiredByName[user.Username] = user
+		existing, ok := current[key.of(user)]
+		if !ok {
+			u := user
+			plan.Operations = append(plan.Operations, Operation{Kind: KindUser, Action: ActionCreate, Name: user.Username, User: &u})
+			continue
+		}
+		if existing.Username != user.Username {
+			// The user is renamed, so memberships refer to the new
+			// username.
+			if plan.UserIDs[existing.Username] == existing.ID {
+				delete(plan.UserIDs, existing.Username)
+			}
+			plan.UserIDs[user.Username] = existing.ID
+			usernames[existing.ID] = user.Username
+		}
+		if updated, changed := mergeUser(existing, user); changed {
+			plan.Operations = append(plan.Operations, Operation{Kind: KindUser, Action: ActionUpdate, Name: user.Username, User: &updated})
+		}
+	}
+	if deleteUsers {
+		for _, user := range users {
+			if _, ok := desired[key.of(user)]; !ok {
+				u := user
+				plan.Operations = append(plan.Operations, Operation{Kind: KindUser, Action: ActionDelete, Name: user.Username, User: &u})
+			}
+		}
+	}
+	// member returns the user a membership refers to.
+	member := func(username string) *uaa.User {
+		user, ok := desiredByName[username]
+		if !ok {
+			user = currentByName[username]
+		}
+		return &uaa.User{Username: username, Origin: user.Origin}
+	}
+
+	currentGroups := map[string]uaa.Group{}
+	for _, group := range groups {
+		currentGroups[group.DisplayName] = group
+		plan.GroupIDs[group.DisplayName] = group.ID
+	}
+	desiredGroups := map[string]bool{}
+	for _, group := range snapshot.Groups {
+		desiredGroups[group.DisplayName] = true
+		existing, ok := currentGroups[group.DisplayName]
+		if !ok {
+			plan.Operations = append(plan.Operations, Operation{
+				Kind:   KindGroup,
+				Action: ActionCreate,
+				Name:   group.DisplayName,
+				Group:  &uaa.Group{DisplayName: group.DisplayName, Description: group.Description},
+			})
+		} else if group.Description != "" && group.Description != existing.Description {
+			updated := existing
+			updated.Description = group.Description
+			updated.Members = nil
+			plan.Operations = append(plan.Operations, Operation{Kind: KindGroup, Action: ActionUpdate, Name: group.DisplayName, Group: &updated})
+		}
+
+		members := map[string]bool{}
+		for _, m := range existing.Members {
+			if username, ok := usernames[m.Value]; ok && (m.Type == "" || m.Type == "USER") {
+				members[username] = true
+			}
+		}
+		wanted := map[string]bool{}
+		for _, username := range group.Members {
+			wanted[username] = true
+			if !members[username] {
+				plan.Operations = append(plan.Operations, Operation{Kind: KindMembership, Action: ActionCreate, Name: group.DisplayName, Member: username, User: member(username)})
+			}
+		}
+		for username := range members {
+			_, keep := desiredByName[username]
+			if !wanted[username] && (keep || !deleteUsers) {
+				plan.Operations = append(plan.Operations, Operation{Kind: KindMembership, Action: ActionDelete, Name: group.DisplayName, Member: username, User: member(username)})
+			}
+		}
+	}
+	if deleteGroups {
+		for _, group := range groups {
+			if !desiredGroups[group.DisplayName] {
+				g := group
+				g.Members = nil
+				plan.Operations = append(plan.Operations, Operation{Kind: KindGroup, Action: ActionDelete, Name: group.DisplayName, Group: &g})
+			}
+		}
+	}
+
+	sort.SliceStable(plan.Operations, func(i, j int) bool {
+		return plan.Operations[i].String() < plan.Operations[j].String()
+	})
+	return plan
+}
+
+// mergeUser applies the attributes set on the desired user to the existing
+// user, and reports whether any of them changed.
+func mergeUser(existing, desired uaa.User) (uaa.User, bool) {
+	merged := existing
+	merged.Groups = nil
+	merged.Approvals = nil
+	changed := false
+	set := func(want, have interface{}, apply func()) {
+		if !reflect.DeepEqual(want, have) {
+			apply()
+			changed = true
+		}
+	}
+	if desired.Username != "" {
+		set(desired.Username, existing.Username, func() { merged.Username = desired.Username })
+	}
+	if desired.ExternalID != "" {
+		set(desired.ExternalID, existing.ExternalID, func() { merged.ExternalID = desired.ExternalID })
+	}
+	if desired.Name != nil {
+		set(*desired.Name, nameOf(existing), func() { merged.Name = desired.Name })
+	}
+	if len(desired.Emails) > 0 {
+		set(emailsOf(desired), emailsOf(existing), func() { merged.Emails = desired.Emails })
+	}
+	if len(desired.PhoneNumbers) > 0 {
+		set(desired.PhoneNumbers, existing.PhoneNumbers, func() { merged.PhoneNumbers = desired.PhoneNumbers })
+	}
+	if desired.Active != nil {
+		set(*desired.Active, existing.Active != nil && *existing.Active, func() { merged.Active = desired.Active })
+	}
+	if desired.Verified != nil {
+		set(*desired.Verified, existing.Verified != nil && *existing.Verified, func() { merged.Verified = desired.Verified })
+	}
+	return merged, changed
+}
+
+func nameOf(user uaa.User) uaa.UserName {
+	if user.Name == nil {
+		return uaa.UserName{}
+	}
+	return *user.Name
+}
+
+func emailsOf(user uaa.User) []string {
+	var emails []string
+	for _, email := range user.Emails {
+		emails = append(emails, email.Value)
+	}
+	return emails
+}
@@ -0,0 +1,327 @@
+package uaasync_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	"github.com/cloudfoundry-community/go-uaa/uaasync"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+// fakeUAA is an in-memory UAA that supports the requests made by a Syncer.
+type fakeUAA struct {
+	mu       sync.Mutex
+	users    map[string]uaa.User
+	groups   map[string]uaa.Group
+	requests []string
+	fail     map[string]bool
+}
+
+func newFakeUAA() *fakeUAA {
+	return &fakeUAA{users: map[string]uaa.User{}, groups: map[string]uaa.Group{}, fail: map[string]bool{}}
+}
+
+func (f *fakeUAA) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	request := req.Method + " " + req.URL.Path
+	if req.Method != http.MethodGet {
+		f.requests = append(f.requests, request)
+	}
+	if f.fail[request] {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == uaa.UsersEndpoint:
+		users := []uaa.User{}
+		for _, user := range f.users {
+			users = append(users, user)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"resources": users, "startIndex": 1, "itemsPerPage": 100, "totalResults": len(users)})
+	case req.Method == http.MethodGet && req.URL.Path == uaa.GroupsEndpoint:
+		groups := []uaa.Group{}
+		for _, group := range f.groups {
+			groups = append(groups, group)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"resources": groups, "startIndex": 1, "itemsPerPage": 100, "totalResults": len(groups)})
+	case req.Method == http.MethodPost && req.URL.Path == uaa.UsersEndpoint:
+		user := uaa.User{}
+		json.Unmarshal(body, &user)
+		user.ID = user.Username + "-id"
+		f.users[user.ID] = user
+		json.NewEncoder(w).Encode(user)
+	case req.Method == http.MethodPut && req.URL.Path == uaa.UsersEndpoint:
+		user := uaa.User{}
+		json.Unmarshal(body, &user)
+		f.users[user.ID] = user
+		json.NewEncoder(w).Encode(user)
+	case req.Method == http.MethodDelete && parts[0] == "Users":
+		delete(f.users, parts[1])
+		w.Write([]byte("{}"))
+	case req.Method == http.MethodPost && req.URL.Path == uaa.GroupsEndpoint:
+		group := uaa.Group{}
+		json.Unmarshal(body, &group)
+		group.ID = group.DisplayName + "-id"
+		f.groups[group.ID] = group
+		json.NewEncoder(w).Encode(group)
+	case req.Method == http.MethodPost && len(parts) == 3 && parts[2] == "members":
+		member := uaa.GroupMember{}
+		json.Unmarshal(body, &member)
+		group := f.groups[parts[1]]
+		group.Members = append(group.Members, member)
+		f.groups[parts[1]] = group
+		w.Write(body)
+	case req.Method == http.MethodDelete && len(parts) == 4 && parts[2] == "members":
+		group := f.groups[parts[1]]
+		members := []uaa.GroupMember{}
+		for _, m := range group.Members {
+			if m.Value != parts[3] {
+				members = append(members, m)
+			}
+		}
+		group.Members = members
+		f.groups[parts[1]] = group
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSync(t *testing.T) {
+	spec.Run(t, "Sync", testSync, spec.Report(report.Terminal{}))
+}
+
+func testSync(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		fake     *fakeUAA
+		syncer   *uaasync.Syncer
+		snapshot *uaasync.Snapshot
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		fake = newFakeUAA()
+		s = httptest.NewServer(fake)
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a := &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+
+		fake.users["marcus-id"] = uaa.User{ID: "marcus-id", Username: "marcus", Emails: []uaa.Email{{Value: "marcus@old.com"}}}
+		fake.users["seneca-id"] = uaa.User{ID: "seneca-id", Username: "seneca"}
+		fake.users["epictetus-id"] = uaa.User{ID: "epictetus-id", Username: "epictetus"}
+		fake.groups["stoics-id"] = uaa.Group{ID: "stoics-id", DisplayName: "stoics", Members: []uaa.GroupMember{
+			{Type: "USER", Value: "seneca-id"},
+			{Type: "USER", Value: "epictetus-id"},
+		}}
+
+		snapshot = &uaasync.Snapshot{
+			Users: []uaa.User{
+				{Username: "marcus", Emails: []uaa.Email{{Value: "marcus@stoicism.com"}}},
+				{Username: "seneca"},
+				{Username: "zeno", Origin: "ldap"},
+			},
+			Groups: []uaasync.Group{
+				{DisplayName: "stoics", Members: []string{"marcus", "seneca", "zeno"}},
+				{DisplayName: "emperors", Members: []string{"marcus"}},
+			},
+		}
+		syncer = &uaasync.Syncer{
+			API:         a,
+			Source:      uaasync.SourceFunc(func() (*uaasync.Snapshot, error) { return snapshot, nil }),
+			DeleteUsers: true,
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("plans the changes without applying them", func() {
+		plan, err := syncer.Plan()
+		Expect(err).NotTo(HaveOccurred())
+		var ops []string
+		for _, op := range plan.Operations {
+			ops = append(ops, op.String())
+		}
+		Expect(ops).To(ConsistOf(
+			"create user zeno",
+			"update user marcus",
+			"delete user epictetus",
+			"create group emperors",
+			"create membership stoics/marcus",
+			"create membership stoics/zeno",
+			"create membership emperors/marcus",
+		))
+		Expect(fake.requests).To(BeEmpty())
+	})
+
+	it("applies the plan", func() {
+		result, err := syncer.Run()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Errors).To(BeEmpty())
+		Expect(result.Applied).To(HaveLen(7))
+		Expect(fake.users).NotTo(HaveKey("epictetus-id"))
+		Expect(fake.users["marcus-id"].Emails[0].Value).To(Equal("marcus@stoicism.com"))
+		Expect(fake.groups["stoics-id"].Members).To(ContainElement(uaa.GroupMember{Origin: "ldap", Type: "USER", Value: "zeno-id"}))
+		Expect(fake.groups["emperors-id"].Members).To(ConsistOf(uaa.GroupMember{Origin: "uaa", Type: "USER", Value: "marcus-id"}))
+
+		plan, err := syncer.Plan()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Empty()).To(BeTrue())
+	})
+
+	it("removes members who are no longer in a group", func() {
+		syncer.DeleteUsers = false
+		snapshot.Groups[0].Members = []string{"marcus"}
+		plan, err := syncer.Plan()
+		Expect(err).NotTo(HaveOccurred())
+		var ops []string
+		for _, op := range plan.Operations {
+			if op.Kind == uaasync.KindMembership {
+				ops = append(ops, op.String())
+			}
+		}
+		Expect(ops).To(ConsistOf(
+			"create membership stoics/marcus",
+			"delete membership stoics/seneca",
+			"delete membership stoics/epictetus",
+			"create membership emperors/marcus",
+		))
+	})
+
+	it("reports failed operations and continues", func() {
+		var last uaa.Progress
+		syncer.Progress = func(p uaa.Progress) { last = p }
+		fake.fail["POST /Users"] = true
+		result, err := syncer.Run()
+		Expect(err).NotTo(HaveOccurred())
+		var failed []string
+		for _, e := range result.Errors {
+			failed = append(failed, e.Operation.String())
+		}
+		Expect(failed).To(ConsistOf("create user zeno", "create membership stoics/zeno"))
+		Expect(result.Applied).To(HaveLen(5))
+		Expect(last.Processed).To(Equal(7))
+		Expect(last.Total).To(Equal(7))
+		Expect(last.Failed).To(Equal(2))
+	})
+
+	it("emits an event for each operation", func() {
+		fake.fail["POST /Users"] = true
+		events := make(chan uaasync.Event, 10)
+		syncer.Events = events
+		_, err := syncer.Run()
+		Expect(err).NotTo(HaveOccurred())
+		close(events)
+		counts := map[uaasync.EventType]int{}
+		for event := range events {
+			counts[event.Type]++
+			if event.Type == uaasync.ResourceFailed {
+				Expect(event.Err).To(HaveOccurred())
+			}
+			if event.Operation.String() == "update user marcus" {
+				Expect(event.Type).To(Equal(uaasync.ResourceUpdated))
+				Expect(event.ID).To(Equal("marcus-id"))
+			}
+		}
+		Expect(counts).To(Equal(map[uaasync.EventType]int{
+			uaasync.ResourceCreated: 3,
+			uaasync.ResourceUpdated: 1,
+			uaasync.ResourceDeleted: 1,
+			uaasync.ResourceFailed:  2,
+		}))
+	})
+
+	when("users are keyed on external ID", func() {
+		it.Before(func() {
+			syncer.UserKey = uaasync.KeyExternalID
+			syncer.DeleteUsers = false
+			fake.users["marcus-id"] = uaa.User{ID: "marcus-id", Username: "marcus", ExternalID: "emp-1"}
+			snapshot.Users = []uaa.User{{Username: "marcus.aurelius", ExternalID: "emp-1"}}
+			snapshot.Groups = []uaasync.Group{{DisplayName: "emperors", Members: []string{"marcus.aurelius"}}}
+		})
+
+		it("renames a user whose username changed upstream", func() {
+			plan, err := syncer.Plan()
+			Expect(err).NotTo(HaveOccurred())
+			var ops []string
+			for _, op := range plan.Operations {
+				ops = append(ops, op.String())
+			}
+			Expect(ops).To(ConsistOf(
+				"update user marcus.aurelius",
+				"create group emperors",
+				"create membership emperors/marcus.aurelius",
+			))
+
+			result, err := syncer.Apply(plan)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Errors).To(BeEmpty())
+			Expect(fake.users["marcus-id"].Username).To(Equal("marcus.aurelius"))
+			Expect(fake.groups["emperors-id"].Members).To(ConsistOf(uaa.GroupMember{Origin: "uaa", Type: "USER", Value: "marcus-id"}))
+		})
+
+		it("requires every desired user to have an external ID", func() {
+			snapshot.Users = append(snapshot.Users, uaa.User{Username: "zeno"})
+			_, err := syncer.Plan()
+			Expect(err).To(MatchError("user zeno has no externalId"))
+		})
+	})
+
+	it("resumes from a checkpoint", func() {
+		dir, err := ioutil.TempDir("", "uaasync")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		syncer.Checkpoint = &uaasync.FileCheckpoint{Path: filepath.Join(dir, "checkpoint")}
+
+		plan, err := syncer.Plan()
+		Expect(err).NotTo(HaveOccurred())
+		fake.fail["POST /Groups/stoics-id/members"] = true
+		result, err := syncer.Apply(plan)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Errors).To(HaveLen(2))
+
+		delete(fake.fail, "POST /Groups/stoics-id/members")
+		fake.requests = nil
+		result, err = syncer.Apply(plan)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Errors).To(BeEmpty())
+		Expect(result.Skipped).To(HaveLen(5))
+		Expect(fake.requests).To(ConsistOf("POST /Groups/stoics-id/members", "POST /Groups/stoics-id/members"))
+		Expect(fake.groups["stoics-id"].Members).To(ContainElement(uaa.GroupMember{Origin: "ldap", Type: "USER", Value: "zeno-id"}))
+
+		_, err = os.Stat(filepath.Join(dir, "checkpoint"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	it("does not skip operations recorded for another plan", func() {
+		syncer.Checkpoint = uaasync.NewMemoryCheckpoint()
+		plan, err := syncer.Plan()
+		Expect(err).NotTo(HaveOccurred())
+		fake.fail["POST /Groups/stoics-id/members"] = true
+		_, err = syncer.Apply(plan)
+		Expect(err).NotTo(HaveOccurred())
+
+		delete(fake.fail, "POST /Groups/stoics-id/members")
+		other := *plan
+		other.ID = "another-plan"
+		result, err := syncer.Apply(&other)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Skipped).To(BeEmpty())
+		Expect(result.Applied).To(HaveLen(len(plan.Operations)))
+	})
+}
@@ -0,0 +1,251 @@
+// Package uaatest provides helpers for end-to-end tests of applications that
+// use a real UAA.
+package uaatest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	"golang.org/x/net/html"
+)
+
+// maxAuthorizeSteps bounds the number of pages the flow visits, so that a
+// misconfigured UAA that redirects in a loop does not hang a test.
+const maxAuthorizeSteps = 20
+
+// AuthorizationCodeFlow logs a user in to a UAA through its web pages and
+// approves a client's request, the way a user would in a browser, to obtain
+// an authorization code. It lets integration tests exercise clients that use
+// the authorization code grant without a browser. It relies on the markup of
+// the UAA's login and approval pages and is not meant for production use.
+type AuthorizationCodeFlow struct {
+	// Target is the URL of the UAA.
+	Target      string
+	ClientID    string
+	RedirectURI string
+	Scopes      []string
+	State       string
+	Username    string
+	Password    string
+	// Client is the HTTP client to use. Its cookie jar and redirect policy
+	// are replaced in a copy. Defaults to an http.Client with the default
+	// transport.
+	Client *http.Client
+}
+
+// Code logs the user in, approves every scope the client requests, and
+// returns the authorization code that the UAA redirects to the redirect URI
+// with. If the UAA redirects with an error instead, Code returns it.
+func (f *AuthorizationCodeFlow) Code() (string, error) {
+	target, err := uaa.BuildTargetURL(f.Target)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{Transport: http.DefaultTransport}
+	if f.Client != nil {
+		copied := *f.Client
+		client = &copied
+	}
+	if client.Jar, err = cookiejar.New(nil); err != nil {
+		return "", err
+	}
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	authorize := *target
+	authorize.Path = strings.TrimRight(authorize.Path, "/") + "/oauth/authorize"
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", f.ClientID)
+	if f.RedirectURI != "" {
+		query.Set("redirect_uri", f.RedirectURI)
+	}
+	if len(f.Scopes) > 0 {
+		query.Set("scope", strings.Join(f.Scopes, " "))
+	}
+	if f.State != "" {
+		query.Set("state", f.State)
+	}
+	authorize.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, authorize.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	submittedLogin := false
+	for step := 0; step < maxAuthorizeSteps; step++ {
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		next, code, err := f.follow(resp, &submittedLogin)
+		resp.Body.Close()
+		if err != nil || code != "" {
+			return code, err
+		}
+		req = next
+	}
+	return "", fmt.Errorf("gave up after %d pages without receiving a code", maxAuthorizeSteps)
+}
+
+// follow returns the request that continues the flow from the response, or
+// the code if the response redirects to the redirect URI. The UAA shows the
+// login form again when the credentials are rejected, so a second login form
+// is reported as an error.
+func (f *AuthorizationCodeFlow) follow(resp *http.Response, submittedLogin *bool) (*http.Request, string, error) {
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		location, err := resp.Location()
+		if err != nil {
+			return nil, "", err
+		}
+		if f.isRedirectURI(location) {
+			code, err := codeFromRedirect(location)
+			return nil, code, err
+		}
+		req, err := http.NewRequest(http.MethodGet, location.String(), nil)
+		return req, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s returned %s", resp.Request.URL, resp.Status)
+	}
+
+	forms, err := parseForms(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, form := range forms {
+		switch {
+		case form.has("username") && form.has("password"):
+			if *submittedLogin {
+				return nil, "", fmt.Errorf("the UAA rejected the credentials for %v", f.Username)
+			}
+			*submittedLogin = true
+			form.values.Set("username", f.Username)
+			form.values.Set("password", f.Password)
+			return form.request(resp.Request.URL)
+		case form.has("user_oauth_approval"):
+			form.values.Set("user_oauth_approval", "true")
+			for name, value := range form.checkboxes {
+				if strings.HasPrefix(name, "scope.") {
+					form.values.Set(name, value)
+				}
+			}
+			return form.request(resp.Request.URL)
+		}
+	}
+	return nil, "", fmt.Errorf("%s is neither a login nor an approval page", resp.Request.URL)
+}
+
+func (f *AuthorizationCodeFlow) isRedirectURI(location *url.URL) bool {
+	if f.RedirectURI == "" {
+		return location.Query().Get("code") != "" || location.Query().Get("error") != ""
+	}
+	redirect, err := url.Parse(f.RedirectURI)
+	if err != nil {
+		return false
+	}
+	return location.Scheme == redirect.Scheme && location.Host == redirect.Host && location.Path == redirect.Path
+}
+
+func codeFromRedirect(location *url.URL) (string, error) {
+	query := location.Query()
+	if e := query.Get("error"); e != "" {
+		if description := query.Get("error_description"); description != "" {
+			return "", fmt.Errorf("%s: %s", e, description)
+		}
+		return "", errors.New(e)
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", fmt.Errorf("the redirect to %v has no code", location)
+	}
+	return code, nil
+}
+
+// form is an HTML form and the values it submits by default.
+type form struct {
+	method     string
+	action     string
+	values     url.Values
+	checkboxes map[string]string
+}
+
+func (f *form) has(name string) bool {
+	_, ok := f.values[name]
+	return ok
+}
+
+func (f *form) request(page *url.URL) (*http.Request, string, error) {
+	action, err := page.Parse(f.action)
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.EqualFold(f.method, http.MethodGet) {
+		action.RawQuery = f.values.Encode()
+		req, err := http.NewRequest(http.MethodGet, action.String(), nil)
+		return req, "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, action.String(), strings.NewReader(f.values.Encode()))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, "", nil
+}
+
+// parseForms returns the forms on the page with their inputs. Unchecked
+// checkboxes are recorded separately so that they can be ticked.
+func parseForms(r io.Reader) ([]*form, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		forms []*form
+		walk  func(n *html.Node, current *form)
+	)
+	walk = func(n *html.Node, current *form) {
+		if n.Type == html.ElementNode {
+			attrs := map[string]string{}
+			checked := false
+			for _, a := range n.Attr {
+				attrs[a.Key] = a.Val
+				if a.Key == "checked" {
+					checked = true
+				}
+			}
+			switch {
+			case n.Data == "form":
+				current = &form{method: attrs["method"], action: attrs["action"], values: url.Values{}, checkboxes: map[string]string{}}
+				forms = append(forms, current)
+			case current != nil && (n.Data == "input" || n.Data == "button") && attrs["name"] != "":
+				switch strings.ToLower(attrs["type"]) {
+				case "checkbox", "radio":
+					if checked {
+						current.values.Add(attrs["name"], attrs["value"])
+					} else {
+						current.checkboxes[attrs["name"]] = attrs["value"]
+					}
+				case "submit":
+					if n.Data == "button" || attrs["value"] != "" {
+						current.values.Set(attrs["name"], attrs["value"])
+					}
+				default:
+					current.values.Add(attrs["name"], attrs["value"])
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, current)
+		}
+	}
+	walk(doc, nil)
+	return forms, nil
+}
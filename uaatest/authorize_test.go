@@ -0,0 +1,113 @@
+package uaatest_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cloudfoundry-community/go-uaa/uaatest"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+// fakeLogin mimics the UAA's login and approval pages.
+type fakeLogin struct {
+	approved url.Values
+}
+
+func (f *fakeLogin) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	req.ParseForm()
+	_, err := req.Cookie("JSESSIONID")
+	loggedIn := err == nil
+	switch req.URL.Path {
+	case "/oauth/authorize":
+		if !loggedIn {
+			http.SetCookie(w, &http.Cookie{Name: "saved_authorize", Value: url.QueryEscape(req.URL.RawQuery), Path: "/"})
+			http.Redirect(w, req, "/login", http.StatusFound)
+			return
+		}
+		if req.Method == http.MethodPost {
+			f.approved = req.PostForm
+			if req.PostForm.Get("user_oauth_approval") != "true" {
+				http.Redirect(w, req, "http://app.example.com/callback?error=access_denied", http.StatusFound)
+				return
+			}
+			http.Redirect(w, req, "http://app.example.com/callback?code=the-code&state=xyz", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, `<html><body><form id="application_authorization" action="/oauth/authorize" method="POST">
+<input type="hidden" name="X-Uaa-Csrf" value="csrf-2">
+<input type="checkbox" name="scope.0" value="scope.openid" checked>
+<input type="checkbox" name="scope.1" value="scope.cloud_controller.read">
+<button type="submit" name="user_oauth_approval" value="true">Authorize</button>
+<button type="submit" name="user_oauth_approval" value="false">Deny</button>
+</form></body></html>`)
+	case "/login":
+		fmt.Fprint(w, `<html><body><form action="/login.do" method="post">
+<input type="hidden" name="X-Uaa-Csrf" value="csrf-1">
+<input name="username" type="text">
+<input name="password" type="password">
+<input type="submit" value="Sign in">
+</form></body></html>`)
+	case "/login.do":
+		if req.PostForm.Get("X-Uaa-Csrf") != "csrf-1" || req.PostForm.Get("username") != "marcus" || req.PostForm.Get("password") != "meditations" {
+			http.Redirect(w, req, "/login?error=login_failure", http.StatusFound)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "session", Path: "/"})
+		saved, _ := req.Cookie("saved_authorize")
+		query, _ := url.QueryUnescape(saved.Value)
+		http.Redirect(w, req, "/oauth/authorize?"+query, http.StatusFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	spec.Run(t, "AuthorizationCodeFlow", testAuthorizationCodeFlow, spec.Report(report.Terminal{}))
+}
+
+func testAuthorizationCodeFlow(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s    *httptest.Server
+		fake *fakeLogin
+		flow *uaatest.AuthorizationCodeFlow
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		fake = &fakeLogin{}
+		s = httptest.NewServer(fake)
+		flow = &uaatest.AuthorizationCodeFlow{
+			Target:      s.URL,
+			ClientID:    "app",
+			RedirectURI: "http://app.example.com/callback",
+			Scopes:      []string{"openid", "cloud_controller.read"},
+			State:       "xyz",
+			Username:    "marcus",
+			Password:    "meditations",
+		}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("logs in and approves every scope", func() {
+		code, err := flow.Code()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(Equal("the-code"))
+		Expect(fake.approved.Get("X-Uaa-Csrf")).To(Equal("csrf-2"))
+		Expect(fake.approved.Get("scope.0")).To(Equal("scope.openid"))
+		Expect(fake.approved.Get("scope.1")).To(Equal("scope.cloud_controller.read"))
+	})
+
+	it("reports rejected credentials", func() {
+		flow.Password = "wrong"
+		_, err := flow.Code()
+		Expect(err).To(MatchError(ContainSubstring("rejected the credentials for marcus")))
+	})
+}
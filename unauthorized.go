@@ -0,0 +1,114 @@
+package uaa
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrInvalidToken is returned when the UAA rejects the API's token as
+// expired or revoked, and a new token did not help.
+var ErrInvalidToken = errors.New("the UAA rejected the token as invalid")
+
+// ErrBadCredentials is returned when the UAA refuses to issue the API a
+// token because the client's or the user's credentials are wrong, so that
+// repeating the request will not help.
+type ErrBadCredentials struct {
+	// Code is the OAuth error code, such as unauthorized or invalid_client.
+	Code        string
+	Description string
+}
+
+func (e ErrBadCredentials) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("the UAA rejected the credentials (%s)", e.Code)
+	}
+	return fmt.Sprintf("the UAA rejected the credentials (%s): %s", e.Code, e.Description)
+}
+
+// oauthError is the body of an OAuth error response.
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// badCredentials returns an ErrBadCredentials if err is the failure of a
+// token request because of wrong credentials, and nil otherwise.
+func badCredentials(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	retrieveErr, ok := err.(*oauth2.RetrieveError)
+	if !ok || retrieveErr.Response == nil {
+		return nil
+	}
+	body := oauthError{}
+	json.Unmarshal(retrieveErr.Body, &body)
+	if body.Error == "invalid_client" || (retrieveErr.Response.StatusCode == http.StatusUnauthorized && body.Error != "invalid_token") {
+		return ErrBadCredentials{Code: body.Error, Description: body.Description}
+	}
+	return nil
+}
+
+// isInvalidToken returns true if the response rejects the request's token as
+// expired or revoked, as opposed to refusing it for lack of authority. The
+// response's body is left unread.
+func isInvalidToken(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	if strings.Contains(resp.Header.Get("WWW-Authenticate"), "invalid_token") {
+		return true
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	e := oauthError{}
+	return json.Unmarshal(body, &e) == nil && e.Error == "invalid_token"
+}
+
+// retryWithNewToken sends the request again with a new token if the UAA
+// rejected the token it was sent with and the API can obtain another.
+func (a *API) retryWithNewToken(client *http.Client, req *http.Request, resp *http.Response, send func() (*http.Response, error)) (*http.Response, error) {
+	if client != a.AuthenticatedClient || !isInvalidToken(resp) {
+		return resp, nil
+	}
+	t, ok := unwrapTransport(client.Transport).(*oauth2.Transport)
+	if !ok {
+		return resp, nil
+	}
+	source, ok := t.Source.(*expiryTokenSource)
+	if !ok || !source.replace() || !rewindBody(req) {
+		return resp, nil
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return send()
+}
+
+// replace obtains a new token in place of the current one, which the UAA has
+// rejected, and returns false if it cannot.
+func (s *expiryTokenSource) replace() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil || s.refresh == nil {
+		return false
+	}
+	token, err := s.fetch(s.token)
+	if err != nil {
+		return false
+	}
+	s.token = token
+	return true
+}
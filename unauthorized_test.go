@@ -0,0 +1,110 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnauthorized(t *testing.T) {
+	spec.Run(t, "Unauthorized", testUnauthorized, spec.Report(report.Terminal{}))
+}
+
+func testUnauthorized(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s              *httptest.Server
+		a              *uaa.API
+		issued         int
+		userRequests   int
+		badCredentials bool
+		rejected       func(token string) (int, string)
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		issued = 0
+		userRequests = 0
+		badCredentials = false
+		rejected = func(string) (int, string) { return 0, "" }
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if req.URL.Path == "/oauth/token" {
+				if badCredentials {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error":"unauthorized","error_description":"Bad credentials"}`))
+					return
+				}
+				issued++
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"access_token": "token-" + strconv.Itoa(issued),
+					"token_type":   "bearer",
+					"expires_in":   3600,
+				})
+				return
+			}
+			userRequests++
+			if status, body := rejected(req.Header.Get("Authorization")); status != 0 {
+				w.WriteHeader(status)
+				w.Write([]byte(body))
+				return
+			}
+			w.Write([]byte(`{"id":"user-id","userName":"marcus"}`))
+		}))
+		var err error
+		a, err = uaa.NewWithClientCredentials(s.URL, "", "client", "secret", uaa.OpaqueToken)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("retries once with a new token when the token is rejected as invalid", func() {
+		rejected = func(token string) (int, string) {
+			if token == "Bearer token-1" {
+				return http.StatusUnauthorized, `{"error":"invalid_token","error_description":"Token has been revoked"}`
+			}
+			return 0, ""
+		}
+		user, err := a.GetUser("user-id")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Username).To(Equal("marcus"))
+		Expect(issued).To(Equal(2))
+		Expect(userRequests).To(Equal(2))
+	})
+
+	it("returns ErrInvalidToken when the new token is rejected too", func() {
+		rejected = func(string) (int, string) {
+			return http.StatusUnauthorized, `{"error":"invalid_token"}`
+		}
+		_, err := a.GetUser("user-id")
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrInvalidToken))
+		Expect(userRequests).To(Equal(2))
+	})
+
+	it("does not retry other 401s", func() {
+		rejected = func(string) (int, string) {
+			return http.StatusUnauthorized, `{"error":"unauthorized","error_description":"Full authentication is required"}`
+		}
+		_, err := a.GetUser("user-id")
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Cause(err)).NotTo(Equal(uaa.ErrInvalidToken))
+		Expect(userRequests).To(Equal(1))
+		Expect(issued).To(Equal(1))
+	})
+
+	it("fails fast with ErrBadCredentials when the credentials are rejected", func() {
+		badCredentials = true
+		_, err := a.GetUser("user-id")
+		Expect(errors.Cause(err)).To(Equal(uaa.ErrBadCredentials{Code: "unauthorized", Description: "Bad credentials"}))
+		Expect(userRequests).To(Equal(0))
+	})
+}
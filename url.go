@@ -2,7 +2,9 @@ package uaa
 
 import (
 	"fmt"
+	"net"
 	"net/url"
+	"regexp"
 	"strings"
 )
 
@@ -24,13 +26,58 @@ func BuildSubdomainURL(target string, zoneID string) (*url.URL, error) {
 		return nil, err
 	}
 
-	if !strings.HasPrefix(url.Hostname(), zoneID) {
-		url.Host = fmt.Sprintf("%s.%s", zoneID, url.Host)
+	if zoneID != "" {
+		addSubdomain(url, zoneID)
 	}
 
 	return url, nil
 }
 
+var subdomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// BuildZoneSubdomainURL returns the URL of the identity zone with the given
+// subdomain on the UAA at target, e.g. https://zone1.uaa.example.com:8443 for
+// the subdomain zone1 and the target uaa.example.com:8443. If the target
+// already starts with the subdomain it is returned unchanged. If the target
+// does not include a scheme, https will be used. An error is returned if the
+// subdomain is not a valid DNS label or the target's host is an IP address.
+func BuildZoneSubdomainURL(target string, subdomain string) (*url.URL, error) {
+	u, err := BuildTargetURL(target)
+	if err != nil {
+		return nil, err
+	}
+	if subdomain == "" {
+		return u, nil
+	}
+
+	subdomain = strings.ToLower(subdomain)
+	if !subdomainPattern.MatchString(subdomain) {
+		return nil, fmt.Errorf("%q is not a valid zone subdomain", subdomain)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("the target %q has no host", target)
+	}
+	if net.ParseIP(u.Hostname()) != nil {
+		return nil, fmt.Errorf("cannot add the zone subdomain to the IP address %v", u.Hostname())
+	}
+	addSubdomain(u, subdomain)
+	return u, nil
+}
+
+// addSubdomain prefixes the URL's host with the subdomain, keeping the port,
+// unless the host already starts with it.
+func addSubdomain(u *url.URL, subdomain string) {
+	host := u.Hostname()
+	if strings.HasPrefix(strings.ToLower(host), strings.ToLower(subdomain)+".") {
+		return
+	}
+	host = subdomain + "." + host
+	if port := u.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	u.Host = host
+}
+
 // urlWithPath copies the URL and sets the path on the copy.
 func urlWithPath(u url.URL, path string) url.URL {
 	u.Path = path
@@ -58,4 +58,52 @@ func testBuildSubdomainURL(t *testing.T, when spec.G, it spec.S) {
 			Expect(url).To(BeNil())
 		})
 	})
+
+	it("only skips the zone ID when the host already starts with it", func() {
+		url, err := uaa.BuildSubdomainURL("https://test.example.com", "te")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(url.String()).To(Equal("https://te.test.example.com"))
+	})
+}
+
+func TestBuildZoneSubdomainURL(t *testing.T) {
+	spec.Run(t, "BuildZoneSubdomainURL", testBuildZoneSubdomainURL, spec.Report(report.Terminal{}))
+}
+
+func testBuildZoneSubdomainURL(t *testing.T, when spec.G, it spec.S) {
+	it.Before(func() {
+		RegisterTestingT(t)
+	})
+
+	it("adds the subdomain to the target's host", func() {
+		testCases := []struct {
+			target    string
+			subdomain string
+			expected  string
+		}{
+			{"https://uaa.example.com", "zone1", "https://zone1.uaa.example.com"},
+			{"uaa.example.com:8443", "zone1", "https://zone1.uaa.example.com:8443"},
+			{"http://localhost:8080/uaa", "Zone1", "http://zone1.localhost:8080/uaa"},
+			{"https://zone1.uaa.example.com", "zone1", "https://zone1.uaa.example.com"},
+			{"https://zone10.uaa.example.com", "zone1", "https://zone1.zone10.uaa.example.com"},
+			{"https://uaa.example.com", "", "https://uaa.example.com"},
+		}
+		for _, tc := range testCases {
+			url, err := uaa.BuildZoneSubdomainURL(tc.target, tc.subdomain)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(url.String()).To(Equal(tc.expected))
+		}
+	})
+
+	it("rejects invalid subdomains", func() {
+		for _, subdomain := range []string{"zone.1", "-zone", "zone_1"} {
+			_, err := uaa.BuildZoneSubdomainURL("https://uaa.example.com", subdomain)
+			Expect(err).To(HaveOccurred())
+		}
+	})
+
+	it("rejects IP addresses", func() {
+		_, err := uaa.BuildZoneSubdomainURL("https://10.0.0.1:8443", "zone1")
+		Expect(err).To(MatchError("cannot add the zone subdomain to the IP address 10.0.0.1"))
+	})
 }
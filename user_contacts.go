@@ -0,0 +1,174 @@
+package uaa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AddEmail adds the email address to the user with the given ID, unless the
+// user already has it. The first address a user has is made primary.
+func (a *API) AddEmail(userID, email string) (*User, error) {
+	return a.modifyUser(userID, func(user *User) error {
+		if email == "" {
+			return errors.New("email cannot be blank")
+		}
+		user.Emails = dedupeEmails(append(user.Emails, Email{Value: email}))
+		ensurePrimaryEmail(user.Emails)
+		return nil
+	})
+}
+
+// RemoveEmail removes the email address from the user with the given ID. If
+// it was the primary address, the first remaining address becomes primary.
+// A user's only address cannot be removed.
+func (a *API) RemoveEmail(userID, email string) (*User, error) {
+	return a.modifyUser(userID, func(user *User) error {
+		emails := dedupeEmails(user.Emails)
+		var kept []Email
+		for _, e := range emails {
+			if !sameEmail(e.Value, email) {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == len(emails) {
+			return fmt.Errorf("user %v does not have email %v", userID, email)
+		}
+		if len(kept) == 0 {
+			return fmt.Errorf("cannot remove the only email of user %v", userID)
+		}
+		user.Emails = kept
+		ensurePrimaryEmail(user.Emails)
+		return nil
+	})
+}
+
+// SetPrimaryEmail makes the email address the primary address of the user
+// with the given ID, adding it if the user does not already have it. Every
+// other address is marked as not primary.
+func (a *API) SetPrimaryEmail(userID, email string) (*User, error) {
+	return a.modifyUser(userID, func(user *User) error {
+		if email == "" {
+			return errors.New("email cannot be blank")
+		}
+		user.Emails = dedupeEmails(append(user.Emails, Email{Value: email}))
+		for i := range user.Emails {
+			primary := sameEmail(user.Emails[i].Value, email)
+			user.Emails[i].Primary = &primary
+		}
+		return nil
+	})
+}
+
+// AddPhoneNumber adds the phone number to the user with the given ID, unless
+// the user already has it. Numbers that differ only in punctuation and
+// spacing are considered the same. The UAA does not record a primary phone
+// number, so there is no phone number equivalent of SetPrimaryEmail.
+func (a *API) AddPhoneNumber(userID, number string) (*User, error) {
+	return a.modifyUser(userID, func(user *User) error {
+		if normalizePhoneNumber(number) == "" {
+			return errors.New("phone number cannot be blank")
+		}
+		user.PhoneNumbers = dedupePhoneNumbers(append(user.PhoneNumbers, PhoneNumber{Value: number}))
+		return nil
+	})
+}
+
+// RemovePhoneNumber removes the phone number from the user with the given ID.
+func (a *API) RemovePhoneNumber(userID, number string) (*User, error) {
+	return a.modifyUser(userID, func(user *User) error {
+		numbers := dedupePhoneNumbers(user.PhoneNumbers)
+		var kept []PhoneNumber
+		for _, p := range numbers {
+			if normalizePhoneNumber(p.Value) != normalizePhoneNumber(number) {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == len(numbers) {
+			return fmt.Errorf("user %v does not have phone number %v", userID, number)
+		}
+		user.PhoneNumbers = kept
+		return nil
+	})
+}
+
+// modifyUser reads the user with the given ID, applies fn, and writes the
+// user back.
+func (a *API) modifyUser(userID string, fn func(*User) error) (*User, error) {
+	if userID == "" {
+		return nil, errors.New("userID cannot be blank")
+	}
+	user, err := a.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(user); err != nil {
+		return nil, err
+	}
+	// Groups and approvals are read-only and are managed elsewhere.
+	user.Groups = nil
+	user.Approvals = nil
+	return a.UpdateUser(*user)
+}
+
+func sameEmail(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// dedupeEmails removes repeated addresses, keeping the first occurrence but
+// marking it primary if any occurrence was.
+func dedupeEmails(emails []Email) []Email {
+	var result []Email
+	for _, email := range emails {
+		found := false
+		for i := range result {
+			if sameEmail(result[i].Value, email.Value) {
+				if email.Primary != nil && *email.Primary {
+					result[i].Primary = email.Primary
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, email)
+		}
+	}
+	return result
+}
+
+// ensurePrimaryEmail marks the first address primary if none is.
+func ensurePrimaryEmail(emails []Email) {
+	for _, email := range emails {
+		if email.Primary != nil && *email.Primary {
+			return
+		}
+	}
+	if len(emails) > 0 {
+		primary := true
+		emails[0].Primary = &primary
+	}
+}
+
+func normalizePhoneNumber(number string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '+' {
+			return r
+		}
+		return -1
+	}, number)
+}
+
+func dedupePhoneNumbers(numbers []PhoneNumber) []PhoneNumber {
+	var result []PhoneNumber
+	seen := map[string]bool{}
+	for _, number := range numbers {
+		key := normalizePhoneNumber(number.Value)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, number)
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUserContacts(t *testing.T) {
+	spec.Run(t, "UserContacts", testUserContacts, spec.Report(report.Terminal{}))
+}
+
+func testUserContacts(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		a       *uaa.API
+		current uaa.User
+		updated *uaa.User
+	)
+
+	primary := func(emails []uaa.Email) []string {
+		var result []string
+		for _, email := range emails {
+			if email.Primary != nil && *email.Primary {
+				result = append(result, email.Value)
+			}
+		}
+		return result
+	}
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		updated = nil
+		yes := true
+		current = uaa.User{
+			ID:           "marcus-id",
+			Username:     "marcus",
+			Emails:       []uaa.Email{{Value: "marcus@stoicism.com", Primary: &yes}, {Value: "m@rome.gov"}},
+			PhoneNumbers: []uaa.PhoneNumber{{Value: "+1 (555) 010-0000"}},
+			Groups:       []uaa.UserGroup{{Display: "stoics"}},
+		}
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodGet {
+				Expect(req.URL.Path).To(Equal(uaa.UsersEndpoint + "/marcus-id"))
+				json.NewEncoder(w).Encode(current)
+				return
+			}
+			Expect(req.Method).To(Equal(http.MethodPut))
+			body, _ := ioutil.ReadAll(req.Body)
+			updated = &uaa.User{}
+			Expect(json.Unmarshal(body, updated)).To(Succeed())
+			w.Write(body)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("adds an email only once", func() {
+		_, err := a.AddEmail("marcus-id", "M@Rome.gov")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Emails).To(HaveLen(2))
+		Expect(updated.Groups).To(BeEmpty())
+	})
+
+	it("moves the primary flag", func() {
+		_, err := a.SetPrimaryEmail("marcus-id", "m@rome.gov")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(primary(updated.Emails)).To(Equal([]string{"m@rome.gov"}))
+	})
+
+	it("makes another email primary when the primary is removed", func() {
+		_, err := a.RemoveEmail("marcus-id", "marcus@stoicism.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Emails).To(HaveLen(1))
+		Expect(primary(updated.Emails)).To(Equal([]string{"m@rome.gov"}))
+	})
+
+	it("refuses to remove the only email", func() {
+		current.Emails = current.Emails[:1]
+		_, err := a.RemoveEmail("marcus-id", "marcus@stoicism.com")
+		Expect(err).To(MatchError("cannot remove the only email of user marcus-id"))
+		Expect(updated).To(BeNil())
+	})
+
+	it("treats differently formatted phone numbers as the same", func() {
+		_, err := a.AddPhoneNumber("marcus-id", "+15550100000")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.PhoneNumbers).To(HaveLen(1))
+
+		_, err = a.RemovePhoneNumber("marcus-id", "+1 555 010 0000")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.PhoneNumbers).To(BeEmpty())
+	})
+}
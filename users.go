@@ -57,6 +57,10 @@ type PhoneNumber struct {
 
 // User is a UAA user
 // http://docs.cloudfoundry.org/api/uaa/version/4.14.0/index.html#get-3.
+//
+// The UAA does not report whether a user's account is locked after failed
+// login attempts, or how many attempts have failed; that is only written to
+// its audit log.
 type User struct {
 	ID                   string        `json:"id,omitempty"`
 	Password             string        `json:"password,omitempty"`
@@ -120,6 +124,40 @@ func (a *API) GetUserByUsername(username, origin, attributes string) (*User, err
 	return &users[0], nil
 }
 
+// GetUserByExternalID gets the user with the given external ID, the user's
+// ID in their identity provider. Unlike a username, the external ID does not
+// change when the user is renamed upstream. If origin is blank and users
+// with the external ID exist in more than one origin, an error is returned.
+func (a *API) GetUserByExternalID(externalID, origin, attributes string) (*User, error) {
+	if externalID == "" {
+		return nil, errors.New("external ID cannot be blank")
+	}
+
+	filter := fmt.Sprintf(`externalId eq "%v"`, externalID)
+	help := fmt.Sprintf("user with external ID %v not found", externalID)
+
+	if origin != "" {
+		filter = fmt.Sprintf(`%s and origin eq "%v"`, filter, origin)
+		help = fmt.Sprintf(`%s in origin %v`, help, origin)
+	}
+
+	users, err := a.ListAllUsers(filter, "", attributes, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, errors.New(help)
+	}
+	if len(users) > 1 && origin == "" {
+		var foundOrigins []string
+		for _, user := range users {
+			foundOrigins = append(foundOrigins, user.Origin)
+		}
+		return nil, fmt.Errorf("Found users with external ID %v in multiple origins [%v].", externalID, strings.Join(foundOrigins, ", "))
+	}
+	return &users[0], nil
+}
+
 // DeactivateUser deactivates the user with the given user ID
 // http://docs.cloudfoundry.org/api/uaa/version/4.14.0/index.html#patch.
 func (a *API) DeactivateUser(userID string, userMetaVersion int) error {
@@ -147,3 +185,16 @@ func (a *API) setActive(active bool, userID string, userMetaVersion int) error {
 	}
 	return a.doJSONWithHeaders(http.MethodPatch, &u, extraHeaders, bytes.NewBuffer([]byte(j)), nil, true)
 }
+
+// ResetUserMFA removes the multi-factor authentication registration of the
+// user with the given user ID, so that a user who has lost their
+// authenticator is asked to register a new one at their next login. The UAA
+// does not report whether a user has registered, so the call succeeds either
+// way.
+func (a *API) ResetUserMFA(userID string) error {
+	if userID == "" {
+		return errors.New("userID cannot be blank")
+	}
+	u := urlWithPath(*a.TargetURL, fmt.Sprintf("%s/%s/mfa", UsersEndpoint, userID))
+	return a.doJSON(http.MethodDelete, &u, nil, nil, true)
+}
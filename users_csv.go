@@ -0,0 +1,332 @@
+package uaa
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DefaultCSVGroupSeparator separates the group names in the groups column of
+// a user CSV file.
+const DefaultCSVGroupSeparator = ";"
+
+var userCSVColumns = []string{"userName", "email", "origin", "givenName", "familyName", "externalId", "groups"}
+
+// UserRecord is a row of a user CSV file.
+type UserRecord struct {
+	Username   string
+	Email      string
+	Origin     string
+	GivenName  string
+	FamilyName string
+	ExternalID string
+	Groups     []string
+}
+
+// User returns the UAA user described by the record. Group memberships are
+// not included; they are managed through the groups.
+func (r UserRecord) User() User {
+	user := User{
+		Username:   r.Username,
+		Origin:     r.Origin,
+		ExternalID: r.ExternalID,
+	}
+	if r.Email != "" {
+		user.Emails = []Email{{Value: r.Email}}
+	}
+	if r.GivenName != "" || r.FamilyName != "" {
+		user.Name = &UserName{GivenName: r.GivenName, FamilyName: r.FamilyName}
+	}
+	return user
+}
+
+// NewUserRecord returns the CSV record for the given user.
+func NewUserRecord(user User) UserRecord {
+	record := UserRecord{
+		Username:   user.Username,
+		Origin:     user.Origin,
+		ExternalID: user.ExternalID,
+	}
+	for _, email := range user.Emails {
+		if record.Email == "" || (email.Primary != nil && *email.Primary) {
+			record.Email = email.Value
+		}
+	}
+	if user.Name != nil {
+		record.GivenName = user.Name.GivenName
+		record.FamilyName = user.Name.FamilyName
+	}
+	for _, group := range user.Groups {
+		record.Groups = append(record.Groups, group.Display)
+	}
+	return record
+}
+
+// UserCSVReader reads user records from a CSV file one at a time, so that
+// large files do not have to be held in memory. The first row must be a
+// header naming the columns; userName is required and email, origin,
+// givenName, familyName, externalId, and groups are optional.
+type UserCSVReader struct {
+	// GroupSeparator separates group names in the groups column. Defaults to
+	// DefaultCSVGroupSeparator.
+	GroupSeparator string
+
+	reader  *csv.Reader
+	columns map[string]int
+	records int
+}
+
+// NewUserCSVReader reads the header row from r and returns a UserCSVReader.
+func NewUserCSVReader(r io.Reader) (*UserCSVReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		for _, known := range userCSVColumns {
+			if strings.EqualFold(strings.TrimSpace(name), known) {
+				columns[known] = i
+			}
+		}
+	}
+	if _, ok := columns["userName"]; !ok {
+		return nil, errors.New("the CSV header must include a userName column")
+	}
+	return &UserCSVReader{reader: reader, columns: columns}, nil
+}
+
+// Read returns the next record, or io.EOF when there are no more records.
+func (r *UserCSVReader) Read() (*UserRecord, error) {
+	row, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	r.records++
+	field := func(name string) string {
+		i, ok := r.columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	record := &UserRecord{
+		Username:   field("userName"),
+		Email:      field("email"),
+		Origin:     field("origin"),
+		GivenName:  field("givenName"),
+		FamilyName: field("familyName"),
+		ExternalID: field("externalId"),
+	}
+	if record.Username == "" {
+		return nil, fmt.Errorf("record %d: userName cannot be blank", r.records)
+	}
+	for _, group := range strings.Split(field("groups"), r.groupSeparator()) {
+		if group = strings.TrimSpace(group); group != "" {
+			record.Groups = append(record.Groups, group)
+		}
+	}
+	return record, nil
+}
+
+func (r *UserCSVReader) groupSeparator() string {
+	if r.GroupSeparator == "" {
+		return DefaultCSVGroupSeparator
+	}
+	return r.GroupSeparator
+}
+
+// UserCSVWriter writes user records to a CSV file.
+type UserCSVWriter struct {
+	// GroupSeparator separates group names in the groups column. Defaults to
+	// DefaultCSVGroupSeparator.
+	GroupSeparator string
+
+	writer      *csv.Writer
+	wroteHeader bool
+}
+
+// NewUserCSVWriter returns a UserCSVWriter that writes to w.
+func NewUserCSVWriter(w io.Writer) *UserCSVWriter {
+	return &UserCSVWriter{writer: csv.NewWriter(w)}
+}
+
+// Write writes the record, preceded by the header row if it is the first
+// record.
+func (w *UserCSVWriter) Write(record UserRecord) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	separator := w.GroupSeparator
+	if separator == "" {
+		separator = DefaultCSVGroupSeparator
+	}
+	return w.writer.Write([]string{
+		record.Username,
+		record.Email,
+		record.Origin,
+		record.GivenName,
+		record.FamilyName,
+		record.ExternalID,
+		strings.Join(record.Groups, separator),
+	})
+}
+
+// Flush writes any buffered data, including the header row if no records
+// were written.
+func (w *UserCSVWriter) Flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *UserCSVWriter) writeHeader() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+	return w.writer.Write(userCSVColumns)
+}
+
+// CSVImportError describes a record that could not be imported.
+type CSVImportError struct {
+	Username string
+	Err      error
+}
+
+func (e CSVImportError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Username, e.Err)
+}
+
+// CSVPartialImport describes a record whose user was created but could not be
+// added to some of its groups. The user exists, so importing the record again
+// fails; add the user to the failed groups instead.
+type CSVPartialImport struct {
+	Username string
+	// FailedGroups maps the name of each group the user was not added to to
+	// the reason.
+	FailedGroups map[string]error
+}
+
+func (p CSVPartialImport) Error() string {
+	names := make([]string, 0, len(p.FailedGroups))
+	for name := range p.FailedGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	reasons := make([]string, len(names))
+	for i, name := range names {
+		reasons[i] = fmt.Sprintf("%v (%v)", name, p.FailedGroups[name])
+	}
+	return fmt.Sprintf("%v: created, but not added to %v", p.Username, strings.Join(reasons, ", "))
+}
+
+// CSVImportResult summarizes a CSV import. Created counts the users that
+// were created and added to all of their groups; users that were created but
+// not added to some of their groups are listed in Partial instead.
+type CSVImportResult struct {
+	Created int
+	Partial []CSVPartialImport
+	Errors  []CSVImportError
+}
+
+// ImportUsersCSV creates a user for each record read from r and adds the user
+// to the record's groups. Records are processed as they are read. A record
+// that fails is reported in the result and the import continues; an error is
+// returned only if the CSV itself cannot be read. If a user is created but
+// cannot be added to a group, the user is still added to the record's other
+// groups and the record is reported in the result's Partial.
+func (a *API) ImportUsersCSV(r io.Reader) (*CSVImportResult, error) {
+	reader, err := NewUserCSVReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CSVImportResult{}
+	groupIDs := map[string]string{}
+	progress := NewProgressTracker(0, a.progress)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				return result, err
+			}
+			result.Errors = append(result.Errors, CSVImportError{Err: err})
+			progress.Done(err)
+			continue
+		}
+
+		failedGroups, err := a.importUserRecord(*record, groupIDs)
+		if err != nil {
+			result.Errors = append(result.Errors, CSVImportError{Username: record.Username, Err: err})
+			progress.Done(err)
+			continue
+		}
+		if len(failedGroups) > 0 {
+			partial := CSVPartialImport{Username: record.Username, FailedGroups: failedGroups}
+			result.Partial = append(result.Partial, partial)
+			progress.Done(partial)
+			continue
+		}
+		result.Created++
+		progress.Done(nil)
+	}
+}
+
+// importUserRecord creates the record's user and adds it to the record's
+// groups. It returns an error if the user cannot be created, and otherwise
+// the reasons the user could not be added to any of the groups.
+func (a *API) importUserRecord(record UserRecord, groupIDs map[string]string) (map[string]error, error) {
+	user, err := a.CreateUser(record.User())
+	if err != nil {
+		return nil, err
+	}
+	failed := map[string]error{}
+	for _, name := range record.Groups {
+		id, ok := groupIDs[name]
+		if !ok {
+			group, err := a.GetGroupByName(name, "")
+			if err != nil {
+				failed[name] = err
+				continue
+			}
+			id = group.ID
+			groupIDs[name] = id
+		}
+		if err := a.AddGroupMember(id, user.ID, "USER", user.Origin); err != nil {
+			failed[name] = err
+		}
+	}
+	return failed, nil
+}
+
+// ExportUsersCSV writes a record for every user matching the filter to w. The
+// users are decoded and written one at a time rather than being held in
+// memory.
+func (a *API) ExportUsersCSV(w io.Writer, filter string) error {
+	writer := NewUserCSVWriter(w)
+	progress := NewProgressTracker(0, a.progress)
+	err := a.EachUser(filter, "", "", "", func(user User) error {
+		if err := writer.Write(NewUserRecord(user)); err != nil {
+			return err
+		}
+		progress.Done(nil)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
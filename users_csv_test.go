@@ -0,0 +1,189 @@
+package uaa_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUsersCSV(t *testing.T) {
+	spec.Run(t, "UsersCSV", testUsersCSV, spec.Report(report.Terminal{}))
+}
+
+func testUsersCSV(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		handler http.Handler
+		a       *uaa.API
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			handler.ServeHTTP(w, req)
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{
+			TargetURL:             u,
+			AuthenticatedClient:   c,
+			UnauthenticatedClient: c,
+		}
+	})
+
+	it.After(func() {
+		if s != nil {
+			s.Close()
+		}
+	})
+
+	when("UserCSVReader", func() {
+		it("reads records using the header to find columns", func() {
+			r, err := uaa.NewUserCSVReader(strings.NewReader("email,UserName,groups\nmarcus@stoicism.com,marcus,philosophy.read; philosophy.write\n"))
+			Expect(err).NotTo(HaveOccurred())
+			record, err := r.Read()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(record.Username).To(Equal("marcus"))
+			Expect(record.Email).To(Equal("marcus@stoicism.com"))
+			Expect(record.Groups).To(Equal([]string{"philosophy.read", "philosophy.write"}))
+			_, err = r.Read()
+			Expect(err).To(Equal(io.EOF))
+		})
+
+		it("requires a userName column", func() {
+			_, err := uaa.NewUserCSVReader(strings.NewReader("email\nmarcus@stoicism.com\n"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		it("rejects records without a username", func() {
+			r, _ := uaa.NewUserCSVReader(strings.NewReader("userName,email\n,marcus@stoicism.com\n"))
+			_, err := r.Read()
+			Expect(err).To(MatchError("record 1: userName cannot be blank"))
+		})
+	})
+
+	when("UserCSVWriter", func() {
+		it("writes a header and records", func() {
+			buf := &bytes.Buffer{}
+			w := uaa.NewUserCSVWriter(buf)
+			Expect(w.Write(uaa.UserRecord{Username: "marcus", Email: "marcus@stoicism.com", Origin: "uaa", Groups: []string{"a", "b"}})).To(Succeed())
+			Expect(w.Flush()).To(Succeed())
+			Expect(buf.String()).To(Equal("userName,email,origin,givenName,familyName,externalId,groups\nmarcus,marcus@stoicism.com,uaa,,,,a;b\n"))
+		})
+	})
+
+	when("ImportUsersCSV()", func() {
+		it("creates users and adds them to their groups", func() {
+			var created []uaa.User
+			var members []string
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch {
+				case req.Method == http.MethodPost && req.URL.Path == uaa.UsersEndpoint:
+					user := uaa.User{}
+					body, _ := ioutil.ReadAll(req.Body)
+					Expect(json.Unmarshal(body, &user)).To(Succeed())
+					if user.Username == "duplicate" {
+						w.WriteHeader(http.StatusConflict)
+						return
+					}
+					user.ID = user.Username + "-id"
+					created = append(created, user)
+					json.NewEncoder(w).Encode(user)
+				case req.Method == http.MethodGet && req.URL.Path == uaa.GroupsEndpoint:
+					Expect(req.URL.Query().Get("filter")).To(Equal(`displayName eq "philosophy.read"`))
+					w.Write([]byte(PaginatedResponse(uaa.Group{ID: "group-id", DisplayName: "philosophy.read"})))
+				case req.Method == http.MethodPost && req.URL.Path == uaa.GroupsEndpoint+"/group-id/members":
+					member := uaa.GroupMember{}
+					body, _ := ioutil.ReadAll(req.Body)
+					Expect(json.Unmarshal(body, &member)).To(Succeed())
+					members = append(members, member.Value)
+					w.Write(body)
+				default:
+					t.Errorf("unexpected request %v %v", req.Method, req.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			var last uaa.Progress
+			uaa.WithProgress(func(p uaa.Progress) { last = p })(a)
+			csv := "userName,email,origin,groups\nmarcus,marcus@stoicism.com,uaa,philosophy.read\nduplicate,,,\nseneca,,,philosophy.read\n"
+			result, err := a.ImportUsersCSV(strings.NewReader(csv))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Created).To(Equal(2))
+			Expect(result.Errors).To(HaveLen(1))
+			Expect(result.Errors[0].Username).To(Equal("duplicate"))
+			Expect(created[0].Emails[0].Value).To(Equal("marcus@stoicism.com"))
+			Expect(members).To(Equal([]string{"marcus-id", "seneca-id"}))
+			Expect(last.Processed).To(Equal(3))
+			Expect(last.Failed).To(Equal(1))
+		})
+
+		it("reports users that could not be added to some of their groups", func() {
+			var members []string
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch {
+				case req.Method == http.MethodPost && req.URL.Path == uaa.UsersEndpoint:
+					user := uaa.User{}
+					body, _ := ioutil.ReadAll(req.Body)
+					Expect(json.Unmarshal(body, &user)).To(Succeed())
+					user.ID = user.Username + "-id"
+					json.NewEncoder(w).Encode(user)
+				case req.Method == http.MethodGet && req.URL.Path == uaa.GroupsEndpoint:
+					if req.URL.Query().Get("filter") == `displayName eq "philosophy.read"` {
+						w.Write([]byte(PaginatedResponse(uaa.Group{ID: "group-id", DisplayName: "philosophy.read"})))
+						return
+					}
+					w.Write([]byte(PaginatedResponse()))
+				case req.Method == http.MethodPost && req.URL.Path == uaa.GroupsEndpoint+"/group-id/members":
+					member := uaa.GroupMember{}
+					body, _ := ioutil.ReadAll(req.Body)
+					Expect(json.Unmarshal(body, &member)).To(Succeed())
+					members = append(members, member.Value)
+					w.Write(body)
+				default:
+					t.Errorf("unexpected request %v %v", req.Method, req.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+
+			csv := "userName,groups\nmarcus,missing;philosophy.read\n"
+			result, err := a.ImportUsersCSV(strings.NewReader(csv))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Created).To(Equal(0))
+			Expect(result.Errors).To(BeEmpty())
+			Expect(result.Partial).To(HaveLen(1))
+			Expect(result.Partial[0].Username).To(Equal("marcus"))
+			Expect(result.Partial[0].FailedGroups).To(HaveKey("missing"))
+			Expect(result.Partial[0].FailedGroups).To(HaveLen(1))
+			Expect(result.Partial[0].Error()).To(HavePrefix("marcus: created, but not added to missing ("))
+			Expect(members).To(Equal([]string{"marcus-id"}))
+		})
+	})
+
+	when("ExportUsersCSV()", func() {
+		it("writes every page of users", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.URL.Path).To(Equal(uaa.UsersEndpoint))
+				if req.URL.Query().Get("startIndex") == "1" {
+					w.Write([]byte(MultiPaginatedResponse(1, 1, 2, uaa.User{Username: "marcus", Groups: []uaa.UserGroup{{Display: "philosophy.read"}}})))
+				} else {
+					w.Write([]byte(MultiPaginatedResponse(2, 1, 2, uaa.User{Username: "seneca"})))
+				}
+			})
+			buf := &bytes.Buffer{}
+			Expect(a.ExportUsersCSV(buf, "")).To(Succeed())
+			Expect(buf.String()).To(Equal("userName,email,origin,givenName,familyName,externalId,groups\nmarcus,,,,,,philosophy.read\nseneca,,,,,,\n"))
+		})
+	})
+}
@@ -269,6 +269,44 @@ func testUsers(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("GetUserByExternalID()", func() {
+		it("returns an error when no external ID is specified", func() {
+			_, err := a.GetUserByExternalID("", "", "")
+			Expect(err).To(MatchError("external ID cannot be blank"))
+		})
+
+		it("looks up a user with a SCIM filter", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.URL.Path).To(Equal("/Users"))
+				Expect(req.URL.Query().Get("filter")).To(Equal(`externalId eq "cn=marcus,ou=people" and origin eq "ldap"`))
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(PaginatedResponse(uaa.User{Username: "marcus", Origin: "ldap", ExternalID: "cn=marcus,ou=people"})))
+			})
+			u, err := a.GetUserByExternalID("cn=marcus,ou=people", "ldap", "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(u.Username).To(Equal("marcus"))
+		})
+
+		it("returns an error if no results are found", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(PaginatedResponse()))
+			})
+			_, err := a.GetUserByExternalID("00u1abc", "okta", "")
+			Expect(err).To(MatchError("user with external ID 00u1abc not found in origin okta"))
+		})
+
+		it("returns an error if users in several origins are found", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.URL.Query().Get("filter")).To(Equal(`externalId eq "00u1abc"`))
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(PaginatedResponse(uaa.User{Username: "marcus", Origin: "okta"}, uaa.User{Username: "marcus", Origin: "azure"})))
+			})
+			_, err := a.GetUserByExternalID("00u1abc", "", "")
+			Expect(err).To(MatchError("Found users with external ID 00u1abc in multiple origins [okta, azure]."))
+		})
+	})
+
 	when("ListAllUsers()", func() {
 		it("can return multiple pages", func() {
 			page1 := MultiPaginatedResponse(1, 1, 2, uaa.User{Username: "marcus", Origin: "uaa"})
@@ -517,6 +555,34 @@ func testUsers(t *testing.T, when spec.G, it spec.S) {
 		})
 	})
 
+	when("ResetUserMFA()", func() {
+		it("returns an error when the userID is empty", func() {
+			err := a.ResetUserMFA("")
+			Expect(err).To(HaveOccurred())
+			Expect(called).To(Equal(0))
+		})
+
+		it("deletes the user's MFA registration", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.Method).To(Equal(http.MethodDelete))
+				Expect(req.URL.Path).To(Equal("/Users/fb5f32e1-5cb3-49e6-93df-6df9c8c8bd70/mfa"))
+				w.WriteHeader(http.StatusOK)
+			})
+			err := a.ResetUserMFA("fb5f32e1-5cb3-49e6-93df-6df9c8c8bd70")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(called).To(Equal(1))
+		})
+
+		it("returns a helpful error the request fails", func() {
+			handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+			err := a.ResetUserMFA("fb5f32e1-5cb3-49e6-93df-6df9c8c8bd70")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("An unknown error occurred while calling"))
+		})
+	})
+
 	when("using user structs", func() {
 		when("verified", func() {
 			it("correctly shows false boolean values", func() {
@@ -0,0 +1,16 @@
+package uaa
+
+// SetVerbose turns the logging of requests and responses to standard output
+// on or off. Unlike setting the Verbose field, it is safe to call while the
+// API is in use.
+func (a *API) SetVerbose(verbose bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Verbose = verbose
+}
+
+func (a *API) verbose() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Verbose
+}
@@ -0,0 +1,132 @@
+package uaa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default WaitForReady settings.
+const (
+	DefaultWaitInitialDelay   = 500 * time.Millisecond
+	DefaultWaitMaxDelay       = 15 * time.Second
+	DefaultWaitRequestTimeout = 10 * time.Second
+)
+
+// WaitOptions configures WaitForReady. The zero value uses the defaults.
+type WaitOptions struct {
+	// Client makes the requests; if it is nil, a client using
+	// http.DefaultTransport is used.
+	Client *http.Client
+	// InitialDelay is the delay after the first failed check, which doubles
+	// with each further failure up to MaxDelay.
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	// RequestTimeout limits each request, so that a UAA that accepts
+	// connections but does not answer is checked again.
+	RequestTimeout time.Duration
+}
+
+// WaitForReady waits until the UAA at target is serving requests, and returns
+// its version. The UAA is ready when /healthz responds 200 OK and /info
+// responds with the server's information; until then both are checked again
+// after a delay that backs off exponentially. WaitForReady gives up when ctx
+// is done, returning an error whose cause, according to errors.Cause from
+// github.com/pkg/errors, is ctx.Err() and that describes the last failure.
+//
+// Deployment pipelines can use it to wait for a UAA they have just started
+// before configuring it.
+func WaitForReady(ctx context.Context, target string, opts WaitOptions) (string, error) {
+	u, err := BuildTargetURL(target)
+	if err != nil {
+		return "", err
+	}
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Transport: http.DefaultTransport}
+	}
+	delay := opts.InitialDelay
+	if delay <= 0 {
+		delay = DefaultWaitInitialDelay
+	}
+	maxDelay := opts.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultWaitMaxDelay
+	}
+	timeout := opts.RequestTimeout
+	if timeout <= 0 {
+		timeout = DefaultWaitRequestTimeout
+	}
+
+	var lastErr error
+	for {
+		version, err := checkReady(ctx, client, u, timeout)
+		if err == nil {
+			return version, nil
+		}
+		// A check cut short by ctx says less about the UAA than the one
+		// before it.
+		if ctx.Err() == nil || lastErr == nil {
+			lastErr = err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", errors.Wrapf(ctx.Err(), "the UAA at %s is not ready (%v)", u.String(), lastErr)
+		case <-timer.C:
+		}
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// checkReady checks /healthz and /info once, and returns the UAA's version.
+func checkReady(ctx context.Context, client *http.Client, target *url.URL, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	healthz := urlWithPath(*target, "/healthz")
+	resp, err := getWithContext(ctx, client, &healthz)
+	if err != nil {
+		return "", err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s responded %s", healthz.String(), resp.Status)
+	}
+
+	info := urlWithPath(*target, "/info")
+	resp, err = getWithContext(ctx, client, &info)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s responded %s", info.String(), resp.Status)
+	}
+	i := Info{}
+	if err := json.NewDecoder(resp.Body).Decode(&i); err != nil {
+		return "", fmt.Errorf("%s responded with invalid JSON: %v", info.String(), err)
+	}
+	return i.App.Version, nil
+}
+
+func getWithContext(ctx context.Context, client *http.Client, u *url.URL) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	return client.Do(req.WithContext(ctx))
+}
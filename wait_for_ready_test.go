@@ -0,0 +1,83 @@
+package uaa_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestWaitForReady(t *testing.T) {
+	spec.Run(t, "WaitForReady", testWaitForReady, spec.Report(report.Terminal{}))
+}
+
+func testWaitForReady(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		checks   int32
+		readyAt  int32
+		infoDown int32
+		opts     uaa.WaitOptions
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		checks = 0
+		readyAt = 3
+		infoDown = 0
+		opts = uaa.WaitOptions{InitialDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/healthz":
+				if atomic.AddInt32(&checks, 1) < atomic.LoadInt32(&readyAt) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte("ok"))
+			case "/info":
+				if atomic.LoadInt32(&infoDown) == 1 {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"app":{"version":"74.4.0"}}`))
+			}
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("waits until the UAA is healthy and returns its version", func() {
+		version, err := uaa.WaitForReady(context.Background(), s.URL, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("74.4.0"))
+		Expect(atomic.LoadInt32(&checks)).To(Equal(int32(3)))
+	})
+
+	it("gives up when the context is done", func() {
+		atomic.StoreInt32(&readyAt, 1000000)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := uaa.WaitForReady(ctx, s.URL, opts)
+		Expect(errors.Cause(err)).To(Equal(context.DeadlineExceeded))
+		Expect(err).To(MatchError(ContainSubstring("503 Service Unavailable")))
+	})
+
+	it("waits for /info as well as /healthz", func() {
+		atomic.StoreInt32(&infoDown, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := uaa.WaitForReady(ctx, s.URL, opts)
+		Expect(err).To(MatchError(ContainSubstring("/info responded 404 Not Found")))
+	})
+}
@@ -0,0 +1,108 @@
+package uaa
+
+import (
+	"time"
+)
+
+// PrincipalType is the kind of principal a token was issued to.
+type PrincipalType string
+
+// The kinds of principal.
+const (
+	// PrincipalUser is a user, whose token was obtained with the password,
+	// authorization code, implicit or another user grant.
+	PrincipalUser PrincipalType = "user"
+	// PrincipalClient is a client acting on its own behalf, whose token was
+	// obtained with the client credentials grant.
+	PrincipalClient PrincipalType = "client"
+)
+
+// Identity describes who the API is authenticated as.
+type Identity struct {
+	Type PrincipalType
+	// ID is the user's ID, or the client's ID.
+	ID string
+	// Name is the username, or the client's ID.
+	Name string
+	// Email is the user's email address, if known.
+	Email string
+	// Origin is the alias of the user's identity provider, or "" for a
+	// client.
+	Origin string
+	// ZoneID is the identity zone that issued the token.
+	ZoneID string
+	// ClientID is the client the token was issued to.
+	ClientID string
+	// GrantType is the grant with which the token was obtained.
+	GrantType string
+	// Scopes are the token's scopes, or a client's authorities.
+	Scopes []string
+	// ExpiresAt is when the token expires, or the zero time if unknown.
+	ExpiresAt time.Time
+}
+
+// WhoAmI returns who the API is authenticated as, whichever grant was used to
+// obtain its token. The identity is read from the claims of a JSON Web Token,
+// or by introspecting an opaque token, and for a user whose token has the
+// openid scope it is completed from the userinfo endpoint.
+func (a *API) WhoAmI() (*Identity, error) {
+	token, err := a.Token()
+	if err != nil {
+		return nil, err
+	}
+	claims, err := DecodeClaims(token.AccessToken)
+	if err != nil {
+		introspection, err := a.IntrospectToken(token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		claims = &introspection.Claims
+	}
+
+	identity := &Identity{
+		ZoneID:    claims.ZoneID,
+		ClientID:  claims.ClientID,
+		GrantType: claims.GrantType,
+		Scopes:    claims.Scope,
+	}
+	if identity.ClientID == "" {
+		identity.ClientID = claims.CID
+	}
+	if claims.Expiry != 0 {
+		identity.ExpiresAt = claims.ExpiresAt()
+	} else if !token.Expiry.IsZero() {
+		identity.ExpiresAt = token.Expiry
+	}
+
+	if claims.UserID == "" && claims.Username == "" {
+		identity.Type = PrincipalClient
+		identity.ID = identity.ClientID
+		identity.Name = identity.ClientID
+		if len(identity.Scopes) == 0 {
+			identity.Scopes = claims.Authorities
+		}
+		return identity, nil
+	}
+
+	identity.Type = PrincipalUser
+	identity.ID = claims.UserID
+	identity.Name = claims.Username
+	identity.Email = claims.Email
+	identity.Origin = claims.Origin
+	if claims.HasScope("openid") {
+		info, err := a.GetMe()
+		if err != nil {
+			return nil, err
+		}
+		if info.UserID != "" {
+			identity.ID = info.UserID
+		}
+		if info.Username != "" {
+			identity.Name = info.Username
+		}
+		if info.Email != "" {
+			identity.Email = info.Email
+		}
+	}
+	return identity, nil
+}
@@ -0,0 +1,120 @@
+package uaa_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"golang.org/x/oauth2"
+)
+
+func TestWhoAmI(t *testing.T) {
+	spec.Run(t, "WhoAmI", testWhoAmI, spec.Report(report.Terminal{}))
+}
+
+func testWhoAmI(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s     *httptest.Server
+		key   *rsa.PrivateKey
+		calls []string
+		exp   time.Time
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		calls = nil
+		exp = time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, req.URL.Path)
+			w.Header().Set("Content-Type", "application/json")
+			switch req.URL.Path {
+			case "/userinfo":
+				w.Write([]byte(`{"user_id":"fb5f32e1-5cb3-49e6-93df-6df9c8c8bd70","sub":"fb5f32e1-5cb3-49e6-93df-6df9c8c8bd70","user_name":"marcus@stoicism.com","email":"marcus@stoicism.com"}`))
+			case "/introspect":
+				fmt.Fprintf(w, `{"active":true,"client_id":"robot","cid":"robot","grant_type":"client_credentials","zid":"uaa","authorities":["scim.read"],"exp":%d}`, exp.Unix())
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	apiWithToken := func(accessToken string) *uaa.API {
+		a, err := uaa.NewWithToken(s.URL, "", oauth2.Token{AccessToken: accessToken, Expiry: exp})
+		Expect(err).NotTo(HaveOccurred())
+		return a
+	}
+
+	it("merges a user's claims with their userinfo", func() {
+		a := apiWithToken(signedJWT(key, "key-1", map[string]interface{}{
+			"user_id":    "fb5f32e1-5cb3-49e6-93df-6df9c8c8bd70",
+			"user_name":  "marcus",
+			"origin":     "uaa",
+			"zid":        "uaa",
+			"client_id":  "cf",
+			"grant_type": "password",
+			"scope":      []string{"openid", "scim.read"},
+			"exp":        exp.Unix(),
+		}))
+		identity, err := a.WhoAmI()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identity).To(Equal(&uaa.Identity{
+			Type:      uaa.PrincipalUser,
+			ID:        "fb5f32e1-5cb3-49e6-93df-6df9c8c8bd70",
+			Name:      "marcus@stoicism.com",
+			Email:     "marcus@stoicism.com",
+			Origin:    "uaa",
+			ZoneID:    "uaa",
+			ClientID:  "cf",
+			GrantType: "password",
+			Scopes:    []string{"openid", "scim.read"},
+			ExpiresAt: exp,
+		}))
+		Expect(calls).To(Equal([]string{"/userinfo"}))
+	})
+
+	it("does not call userinfo without the openid scope", func() {
+		a := apiWithToken(signedJWT(key, "key-1", map[string]interface{}{
+			"user_id":   "fb5f32e1",
+			"user_name": "marcus",
+			"scope":     []string{"scim.read"},
+		}))
+		identity, err := a.WhoAmI()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identity.Type).To(Equal(uaa.PrincipalUser))
+		Expect(identity.Name).To(Equal("marcus"))
+		Expect(identity.ExpiresAt).To(Equal(exp))
+		Expect(calls).To(BeEmpty())
+	})
+
+	it("introspects an opaque client token", func() {
+		a := apiWithToken("opaque-token")
+		identity, err := a.WhoAmI()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(identity).To(Equal(&uaa.Identity{
+			Type:      uaa.PrincipalClient,
+			ID:        "robot",
+			Name:      "robot",
+			ZoneID:    "uaa",
+			ClientID:  "robot",
+			GrantType: "client_credentials",
+			Scopes:    []string{"scim.read"},
+			ExpiresAt: exp,
+		}))
+		Expect(calls).To(Equal([]string{"/introspect"}))
+	})
+}
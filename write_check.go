@@ -0,0 +1,19 @@
+package uaa
+
+// writeChecker is implemented by resources whose fields are checked before
+// they are created or updated.
+type writeChecker interface {
+	checkWrite() error
+}
+
+// checkWrite returns an error if the resource has a field that the UAA would
+// refuse, such as a misspelled grant type, so that the mistake is reported
+// without sending a request. Such fields are not checked when resources are
+// marshaled, so that resources using values added in a newer UAA can still
+// be decoded and encoded.
+func checkWrite(resource interface{}) error {
+	if c, ok := resource.(writeChecker); ok {
+		return c.checkWrite()
+	}
+	return nil
+}
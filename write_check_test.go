@@ -0,0 +1,68 @@
+package uaa_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestWriteCheck(t *testing.T) {
+	spec.Run(t, "WriteCheck", testWriteCheck, spec.Report(report.Terminal{}))
+}
+
+func testWriteCheck(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s        *httptest.Server
+		a        *uaa.API
+		requests int
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		requests = 0
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests++
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("refuses to write a client with an unknown grant type", func() {
+		client := uaa.Client{ClientID: "app", AuthorizedGrantTypes: []string{"client_credential"}}
+		_, err := a.CreateClient(client)
+		Expect(err).To(MatchError(ContainSubstring(`unknown grant type "client_credential"`)))
+		_, err = a.UpdateClient(client)
+		Expect(err).To(MatchError(ContainSubstring(`unknown grant type "client_credential"`)))
+		Expect(requests).To(Equal(0))
+	})
+
+	it("refuses to write an identity provider with an unknown type", func() {
+		provider := uaa.IdentityProvider{OriginKey: "okta", Type: uaa.Origin("odic1.0")}
+		_, err := a.CreateIdentityProvider(provider)
+		Expect(err).To(MatchError(ContainSubstring(`unknown identity provider type "odic1.0"`)))
+		_, err = a.UpdateIdentityProvider(provider)
+		Expect(err).To(MatchError(ContainSubstring(`unknown identity provider type "odic1.0"`)))
+		Expect(requests).To(Equal(0))
+	})
+
+	it("writes resources with known values", func() {
+		_, err := a.CreateClient(uaa.Client{ClientID: "app", AuthorizedGrantTypes: []string{string(uaa.CLIENTCREDENTIALS)}})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = a.CreateIdentityProvider(uaa.IdentityProvider{OriginKey: "okta", Type: uaa.OIDCOrigin})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(Equal(2))
+	})
+}
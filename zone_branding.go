@@ -0,0 +1,39 @@
+package uaa
+
+import "errors"
+
+// UpdateZoneBranding replaces the branding of the identity zone with the
+// given ID, leaving the rest of its configuration as it is, and returns the
+// updated zone.
+func (a *API) UpdateZoneBranding(zoneID string, branding Branding) (*IdentityZone, error) {
+	return a.updateZoneConfig(zoneID, func(config *IdentityZoneConfig) error {
+		config.Branding = &branding
+		return nil
+	})
+}
+
+// UpdateZoneLinks replaces the links of the identity zone with the given ID,
+// such as its home redirect and self-service links, leaving the rest of its
+// configuration as it is, and returns the updated zone.
+func (a *API) UpdateZoneLinks(zoneID string, links IdentityZoneLinks) (*IdentityZone, error) {
+	return a.updateZoneConfig(zoneID, func(config *IdentityZoneConfig) error {
+		config.Links = &links
+		return nil
+	})
+}
+
+// updateZoneConfig reads the identity zone, changes its configuration with
+// update, and writes it back unless update fails.
+func (a *API) updateZoneConfig(zoneID string, update func(config *IdentityZoneConfig) error) (*IdentityZone, error) {
+	if zoneID == "" {
+		return nil, errors.New("zoneID cannot be blank")
+	}
+	zone, err := a.GetIdentityZone(zoneID)
+	if err != nil {
+		return nil, err
+	}
+	if err := update(&zone.Config); err != nil {
+		return nil, err
+	}
+	return a.UpdateIdentityZone(*zone)
+}
@@ -0,0 +1,95 @@
+package uaa_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestZoneBranding(t *testing.T) {
+	spec.Run(t, "ZoneBranding", testZoneBranding, spec.Report(report.Terminal{}))
+}
+
+func testZoneBranding(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		a       *uaa.API
+		updated map[string]interface{}
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		updated = nil
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch req.Method {
+			case http.MethodGet:
+				Expect(req.URL.Path).To(Equal("/identity-zones/tenant"))
+				w.Write([]byte(`{"id":"tenant","subdomain":"tenant","name":"Tenant","config":{
+					"tokenPolicy":{"accessTokenValidity":3600},
+					"links":{"homeRedirect":"https://old.example.com"},
+					"branding":{"companyName":"Old"}
+				}}`))
+			case http.MethodPut:
+				Expect(req.URL.Path).To(Equal("/identity-zones"))
+				body, _ := ioutil.ReadAll(req.Body)
+				Expect(json.Unmarshal(body, &updated)).To(Succeed())
+				w.Write(body)
+			}
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("replaces the branding and keeps the rest of the config", func() {
+		zone, err := a.UpdateZoneBranding("tenant", uaa.Branding{
+			CompanyName:     "Stoa",
+			FooterLegalText: "© Stoa",
+			FooterLinks:     map[string]string{"Terms": "https://stoa.example.com/terms"},
+			Banner:          &uaa.BrandingBanner{Text: "Maintenance tonight", BackgroundColor: "#0071bc"},
+			Consent:         &uaa.BrandingConsent{Text: "I accept", Link: "https://stoa.example.com/terms"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(zone.Config.Branding.CompanyName).To(Equal("Stoa"))
+		Expect(zone.Config.TokenPolicy.AccessTokenValidity).To(Equal(3600))
+		config := updated["config"].(map[string]interface{})
+		Expect(config["branding"]).To(Equal(map[string]interface{}{
+			"companyName":     "Stoa",
+			"footerLegalText": "© Stoa",
+			"footerLinks":     map[string]interface{}{"Terms": "https://stoa.example.com/terms"},
+			"banner":          map[string]interface{}{"text": "Maintenance tonight", "backgroundColor": "#0071bc"},
+			"consent":         map[string]interface{}{"text": "I accept", "link": "https://stoa.example.com/terms"},
+		}))
+		Expect(config["links"]).To(HaveKeyWithValue("homeRedirect", "https://old.example.com"))
+	})
+
+	it("replaces the links", func() {
+		links := uaa.IdentityZoneLinks{HomeRedirect: "https://stoa.example.com"}
+		links.SelfService = uaa.IdentityZoneSelfServiceLinks{SelfServiceLinksEnabled: true, Signup: "https://stoa.example.com/signup"}
+		links.Logout.Whitelist = []string{"https://stoa.example.com/**"}
+		zone, err := a.UpdateZoneLinks("tenant", links)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(zone.Config.Links.HomeRedirect).To(Equal("https://stoa.example.com"))
+		Expect(zone.Config.Links.SelfService.Signup).To(Equal("https://stoa.example.com/signup"))
+		config := updated["config"].(map[string]interface{})
+		Expect(config["branding"]).To(HaveKeyWithValue("companyName", "Old"))
+	})
+
+	it("requires a zone ID", func() {
+		_, err := a.UpdateZoneBranding("", uaa.Branding{})
+		Expect(err).To(HaveOccurred())
+	})
+}
@@ -0,0 +1,83 @@
+package uaa
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AddKey validates the key pair and adds it to the SAML config under the
+// given ID, without changing the active key unless there is none. It fails if
+// the ID is already in use, so that an existing key pair is never replaced.
+func (c *SAMLConfig) AddKey(keyID string, key SAMLKey, now time.Time) error {
+	if keyID == "" {
+		return errors.New("keyID cannot be blank")
+	}
+	if _, ok := c.Keys[keyID]; ok {
+		return fmt.Errorf("the SAML config already has a key with ID %v", keyID)
+	}
+	if err := key.validate(now); err != nil {
+		return err
+	}
+	if c.Keys == nil {
+		c.Keys = map[string]SAMLKey{}
+	}
+	c.Keys[keyID] = key
+	if c.ActiveKeyID == "" {
+		c.ActiveKeyID = keyID
+	}
+	return nil
+}
+
+// RotateZoneSAMLKey adds the key pair to the SAML config of the identity zone
+// with the given ID and makes it the active key, keeping the previous key
+// pairs so that identity providers that have not fetched the new metadata can
+// still verify the zone's signatures. The key pair is checked before the zone
+// is changed: the certificate must be current and match the key.
+func (a *API) RotateZoneSAMLKey(zoneID string, keyID string, key SAMLKey) (*IdentityZone, error) {
+	return a.updateZoneConfig(zoneID, func(config *IdentityZoneConfig) error {
+		if config.SAMLConfig == nil {
+			config.SAMLConfig = &SAMLConfig{}
+		}
+		if err := config.SAMLConfig.AddKey(keyID, key, a.getClock().Now()); err != nil {
+			return err
+		}
+		config.SAMLConfig.ActiveKeyID = keyID
+		return nil
+	})
+}
+
+// validate returns an error unless the certificate is valid at the given time
+// and its public key matches the private key.
+func (k SAMLKey) validate(now time.Time) error {
+	certBlock, _ := pem.Decode([]byte(k.Certificate))
+	if certBlock == nil {
+		return errors.New("the SAML certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return fmt.Errorf("the SAML certificate is invalid: %v", err)
+	}
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return fmt.Errorf("the SAML certificate is only valid from %v to %v", cert.NotBefore, cert.NotAfter)
+	}
+
+	keyBlock, _ := pem.Decode([]byte(k.Key))
+	if keyBlock == nil {
+		return errors.New("the SAML key is not PEM encoded")
+	}
+	if x509.IsEncryptedPEMBlock(keyBlock) {
+		der, err := x509.DecryptPEMBlock(keyBlock, []byte(k.Passphrase))
+		if err != nil {
+			return fmt.Errorf("the SAML key cannot be decrypted: %v", err)
+		}
+		keyBlock = &pem.Block{Type: keyBlock.Type, Bytes: der}
+	}
+	if _, err := tls.X509KeyPair(pem.EncodeToMemory(certBlock), pem.EncodeToMemory(keyBlock)); err != nil {
+		return fmt.Errorf("the SAML key does not match the certificate: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package uaa_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	uaa "github.com/cloudfoundry-community/go-uaa"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+// samlKeyPair returns a PEM encoded private key and a self-signed certificate
+// for it that is valid between the given times.
+func samlKeyPair(notBefore, notAfter time.Time) (*rsa.PrivateKey, string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	Expect(err).NotTo(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "tenant.uaa.example.com"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return key, string(keyPEM), string(certPEM)
+}
+
+func TestZoneSAML(t *testing.T) {
+	spec.Run(t, "ZoneSAML", testZoneSAML, spec.Report(report.Terminal{}))
+}
+
+func testZoneSAML(t *testing.T, when spec.G, it spec.S) {
+	var (
+		s       *httptest.Server
+		a       *uaa.API
+		updated *uaa.IdentityZone
+		now     time.Time
+	)
+
+	it.Before(func() {
+		RegisterTestingT(t)
+		updated = nil
+		now = time.Now()
+		s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch req.Method {
+			case http.MethodGet:
+				w.Write([]byte(`{"id":"tenant","subdomain":"tenant","name":"Tenant","config":{
+					"samlConfig":{"requestSigned":true,"activeKeyId":"old","keys":{"old":{"certificate":"old-cert"}}}
+				}}`))
+			case http.MethodPut:
+				body, _ := ioutil.ReadAll(req.Body)
+				updated = &uaa.IdentityZone{}
+				Expect(json.Unmarshal(body, updated)).To(Succeed())
+				w.Write(body)
+			}
+		}))
+		c := &http.Client{Transport: http.DefaultTransport}
+		u, _ := url.Parse(s.URL)
+		a = &uaa.API{TargetURL: u, AuthenticatedClient: c, UnauthenticatedClient: c}
+	})
+
+	it.After(func() {
+		s.Close()
+	})
+
+	it("adds the key pair and makes it active", func() {
+		_, key, cert := samlKeyPair(now.Add(-time.Hour), now.Add(time.Hour))
+		zone, err := a.RotateZoneSAMLKey("tenant", "new", uaa.SAMLKey{Key: key, Certificate: cert})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(zone.Config.SAMLConfig.ActiveKeyID).To(Equal("new"))
+		Expect(updated.Config.SAMLConfig.RequestSigned).To(BeTrue())
+		Expect(updated.Config.SAMLConfig.Keys).To(HaveKeyWithValue("old", uaa.SAMLKey{Certificate: "old-cert"}))
+		Expect(updated.Config.SAMLConfig.Keys).To(HaveKeyWithValue("new", uaa.SAMLKey{Key: key, Certificate: cert}))
+	})
+
+	it("accepts an encrypted key with its passphrase", func() {
+		rsaKey, _, cert := samlKeyPair(now.Add(-time.Hour), now.Add(time.Hour))
+		block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), []byte("secret"), x509.PEMCipherAES256)
+		Expect(err).NotTo(HaveOccurred())
+		key := string(pem.EncodeToMemory(block))
+		_, err = a.RotateZoneSAMLKey("tenant", "new", uaa.SAMLKey{Key: key, Passphrase: "secret", Certificate: cert})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = a.RotateZoneSAMLKey("tenant", "new", uaa.SAMLKey{Key: key, Passphrase: "wrong", Certificate: cert})
+		Expect(err).To(HaveOccurred())
+	})
+
+	it("does not change the zone if the key pair is invalid", func() {
+		_, key, _ := samlKeyPair(now.Add(-time.Hour), now.Add(time.Hour))
+		_, _, cert := samlKeyPair(now.Add(-time.Hour), now.Add(time.Hour))
+		_, err := a.RotateZoneSAMLKey("tenant", "new", uaa.SAMLKey{Key: key, Certificate: cert})
+		Expect(err).To(MatchError(ContainSubstring("does not match")))
+
+		_, key, cert = samlKeyPair(now.Add(-2*time.Hour), now.Add(-time.Hour))
+		_, err = a.RotateZoneSAMLKey("tenant", "new", uaa.SAMLKey{Key: key, Certificate: cert})
+		Expect(err).To(MatchError(ContainSubstring("only valid")))
+		Expect(updated).To(BeNil())
+	})
+
+	it("does not replace an existing key pair", func() {
+		_, key, cert := samlKeyPair(now.Add(-time.Hour), now.Add(time.Hour))
+		_, err := a.RotateZoneSAMLKey("tenant", "old", uaa.SAMLKey{Key: key, Certificate: cert})
+		Expect(err).To(MatchError(ContainSubstring("already has a key")))
+		Expect(updated).To(BeNil())
+	})
+
+	it("activates the first key added to a config", func() {
+		_, key, cert := samlKeyPair(now.Add(-time.Hour), now.Add(time.Hour))
+		config := &uaa.SAMLConfig{}
+		Expect(config.AddKey("first", uaa.SAMLKey{Key: key, Certificate: cert}, now)).To(Succeed())
+		Expect(config.ActiveKeyID).To(Equal("first"))
+		_, key, cert = samlKeyPair(now.Add(-time.Hour), now.Add(time.Hour))
+		Expect(config.AddKey("second", uaa.SAMLKey{Key: key, Certificate: cert}, now)).To(Succeed())
+		Expect(config.ActiveKeyID).To(Equal("first"))
+	})
+}